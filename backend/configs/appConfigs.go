@@ -4,6 +4,8 @@ import (
 	"errors"
 	"log"
 	"os"
+	"runtime"
+	"strconv"
 
 	"github.com/joho/godotenv"
 	"github.com/markbates/goth"
@@ -20,6 +22,25 @@ type AppConfigs struct {
 	// GITHUBCLIENTID     string
 	// GITHUBCALLBACKURL  string
 	GOOGLECALLBACKURL string
+	// JUDGEWORKERS is the number of submissions judged in parallel.
+	JUDGEWORKERS int
+	// JUDGEGOCACHE is a warmed Go build cache that Go builds start from.
+	JUDGEGOCACHE string
+	// MAXUPLOADMB caps request bodies, which carry problem packages and
+	// test archives.
+	MAXUPLOADMB int
+	// STORAGEBACKEND is where test data and attachments are kept: "local"
+	// keeps them in STORAGEDIR, "s3" in an S3 compatible bucket.
+	STORAGEBACKEND string
+	STORAGEDIR     string
+	S3ENDPOINT     string
+	S3REGION       string
+	S3BUCKET       string
+	S3ACCESSKEY    string
+	S3SECRETKEY    string
+	// S3VIRTUALHOST addresses the bucket as a subdomain of S3ENDPOINT
+	// instead of as the first path segment.
+	S3VIRTUALHOST bool
 }
 
 func SetUpEnv() (AppConfigs, error) {
@@ -40,11 +61,47 @@ func SetUpEnv() (AppConfigs, error) {
 		// GITHUBCLIENTID:     os.Getenv("GITHUBCLIENTID"),
 		// GITHUBCALLBACKURL:  os.Getenv("GITHUB_CALLBACK_URL"),
 		GOOGLECALLBACKURL: os.Getenv("GOOGLE_CALLBACK_URL"),
+		STORAGEBACKEND:    os.Getenv("STORAGE_BACKEND"),
+		STORAGEDIR:        os.Getenv("STORAGE_DIR"),
+		S3ENDPOINT:        os.Getenv("S3_ENDPOINT"),
+		S3REGION:          os.Getenv("S3_REGION"),
+		S3BUCKET:          os.Getenv("S3_BUCKET"),
+		S3ACCESSKEY:       os.Getenv("S3_ACCESS_KEY"),
+		S3SECRETKEY:       os.Getenv("S3_SECRET_KEY"),
+		S3VIRTUALHOST:     os.Getenv("S3_VIRTUAL_HOST") == "true",
+		JUDGEGOCACHE:      os.Getenv("JUDGE_GO_CACHE"),
 	}
 
-	
+	cfg.JUDGEWORKERS = runtime.NumCPU()
+	if v := os.Getenv("JUDGE_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return AppConfigs{}, errors.New("JUDGE_WORKERS must be a positive number")
+		}
+		cfg.JUDGEWORKERS = n
+	}
+
+	cfg.MAXUPLOADMB = 64
+	if v := os.Getenv("MAX_UPLOAD_MB"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return AppConfigs{}, errors.New("MAX_UPLOAD_MB must be a positive number")
+		}
+		cfg.MAXUPLOADMB = n
+	}
+
+	if cfg.STORAGEBACKEND == "" {
+		cfg.STORAGEBACKEND = "local"
+	}
+	if cfg.STORAGEDIR == "" {
+		cfg.STORAGEDIR = "data/blobs"
+	}
 
 	switch {
+	case cfg.STORAGEBACKEND != "local" && cfg.STORAGEBACKEND != "s3":
+		return AppConfigs{}, errors.New("STORAGE_BACKEND must be local or s3")
+	case cfg.STORAGEBACKEND == "s3" && (cfg.S3ENDPOINT == "" || cfg.S3BUCKET == ""):
+		return AppConfigs{}, errors.New("S3_ENDPOINT or S3_BUCKET missing in environment")
 	case cfg.PORT == "":
 		return AppConfigs{}, errors.New("PORT missing in environment")
 	case cfg.DSN == "":
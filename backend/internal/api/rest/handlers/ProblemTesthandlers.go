@@ -1,16 +1,24 @@
 package handlers
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sudankdk/codearena/internal/api/rest"
 	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/harness"
+	"github.com/sudankdk/codearena/internal/judge"
+	"github.com/sudankdk/codearena/internal/problempkg"
 	"github.com/sudankdk/codearena/internal/repo"
 	"github.com/sudankdk/codearena/internal/service"
+	"github.com/sudankdk/codearena/internal/storage"
 	"go.uber.org/zap"
 )
 
@@ -22,10 +30,15 @@ type ProblemTestHandlers struct {
 func SetupProblemTestRoutes(rh *rest.RestHandlers) {
 	app := rh.App
 	svc := service.ProblemTestService{
-		Repo:     repo.NewProblemsRepo(rh.DB),
-		TestRepo: repo.NewTestcase(rh.DB),
-		Auth:     rh.Auth,
-		Config:   rh.Configs,
+		Repo:         repo.NewProblemsRepo(rh.DB),
+		TestRepo:     repo.NewTestcase(rh.DB),
+		LanguageRepo: repo.NewLanguageRepo(rh.DB),
+		UserRepo:     repo.NewUserRepo(rh.DB),
+		RevisionRepo: repo.NewRevisionRepo(rh.DB),
+		Auth:         rh.Auth,
+		Config:       rh.Configs,
+		Data:         storage.TestData{Store: rh.Store},
+		Attachments:  storage.Attachments{Store: rh.Store},
 	}
 	handler := ProblemTestHandlers{
 		svc:    svc,
@@ -33,15 +46,37 @@ func SetupProblemTestRoutes(rh *rest.RestHandlers) {
 	}
 	// priRoutes := app.Group("/problems", rh.Auth.Authorize)
 	priRoutes := app.Group("/problems")
-	priRoutes.Post("", handler.Create)
-	priRoutes.Get("", handler.List)
-	priRoutes.Get(":id", handler.GetProblemByID)
-	priRoutes.Get("/slug/:slug", handler.GetProblemBySlug)
-	priRoutes.Put(":id", handler.Update)
-	priRoutes.Delete(":id", handler.Delete)
+	priRoutes.Post("", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.Create)
+	priRoutes.Get("", rh.Auth.OptionalAuth, handler.List)
+	priRoutes.Get(":id", rh.Auth.OptionalAuth, handler.GetProblemByID)
+	priRoutes.Get("/slug/:slug", rh.Auth.OptionalAuth, handler.GetProblemBySlug)
+	priRoutes.Put(":id", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.Update)
+	priRoutes.Delete(":id", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.Delete)
+	priRoutes.Post("/import", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.ImportPackage)
+	priRoutes.Get(":id/export", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.ExportPackage)
+	priRoutes.Get(":id/attachments", rh.Auth.OptionalAuth, handler.ListAttachments)
+	priRoutes.Get(":id/attachments/:name", rh.Auth.OptionalAuth, handler.DownloadAttachment)
+	priRoutes.Post(":id/attachments", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.AddAttachment)
+	priRoutes.Delete(":id/attachments/:name", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.DeleteAttachment)
+	priRoutes.Get(":id/solutions", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.ListSolutions)
+	priRoutes.Put(":id/solutions", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.SaveSolution)
+	priRoutes.Delete(":id/solutions/*", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.DeleteSolution)
+	priRoutes.Get(":id/generators", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.GetGeneration)
+	priRoutes.Put(":id/generators", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.SaveGenerator)
+	priRoutes.Delete(":id/generators/:name", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.DeleteGenerator)
+	priRoutes.Post(":id/status", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.ChangeStatus)
+	priRoutes.Get(":id/revisions", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.ListRevisions)
+	priRoutes.Get(":id/revisions/diff", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.DiffRevisions)
+	priRoutes.Get(":id/revisions/:number", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.GetRevision)
+	priRoutes.Post(":id/revisions/:number/restore", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.RestoreRevision)
 	testRoutes := app.Group("/testcase")
-	testRoutes.Post("", handler.CreateTestCases)
-	testRoutes.Get(":id", handler.ListTestCasesOfProblems)
+	testRoutes.Post("", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.CreateTestCases)
+	testRoutes.Get(":id", rh.Auth.OptionalAuth, handler.ListTestCasesOfProblems)
+	testRoutes.Get(":id/all", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.ListAllTestCasesOfProblems)
+	testRoutes.Put(":id", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.UpdateTestCase)
+	testRoutes.Post(":id/upload", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.UploadTestCases)
+	testRoutes.Get(":id/input", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.DownloadTestFile("input"))
+	testRoutes.Get(":id/expected", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.DownloadTestFile("expected"))
 
 }
 
@@ -56,8 +91,16 @@ func (u *ProblemTestHandlers) Create(ctx *fiber.Ctx) error {
 
 	u.logger.Info("Creating problem", zap.String("slug", req.Slug))
 	// Call service to create problem
-	err := u.svc.CreateProblem(req)
+	user, err := u.svc.Auth.CurrentUserInfo(ctx)
 	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusUnauthorized, err)
+	}
+	err = u.svc.CreateProblem(req, user.ID)
+	if err != nil {
+		if isInvalidProblem(err) {
+			u.logger.Warn("Invalid problem definition", zap.Error(err))
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
 		if strings.Contains(err.Error(), "slug must be unique") {
 			u.logger.Warn("Problem slug already exists", zap.String("slug", req.Slug))
 			return rest.ErrorMessage(ctx, http.StatusConflict, err)
@@ -73,6 +116,65 @@ func (u *ProblemTestHandlers) Create(ctx *fiber.Ctx) error {
 	})
 }
 
+// ImportPackage creates a problem from a problem package uploaded as the
+// "package" form file.
+func (u *ProblemTestHandlers) ImportPackage(ctx *fiber.Ctx) error {
+	fh, err := ctx.FormFile("package")
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("package file is required"))
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+	defer f.Close()
+
+	u.logger.Info("Importing problem package", zap.String("file", fh.Filename), zap.Int64("size", fh.Size))
+	user, err := u.svc.Auth.CurrentUserInfo(ctx)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusUnauthorized, err)
+	}
+	problem, err := u.svc.ImportPackage(f, fh.Size, user.ID)
+	if err != nil {
+		if isInvalidProblem(err) || errors.Is(err, problempkg.ErrInvalidPackage) {
+			u.logger.Warn("Invalid problem package", zap.String("file", fh.Filename), zap.Error(err))
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		if strings.Contains(err.Error(), "already exists") {
+			return rest.ErrorMessage(ctx, http.StatusConflict, err)
+		}
+		u.logger.Error("Failed to import problem package", zap.String("file", fh.Filename), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	u.logger.Info("Problem package imported", zap.String("slug", problem.Slug))
+	return rest.SuccessMessage(ctx, "Problem imported successfully", map[string]string{
+		"id":   problem.ID.String(),
+		"slug": problem.Slug,
+	})
+}
+
+// ExportPackage downloads a problem as a problem package.
+func (u *ProblemTestHandlers) ExportPackage(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	var buf bytes.Buffer
+	problem, err := u.svc.ExportPackage(id, &buf)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, errors.New("problem not found"))
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		u.logger.Error("Failed to export problem package", zap.String("id", id), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "application/zip")
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.zip"`, problem.Slug))
+	return ctx.Send(buf.Bytes())
+}
+
 func (u *ProblemTestHandlers) List(ctx *fiber.Ctx) error {
 	page, _ := strconv.Atoi(ctx.Query("page", "1"))
 	pageSize, _ := strconv.Atoi(ctx.Query("page_size", "10"))
@@ -92,6 +194,7 @@ func (u *ProblemTestHandlers) List(ctx *fiber.Ctx) error {
 
 	q.Limit = pageSize
 	q.Offset = (page - 1) * pageSize
+	q.Public = !u.svc.Auth.IsAdmin(ctx)
 
 	u.logger.Info("Listing problems", zap.Int("page", page), zap.Int("limit", pageSize), zap.String("search", q.Search), zap.Bool("test-cases", q.Testcases))
 	res, err := u.svc.ListProblems(q)
@@ -114,8 +217,14 @@ func (u *ProblemTestHandlers) GetProblemByID(ctx *fiber.Ctx) error {
 	includeTc := ctx.QueryBool("include_tc", false)
 	u.logger.Info("Fetching problem by ID", zap.String("id", id), zap.Bool("include_tc", includeTc))
 
-	problem, err := u.svc.GetProblemById(id, includeTc)
+	problem, err := u.svc.GetProblemById(id, includeTc, u.svc.Auth.IsAdmin(ctx))
 	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, errors.New("problem not found"))
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
 		u.logger.Error("Failed to fetch problem", zap.String("id", id), zap.Error(err))
 		return rest.InternalError(ctx, err)
 	}
@@ -134,8 +243,11 @@ func (u *ProblemTestHandlers) GetProblemBySlug(ctx *fiber.Ctx) error {
 	includeTc := ctx.QueryBool("include_tc", false)
 	u.logger.Info("Fetching problem by Slug", zap.String("slug", slug), zap.Bool("include_tc", includeTc))
 
-	problem, err := u.svc.GetProblemBySlug(slug, includeTc)
+	problem, err := u.svc.GetProblemBySlug(slug, includeTc, u.svc.Auth.IsAdmin(ctx))
 	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, errors.New("problem not found"))
+		}
 		u.logger.Error("Failed to fetch problem", zap.String("slug", slug), zap.Error(err))
 		return rest.InternalError(ctx, err)
 	}
@@ -152,7 +264,11 @@ func (u *ProblemTestHandlers) CreateTestCases(ctx *fiber.Ctx) error {
 	}
 
 	u.logger.Info("Creating testcase", zap.String("problem_id", req.ProblemID.String()))
-	if err := u.svc.CreateTestCase(req); err != nil {
+	user, err := u.svc.Auth.CurrentUserInfo(ctx)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusUnauthorized, err)
+	}
+	if err := u.svc.CreateTestCase(req, user.ID); err != nil {
 		u.logger.Error("Failed to create testcase", zap.String("problem_id", req.ProblemID.String()), zap.Error(err))
 		return rest.InternalError(ctx, err)
 	}
@@ -161,6 +277,47 @@ func (u *ProblemTestHandlers) CreateTestCases(ctx *fiber.Ctx) error {
 	return rest.SuccessMessage(ctx, "testcase createion", "successful")
 }
 
+// UploadTestCases adds the tests of a zip of NN.in/NN.out pairs, sent as the
+// "archive" form file, to the problem :id. The form value "mode" is append
+// (the default) or replace.
+func (u *ProblemTestHandlers) UploadTestCases(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	fh, err := ctx.FormFile("archive")
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("archive file is required"))
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+	defer f.Close()
+
+	mode := ctx.FormValue("mode")
+	u.logger.Info("Uploading testcases", zap.String("problem_id", id), zap.String("mode", mode), zap.Int64("size", fh.Size))
+	user, err := u.svc.Auth.CurrentUserInfo(ctx)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusUnauthorized, err)
+	}
+	summary, err := u.svc.UploadTestCases(id, f, fh.Size, mode, user.ID)
+	if err != nil {
+		if errors.Is(err, problempkg.ErrInvalidPackage) || errors.Is(err, service.ErrInvalidSubtasks) {
+			u.logger.Warn("Invalid test archive", zap.String("problem_id", id), zap.Error(err))
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		u.logger.Error("Failed to upload testcases", zap.String("problem_id", id), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	u.logger.Info("Testcases uploaded", zap.String("problem_id", id), zap.Int("added", summary.Added), zap.Int64("removed", summary.Removed))
+	return rest.SuccessMessage(ctx, "Testcases uploaded successfully", summary)
+}
+
 func (u *ProblemTestHandlers) ListTestCasesOfProblems(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 	if id == "" {
@@ -169,8 +326,14 @@ func (u *ProblemTestHandlers) ListTestCasesOfProblems(ctx *fiber.Ctx) error {
 	}
 
 	u.logger.Info("Listing testcases for problem", zap.String("problem_id", id))
-	testcases, err := u.svc.ListTestCasesOfProblems(id)
+	testcases, err := u.svc.ListTestCasesOfProblems(id, u.svc.Auth.IsAdmin(ctx))
 	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
 		u.logger.Error("Failed to list testcases", zap.String("problem_id", id), zap.Error(err))
 		return rest.InternalError(ctx, err)
 	}
@@ -179,6 +342,72 @@ func (u *ProblemTestHandlers) ListTestCasesOfProblems(ctx *fiber.Ctx) error {
 	return rest.SuccessMessage(ctx, "Success", testcases)
 }
 
+func (u *ProblemTestHandlers) ListAllTestCasesOfProblems(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	u.logger.Info("Listing all testcases for problem", zap.String("problem_id", id))
+	testcases, err := u.svc.ListAllTestCasesOfProblems(id)
+	if err != nil {
+		u.logger.Error("Failed to list testcases", zap.String("problem_id", id), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	u.logger.Info("Testcases listed successfully", zap.String("problem_id", id), zap.Int("count", len(testcases)))
+	return rest.SuccessMessage(ctx, "Success", testcases)
+}
+
+// DownloadTestFile streams the input or expected output of test :id, which
+// the test listings only preview.
+func (u *ProblemTestHandlers) DownloadTestFile(file string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		id := ctx.Params("id")
+		r, size, err := u.svc.OpenTestFile(id, file)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+			}
+			if strings.Contains(err.Error(), "invalid") {
+				return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+			}
+			u.logger.Error("Failed to open test file", zap.String("id", id), zap.String("file", file), zap.Error(err))
+			return rest.InternalError(ctx, err)
+		}
+
+		ctx.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, id, file))
+		return ctx.SendStream(r, int(size))
+	}
+}
+
+func (u *ProblemTestHandlers) UpdateTestCase(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	var req dto.UpdateTestCaseDTO
+	if err := ctx.BodyParser(&req); err != nil {
+		u.logger.Warn("Invalid update testcase payload", zap.Error(err))
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("invalid payload"))
+	}
+
+	u.logger.Info("Updating testcase", zap.String("id", id))
+	user, err := u.svc.Auth.CurrentUserInfo(ctx)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusUnauthorized, err)
+	}
+	if err := u.svc.UpdateTestCase(id, req, user.ID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		u.logger.Error("Failed to update testcase", zap.String("id", id), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	u.logger.Info("Testcase updated successfully", zap.String("id", id))
+	return rest.SuccessMessage(ctx, "Testcase updated successfully", map[string]string{
+		"id": id,
+	})
+}
+
 func (u *ProblemTestHandlers) Update(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 	if id == "" {
@@ -193,8 +422,16 @@ func (u *ProblemTestHandlers) Update(ctx *fiber.Ctx) error {
 	}
 
 	u.logger.Info("Updating problem", zap.String("id", id))
-	err := u.svc.UpdateProblem(id, req)
+	user, err := u.svc.Auth.CurrentUserInfo(ctx)
 	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusUnauthorized, err)
+	}
+	err = u.svc.UpdateProblem(id, req, user.ID)
+	if err != nil {
+		if isInvalidProblem(err) {
+			u.logger.Warn("Invalid problem update", zap.String("id", id), zap.Error(err))
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
 		if strings.Contains(err.Error(), "not found") {
 			u.logger.Warn("Problem not found", zap.String("id", id))
 			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
@@ -209,6 +446,265 @@ func (u *ProblemTestHandlers) Update(ctx *fiber.Ctx) error {
 	})
 }
 
+// AddAttachment stores the "file" form file on problem :id under its file
+// name, or under the form value "name" when given.
+func (u *ProblemTestHandlers) AddAttachment(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("file is required"))
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	name := ctx.FormValue("name", fh.Filename)
+	contentType := fh.Header.Get(fiber.HeaderContentType)
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	u.logger.Info("Adding attachment", zap.String("problem_id", id), zap.String("name", name), zap.Int64("size", fh.Size))
+	attachment, err := u.svc.AddAttachment(id, name, contentType, data)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAttachment) || strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		u.logger.Error("Failed to add attachment", zap.String("problem_id", id), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Attachment added successfully", attachment)
+}
+
+func (u *ProblemTestHandlers) ListAttachments(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	attachments, err := u.svc.ListAttachments(id, u.svc.Auth.IsAdmin(ctx))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		u.logger.Error("Failed to list attachments", zap.String("problem_id", id), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Success", attachments)
+}
+
+// DownloadAttachment streams an attachment so statements can link to it.
+func (u *ProblemTestHandlers) DownloadAttachment(ctx *fiber.Ctx) error {
+	id, name := ctx.Params("id"), attachmentName(ctx)
+	attachment, r, err := u.svc.OpenAttachment(id, name, u.svc.Auth.IsAdmin(ctx))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		u.logger.Error("Failed to open attachment", zap.String("problem_id", id), zap.String("name", name), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	if attachment.ContentType != "" {
+		ctx.Set(fiber.HeaderContentType, attachment.ContentType)
+	}
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%s"`, attachment.Name))
+	return ctx.SendStream(r, int(attachment.Size))
+}
+
+func (u *ProblemTestHandlers) DeleteAttachment(ctx *fiber.Ctx) error {
+	id, name := ctx.Params("id"), attachmentName(ctx)
+	if err := u.svc.DeleteAttachment(id, name); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		u.logger.Error("Failed to delete attachment", zap.String("problem_id", id), zap.String("name", name), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Attachment deleted successfully", nil)
+}
+
+func (u *ProblemTestHandlers) ListSolutions(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	solutions, err := u.svc.ListSolutions(id)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		u.logger.Error("Failed to list solutions", zap.String("problem_id", id), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Success", solutions)
+}
+
+// SaveSolution adds a reference solution to problem :id, replacing the one
+// with the same name.
+func (u *ProblemTestHandlers) SaveSolution(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	var req dto.SolutionDTO
+	if err := ctx.BodyParser(&req); err != nil {
+		u.logger.Warn("Invalid solution payload", zap.Error(err))
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("invalid payload"))
+	}
+
+	solution, err := u.svc.SaveSolution(id, req)
+	if err != nil {
+		if isInvalidProblem(err) || strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		u.logger.Error("Failed to save solution", zap.String("problem_id", id), zap.String("name", req.Name), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Solution saved successfully", solution)
+}
+
+// DeleteSolution deletes the solution named by the rest of the path, since
+// names such as "accepted/fast.cpp" contain slashes.
+func (u *ProblemTestHandlers) DeleteSolution(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	name, err := url.PathUnescape(ctx.Params("*"))
+	if err != nil {
+		name = ctx.Params("*")
+	}
+	if err := u.svc.DeleteSolution(id, name); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		u.logger.Error("Failed to delete solution", zap.String("problem_id", id), zap.String("name", name), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Solution deleted successfully", nil)
+}
+
+// ChangeStatus moves problem :id to another status of its lifecycle.
+func (u *ProblemTestHandlers) ChangeStatus(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	var req dto.ProblemStatusDTO
+	if err := ctx.BodyParser(&req); err != nil {
+		u.logger.Warn("Invalid problem status payload", zap.Error(err))
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("invalid payload"))
+	}
+	user, err := u.svc.Auth.CurrentUserInfo(ctx)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusUnauthorized, err)
+	}
+
+	u.logger.Info("Changing problem status", zap.String("id", id), zap.String("status", req.Status), zap.String("by", user.ID.String()))
+	problem, err := u.svc.ChangeStatus(id, req, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotReviewer):
+			return rest.ErrorMessage(ctx, http.StatusForbidden, err)
+		case errors.Is(err, service.ErrInvalidTransition), strings.Contains(err.Error(), "invalid"):
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		case strings.Contains(err.Error(), "not found"):
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		u.logger.Error("Failed to change problem status", zap.String("id", id), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Problem status changed successfully", problem)
+}
+
+// GetGeneration returns the generation script and generators of problem :id.
+func (u *ProblemTestHandlers) GetGeneration(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	generation, err := u.svc.GetGeneration(id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		u.logger.Error("Failed to load generators", zap.String("problem_id", id), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Success", generation)
+}
+
+// SaveGenerator adds a test generator to problem :id, replacing the one
+// with the same name.
+func (u *ProblemTestHandlers) SaveGenerator(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	var req dto.GeneratorDTO
+	if err := ctx.BodyParser(&req); err != nil {
+		u.logger.Warn("Invalid generator payload", zap.Error(err))
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("invalid payload"))
+	}
+
+	if err := u.svc.SaveGenerator(id, req); err != nil {
+		if isInvalidProblem(err) || strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		u.logger.Error("Failed to save generator", zap.String("problem_id", id), zap.String("name", req.Name), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Generator saved successfully", map[string]string{
+		"name": req.Name,
+	})
+}
+
+func (u *ProblemTestHandlers) DeleteGenerator(ctx *fiber.Ctx) error {
+	id, name := ctx.Params("id"), ctx.Params("name")
+	if err := u.svc.DeleteGenerator(id, name); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		u.logger.Error("Failed to delete generator", zap.String("problem_id", id), zap.String("name", name), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Generator deleted successfully", nil)
+}
+
+// attachmentName returns the :name parameter, which clients URL-encode.
+func attachmentName(ctx *fiber.Ctx) string {
+	name, err := url.PathUnescape(ctx.Params("name"))
+	if err != nil {
+		return ctx.Params("name")
+	}
+	return name
+}
+
+// isInvalidProblem reports errors caused by a bad problem definition.
+func isInvalidProblem(err error) bool {
+	return errors.Is(err, judge.ErrUnsupportedLanguage) ||
+		errors.Is(err, service.ErrInvalidLimits) ||
+		errors.Is(err, service.ErrInvalidChecker) ||
+		errors.Is(err, service.ErrInvalidInteractor) ||
+		errors.Is(err, service.ErrInvalidSubtasks) ||
+		errors.Is(err, service.ErrInvalidValidator) ||
+		errors.Is(err, service.ErrInvalidSolution) ||
+		errors.Is(err, service.ErrInvalidGenerator) ||
+		errors.Is(err, problempkg.ErrInvalidScript) ||
+		errors.Is(err, harness.ErrInvalidSignature)
+}
+
 func (u *ProblemTestHandlers) Delete(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 	if id == "" {
@@ -230,3 +726,74 @@ func (u *ProblemTestHandlers) Delete(ctx *fiber.Ctx) error {
 	u.logger.Info("Problem deleted successfully", zap.String("id", id))
 	return rest.SuccessMessage(ctx, "Problem deleted successfully", nil)
 }
+
+// ListRevisions lists the revisions of problem :id, newest first.
+func (u *ProblemTestHandlers) ListRevisions(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	revisions, err := u.svc.ListRevisions(id)
+	if err != nil {
+		return u.revisionError(ctx, id, err)
+	}
+	return rest.SuccessMessage(ctx, "Success", revisions)
+}
+
+// GetRevision returns revision :number of problem :id with its statement
+// and tests.
+func (u *ProblemTestHandlers) GetRevision(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	number, err := ctx.ParamsInt("number")
+	if err != nil || number < 1 {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("invalid revision number"))
+	}
+	revision, err := u.svc.GetRevision(id, number)
+	if err != nil {
+		return u.revisionError(ctx, id, err)
+	}
+	return rest.SuccessMessage(ctx, "Success", revision)
+}
+
+// DiffRevisions compares the revisions ?from and ?to of problem :id.
+func (u *ProblemTestHandlers) DiffRevisions(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	from, to := ctx.QueryInt("from"), ctx.QueryInt("to")
+	if from < 1 || to < 1 {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("from and to must be revision numbers"))
+	}
+	diff, err := u.svc.DiffRevisions(id, from, to)
+	if err != nil {
+		return u.revisionError(ctx, id, err)
+	}
+	return rest.SuccessMessage(ctx, "Success", diff)
+}
+
+// RestoreRevision brings problem :id back to revision :number and returns
+// the revision this records.
+func (u *ProblemTestHandlers) RestoreRevision(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	number, err := ctx.ParamsInt("number")
+	if err != nil || number < 1 {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("invalid revision number"))
+	}
+	user, err := u.svc.Auth.CurrentUserInfo(ctx)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusUnauthorized, err)
+	}
+
+	u.logger.Info("Restoring problem revision", zap.String("problem_id", id), zap.Int("revision", number), zap.String("by", user.ID.String()))
+	revision, err := u.svc.RestoreRevision(id, number, user.ID)
+	if err != nil {
+		return u.revisionError(ctx, id, err)
+	}
+	return rest.SuccessMessage(ctx, "Revision restored successfully", revision)
+}
+
+func (u *ProblemTestHandlers) revisionError(ctx *fiber.Ctx, id string, err error) error {
+	switch {
+	case strings.Contains(err.Error(), "invalid"):
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	case strings.Contains(err.Error(), "not found"):
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+	u.logger.Error("Problem revision request failed", zap.String("problem_id", id), zap.Error(err))
+	return rest.InternalError(ctx, err)
+}
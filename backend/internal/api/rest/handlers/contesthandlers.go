@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/sudankdk/codearena/internal/api/rest"
 	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/helper"
 	"github.com/sudankdk/codearena/internal/repo"
 	"github.com/sudankdk/codearena/internal/service"
 	"go.uber.org/zap"
@@ -15,6 +19,7 @@ import (
 
 type ContestHandlers struct {
 	svc    service.ContestService
+	auth   helper.Auth
 	logger *zap.Logger
 }
 
@@ -29,26 +34,30 @@ func SetupContestRoutes(rh *rest.RestHandlers) {
 	}
 	handler := ContestHandlers{
 		svc:    svc,
+		auth:   rh.Auth,
 		logger: rh.Logger,
 	}
 
 	// Public routes
 	app.Get("/contests", handler.ListContests)
 	app.Get("/contests/:id", handler.GetContestByID)
-	app.Get("/contests/:id/problems", handler.GetContestProblems)
+	app.Get("/contests/:id/problems", rh.Auth.OptionalAuth, handler.GetContestProblems)
 	app.Get("/contests/:id/leaderboard", handler.GetContestLeaderboard)
 	app.Get("/contests/:id/participants", handler.GetContestParticipants)
 	app.Get("/leaderboard/global", handler.GetGlobalLeaderboard)
+	app.Get("/users/:id/rating-history", handler.GetRatingHistory)
 
 	// Protected routes (require authentication)
 	contestRoutes := app.Group("/contests", rh.Auth.Authorize)
 	contestRoutes.Post("", handler.CreateContest)
+	contestRoutes.Put("/:id", rh.Auth.AdminOnly, handler.UpdateContest)
 	contestRoutes.Post("/:id/problems", handler.AddProblemToContest)
 	contestRoutes.Delete("/:id/problems/:problemId", handler.RemoveProblemFromContest)
 	contestRoutes.Post("/:id/register", handler.RegisterParticipant)
 	contestRoutes.Delete("/:id/register", handler.UnregisterParticipant)
 	contestRoutes.Get("/:id/registration-status", handler.CheckRegistrationStatus)
-	contestRoutes.Post("/:id/finalize", handler.FinalizeContestRankings)
+	contestRoutes.Post("/:id/finalize", rh.Auth.AdminOnly, handler.FinalizeContestRankings)
+	contestRoutes.Post("/:id/rollback", rh.Auth.AdminOnly, handler.RollbackFinalization)
 }
 
 func (ch *ContestHandlers) CreateContest(ctx *fiber.Ctx) error {
@@ -61,6 +70,10 @@ func (ch *ContestHandlers) CreateContest(ctx *fiber.Ctx) error {
 
 	ch.logger.Info("Creating contest", zap.String("title", req.Title))
 	contest, err := ch.svc.CreateContest(req)
+	if errors.Is(err, service.ErrInvalidScoringMode) || errors.Is(err, service.ErrInvalidScoringConfig) ||
+		errors.Is(err, service.ErrInvalidContestTimes) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
 	if err != nil {
 		ch.logger.Error("Failed to create contest", zap.Error(err))
 		return rest.InternalError(ctx, err)
@@ -70,6 +83,36 @@ func (ch *ContestHandlers) CreateContest(ctx *fiber.Ctx) error {
 	return rest.SuccessMessage(ctx, "Contest created successfully", contest)
 }
 
+func (ch *ContestHandlers) UpdateContest(ctx *fiber.Ctx) error {
+	idStr := ctx.Params("id")
+	contestID, err := uuid.Parse(idStr)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	var req dto.UpdateContestDTO
+	if err := ctx.BodyParser(&req); err != nil {
+		ch.logger.Warn("Invalid contest payload", zap.Error(err))
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+
+	ch.logger.Info("Updating contest", zap.String("id", idStr))
+	contest, err := ch.svc.UpdateContest(contestID, req, time.Now())
+	switch {
+	case errors.Is(err, service.ErrInvalidScoringMode), errors.Is(err, service.ErrInvalidScoringConfig),
+		errors.Is(err, service.ErrInvalidContestTimes):
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	case errors.Is(err, service.ErrContestEnded), errors.Is(err, service.ErrContestFinalized):
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	case err != nil && strings.Contains(err.Error(), "not found"):
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	case err != nil:
+		ch.logger.Error("Failed to update contest", zap.String("id", idStr), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessMessage(ctx, "Contest updated successfully", contest)
+}
+
 func (ch *ContestHandlers) GetContestByID(ctx *fiber.Ctx) error {
 	idStr := ctx.Params("id")
 
@@ -127,6 +170,9 @@ func (ch *ContestHandlers) AddProblemToContest(ctx *fiber.Ctx) error {
 		zap.String("problem_title", req.ProblemTitle))
 
 	err := ch.svc.AddProblemsToContest(contestID, req)
+	if errors.Is(err, service.ErrInvalidScoringConfig) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
 	if err != nil {
 		ch.logger.Error("Failed to add problem to contest", zap.Error(err))
 		return rest.InternalError(ctx, err)
@@ -245,7 +291,7 @@ func (ch *ContestHandlers) GetContestProblems(ctx *fiber.Ctx) error {
 	contestID := ctx.Params("id")
 
 	ch.logger.Info("Fetching contest problems", zap.String("contest_id", contestID))
-	problems, err := ch.svc.GetContestProblems(contestID)
+	problems, err := ch.svc.GetContestProblems(contestID, ch.auth.IsAdmin(ctx))
 	if err != nil {
 		ch.logger.Error("Failed to fetch problems", zap.Error(err))
 		return rest.InternalError(ctx, err)
@@ -280,8 +326,13 @@ func (ch *ContestHandlers) FinalizeContestRankings(ctx *fiber.Ctx) error {
 	}
 
 	ch.logger.Info("Finalizing contest rankings", zap.String("contest_id", contestIDStr))
-	err = ch.svc.FinalizeContestRankings(contestID)
-	if err != nil {
+	err = ch.svc.FinalizeContest(contestID, time.Now())
+	switch {
+	case errors.Is(err, service.ErrContestNotEnded), errors.Is(err, service.ErrContestJudging), errors.Is(err, service.ErrContestFinalized):
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	case err != nil && strings.Contains(err.Error(), "not found"):
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	case err != nil:
 		ch.logger.Error("Failed to finalize contest rankings", zap.Error(err))
 		return rest.InternalError(ctx, err)
 	}
@@ -289,6 +340,33 @@ func (ch *ContestHandlers) FinalizeContestRankings(ctx *fiber.Ctx) error {
 	return rest.SuccessMessage(ctx, "Contest rankings finalized successfully", nil)
 }
 
+func (ch *ContestHandlers) RollbackFinalization(ctx *fiber.Ctx) error {
+	contestIDStr := ctx.Params("id")
+	contestID, err := uuid.Parse(contestIDStr)
+	if err != nil {
+		ch.logger.Warn("Invalid contest ID", zap.String("id", contestIDStr))
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	admin, err := ch.auth.CurrentUserInfo(ctx)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusUnauthorized, err)
+	}
+
+	ch.logger.Info("Rolling back contest finalization", zap.String("contest_id", contestIDStr), zap.String("admin_id", admin.ID.String()))
+	err = ch.svc.RollbackFinalization(contestID, admin.ID, time.Now())
+	switch {
+	case errors.Is(err, service.ErrContestNotFinalized), errors.Is(err, repo.ErrRatingChanged):
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	case err != nil && strings.Contains(err.Error(), "not found"):
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	case err != nil:
+		ch.logger.Error("Failed to roll back contest finalization", zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessMessage(ctx, "Contest finalization rolled back successfully", nil)
+}
+
 func (ch *ContestHandlers) GetGlobalLeaderboard(ctx *fiber.Ctx) error {
 	limit, _ := strconv.Atoi(ctx.Query("limit", "100"))
 
@@ -301,3 +379,22 @@ func (ch *ContestHandlers) GetGlobalLeaderboard(ctx *fiber.Ctx) error {
 
 	return rest.SuccessMessage(ctx, "Global leaderboard retrieved successfully", leaderboard)
 }
+
+func (ch *ContestHandlers) GetRatingHistory(ctx *fiber.Ctx) error {
+	userID := ctx.Params("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+
+	ch.logger.Info("Fetching rating history", zap.String("user_id", userID))
+	history, err := ch.svc.GetRatingHistory(userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		ch.logger.Error("Failed to fetch rating history", zap.String("user_id", userID), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessMessage(ctx, "Rating history retrieved successfully", history)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sudankdk/codearena/internal/api/rest"
+	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/repo"
+	"github.com/sudankdk/codearena/internal/service"
+	"go.uber.org/zap"
+)
+
+type LanguageHandlers struct {
+	svc    service.LanguageService
+	logger *zap.Logger
+}
+
+func SetupLanguageRoutes(rh *rest.RestHandlers) {
+	app := rh.App
+	svc := service.LanguageService{
+		Repo: repo.NewLanguageRepo(rh.DB),
+	}
+	handler := LanguageHandlers{
+		svc:    svc,
+		logger: rh.Logger,
+	}
+
+	app.Get("/languages", handler.List)
+
+	adminRoutes := app.Group("/languages", rh.Auth.Authorize, rh.Auth.AdminOnly)
+	adminRoutes.Get("/all", handler.ListAll)
+	adminRoutes.Post("", handler.Create)
+	adminRoutes.Put("/:id", handler.Update)
+	adminRoutes.Post("/:id/enable", handler.Enable)
+	adminRoutes.Post("/:id/disable", handler.Disable)
+}
+
+func (lh *LanguageHandlers) List(ctx *fiber.Ctx) error {
+	langs, err := lh.svc.ListLanguages(false)
+	if err != nil {
+		lh.logger.Error("Failed to list languages", zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Languages", langs)
+}
+
+func (lh *LanguageHandlers) ListAll(ctx *fiber.Ctx) error {
+	langs, err := lh.svc.ListLanguages(true)
+	if err != nil {
+		lh.logger.Error("Failed to list languages", zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Languages", langs)
+}
+
+func (lh *LanguageHandlers) Create(ctx *fiber.Ctx) error {
+	var req dto.CreateLanguageDTO
+	if err := ctx.BodyParser(&req); err != nil {
+		lh.logger.Warn("Invalid create language payload", zap.Error(err))
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("invalid payload"))
+	}
+
+	lh.logger.Info("Creating language", zap.String("id", req.ID))
+	lang, err := lh.svc.CreateLanguage(req)
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			return rest.ErrorMessage(ctx, http.StatusConflict, err)
+		}
+		lh.logger.Warn("Failed to create language", zap.String("id", req.ID), zap.Error(err))
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+
+	lh.logger.Info("Language created successfully", zap.String("id", lang.ID))
+	return rest.SuccessMessage(ctx, "Language created successfully", lang)
+}
+
+func (lh *LanguageHandlers) Update(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	var req dto.UpdateLanguageDTO
+	if err := ctx.BodyParser(&req); err != nil {
+		lh.logger.Warn("Invalid update language payload", zap.Error(err))
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("invalid payload"))
+	}
+
+	lh.logger.Info("Updating language", zap.String("id", id))
+	lang, err := lh.svc.UpdateLanguage(id, req)
+	if err != nil {
+		return lh.writeError(ctx, id, err)
+	}
+
+	lh.logger.Info("Language updated successfully", zap.String("id", id))
+	return rest.SuccessMessage(ctx, "Language updated successfully", lang)
+}
+
+func (lh *LanguageHandlers) Enable(ctx *fiber.Ctx) error {
+	return lh.setEnabled(ctx, true)
+}
+
+func (lh *LanguageHandlers) Disable(ctx *fiber.Ctx) error {
+	return lh.setEnabled(ctx, false)
+}
+
+func (lh *LanguageHandlers) setEnabled(ctx *fiber.Ctx, enabled bool) error {
+	id := ctx.Params("id")
+	lh.logger.Info("Changing language availability", zap.String("id", id), zap.Bool("enabled", enabled))
+	if err := lh.svc.SetEnabled(id, enabled); err != nil {
+		return lh.writeError(ctx, id, err)
+	}
+	return rest.SuccessMessage(ctx, "Language updated successfully", map[string]interface{}{
+		"id":      id,
+		"enabled": enabled,
+	})
+}
+
+func (lh *LanguageHandlers) writeError(ctx *fiber.Ctx, id string, err error) error {
+	if strings.Contains(err.Error(), "not found") {
+		lh.logger.Warn("Language not found", zap.String("id", id))
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+	lh.logger.Warn("Failed to update language", zap.String("id", id), zap.Error(err))
+	return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sudankdk/codearena/configs"
+	"github.com/sudankdk/codearena/internal/api/rest"
+	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/helper"
+	"github.com/sudankdk/codearena/internal/judge"
+	"github.com/sudankdk/codearena/internal/problempkg"
+	"github.com/sudankdk/codearena/internal/repo"
+	"github.com/sudankdk/codearena/internal/sandbox"
+	"github.com/sudankdk/codearena/internal/service"
+	"github.com/sudankdk/codearena/internal/storage"
+	"go.uber.org/zap"
+)
+
+type RunHandlers struct {
+	svc    service.RunService
+	auth   helper.Auth
+	logger *zap.Logger
+}
+
+// NewJudge returns the sandboxed judge shared by runs and queued
+// submissions.
+func NewJudge(cfg configs.AppConfigs) *judge.Judge {
+	j := judge.New(sandbox.New(sandbox.DefaultConfig()))
+	j.GoCache = cfg.JUDGEGOCACHE
+	return j
+}
+
+func SetupRunRoutes(rh *rest.RestHandlers) {
+	app := rh.App
+	svc := service.RunService{
+		ProblemRepo:  repo.NewProblemsRepo(rh.DB),
+		LanguageRepo: repo.NewLanguageRepo(rh.DB),
+		TestRepo:     repo.NewTestcase(rh.DB),
+		RevisionRepo: repo.NewRevisionRepo(rh.DB),
+		Judge:        NewJudge(rh.Configs),
+		Data:         storage.TestData{Store: rh.Store},
+		Slots:        make(chan struct{}, max(rh.Configs.JUDGEWORKERS, 1)),
+	}
+	handler := RunHandlers{
+		svc:    svc,
+		auth:   rh.Auth,
+		logger: rh.Logger,
+	}
+
+	app.Post("/run", rh.Auth.Authorize, handler.Run)
+	app.Post("/problems/:id/verify", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.Verify)
+	app.Post("/problems/:id/generate", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.GenerateTests)
+}
+
+// Run executes code on custom input or the problem's samples without
+// creating a submission.
+func (h *RunHandlers) Run(ctx *fiber.Ctx) error {
+	var req dto.RunCodeDTO
+	if err := ctx.BodyParser(&req); err != nil {
+		h.logger.Warn("Invalid run payload", zap.Error(err))
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("invalid payload"))
+	}
+	if req.Language == "" || req.Code == "" {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("language and code are required"))
+	}
+
+	res, err := h.svc.Run(ctx.UserContext(), req, h.auth.IsAdmin(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRunnerBusy):
+			return rest.ErrorMessage(ctx, http.StatusTooManyRequests, err)
+		case errors.Is(err, judge.ErrUnsupportedLanguage),
+			errors.Is(err, judge.ErrNoTestCases),
+			errors.Is(err, service.ErrCustomInteractive),
+			err.Error() == "problem_id or stdin is required":
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		case err.Error() == "problem not found":
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		h.logger.Error("Failed to run code", zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Run finished", res)
+}
+
+// Verify validates the tests of problem :id and judges its reference
+// solutions on them.
+func (h *RunHandlers) Verify(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	res, err := h.svc.Verify(ctx.UserContext(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRunnerBusy):
+			return rest.ErrorMessage(ctx, http.StatusTooManyRequests, err)
+		case errors.Is(err, judge.ErrUnsupportedLanguage),
+			errors.Is(err, judge.ErrNoTestCases),
+			err.Error() == "invalid problem ID":
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		case err.Error() == "problem not found":
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		h.logger.Error("Failed to verify problem", zap.String("id", id), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Verification finished", res)
+}
+
+// GenerateTests runs the generation script of problem :id and adds the tests
+// it makes.
+func (h *RunHandlers) GenerateTests(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	var req dto.GenerateTestsDTO
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			h.logger.Warn("Invalid generate payload", zap.Error(err))
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("invalid payload"))
+		}
+	}
+
+	h.logger.Info("Generating tests", zap.String("problem_id", id), zap.String("mode", req.Mode))
+	user, err := h.auth.CurrentUserInfo(ctx)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusUnauthorized, err)
+	}
+	res, err := h.svc.GenerateTests(ctx.UserContext(), id, req, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRunnerBusy):
+			return rest.ErrorMessage(ctx, http.StatusTooManyRequests, err)
+		case errors.Is(err, judge.ErrGenerationFailed),
+			errors.Is(err, judge.ErrUnsupportedLanguage),
+			errors.Is(err, problempkg.ErrInvalidScript),
+			errors.Is(err, service.ErrInvalidSubtasks),
+			errors.Is(err, service.ErrNoReferenceSolution),
+			err.Error() == "invalid problem ID":
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		case err.Error() == "problem not found":
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		h.logger.Error("Failed to generate tests", zap.String("problem_id", id), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+	h.logger.Info("Tests generated", zap.String("problem_id", id), zap.Int("added", res.Added))
+	return rest.SuccessMessage(ctx, "Tests generated successfully", res)
+}
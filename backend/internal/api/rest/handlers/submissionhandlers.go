@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"bufio"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
@@ -11,24 +15,39 @@ import (
 	"github.com/sudankdk/codearena/internal/api/rest"
 	"github.com/sudankdk/codearena/internal/domain"
 	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/judge"
 	"github.com/sudankdk/codearena/internal/repo"
 	"github.com/sudankdk/codearena/internal/service"
+	"github.com/sudankdk/codearena/internal/storage"
 	"go.uber.org/zap"
 )
 
+const (
+	statusPollInterval  = 500 * time.Millisecond
+	statusStreamTimeout = 10 * time.Minute
+	maxStatusStreams    = 256
+)
+
+var errTooManyStreams = errors.New("too many status streams open, poll the status endpoint instead")
+
 type SubmissionHandlers struct {
 	svc        service.SubmissionService
 	contestSvc service.ContestService
 	logger     *zap.Logger
+	// streams holds a slot for every open status stream
+	streams chan struct{}
 }
 
 func SetupSubmissionRoutes(rh *rest.RestHandlers) {
 	app := rh.App
 	svc := service.SubmissionService{
-		Repo:     repo.NewSubmissionRepo(rh.DB),
-		UserRepo: repo.NewUserRepo(rh.DB),
-		Auth:     rh.Auth,
-		Config:   rh.Configs,
+		Repo:         repo.NewSubmissionRepo(rh.DB),
+		UserRepo:     repo.NewUserRepo(rh.DB),
+		ProblemRepo:  repo.NewProblemsRepo(rh.DB),
+		LanguageRepo: repo.NewLanguageRepo(rh.DB),
+		Auth:         rh.Auth,
+		Config:       rh.Configs,
+		Data:         storage.TestData{Store: rh.Store},
 	}
 	contestSvc := service.ContestService{
 		ContestRepo:    repo.NewContestRepo(rh.DB),
@@ -41,15 +60,24 @@ func SetupSubmissionRoutes(rh *rest.RestHandlers) {
 		svc:        svc,
 		contestSvc: contestSvc,
 		logger:     rh.Logger,
+		streams:    make(chan struct{}, maxStatusStreams),
 	}
 
 	submissionRoutes := app.Group("/submissions", rh.Auth.Authorize)
 	submissionRoutes.Post("", handler.CreateSubmission)
 	submissionRoutes.Get("", handler.ListSubmissions)
 	submissionRoutes.Get("/:id", handler.GetSubmissionByID)
+	submissionRoutes.Get("/:id/status", handler.GetSubmissionStatus)
+	submissionRoutes.Get("/:id/events", handler.StreamSubmissionStatus)
+	submissionRoutes.Get("/:id/judgements", handler.ListJudgements)
+	submissionRoutes.Post("/:id/rejudge", rh.Auth.AdminOnly, handler.RejudgeSubmission)
 	submissionRoutes.Get("/stats/user", handler.GetUserStats)
 	submissionRoutes.Get("/stats/problem/:problemId", handler.GetProblemStats)
 
+	// Rejudging whole problems and contests is left to admins
+	app.Post("/problems/:id/rejudge", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.RejudgeProblem)
+	app.Post("/contests/:id/rejudge", rh.Auth.Authorize, rh.Auth.AdminOnly, handler.RejudgeContest)
+
 	// Public route for topic stats
 	app.Get("/stats/topics", handler.GetTopicStats)
 }
@@ -85,9 +113,23 @@ func (sh *SubmissionHandlers) CreateSubmission(ctx *fiber.Ctx) error {
 			return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("contest is not currently active"))
 		}
 
+		// Check if the problem is part of the contest
+		if !slices.ContainsFunc(contest.Problems, func(p domain.ContestProblem) bool {
+			return p.ProblemID == req.ProblemID
+		}) {
+			sh.logger.Warn("Problem not in contest",
+				zap.String("problem_id", req.ProblemID.String()),
+				zap.String("contest_id", req.ContestID.String()))
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, errors.New("problem is not part of the contest"))
+		}
+
 		// Check if user is registered
 		isRegistered, err := sh.contestSvc.ContestRepo.IsUserRegistered(*req.ContestID, user.ID)
-		if err != nil || !isRegistered {
+		if err != nil {
+			sh.logger.Error("Failed to check contest registration", zap.Error(err))
+			return rest.InternalError(ctx, err)
+		}
+		if !isRegistered {
 			sh.logger.Warn("User not registered for contest",
 				zap.String("user_id", user.ID.String()),
 				zap.String("contest_id", req.ContestID.String()))
@@ -98,40 +140,186 @@ func (sh *SubmissionHandlers) CreateSubmission(ctx *fiber.Ctx) error {
 	sh.logger.Info("Creating submission",
 		zap.String("user_id", user.ID.String()),
 		zap.String("problem_id", req.ProblemID.String()),
-		zap.String("status", req.Status),
+		zap.String("language", req.Language),
 		zap.Bool("is_contest", req.ContestID != nil))
 
-	submission, err := sh.svc.CreateSubmission(user.ID, req)
+	submission, err := sh.svc.CreateSubmission(user.ID, req, user.Role == domain.ADMIN)
 	if err != nil {
+		if errors.Is(err, judge.ErrUnsupportedLanguage) || errors.Is(err, judge.ErrNoTestCases) {
+			sh.logger.Warn("Submission rejected", zap.Error(err))
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		if err.Error() == "problem not found" {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
 		sh.logger.Error("Failed to create submission", zap.Error(err))
 		return rest.InternalError(ctx, err)
 	}
 
-	// If this is a contest submission, process scoring and update leaderboard
-	if req.ContestID != nil {
-		err = sh.contestSvc.ProcessSubmission(
-			*req.ContestID,
-			user.ID,
-			req.ProblemID,
-			submission.ID,
-			req.Status,
-			req.TestCasesPassed,
-			req.TotalTestCases,
-			req.ExecutionTime,
-		)
-		if err != nil {
-			sh.logger.Error("Failed to process contest submission", zap.Error(err))
-			// Don't fail the request, submission is saved, just log the error
+	// The verdict and contest points are filled in by the judge workers;
+	// clients follow the submission through its status or events endpoint.
+	detail, err := sh.svc.GetSubmissionDetail(submission.ID)
+	if err != nil {
+		sh.logger.Error("Failed to load submission", zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	sh.logger.Info("Submission queued",
+		zap.String("id", detail.ID.String()))
+	return rest.SuccessMessage(ctx, "Submission queued", detail)
+}
+
+// GetSubmissionStatus is a cheap endpoint for polling a submission until
+// the judge has decided its verdict.
+func (sh *SubmissionHandlers) GetSubmissionStatus(ctx *fiber.Ctx) error {
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+
+	status, err := sh.svc.GetSubmissionStatus(id)
+	if err != nil {
+		if err.Error() == "submission not found" {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Submission status retrieved", status)
+}
+
+// StreamSubmissionStatus sends a server-sent "status" event whenever the
+// submission's status changes and closes the stream once it has a verdict.
+// In between it sends heartbeat comments, so a stream the client left is
+// closed. At most maxStatusStreams streams are open at a time.
+func (sh *SubmissionHandlers) StreamSubmissionStatus(ctx *fiber.Ctx) error {
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	if _, err := sh.svc.GetSubmissionStatus(id); err != nil {
+		if err.Error() == "submission not found" {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		return rest.InternalError(ctx, err)
+	}
+
+	select {
+	case sh.streams <- struct{}{}:
+	default:
+		return rest.ErrorMessage(ctx, http.StatusTooManyRequests, errTooManyStreams)
+	}
+
+	ctx.Set("Content-Type", "text/event-stream")
+	ctx.Set("Cache-Control", "no-cache")
+	ctx.Set("Connection", "keep-alive")
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer func() { <-sh.streams }()
+		deadline := time.Now().Add(statusStreamTimeout)
+		var last *dto.SubmissionStatusDTO
+		for time.Now().Before(deadline) {
+			status, err := sh.svc.GetSubmissionStatus(id)
+			if err != nil {
+				fmt.Fprintf(w, "event: error\ndata: %q\n\n", err.Error())
+				w.Flush()
+				return
+			}
+			if last == nil || *status != *last {
+				data, _ := json.Marshal(status)
+				fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+				last = status
+			} else {
+				// A heartbeat lets a client that went away be noticed
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				return // client went away
+			}
+			if !status.Pending {
+				return
+			}
+			time.Sleep(statusPollInterval)
+		}
+	})
+	return nil
+}
+
+// ListJudgements returns the verdict history of a submission, including
+// the verdicts it had before it was rejudged.
+func (sh *SubmissionHandlers) ListJudgements(ctx *fiber.Ctx) error {
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+
+	judgements, err := sh.svc.ListJudgements(id)
+	if err != nil {
+		if err.Error() == "submission not found" {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		return rest.InternalError(ctx, err)
+	}
+	return rest.SuccessMessage(ctx, "Judgements retrieved", judgements)
+}
+
+func (sh *SubmissionHandlers) RejudgeSubmission(ctx *fiber.Ctx) error {
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+
+	res, err := sh.svc.RejudgeSubmission(id)
+	if err != nil {
+		if err.Error() == "submission not found" {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		sh.logger.Error("Failed to rejudge submission", zap.String("id", id.String()), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	sh.logger.Info("Submission queued for rejudge", zap.String("id", id.String()))
+	return rest.SuccessMessage(ctx, "Submission queued for rejudge", res)
+}
+
+func (sh *SubmissionHandlers) RejudgeProblem(ctx *fiber.Ctx) error {
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+
+	res, err := sh.svc.RejudgeProblem(id)
+	if err != nil {
+		if err.Error() == "problem not found" {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
 		}
+		sh.logger.Error("Failed to rejudge problem", zap.String("problem_id", id.String()), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
 
-		// Reload submission to get updated points
-		submission, _ = sh.svc.Repo.GetSubmissionByID(submission.ID)
+	sh.logger.Info("Problem queued for rejudge",
+		zap.String("problem_id", id.String()),
+		zap.Int("submissions", res.Queued))
+	return rest.SuccessMessage(ctx, "Submissions queued for rejudge", res)
+}
+
+func (sh *SubmissionHandlers) RejudgeContest(ctx *fiber.Ctx) error {
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	if _, err := sh.contestSvc.ContestRepo.GetByID(id); err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, errors.New("contest not found"))
 	}
 
-	sh.logger.Info("Submission created successfully",
-		zap.String("id", submission.ID.String()),
-		zap.Int("points", submission.PointsEarned))
-	return rest.SuccessMessage(ctx, "Submission created successfully", submission)
+	res, err := sh.svc.RejudgeContest(id)
+	if err != nil {
+		sh.logger.Error("Failed to rejudge contest", zap.String("contest_id", id.String()), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	sh.logger.Info("Contest queued for rejudge",
+		zap.String("contest_id", id.String()),
+		zap.Int("submissions", res.Queued))
+	return rest.SuccessMessage(ctx, "Submissions queued for rejudge", res)
 }
 
 func (sh *SubmissionHandlers) GetSubmissionByID(ctx *fiber.Ctx) error {
@@ -141,8 +329,11 @@ func (sh *SubmissionHandlers) GetSubmissionByID(ctx *fiber.Ctx) error {
 		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
 	}
 
-	submission, err := sh.svc.GetSubmissionByID(id)
+	submission, err := sh.svc.GetSubmissionDetail(id)
 	if err != nil {
+		if err.Error() == "submission not found" {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
 		return rest.InternalError(ctx, err)
 	}
 
@@ -9,6 +9,7 @@ import (
 	"github.com/shareed2k/goth_fiber"
 	"github.com/sudankdk/codearena/internal/api/rest"
 	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/judge"
 	"github.com/sudankdk/codearena/internal/repo"
 	"github.com/sudankdk/codearena/internal/service"
 	"go.uber.org/zap"
@@ -22,9 +23,10 @@ type UserHandlers struct {
 func SetupRoutes(rh *rest.RestHandlers) {
 	app := rh.App
 	svc := service.UserService{
-		Repo:   repo.NewUserRepo(rh.DB),
-		Auth:   rh.Auth,
-		Config: rh.Configs,
+		Repo:         repo.NewUserRepo(rh.DB),
+		LanguageRepo: repo.NewLanguageRepo(rh.DB),
+		Auth:         rh.Auth,
+		Config:       rh.Configs,
 	}
 	handler := UserHandlers{
 		svc:    svc,
@@ -47,6 +49,7 @@ func SetupRoutes(rh *rest.RestHandlers) {
 			"user": user,
 		})
 	})
+	pubRoutes.Put("/me/preferences", rh.Auth.Authorize, handler.UpdatePreferences)
 
 }
 
@@ -91,6 +94,32 @@ func (u *UserHandlers) Login(ctx *fiber.Ctx) error {
 
 }
 
+func (u *UserHandlers) UpdatePreferences(ctx *fiber.Ctx) error {
+	var req dto.UpdatePreferencesDTO
+	if err := ctx.BodyParser(&req); err != nil {
+		u.logger.Warn("Invalid preferences payload", zap.Error(err))
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, fmt.Errorf("Invalid Payload"))
+	}
+
+	current, err := u.svc.Auth.CurrentUserInfo(ctx)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusUnauthorized, err)
+	}
+
+	user, err := u.svc.UpdatePreferences(current.ID, req)
+	if err != nil {
+		if errors.Is(err, judge.ErrUnsupportedLanguage) {
+			u.logger.Warn("Rejected language preference", zap.String("language", req.LanguagePreference))
+			return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+		}
+		u.logger.Error("Failed to update preferences", zap.String("user_id", current.ID.String()), zap.Error(err))
+		return rest.InternalError(ctx, err)
+	}
+
+	u.logger.Info("Preferences updated", zap.String("user_id", current.ID.String()))
+	return rest.SuccessMessage(ctx, "preferences updated", user)
+}
+
 func (u *UserHandlers) Logout(ctx *fiber.Ctx) error {
 	ctx.Cookie(&fiber.Cookie{
 		Name:     "token",
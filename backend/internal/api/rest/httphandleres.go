@@ -4,6 +4,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/sudankdk/codearena/configs"
 	"github.com/sudankdk/codearena/internal/helper"
+	"github.com/sudankdk/codearena/internal/storage"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -14,4 +15,5 @@ type RestHandlers struct {
 	Configs configs.AppConfigs
 	Auth    helper.Auth
 	Logger  *zap.Logger
+	Store   storage.Store
 }
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/sudankdk/codearena/configs"
@@ -8,8 +10,14 @@ import (
 	"github.com/sudankdk/codearena/internal/api/rest/handlers"
 	"github.com/sudankdk/codearena/internal/domain"
 	"github.com/sudankdk/codearena/internal/helper"
+	"github.com/sudankdk/codearena/internal/judge"
 	"github.com/sudankdk/codearena/internal/logger"
 	"github.com/sudankdk/codearena/internal/middleware"
+	"github.com/sudankdk/codearena/internal/problempkg"
+	"github.com/sudankdk/codearena/internal/repo"
+	"github.com/sudankdk/codearena/internal/service"
+	"github.com/sudankdk/codearena/internal/storage"
+	"github.com/sudankdk/codearena/internal/worker"
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -17,7 +25,7 @@ import (
 
 func StartServer(cfg configs.AppConfigs) {
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{BodyLimit: cfg.MAXUPLOADMB << 20})
 
 	// Add logging middleware
 	app.Use(middleware.LoggingMiddleware(logger.Log))
@@ -44,6 +52,8 @@ func StartServer(cfg configs.AppConfigs) {
 	logger.Info("Database connected successfully")
 
 	logger.Info("Running database migrations")
+	hadContestStatus := db.Migrator().HasColumn(&domain.Contest{}, "status")
+	hadCheckerType := db.Migrator().HasColumn(&domain.Problem{}, "checker_type")
 	if err := db.AutoMigrate(
 		&domain.User{},
 		&domain.Problem{},
@@ -58,6 +68,17 @@ func StartServer(cfg configs.AppConfigs) {
 		&domain.ContestParticipant{},
 		&domain.ContestLeaderboardEntry{},
 		&domain.GlobalLeaderboardEntry{},
+		&domain.Language{},
+		&domain.ProblemLanguageLimit{},
+		&domain.SubmissionTestResult{},
+		&domain.ProblemProgram{},
+		&domain.Subtask{},
+		&domain.SubmissionSubtaskResult{},
+		&domain.JudgeJob{},
+		&domain.SubmissionJudgement{},
+		&domain.ProblemAttachment{},
+		&domain.ProblemRevision{},
+		&domain.ContestFinalization{},
 	); err != nil {
 		logger.Fatal("Failed to run migrations", zap.Error(err))
 	}
@@ -71,6 +92,83 @@ func StartServer(cfg configs.AppConfigs) {
 		}
 	}
 
+	// Contests from before the contest scheduler start out scheduled; the
+	// ones whose rankings were already finalized must not be finalized again
+	if !hadContestStatus {
+		marked, err := repo.NewContestRepo(db).MarkFinalizedContests()
+		if err != nil {
+			logger.Fatal("Failed to mark finalized contests", zap.Error(err))
+		}
+		logger.Info("Marked finalized contests", zap.Int64("contests", marked))
+	}
+
+	// Problems from before checkers keep the comparison they were judged
+	// with; whitespace is only the default for new problems
+	if !hadCheckerType {
+		updated, err := repo.NewProblemsRepo(db).UseLegacyChecker()
+		if err != nil {
+			logger.Fatal("Failed to set the checker of existing problems", zap.Error(err))
+		}
+		logger.Info("Set the checker of existing problems", zap.Int64("problems", updated))
+	}
+
+	// Seed the language registry and move legacy language names onto its ids
+	if err := repo.NewLanguageRepo(db).SeedLanguages(judge.DefaultLanguages()); err != nil {
+		logger.Fatal("Failed to seed languages", zap.Error(err))
+	}
+	legacyLanguages := map[string]string{
+		"python":     "py",
+		"python3":    "py",
+		"javascript": "js",
+		"golang":     "go",
+		"c++":        "cpp",
+	}
+	for legacy, id := range legacyLanguages {
+		if err := db.Model(&domain.User{}).Where("language_preference = ?", legacy).Update("language_preference", id).Error; err != nil {
+			logger.Warn("Failed to migrate language preference", zap.String("language", legacy), zap.Error(err))
+		}
+		if err := db.Model(&domain.BoilerPlate{}).Where("language = ?", legacy).Update("language", id).Error; err != nil {
+			logger.Warn("Failed to migrate boilerplate language", zap.String("language", legacy), zap.Error(err))
+		}
+	}
+
+	// Reference solutions imported before expected verdicts were stored are
+	// named after their package directory, e.g. "accepted/fast.cpp"
+	for _, outcome := range domain.SolutionOutcomes {
+		if err := db.Model(&domain.ProblemProgram{}).
+			Where("role = ? AND expected = '' AND name LIKE ?", domain.PROGRAM_SOLUTION, problempkg.VerdictDir(outcome)+"/%").
+			Update("expected", outcome).Error; err != nil {
+			logger.Warn("Failed to migrate solution verdicts", zap.String("expected", outcome), zap.Error(err))
+		}
+	}
+
+	store, err := NewStore(cfg)
+	if err != nil {
+		logger.Fatal("Failed to set up blob storage", zap.Error(err))
+	}
+	logger.Info("Blob storage ready", zap.String("backend", cfg.STORAGEBACKEND))
+
+	// Move test data out of the test_cases table into the blob store
+	data := storage.TestData{Store: store}
+	moved, err := repo.NewTestcase(db).MigrateInlineData(func(tc *domain.TestCases) error {
+		return data.Save(context.Background(), tc)
+	})
+	if err != nil {
+		logger.Fatal("Failed to move test data to blob storage", zap.Int("moved", moved), zap.Error(err))
+	}
+	if moved > 0 {
+		logger.Info("Moved test data to blob storage", zap.Int("tests", moved))
+	}
+
+	// Give problems from before revisions existed a first revision
+	recorded, err := service.RecordInitialRevisions(repo.NewProblemsRepo(db), repo.NewRevisionRepo(db))
+	if err != nil {
+		logger.Fatal("Failed to record initial problem revisions", zap.Int("recorded", recorded), zap.Error(err))
+	}
+	if recorded > 0 {
+		logger.Info("Recorded initial problem revisions", zap.Int("problems", recorded))
+	}
+
 	logger.Info("Database migrations completed")
 
 	auth := helper.SetupAuth(cfg.SECRETKEY)
@@ -80,8 +178,11 @@ func StartServer(cfg configs.AppConfigs) {
 		Configs: cfg,
 		Auth:    *auth,
 		Logger:  logger.Log,
+		Store:   store,
 	}
 	SetupRoutes(rh)
+	StartJudgeWorkers(rh)
+	StartContestScheduler(rh)
 
 	logger.Info("Server starting", zap.String("port", cfg.PORT))
 	if err := app.Listen(":" + cfg.PORT); err != nil {
@@ -89,10 +190,67 @@ func StartServer(cfg configs.AppConfigs) {
 	}
 }
 
+// StartJudgeWorkers starts the pool that judges queued submissions in the
+// background.
+func StartJudgeWorkers(rh *rest.RestHandlers) {
+	submissions := repo.NewSubmissionRepo(rh.DB)
+	problems := repo.NewProblemsRepo(rh.DB)
+	users := repo.NewUserRepo(rh.DB)
+	judgeSvc := &service.JudgeService{
+		Repo:         submissions,
+		UserRepo:     users,
+		ProblemRepo:  problems,
+		LanguageRepo: repo.NewLanguageRepo(rh.DB),
+		Judge:        handlers.NewJudge(rh.Configs),
+		Data:         storage.TestData{Store: rh.Store},
+		Contests: &service.ContestService{
+			ContestRepo:    repo.NewContestRepo(rh.DB),
+			ProblemRepo:    problems,
+			SubmissionRepo: submissions,
+			UserRepo:       users,
+			ScoringService: &service.ContestScoringService{},
+		},
+	}
+	pool := worker.New(repo.NewJudgeJobRepo(rh.DB), judgeSvc, rh.Configs.JUDGEWORKERS, rh.Logger)
+	logger.Info("Starting judge workers", zap.Int("workers", pool.Workers))
+	go pool.Run(context.Background())
+}
+
+// StartContestScheduler starts the scheduler that starts, ends and
+// finalizes contests in the background.
+func StartContestScheduler(rh *rest.RestHandlers) {
+	scheduler := worker.NewScheduler(&service.ContestService{
+		ContestRepo:    repo.NewContestRepo(rh.DB),
+		ProblemRepo:    repo.NewProblemsRepo(rh.DB),
+		SubmissionRepo: repo.NewSubmissionRepo(rh.DB),
+		UserRepo:       repo.NewUserRepo(rh.DB),
+		ScoringService: &service.ContestScoringService{},
+	}, rh.Logger)
+	logger.Info("Starting contest scheduler", zap.Duration("interval", scheduler.Interval))
+	go scheduler.Run(context.Background())
+}
+
+// NewStore opens the blob store that cfg selects.
+func NewStore(cfg configs.AppConfigs) (storage.Store, error) {
+	if cfg.STORAGEBACKEND == "s3" {
+		return storage.NewS3(storage.S3Config{
+			Endpoint:    cfg.S3ENDPOINT,
+			Region:      cfg.S3REGION,
+			Bucket:      cfg.S3BUCKET,
+			AccessKey:   cfg.S3ACCESSKEY,
+			SecretKey:   cfg.S3SECRETKEY,
+			VirtualHost: cfg.S3VIRTUALHOST,
+		})
+	}
+	return storage.NewLocal(cfg.STORAGEDIR)
+}
+
 func SetupRoutes(rh *rest.RestHandlers) {
 	handlers.SetupRoutes(rh)
 	handlers.SetupProblemTestRoutes(rh)
 	handlers.SetupSubmissionRoutes(rh)
 	handlers.SetupDiscussionRoutes(rh)
 	handlers.SetupContestRoutes(rh)
+	handlers.SetupLanguageRoutes(rh)
+	handlers.SetupRunRoutes(rh)
 }
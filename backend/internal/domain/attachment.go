@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProblemAttachment is a file attached to a problem, such as an image used
+// by its statement or a large example. The content lives in the blob store
+// under Hash.
+type ProblemAttachment struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	ProblemID   uuid.UUID `json:"problem_id" gorm:"type:uuid;not null;uniqueIndex:idx_problem_attachment;constraint:OnDelete:CASCADE"`
+	Name        string    `json:"name" gorm:"not null;uniqueIndex:idx_problem_attachment"`
+	ContentType string    `json:"content_type"`
+	Hash        string    `json:"hash" gorm:"type:varchar(64);not null;index"`
+	Size        int64     `json:"size" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (a *ProblemAttachment) BeforeCreate(scope *gorm.DB) error {
+	a.ID = uuid.New()
+	return nil
+}
@@ -7,6 +7,50 @@ import (
 	"gorm.io/gorm"
 )
 
+// Contest statuses. The contest scheduler moves a contest from scheduled to
+// running at StartTime and to ended at EndTime, then finalizes its rankings
+// once every submission made during it has been judged.
+const (
+	CONTEST_SCHEDULED  = "scheduled"
+	CONTEST_RUNNING    = "running"
+	CONTEST_ENDED      = "ended"
+	CONTEST_FINALIZING = "finalizing"
+	CONTEST_FINALIZED  = "finalized"
+)
+
+// Contest scoring modes; see CONTEST_SCORING.md.
+const (
+	SCORING_HYBRID   = "hybrid" // points less time and attempt penalties, plus penalty time
+	SCORING_ICPC     = "icpc"
+	SCORING_IOI      = "ioi"
+	SCORING_DECAYING = "decaying"
+)
+
+// ScoringConfig tunes a contest's scoring mode. The modes each use some of
+// the fields; see CONTEST_SCORING.md. Shares are fractions of a problem's
+// points.
+type ScoringConfig struct {
+	TimePenaltyPerMin   float64 `json:"time_penalty_per_min"`  // hybrid: points lost per minute
+	WrongAttemptPenalty int     `json:"wrong_attempt_penalty"` // hybrid: points lost per attempt after the first
+	PartialCreditMin    float64 `json:"partial_credit_min"`    // hybrid: share of tests needed for partial credit
+	TimePenaltyMinutes  int     `json:"time_penalty_minutes"`  // hybrid, icpc: penalty minutes per wrong attempt
+	DecayPerMinute      float64 `json:"decay_per_minute"`      // decaying: share of points lost per minute
+	WrongAttemptDecay   float64 `json:"wrong_attempt_decay"`   // decaying: share lost per wrong attempt
+	PointsFloor         float64 `json:"points_floor"`          // decaying: least share a solve keeps
+}
+
+func DefaultScoringConfig() ScoringConfig {
+	return ScoringConfig{
+		TimePenaltyPerMin:   0.5,
+		WrongAttemptPenalty: 10,
+		PartialCreditMin:    0.5,
+		TimePenaltyMinutes:  20,
+		DecayPerMinute:      1.0 / 250,
+		WrongAttemptDecay:   0.1,
+		PointsFloor:         0.3,
+	}
+}
+
 type Contest struct {
 	ID              uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
 	Name            string    `json:"name" gorm:"not null"`
@@ -17,9 +61,24 @@ type Contest struct {
 	MaxParticipants int       `json:"max_participants" gorm:"default:0"` // 0 = unlimited
 	IsActive        bool      `json:"is_active" gorm:"default:false"`
 	IsRated         bool      `json:"is_rated" gorm:"default:true"` // Whether contest affects user ratings
+	ScoringMode     string    `json:"scoring_mode" gorm:"type:varchar(20);not null;default:'hybrid'"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 
+	// Scoring tunes the scoring mode. Contests from before it existed have
+	// none and use DefaultScoringConfig.
+	Scoring *ScoringConfig `json:"scoring" gorm:"type:json;serializer:json"`
+
+	// Lifecycle. IsActive is true while the contest is running. A contest
+	// stays finalizing with FinalizeError set when finalizing it failed.
+	// Once its finalizing has been rolled back, only an admin finalizes it
+	// again.
+	Status            string     `json:"status" gorm:"type:varchar(20);not null;default:'scheduled';index"`
+	FinalizeStartedAt *time.Time `json:"finalize_started_at,omitempty"`
+	FinalizedAt       *time.Time `json:"finalized_at,omitempty"`
+	FinalizeError     string     `json:"finalize_error,omitempty" gorm:"type:text"`
+	RolledBackAt      *time.Time `json:"rolled_back_at,omitempty"`
+
 	// Relationships (explicit join tables for metadata)
 	Problems     []ContestProblem          `json:"problems" gorm:"foreignKey:ContestID;constraint:OnDelete:CASCADE"`
 	Participants []ContestParticipant      `json:"participants" gorm:"foreignKey:ContestID;constraint:OnDelete:CASCADE"`
@@ -39,6 +98,9 @@ type ContestProblem struct {
 	TimeMultiplier float64   `json:"time_multiplier" gorm:"default:1.0"`     // Multiplier for time-based scoring
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Penalty minutes per wrong attempt on this problem; NULL uses the contest's
+	TimePenaltyMinutes *int `json:"time_penalty_minutes,omitempty"`
 }
 
 // ContestParticipant represents a user's participation in a specific contest with scoring data
@@ -68,6 +130,34 @@ type ContestParticipant struct {
 	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
+// ContestTotals are a participant's contest totals as rebuilt from their
+// submissions, together with the points each judged submission earned.
+type ContestTotals struct {
+	Points            int
+	ProblemsSolved    int
+	ProblemsAttempted int
+	PenaltyTime       int
+	SubmissionPoints  map[uuid.UUID]int
+}
+
+// ScoringOrDefault returns the contest's scoring config, or the default one
+// if it has none.
+func (c *Contest) ScoringOrDefault() ScoringConfig {
+	if c.Scoring == nil {
+		return DefaultScoringConfig()
+	}
+	return *c.Scoring
+}
+
+// PenaltyMinutes returns the penalty minutes per wrong attempt on the
+// problem: its own, or else the contest's.
+func (cp *ContestProblem) PenaltyMinutes(config ScoringConfig) int {
+	if cp.TimePenaltyMinutes != nil {
+		return *cp.TimePenaltyMinutes
+	}
+	return config.TimePenaltyMinutes
+}
+
 func (c *Contest) BeforeCreate(tx *gorm.DB) error {
 	c.ID = uuid.New()
 	return nil
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ContestFinalization records what finalizing a contest changed: every
+// participant's result and rating, and their global leaderboard entry as it
+// was before. It is written in the same transaction as the changes, so a
+// contest is finalized exactly when it has a finalization that has not been
+// rolled back, and it never has more than one. Rolling a finalization back
+// restores what it recorded.
+type ContestFinalization struct {
+	ID           uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey"`
+	ContestID    uuid.UUID         `json:"contest_id" gorm:"type:uuid;not null;uniqueIndex:idx_contest_finalization_active,where:rolled_back_at IS NULL"`
+	IsRated      bool              `json:"is_rated"`
+	FinalizedAt  time.Time         `json:"finalized_at"`
+	RolledBackAt *time.Time        `json:"rolled_back_at,omitempty"`
+	RolledBackBy *uuid.UUID        `json:"rolled_back_by,omitempty" gorm:"type:uuid"` // NULL until rolled back
+	Results      []FinalizedResult `json:"results" gorm:"type:json;default:'[]';serializer:json"`
+}
+
+func (f *ContestFinalization) BeforeCreate(db *gorm.DB) error {
+	f.ID = uuid.New()
+	return nil
+}
+
+// FinalizedResult is a participant's result in a finalization.
+type FinalizedResult struct {
+	UserID       uuid.UUID `json:"user_id"`
+	Rank         int       `json:"rank"`
+	Score        int       `json:"score"`
+	OldRating    float64   `json:"old_rating"`
+	NewRating    float64   `json:"new_rating"`
+	RatingChange int       `json:"rating_change"`
+	SolvedCount  int       `json:"solved_count"` // practice problems solved, for the global leaderboard
+
+	// The user's global leaderboard entry before finalizing; nil if they
+	// had none. Filled in when the finalization is saved.
+	PrevGlobalEntry *GlobalEntryState `json:"prev_global_entry,omitempty"`
+}
+
+// GlobalEntryState is the part of a GlobalLeaderboardEntry a finalization
+// changes.
+type GlobalEntryState struct {
+	Rating               float64 `json:"rating"`
+	Rank                 int     `json:"rank"`
+	SolvedCount          int     `json:"solved_count"`
+	ContestsParticipated int     `json:"contests_participated"`
+}
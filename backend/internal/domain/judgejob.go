@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	JOB_QUEUED  = "queued"
+	JOB_RUNNING = "running" // claimed by a worker until LeaseUntil
+	JOB_DONE    = "done"
+	JOB_FAILED  = "failed" // gave up after too many attempts
+)
+
+// JudgeJob is an entry of the judge queue. A worker claims a job by taking a
+// lease on it; a job whose lease ran out is claimed again, so submissions
+// are not lost when a worker dies while judging.
+type JudgeJob struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	SubmissionID uuid.UUID  `json:"submission_id" gorm:"type:uuid;not null;uniqueIndex"`
+	Status       string     `json:"status" gorm:"type:varchar(20);not null;default:'queued';index"`
+	Attempts     int        `json:"attempts" gorm:"not null;default:0"`
+	WorkerID     string     `json:"worker_id"`
+	LeaseUntil   *time.Time `json:"lease_until,omitempty" gorm:"index"`
+	LastError    string     `json:"last_error,omitempty" gorm:"type:text"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (j *JudgeJob) BeforeCreate(db *gorm.DB) error {
+	j.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// Language is an entry of the language registry. Submissions, boilerplates
+// and user preferences all refer to languages by ID.
+type Language struct {
+	ID               string    `json:"id" gorm:"type:varchar(20);primaryKey"`
+	Name             string    `json:"name" gorm:"not null"`
+	SourceFile       string    `json:"source_file" gorm:"not null"`
+	CompileCmd       []string  `json:"compile_cmd" gorm:"type:json;default:'[]';serializer:json"` // empty for interpreted languages
+	RunCmd           []string  `json:"run_cmd" gorm:"type:json;not null;serializer:json"`
+	Version          string    `json:"version"`
+	TimeMultiplier   float64   `json:"time_multiplier" gorm:"not null;default:1"`
+	MemoryMultiplier float64   `json:"memory_multiplier" gorm:"not null;default:1"`
+	Enabled          bool      `json:"enabled" gorm:"not null;default:true"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
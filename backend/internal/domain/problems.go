@@ -13,6 +13,68 @@ const (
 	HARD   = "hard"
 )
 
+const (
+	DefaultTimeLimitMs   = 2000
+	DefaultMemoryLimitMB = 256
+)
+
+// Problem types. Interactive problems are judged by an interactor program
+// that talks to the submission instead of a checker reading its output.
+// Function problems ask for a single function; a generated driver reads its
+// arguments from the test input and prints what it returns.
+const (
+	PROBLEM_STANDARD    = "standard"
+	PROBLEM_INTERACTIVE = "interactive"
+	PROBLEM_FUNCTION    = "function"
+)
+
+// Problem statuses. New problems start as drafts and only published ones
+// are shown to contestants; see ProblemTransitions.
+const (
+	PROBLEM_DRAFT     = "draft"
+	PROBLEM_IN_REVIEW = "in_review"
+	PROBLEM_PUBLISHED = "published"
+	PROBLEM_ARCHIVED  = "archived"
+)
+
+// ProblemTransitions lists the statuses a problem may move to from each
+// status. Publishing a problem under review needs the reviewer's sign-off;
+// archived problems may be published again without another review.
+var ProblemTransitions = map[string][]string{
+	PROBLEM_DRAFT:     {PROBLEM_IN_REVIEW, PROBLEM_ARCHIVED},
+	PROBLEM_IN_REVIEW: {PROBLEM_DRAFT, PROBLEM_PUBLISHED},
+	PROBLEM_PUBLISHED: {PROBLEM_ARCHIVED},
+	PROBLEM_ARCHIVED:  {PROBLEM_DRAFT, PROBLEM_PUBLISHED},
+}
+
+// Checker types decide how a program's output is compared with the expected
+// answer.
+const (
+	CHECKER_EXACT            = "exact"            // identical apart from line endings and a final newline
+	CHECKER_LINES            = "lines"            // same lines, ignoring trailing spaces and trailing blank lines
+	CHECKER_WHITESPACE       = "whitespace"       // same tokens, any whitespace between them
+	CHECKER_CASE_INSENSITIVE = "case_insensitive" // same tokens, ignoring case
+	CHECKER_FLOAT            = "float"            // numeric tokens within an absolute or relative epsilon
+	CHECKER_UNORDERED_LINES  = "unordered_lines"  // same lines in any order
+	CHECKER_CUSTOM           = "custom"           // a checker program uploaded by the setter
+)
+
+// Roles of the helper programs attached to a problem. A problem has at most
+// one checker, interactor and validator; reference solutions are told apart
+// by name and record the verdict they should get. Generators are named too,
+// and the problem's generation script runs them by name.
+const (
+	PROGRAM_CHECKER    = "checker"
+	PROGRAM_INTERACTOR = "interactor"
+	PROGRAM_VALIDATOR  = "validator" // checks the format of test inputs
+	PROGRAM_SOLUTION   = "solution"
+	PROGRAM_GENERATOR  = "generator" // writes test inputs
+)
+
+// SolutionOutcomes are the verdicts a reference solution may be expected to
+// get.
+var SolutionOutcomes = []string{STATUS_ACCEPTED, STATUS_WRONG_ANSWER, STATUS_TIME_LIMIT, STATUS_RUNTIME_ERROR}
+
 type Problem struct {
 	ID           uuid.UUID     `json:"id" gorm:"type:uuid;primaryKey"`
 	MainHeading  string        `json:"main_heading" gorm:"not null"`
@@ -25,6 +87,84 @@ type Problem struct {
 	UpdatedAt    time.Time     `json:"updated_at"`
 	Boilerplates []BoilerPlate `json:"boilerplates" gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE"`
 	Contests     []Contest     `json:"contests,omitempty" gorm:"many2many:contest_problems;"`
+	Type         string        `json:"type" gorm:"type:varchar(20);not null;default:'standard'"`
+	Signature    string        `json:"signature,omitempty"` // function problems only, see package harness
+
+	// Resource limits before language multipliers are applied.
+	TimeLimitMs    int                    `json:"time_limit_ms" gorm:"not null;default:2000"`
+	MemoryLimitMB  int                    `json:"memory_limit_mb" gorm:"not null;default:256"`
+	LanguageLimits []ProblemLanguageLimit `json:"language_limits,omitempty" gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE"`
+
+	// Output checking; the epsilons only apply to the float checker.
+	CheckerType       string           `json:"checker_type" gorm:"type:varchar(20);not null;default:'whitespace'"`
+	CheckerAbsEpsilon float64          `json:"checker_abs_epsilon,omitempty"`
+	CheckerRelEpsilon float64          `json:"checker_rel_epsilon,omitempty"`
+	Programs          []ProblemProgram `json:"-" gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE"`
+
+	// Subtasks group the tests for partial scoring; none means the problem
+	// is all or nothing.
+	Subtasks []Subtask `json:"subtasks,omitempty" gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE"`
+
+	Attachments []ProblemAttachment `json:"attachments,omitempty" gorm:"foreignKey:ProblemID;constraint:OnDelete:CASCADE"`
+
+	// GenerationScript makes tests with the problem's generators, one test
+	// per line; see problempkg.ParseScript.
+	GenerationScript string `json:"-" gorm:"type:text;not null;default:''"`
+
+	// Lifecycle. Problems from before statuses existed were all public, so
+	// the column defaults to published; new problems are created as drafts.
+	// SignedOffBy is the reviewer who approved publishing.
+	Status      string     `json:"status" gorm:"type:varchar(20);not null;default:'published';index"`
+	ReviewerID  *uuid.UUID `json:"reviewer_id,omitempty" gorm:"type:uuid"`
+	SignedOffBy *uuid.UUID `json:"signed_off_by,omitempty" gorm:"type:uuid"`
+	SignedOffAt *time.Time `json:"signed_off_at,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+
+	// Revision is the number of the latest ProblemRevision, 0 before the
+	// first one is recorded.
+	Revision int `json:"revision" gorm:"not null;default:0"`
+
+	// TestCaseCount counts all tests of the problem for lists, which only
+	// load the samples.
+	TestCaseCount int64 `json:"test_case_count" gorm:"-"`
+}
+
+// ProblemProgram is a helper program written by the problem setter, such as a
+// custom checker or an interactor. Name tells apart programs that share a role.
+type ProblemProgram struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	ProblemID uuid.UUID `json:"problem_id" gorm:"type:uuid;not null;uniqueIndex:idx_problem_program"`
+	Role      string    `json:"role" gorm:"type:varchar(20);not null;uniqueIndex:idx_problem_program"`
+	Name      string    `json:"name" gorm:"not null;default:'';uniqueIndex:idx_problem_program"`
+	Language  string    `json:"language" gorm:"type:varchar(20);not null"`
+	Code      string    `json:"code" gorm:"type:text;not null"`
+	// Expected is the verdict a reference solution should get, one of
+	// SolutionOutcomes; empty for other roles.
+	Expected  string    `json:"expected,omitempty" gorm:"type:varchar(30);not null;default:''"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (u *ProblemProgram) BeforeCreate(scope *gorm.DB) error {
+	u.ID = uuid.New()
+	return nil
+}
+
+// ProblemLanguageLimit overrides a problem's limits for one language. A zero
+// field falls back to the problem limit scaled by the language multiplier.
+type ProblemLanguageLimit struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	ProblemID     uuid.UUID `json:"problem_id" gorm:"type:uuid;not null;uniqueIndex:idx_problem_language_limit"`
+	LanguageID    string    `json:"language" gorm:"type:varchar(20);not null;uniqueIndex:idx_problem_language_limit"`
+	TimeLimitMs   int       `json:"time_limit_ms,omitempty"`
+	MemoryLimitMB int       `json:"memory_limit_mb,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (u *ProblemLanguageLimit) BeforeCreate(scope *gorm.DB) error {
+	u.ID = uuid.New()
+	return nil
 }
 
 func (u *Problem) BeforeCreate(scope *gorm.DB) error {
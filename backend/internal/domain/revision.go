@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProblemRevision is a snapshot of a problem's statement, limits and test
+// set. A new revision is recorded whenever one of them changes, numbered
+// from 1 per problem. Tests are kept by the hashes of their files, which
+// stay in the blob store, so any revision can be restored.
+type ProblemRevision struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	ProblemID uuid.UUID  `json:"problem_id" gorm:"type:uuid;not null;uniqueIndex:idx_problem_revision"`
+	Number    int        `json:"number" gorm:"not null;uniqueIndex:idx_problem_revision"`
+	AuthorID  *uuid.UUID `json:"author_id,omitempty" gorm:"type:uuid"` // NULL for revisions recorded on migration
+	Summary   string     `json:"summary"`                              // what was changed, e.g. "tests uploaded"
+	CreatedAt time.Time  `json:"created_at"`
+
+	MainHeading    string                  `json:"main_heading"`
+	Description    string                  `json:"description" gorm:"type:text"`
+	Tag            string                  `json:"tag"`
+	Difficulty     string                  `json:"difficulty" gorm:"type:varchar(10)"`
+	TimeLimitMs    int                     `json:"time_limit_ms"`
+	MemoryLimitMB  int                     `json:"memory_limit_mb"`
+	LanguageLimits []RevisionLanguageLimit `json:"language_limits" gorm:"type:json;default:'[]';serializer:json"`
+	Subtasks       []RevisionSubtask       `json:"subtasks" gorm:"type:json;default:'[]';serializer:json"`
+	Tests          []RevisionTest          `json:"tests" gorm:"type:json;default:'[]';serializer:json"`
+}
+
+func (r *ProblemRevision) BeforeCreate(db *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
+
+// RevisionLanguageLimit is a ProblemLanguageLimit in a revision.
+type RevisionLanguageLimit struct {
+	Language      string `json:"language"`
+	TimeLimitMs   int    `json:"time_limit_ms,omitempty"`
+	MemoryLimitMB int    `json:"memory_limit_mb,omitempty"`
+}
+
+// RevisionSubtask is a Subtask in a revision.
+type RevisionSubtask struct {
+	Index        int    `json:"index"`
+	Name         string `json:"name,omitempty"`
+	Points       int    `json:"points"`
+	Aggregation  string `json:"aggregation"`
+	Dependencies []int  `json:"dependencies"`
+}
+
+// RevisionTest is a test in a revision, in judging order.
+type RevisionTest struct {
+	InputHash    string `json:"input_hash"`
+	InputSize    int64  `json:"input_size"`
+	ExpectedHash string `json:"expected_hash"`
+	ExpectedSize int64  `json:"expected_size"`
+	IsSample     bool   `json:"is_sample,omitempty"`
+	Explanation  string `json:"explanation,omitempty"`
+	Subtask      int    `json:"subtask,omitempty"`
+	GeneratedBy  string `json:"generated_by,omitempty"`
+	AnsweredBy   string `json:"answered_by,omitempty"`
+}
@@ -14,8 +14,26 @@ const (
 	STATUS_COMPILE_ERROR = "compile_error"
 	STATUS_TIME_LIMIT    = "time_limit_exceeded"
 	STATUS_MEMORY_LIMIT  = "memory_limit_exceeded"
+	STATUS_JUDGE_ERROR   = "judge_error" // the problem's own programs failed
 )
 
+// A submission moves through these statuses before it gets a verdict.
+const (
+	STATUS_QUEUED    = "queued"
+	STATUS_COMPILING = "compiling"
+	STATUS_RUNNING   = "running"
+)
+
+// STATUS_OK is reported by custom runs whose program finished normally; it
+// is never a submission verdict.
+const STATUS_OK = "ok"
+
+// IsPendingStatus reports whether a submission is still waiting for its
+// verdict.
+func IsPendingStatus(status string) bool {
+	return status == STATUS_QUEUED || status == STATUS_COMPILING || status == STATUS_RUNNING
+}
+
 type Submission struct {
 	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
 	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
@@ -33,13 +51,69 @@ type Submission struct {
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 
+	// ProblemRevision is the revision of the problem the submission was
+	// last judged against; 0 for submissions judged before revisions.
+	ProblemRevision int `json:"problem_revision" gorm:"not null;default:0"`
+
 	// Relations
 	User    User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	Problem Problem  `json:"problem,omitempty" gorm:"foreignKey:ProblemID"`
 	Contest *Contest `json:"contest,omitempty" gorm:"foreignKey:ContestID"`
+
+	// TestResults are served through dto.SubmissionDetailDTO, which hides
+	// the data of hidden tests.
+	TestResults    []SubmissionTestResult    `json:"-" gorm:"foreignKey:SubmissionID;constraint:OnDelete:CASCADE"`
+	SubtaskResults []SubmissionSubtaskResult `json:"subtask_results,omitempty" gorm:"foreignKey:SubmissionID;constraint:OnDelete:CASCADE"`
+	Job            *JudgeJob                 `json:"-" gorm:"foreignKey:SubmissionID;constraint:OnDelete:CASCADE"`
+	Judgements     []SubmissionJudgement     `json:"-" gorm:"foreignKey:SubmissionID;constraint:OnDelete:CASCADE"`
 }
 
 func (s *Submission) BeforeCreate(db *gorm.DB) error {
 	s.ID = uuid.New()
 	return nil
 }
+
+// SubmissionTestResult is the outcome of one test case of a submission.
+// Output is stored as a short excerpt only.
+type SubmissionTestResult struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	SubmissionID   uuid.UUID  `json:"submission_id" gorm:"type:uuid;not null;index"`
+	TestCaseID     *uuid.UUID `json:"test_case_id" gorm:"type:uuid;index"` // NULL once the test case is deleted
+	OrderIndex     int        `json:"order_index"`                         // position in the judged test set
+	Status         string     `json:"status" gorm:"type:varchar(50);not null"`
+	ExecutionTime  int        `json:"execution_time"` // in milliseconds
+	MemoryUsed     int        `json:"memory_used"`    // in KB
+	StdoutExcerpt  string     `json:"stdout_excerpt" gorm:"type:text"`
+	StderrExcerpt  string     `json:"stderr_excerpt" gorm:"type:text"`
+	CheckerMessage string     `json:"checker_message" gorm:"type:text"`
+	Score          float64    `json:"score"` // 0..1 as reported by the checker
+	CreatedAt      time.Time  `json:"created_at"`
+
+	TestCase *TestCases `json:"-" gorm:"foreignKey:TestCaseID;constraint:OnDelete:SET NULL"`
+}
+
+func (r *SubmissionTestResult) BeforeCreate(db *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
+
+// SubmissionJudgement is one verdict a submission received. A row is added
+// every time the submission is judged, so the verdicts from before a
+// rejudge are kept next to the new ones.
+type SubmissionJudgement struct {
+	ID              uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	SubmissionID    uuid.UUID `json:"submission_id" gorm:"type:uuid;not null;index"`
+	Status          string    `json:"status" gorm:"type:varchar(50);not null"`
+	ExecutionTime   int       `json:"execution_time"` // in milliseconds
+	MemoryUsed      int       `json:"memory_used"`    // in KB
+	TestCasesPassed int       `json:"test_cases_passed"`
+	TotalTestCases  int       `json:"total_test_cases"`
+	ErrorMessage    string    `json:"error_message,omitempty" gorm:"type:text"`
+	ProblemRevision int       `json:"problem_revision" gorm:"not null;default:0"`
+	CreatedAt       time.Time `json:"created_at" gorm:"index"`
+}
+
+func (j *SubmissionJudgement) BeforeCreate(db *gorm.DB) error {
+	j.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Subtask aggregations decide how a subtask's tests turn into points.
+const (
+	SUBTASK_ALL = "all" // full points only if every test is accepted
+	SUBTASK_MIN = "min" // points scaled by the lowest test score
+)
+
+// Subtask is a group of a problem's tests worth a number of points. Tests
+// refer to it by Index, which starts at 1. A subtask only scores as well as
+// the subtasks it depends on, which must have smaller indexes.
+type Subtask struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	ProblemID    uuid.UUID `json:"problem_id" gorm:"type:uuid;not null;uniqueIndex:idx_problem_subtask"`
+	Index        int       `json:"index" gorm:"not null;uniqueIndex:idx_problem_subtask"`
+	Name         string    `json:"name"`
+	Points       int       `json:"points" gorm:"not null;default:0"`
+	Aggregation  string    `json:"aggregation" gorm:"type:varchar(10);not null;default:'all'"`
+	Dependencies []int     `json:"dependencies" gorm:"type:json;default:'[]';serializer:json"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (s *Subtask) BeforeCreate(scope *gorm.DB) error {
+	s.ID = uuid.New()
+	return nil
+}
+
+// SubmissionSubtaskResult is the score of one subtask in a submission.
+type SubmissionSubtaskResult struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	SubmissionID uuid.UUID `json:"submission_id" gorm:"type:uuid;not null;index"`
+	SubtaskIndex int       `json:"subtask_index" gorm:"not null"`
+	Name         string    `json:"name"`
+	Status       string    `json:"status" gorm:"type:varchar(50);not null"`
+	Points       int       `json:"points"` // the subtask's value
+	Score        float64   `json:"score"`  // points earned, 0..Points
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (r *SubmissionSubtaskResult) BeforeCreate(db *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
@@ -7,13 +7,46 @@ import (
 	"gorm.io/gorm"
 )
 
+// TestCases is one judged test of a problem. Only samples are shown to
+// contestants; hidden tests are used by the judge and visible to admins.
+//
+// Input and Expected live in the blob store under their SHA-256. The row
+// keeps their hashes, sizes and first bytes, and Input and Expected are
+// only filled in when a caller loads them.
+//
+// Tests made by a generation script record where they came from:
+// GeneratedBy is the generator command that wrote the input, e.g.
+// "gen 1000 42", and AnsweredBy the reference solution that wrote Expected.
 type TestCases struct {
-	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
-	Input     string    `json:"input"`
-	Expected  string    `json:"expected"`
-	ProblemID uuid.UUID `json:"problem_id" gorm:"type:uuid;not null;constraint:OnDelete:CASCADE"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	Input           string    `json:"input" gorm:"-"`
+	Expected        string    `json:"expected" gorm:"-"`
+	InputHash       string    `json:"input_hash" gorm:"type:varchar(64)"`
+	InputSize       int64     `json:"input_size" gorm:"not null;default:0"`
+	InputPreview    string    `json:"input_preview" gorm:"type:text"`
+	ExpectedHash    string    `json:"expected_hash" gorm:"type:varchar(64)"`
+	ExpectedSize    int64     `json:"expected_size" gorm:"not null;default:0"`
+	ExpectedPreview string    `json:"expected_preview" gorm:"type:text"`
+	ProblemID       uuid.UUID `json:"problem_id" gorm:"type:uuid;not null;constraint:OnDelete:CASCADE"`
+	IsSample        bool      `json:"is_sample" gorm:"not null;default:false"`
+	OrderIndex      int       `json:"order_index" gorm:"not null;default:0"`
+	Explanation     string    `json:"explanation,omitempty" gorm:"type:text"`
+	Subtask         int       `json:"subtask,omitempty" gorm:"not null;default:0"` // Subtask.Index, 0 if none
+	GeneratedBy     string    `json:"generated_by,omitempty" gorm:"type:text;not null;default:''"`
+	AnsweredBy      string    `json:"answered_by,omitempty" gorm:"not null;default:''"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// SampleTestCases returns the samples of tests, keeping their order.
+func SampleTestCases(tests []TestCases) []TestCases {
+	samples := make([]TestCases, 0, len(tests))
+	for _, tc := range tests {
+		if tc.IsSample {
+			samples = append(samples, tc)
+		}
+	}
+	return samples
 }
 
 func (u *TestCases) BeforeCreate(scope *gorm.DB) error {
@@ -24,7 +24,7 @@ type User struct {
 	MatchesPlayed      int       `json:"matches_played" gorm:"default:0"`
 	MatchesWon         int       `json:"matches_won" gorm:"default:0"`
 	SubmissionsCount   int       `json:"submissions_count" gorm:"default:0"` //attempted
-	LanguagePreference string    `json:"language_preference" gorm:"default:'python'"`
+	LanguagePreference string    `json:"language_preference" gorm:"default:'py'"`
 	Role               string    `json:"role" gorm:"type:varchar(10);default:'regular'"`
 	Code               string    `json:"code,omitempty"`
 	Expiry             time.Time `json:"expiry,omitempty"`
@@ -1,6 +1,10 @@
 package dto
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type CreateContestDTO struct {
 	Title       string    `json:"title" binding:"required"`
@@ -8,12 +12,61 @@ type CreateContestDTO struct {
 	StartTime   time.Time `json:"start_time" binding:"required"`
 	EndTime     time.Time `json:"end_time" binding:"required"`
 	IsRated     bool      `json:"is_rated"`
+	ScoringMode string    `json:"scoring_mode"` // hybrid when empty
+
+	Scoring *ScoringConfigDTO `json:"scoring,omitempty"` // defaults for omitted fields
+}
+
+// UpdateContestDTO changes the fields it sets and leaves the others.
+type UpdateContestDTO struct {
+	Title       *string    `json:"title,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	StartTime   *time.Time `json:"start_time,omitempty"`
+	EndTime     *time.Time `json:"end_time,omitempty"`
+	IsRated     *bool      `json:"is_rated,omitempty"`
+	ScoringMode *string    `json:"scoring_mode,omitempty"`
+
+	Scoring  *ScoringConfigDTO          `json:"scoring,omitempty"`
+	Problems []ContestProblemScoringDTO `json:"problems,omitempty"`
 }
 
-//	AddProblem(contestID, problemID uuid.UUID, orderIndex int, maxPoints int, partialCredit bool, timeMultiplier float64) error
+// ScoringConfigDTO sets the fields of a contest's scoring config that are
+// present.
+type ScoringConfigDTO struct {
+	TimePenaltyPerMin   *float64 `json:"time_penalty_per_min,omitempty"`
+	WrongAttemptPenalty *int     `json:"wrong_attempt_penalty,omitempty"`
+	PartialCreditMin    *float64 `json:"partial_credit_min,omitempty"`
+	TimePenaltyMinutes  *int     `json:"time_penalty_minutes,omitempty"`
+	DecayPerMinute      *float64 `json:"decay_per_minute,omitempty"`
+	WrongAttemptDecay   *float64 `json:"wrong_attempt_decay,omitempty"`
+	PointsFloor         *float64 `json:"points_floor,omitempty"`
+}
+
+// ContestProblemScoringDTO sets the scoring fields that are present on a
+// problem already in the contest.
+type ContestProblemScoringDTO struct {
+	ProblemID          uuid.UUID `json:"problem_id"`
+	MaxPoints          *int      `json:"max_points,omitempty"`
+	PartialCredit      *bool     `json:"partial_credit,omitempty"`
+	TimeMultiplier     *float64  `json:"time_multiplier,omitempty"`
+	TimePenaltyMinutes *int      `json:"time_penalty_minutes,omitempty"`
+}
 
 type AddProblemToContestDTO struct {
-	ProblemTitle       string `json:"problem_title" binding:"required"`
-	MaxPoints          int    `json:"max_points" binding:"required"`
-	TimePenaltyMinutes int    `json:"time_penalty_minutes" binding:"required"`
+	ProblemTitle       string   `json:"problem_title" binding:"required"`
+	MaxPoints          int      `json:"max_points" binding:"required"`
+	TimePenaltyMinutes *int     `json:"time_penalty_minutes,omitempty"` // the contest's when omitted
+	PartialCredit      bool     `json:"partial_credit"`
+	TimeMultiplier     *float64 `json:"time_multiplier,omitempty"` // 1 when omitted
+}
+
+// RatingHistoryEntry is a user's result in one rated contest.
+type RatingHistoryEntry struct {
+	ContestID    uuid.UUID `json:"contest_id"`
+	ContestName  string    `json:"contest_name"`
+	EndTime      time.Time `json:"end_time"`
+	Rank         int       `json:"rank"`
+	OldRating    float64   `json:"old_rating"`
+	NewRating    float64   `json:"new_rating"`
+	RatingChange int       `json:"rating_change"`
 }
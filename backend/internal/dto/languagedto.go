@@ -0,0 +1,24 @@
+package dto
+
+type CreateLanguageDTO struct {
+	ID               string   `json:"id" binding:"required"` // e.g., "py", "cpp"
+	Name             string   `json:"name" binding:"required"`
+	SourceFile       string   `json:"source_file" binding:"required"`
+	CompileCmd       []string `json:"compile_cmd"`
+	RunCmd           []string `json:"run_cmd" binding:"required"`
+	Version          string   `json:"version"`
+	TimeMultiplier   float64  `json:"time_multiplier"`
+	MemoryMultiplier float64  `json:"memory_multiplier"`
+}
+
+// UpdateLanguageDTO only changes the fields that are set.
+type UpdateLanguageDTO struct {
+	Name             *string   `json:"name"`
+	SourceFile       *string   `json:"source_file"`
+	CompileCmd       *[]string `json:"compile_cmd"`
+	RunCmd           *[]string `json:"run_cmd"`
+	Version          *string   `json:"version"`
+	TimeMultiplier   *float64  `json:"time_multiplier"`
+	MemoryMultiplier *float64  `json:"memory_multiplier"`
+	Enabled          *bool     `json:"enabled"`
+}
@@ -7,14 +7,32 @@ import (
 )
 
 type CreateTestCaseDTO struct {
-	Input    string `json:"input" binding:"required"`
-	Expected string `json:"expected" binding:"required"`
+	Input       string `json:"input" binding:"required"`
+	Expected    string `json:"expected" binding:"required"`
+	IsSample    bool   `json:"is_sample"`
+	OrderIndex  *int   `json:"order_index"` // defaults to the position in the list
+	Explanation string `json:"explanation"`
+	Subtask     int    `json:"subtask" binding:"omitempty,min=0"`
 }
 
 type CreateTestCaseWithProblemDTO struct {
-	Input     string    `json:"input" binding:"required"`
-	Expected  string    `json:"expected" binding:"required"`
-	ProblemID uuid.UUID `json:"problem_id" binding:"required"`
+	Input       string    `json:"input" binding:"required"`
+	Expected    string    `json:"expected" binding:"required"`
+	ProblemID   uuid.UUID `json:"problem_id" binding:"required"`
+	IsSample    bool      `json:"is_sample"`
+	OrderIndex  *int      `json:"order_index"` // defaults to after the last test
+	Explanation string    `json:"explanation"`
+	Subtask     int       `json:"subtask" binding:"omitempty,min=0"`
+}
+
+// UpdateTestCaseDTO only changes the fields that are set.
+type UpdateTestCaseDTO struct {
+	Input       *string `json:"input"`
+	Expected    *string `json:"expected"`
+	IsSample    *bool   `json:"is_sample"`
+	OrderIndex  *int    `json:"order_index"`
+	Explanation *string `json:"explanation"`
+	Subtask     *int    `json:"subtask"`
 }
 
 type CreateProblemDTO struct {
@@ -25,6 +43,30 @@ type CreateProblemDTO struct {
 	Difficulty   string                 `json:"difficulty" binding:"omitempty,oneof=easy medium hard"`
 	TestCases    []CreateTestCaseDTO    `json:"test_cases" binding:"omitempty,dive"`
 	Boilerplates []CreateBoilerplateDTO `json:"boilerplates" binding:"omitempty,dive"`
+
+	TimeLimitMs    int                `json:"time_limit_ms" binding:"omitempty,min=1"`
+	MemoryLimitMB  int                `json:"memory_limit_mb" binding:"omitempty,min=1"`
+	LanguageLimits []LanguageLimitDTO `json:"language_limits" binding:"omitempty,dive"`
+	Checker        *CheckerDTO        `json:"checker"` // defaults to the whitespace checker
+
+	Type       string      `json:"type" binding:"omitempty,oneof=standard interactive function"`
+	Interactor *ProgramDTO `json:"interactor"` // required for interactive problems
+	// Signature is required for function problems, e.g.
+	// "twoSum(nums: int[], target: int) -> int[]". Boilerplates are generated
+	// from it for every language without one.
+	Signature string `json:"signature"`
+
+	Subtasks []SubtaskDTO `json:"subtasks" binding:"omitempty,dive"`
+
+	// Validator checks the format of every test input when the problem is
+	// verified. Solutions are the setter's reference solutions.
+	Validator *ProgramDTO   `json:"validator"`
+	Solutions []SolutionDTO `json:"solutions" binding:"omitempty,dive"`
+
+	// Generators and GenerationScript make large tests on the server, see
+	// GenerateTestsDTO.
+	Generators       []GeneratorDTO `json:"generators" binding:"omitempty,dive"`
+	GenerationScript string         `json:"generation_script"`
 }
 
 type UpdateProblemDTO struct {
@@ -34,15 +76,140 @@ type UpdateProblemDTO struct {
 	Difficulty   string                 `json:"difficulty" binding:"omitempty,oneof=easy medium hard"`
 	TestCases    []CreateTestCaseDTO    `json:"test_cases" binding:"omitempty,dive"`
 	Boilerplates []CreateBoilerplateDTO `json:"boilerplates" binding:"omitempty,dive"`
+
+	TimeLimitMs   int `json:"time_limit_ms" binding:"omitempty,min=1"`
+	MemoryLimitMB int `json:"memory_limit_mb" binding:"omitempty,min=1"`
+	// LanguageLimits replaces all overrides when present; send an empty list
+	// to clear them.
+	LanguageLimits *[]LanguageLimitDTO `json:"language_limits" binding:"omitempty,dive"`
+	Checker        *CheckerDTO         `json:"checker"`
+
+	Type       string      `json:"type" binding:"omitempty,oneof=standard interactive function"`
+	Interactor *ProgramDTO `json:"interactor"`
+	// Signature changes the function of a function problem and regenerates
+	// its boilerplates.
+	Signature string `json:"signature"`
+
+	// Subtasks replaces all subtasks when present; send an empty list to
+	// make the problem all or nothing again.
+	Subtasks *[]SubtaskDTO `json:"subtasks" binding:"omitempty,dive"`
+
+	// Validator replaces the input validator; empty code removes it.
+	Validator *ProgramDTO `json:"validator"`
+	// GenerationScript replaces the generation script when present.
+	GenerationScript *string `json:"generation_script"`
+}
+
+// CheckerDTO selects how outputs are checked. Language and Code are only used
+// by the custom checker.
+type CheckerDTO struct {
+	Type       string  `json:"type" binding:"required,oneof=exact lines whitespace case_insensitive float unordered_lines custom"`
+	AbsEpsilon float64 `json:"abs_epsilon"`
+	RelEpsilon float64 `json:"rel_epsilon"`
+	Language   string  `json:"language"`
+	Code       string  `json:"code"`
+}
+
+// ProgramDTO is a helper program written by the problem setter.
+type ProgramDTO struct {
+	Language string `json:"language" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// SolutionDTO is a reference solution and the verdict it should get, one of
+// domain.SolutionOutcomes. Name tells apart the solutions of a problem.
+type SolutionDTO struct {
+	Name     string `json:"name" binding:"required"`
+	Language string `json:"language" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+	Expected string `json:"expected" binding:"required,oneof=accepted wrong_answer time_limit_exceeded runtime_error"`
+}
+
+// ProblemStatusDTO moves a problem to another status. ReviewerID assigns
+// the reviewer when a problem is sent to review, or reassigns it while the
+// problem is in review.
+type ProblemStatusDTO struct {
+	Status     string     `json:"status" binding:"required,oneof=draft in_review published archived"`
+	ReviewerID *uuid.UUID `json:"reviewer_id"`
+}
+
+// GeneratorDTO is a test generator. Generation scripts run it by Name with
+// command line arguments and take its output as a test input.
+type GeneratorDTO struct {
+	Name     string `json:"name" binding:"required"`
+	Language string `json:"language" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// GenerationDTO is what a problem has for generating tests.
+type GenerationDTO struct {
+	Script     string         `json:"script"`
+	Generators []GeneratorDTO `json:"generators"`
+}
+
+// GenerateTestsDTO runs a problem's generation script. Mode is one of the
+// test upload modes. Expected outputs are written by Solution, a reference
+// solution expected to be accepted; it defaults to the first one by name.
+type GenerateTestsDTO struct {
+	Mode     string `json:"mode" binding:"omitempty,oneof=append replace"`
+	Solution string `json:"solution"`
+}
+
+// SubtaskDTO describes a group of tests worth Points. Tests join it through
+// their subtask field.
+type SubtaskDTO struct {
+	Index        int    `json:"index" binding:"required,min=1"`
+	Name         string `json:"name"`
+	Points       int    `json:"points" binding:"min=0"`
+	Aggregation  string `json:"aggregation" binding:"omitempty,oneof=all min"`
+	Dependencies []int  `json:"dependencies"`
+}
+
+// LanguageLimitDTO overrides a problem's limits for one language. Zero fields
+// keep the problem limit scaled by the language multiplier.
+type LanguageLimitDTO struct {
+	Language      string `json:"language" binding:"required"`
+	TimeLimitMs   int    `json:"time_limit_ms,omitempty"`
+	MemoryLimitMB int    `json:"memory_limit_mb,omitempty"`
 }
 
 type TestCaseResponseDTO struct {
-	ID        string    `json:"id"`
-	Input     string    `json:"input"`
-	Expected  string    `json:"expected"`
-	ProblemID string    `json:"problem_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          string    `json:"id"`
+	Input       string    `json:"input"`
+	Expected    string    `json:"expected"`
+	ProblemID   string    `json:"problem_id"`
+	IsSample    bool      `json:"is_sample"`
+	OrderIndex  int       `json:"order_index"`
+	Explanation string    `json:"explanation,omitempty"`
+	Subtask     int       `json:"subtask,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Modes of a test archive upload.
+const (
+	TEST_UPLOAD_APPEND  = "append"
+	TEST_UPLOAD_REPLACE = "replace"
+)
+
+// TestUploadDTO summarises a test archive upload.
+type TestUploadDTO struct {
+	Mode    string            `json:"mode"`
+	Added   int               `json:"added"`
+	Removed int64             `json:"removed"` // replaced tests
+	Samples int               `json:"samples"`
+	Tests   []UploadedTestDTO `json:"tests"`
+}
+
+type UploadedTestDTO struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"` // path in the archive without extension
+	OrderIndex    int    `json:"order_index"`
+	IsSample      bool   `json:"is_sample"`
+	Subtask       int    `json:"subtask,omitempty"`
+	InputBytes    int64  `json:"input_bytes"`
+	ExpectedBytes int64  `json:"expected_bytes"`
+	GeneratedBy   string `json:"generated_by,omitempty"`
 }
 
 type ProblemResponseDTO struct {
@@ -52,10 +219,27 @@ type ProblemResponseDTO struct {
 	Description  string                   `json:"description"`
 	Tag          string                   `json:"tag"`
 	Difficulty   string                   `json:"difficulty"`
+	Type         string                   `json:"type"`
+	Signature    string                   `json:"signature,omitempty"`
 	TestCases    []TestCaseResponseDTO    `json:"test_cases,omitempty"`
 	Boilerplates []BoilerplateResponseDTO `json:"boilerplates,omitempty"`
 	CreatedAt    time.Time                `json:"created_at"`
 	UpdatedAt    time.Time                `json:"updated_at"`
+
+	TimeLimitMs    int                `json:"time_limit_ms"`
+	MemoryLimitMB  int                `json:"memory_limit_mb"`
+	LanguageLimits []LanguageLimitDTO `json:"language_limits,omitempty"`
+
+	CheckerType       string  `json:"checker_type"`
+	CheckerAbsEpsilon float64 `json:"checker_abs_epsilon,omitempty"`
+	CheckerRelEpsilon float64 `json:"checker_rel_epsilon,omitempty"`
+
+	Subtasks []SubtaskDTO `json:"subtasks,omitempty"`
+
+	// TestCaseCount counts hidden tests too; lists only carry the samples.
+	TestCaseCount int64 `json:"test_case_count"`
+
+	Status string `json:"status"`
 }
 
 type ProblemListQueryDTO struct {
@@ -65,6 +249,10 @@ type ProblemListQueryDTO struct {
 	Search     string `query:"search"`
 	Testcases  bool   `query:"test-cases"`
 	Page       string `query:"page"`
+	Status     string `query:"status"` // admins only
+	// Public limits the list to the problems everyone may see. It is set
+	// for non-admins and cannot be given in the query.
+	Public bool `query:"-"`
 }
 
 type ProblemListResponse struct {
@@ -75,7 +263,7 @@ type ProblemListResponse struct {
 }
 
 type CreateBoilerplateDTO struct {
-	Language string `json:"language" binding:"required"` // e.g., "python", "go"
+	Language string `json:"language" binding:"required"` // a language registry id, e.g., "py", "go"
 	Code     string `json:"code" binding:"required"`
 }
 
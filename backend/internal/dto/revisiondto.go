@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Changes of a line or test between two revisions.
+const (
+	DIFF_SAME    = "same"
+	DIFF_ADDED   = "added"
+	DIFF_REMOVED = "removed"
+	DIFF_CHANGED = "changed"
+)
+
+// RevisionSummaryDTO is a revision in the revision list, without its
+// statement and tests.
+type RevisionSummaryDTO struct {
+	Number    int        `json:"number"`
+	AuthorID  *uuid.UUID `json:"author_id,omitempty"`
+	Summary   string     `json:"summary"`
+	Tests     int        `json:"tests"`
+	Current   bool       `json:"current"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RevisionDiffDTO lists what changed from revision From to revision To.
+// Description is a line diff and is empty when the statement is the same.
+type RevisionDiffDTO struct {
+	From        int              `json:"from"`
+	To          int              `json:"to"`
+	Fields      []FieldChangeDTO `json:"fields"`
+	Description []DiffLineDTO    `json:"description,omitempty"`
+	Tests       []TestChangeDTO  `json:"tests"`
+}
+
+type FieldChangeDTO struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type DiffLineDTO struct {
+	Change string `json:"change"` // same, added or removed
+	Text   string `json:"text"`
+}
+
+// TestChangeDTO is a test that differs between two revisions, compared by
+// position. Fields names what changed in a changed test, e.g. "input".
+type TestChangeDTO struct {
+	OrderIndex int      `json:"order_index"`
+	Change     string   `json:"change"` // added, removed or changed
+	Fields     []string `json:"fields,omitempty"`
+}
@@ -0,0 +1,76 @@
+package dto
+
+import "github.com/google/uuid"
+
+// RunCodeDTO runs code without creating a submission. With Stdin set the
+// code runs once on it; otherwise it is judged on the problem's samples.
+type RunCodeDTO struct {
+	ProblemID *uuid.UUID `json:"problem_id"` // optional with Stdin; supplies the limits
+	Language  string     `json:"language" binding:"required"`
+	Code      string     `json:"code" binding:"required"`
+	Stdin     *string    `json:"stdin"`
+}
+
+// RunResultDTO is the outcome of a run. Status is "ok" for a custom input
+// run that finished normally and a verdict for sample runs.
+type RunResultDTO struct {
+	Status        string       `json:"status"`
+	ExecutionTime int          `json:"execution_time"` // slowest run, in milliseconds
+	MemoryUsed    int          `json:"memory_used"`    // peak, in KB
+	ErrorMessage  string       `json:"error_message,omitempty"`
+	Tests         []RunTestDTO `json:"tests"`
+}
+
+// RunTestDTO is one run of the code. Input and Expected are only set for
+// sample runs.
+type RunTestDTO struct {
+	Index          int    `json:"index"`
+	Status         string `json:"status"`
+	ExecutionTime  int    `json:"execution_time"`
+	MemoryUsed     int    `json:"memory_used"`
+	Input          string `json:"input,omitempty"`
+	Expected       string `json:"expected,omitempty"`
+	Stdout         string `json:"stdout"`
+	Stderr         string `json:"stderr"`
+	CheckerMessage string `json:"checker_message,omitempty"`
+}
+
+// VerificationDTO reports a check of a problem's tests and reference
+// solutions. Mismatches counts solutions that did not get their expected
+// verdict and inputs the validator rejected, plus one when the validator
+// does not compile; OK is set when there are none.
+type VerificationDTO struct {
+	OK         bool                `json:"ok"`
+	Mismatches int                 `json:"mismatches"`
+	Tests      int                 `json:"tests"`
+	Validator  *ValidatorReportDTO `json:"validator,omitempty"` // nil without a validator
+	Solutions  []SolutionReportDTO `json:"solutions"`
+	Warnings   []string            `json:"warnings"`
+}
+
+// ValidatorReportDTO lists the inputs the validator rejected.
+type ValidatorReportDTO struct {
+	CompileError string            `json:"compile_error,omitempty"`
+	Invalid      []InvalidInputDTO `json:"invalid"`
+}
+
+type InvalidInputDTO struct {
+	TestCaseID uuid.UUID `json:"test_case_id"`
+	Index      int       `json:"index"` // 1-based position of the test
+	Message    string    `json:"message"`
+}
+
+// SolutionReportDTO is the verdict one reference solution got. FailedTest
+// is the 1-based index of its first failing test, 0 when all passed.
+type SolutionReportDTO struct {
+	Name            string `json:"name"`
+	Language        string `json:"language"`
+	Expected        string `json:"expected"`
+	Status          string `json:"status"`
+	Matches         bool   `json:"matches"`
+	TestCasesPassed int    `json:"test_cases_passed"`
+	TotalTestCases  int    `json:"total_test_cases"`
+	ExecutionTime   int    `json:"execution_time"` // slowest test, in milliseconds
+	FailedTest      int    `json:"failed_test,omitempty"`
+	ErrorMessage    string `json:"error_message,omitempty"`
+}
@@ -2,17 +2,14 @@ package dto
 
 import "github.com/google/uuid"
 
+// CreateSubmissionDTO carries only what the client is allowed to decide.
+// Verdict, test counts and resource usage are set by the judge. Language must
+// be the id of an enabled language in the registry.
 type CreateSubmissionDTO struct {
-	ProblemID       uuid.UUID  `json:"problem_id" validate:"required"`
-	ContestID       *uuid.UUID `json:"contest_id,omitempty"` // Optional: NULL for practice, UUID for contest
-	Language        string     `json:"language" validate:"required,oneof=py js go cpp java"`
-	Code            string     `json:"code" validate:"required"`
-	Status          string     `json:"status" validate:"required"`
-	ExecutionTime   int        `json:"execution_time"`
-	MemoryUsed      int        `json:"memory_used"`
-	TestCasesPassed int        `json:"test_cases_passed"`
-	TotalTestCases  int        `json:"total_test_cases"`
-	ErrorMessage    string     `json:"error_message,omitempty"`
+	ProblemID uuid.UUID  `json:"problem_id" validate:"required"`
+	ContestID *uuid.UUID `json:"contest_id,omitempty"` // Optional: NULL for practice, UUID for contest
+	Language  string     `json:"language" validate:"required"`
+	Code      string     `json:"code" validate:"required"`
 }
 
 type SubmissionListQueryDTO struct {
@@ -39,9 +36,61 @@ type SubmissionResponseDTO struct {
 	TestCasesPassed int        `json:"test_cases_passed"`
 	TotalTestCases  int        `json:"total_test_cases"`
 	PointsEarned    int        `json:"points_earned"`
+	ProblemRevision int        `json:"problem_revision"` // 0 if judged before revisions
 	CreatedAt       string     `json:"created_at"`
 }
 
+// SubmissionStatusDTO tells clients where a submission is in the judge.
+// Pending is false once the status is a final verdict.
+type SubmissionStatusDTO struct {
+	ID              uuid.UUID `json:"id"`
+	Status          string    `json:"status"`
+	Pending         bool      `json:"pending"`
+	TestCasesPassed int       `json:"test_cases_passed"`
+	TotalTestCases  int       `json:"total_test_cases"`
+	PointsEarned    int       `json:"points_earned"`
+}
+
+// RejudgeDTO reports how many submissions were queued to be judged again.
+type RejudgeDTO struct {
+	Queued int `json:"queued"`
+}
+
+// SubmissionDetailDTO is a single submission with its per-test results.
+type SubmissionDetailDTO struct {
+	SubmissionResponseDTO
+	Code         string                    `json:"code"`
+	ErrorMessage string                    `json:"error_message,omitempty"`
+	TestResults  []SubmissionTestResultDTO `json:"test_results"`
+	Subtasks     []SubtaskResultDTO        `json:"subtasks,omitempty"`
+}
+
+// SubmissionTestResultDTO reports one test case. Input, expected output and
+// program output are only filled in for tests that are public.
+type SubmissionTestResultDTO struct {
+	TestCaseID     *uuid.UUID `json:"test_case_id,omitempty"`
+	Index          int        `json:"index"` // 1-based position in the test set
+	Subtask        int        `json:"subtask,omitempty"`
+	Hidden         bool       `json:"hidden"`
+	Status         string     `json:"status"`
+	ExecutionTime  int        `json:"execution_time"`
+	MemoryUsed     int        `json:"memory_used"`
+	Input          string     `json:"input,omitempty"`
+	Expected       string     `json:"expected,omitempty"`
+	Stdout         string     `json:"stdout,omitempty"`
+	Stderr         string     `json:"stderr,omitempty"`
+	CheckerMessage string     `json:"checker_message,omitempty"`
+}
+
+// SubtaskResultDTO is the score of one subtask of a submission.
+type SubtaskResultDTO struct {
+	Index  int     `json:"index"`
+	Name   string  `json:"name,omitempty"`
+	Status string  `json:"status"`
+	Points int     `json:"points"`
+	Score  float64 `json:"score"`
+}
+
 type UserStatsDTO struct {
 	TotalSubmissions  int                     `json:"total_submissions"`
 	AcceptedCount     int                     `json:"accepted_count"`
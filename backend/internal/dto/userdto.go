@@ -6,6 +6,11 @@ type UserRegister struct {
 	Password string `json:"password" validate:"required,min=6"`
 }
 
+// UpdatePreferencesDTO carries the settings a user may change about themselves.
+type UpdatePreferencesDTO struct {
+	LanguagePreference string `json:"language_preference" validate:"required"` // a language registry id
+}
+
 type UserLogin struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -0,0 +1,241 @@
+package harness
+
+import (
+	"fmt"
+	"strings"
+)
+
+type cpp struct{}
+
+const cppDriver = `#include <cctype>
+#include <cstdio>
+#include <cstdlib>
+#include <iostream>
+#include <sstream>
+#include <stdexcept>
+#include <string>
+#include <vector>
+#include <unistd.h>
+
+namespace _h {
+
+struct Reader {
+    const std::string& s;
+    size_t i = 0;
+    explicit Reader(const std::string& s) : s(s) {}
+    void ws() { while (i < s.size() && (s[i] == ' ' || s[i] == '\t' || s[i] == '\r')) i++; }
+    void expect(char c) {
+        ws();
+        if (i >= s.size() || s[i] != c) throw std::runtime_error(std::string("expected ") + c);
+        i++;
+    }
+    bool peek(char c) { ws(); return i < s.size() && s[i] == c; }
+    std::string number() {
+        ws();
+        size_t start = i;
+        while (i < s.size() && (isdigit((unsigned char)s[i]) || s[i] == '-' || s[i] == '+' || s[i] == '.' || s[i] == 'e' || s[i] == 'E')) i++;
+        if (start == i) throw std::runtime_error("expected a number");
+        return s.substr(start, i - start);
+    }
+};
+
+inline void utf8(std::string& out, unsigned cp) {
+    if (cp < 0x80) out += (char)cp;
+    else if (cp < 0x800) { out += (char)(0xC0 | (cp >> 6)); out += (char)(0x80 | (cp & 0x3F)); }
+    else if (cp < 0x10000) { out += (char)(0xE0 | (cp >> 12)); out += (char)(0x80 | ((cp >> 6) & 0x3F)); out += (char)(0x80 | (cp & 0x3F)); }
+    else { out += (char)(0xF0 | (cp >> 18)); out += (char)(0x80 | ((cp >> 12) & 0x3F)); out += (char)(0x80 | ((cp >> 6) & 0x3F)); out += (char)(0x80 | (cp & 0x3F)); }
+}
+
+inline void read(Reader& r, int& v) { v = (int)std::stoll(r.number()); }
+inline void read(Reader& r, long long& v) { v = std::stoll(r.number()); }
+inline void read(Reader& r, double& v) { v = std::stod(r.number()); }
+inline void read(Reader& r, bool& v) {
+    r.ws();
+    if (r.s.compare(r.i, 4, "true") == 0) { v = true; r.i += 4; }
+    else if (r.s.compare(r.i, 5, "false") == 0) { v = false; r.i += 5; }
+    else throw std::runtime_error("expected a boolean");
+}
+inline void read(Reader& r, std::string& v) {
+    r.expect('"');
+    v.clear();
+    while (r.i < r.s.size() && r.s[r.i] != '"') {
+        char c = r.s[r.i++];
+        if (c != '\\') { v += c; continue; }
+        char e = r.s.at(r.i++);
+        switch (e) {
+            case 'n': v += '\n'; break;
+            case 't': v += '\t'; break;
+            case 'r': v += '\r'; break;
+            case 'b': v += '\b'; break;
+            case 'f': v += '\f'; break;
+            case 'u': {
+                unsigned cp = std::stoul(r.s.substr(r.i, 4), nullptr, 16);
+                r.i += 4;
+                if (cp >= 0xD800 && cp < 0xDC00 && r.s.compare(r.i, 2, "\\u") == 0) {
+                    unsigned lo = std::stoul(r.s.substr(r.i + 2, 4), nullptr, 16);
+                    r.i += 6;
+                    cp = 0x10000 + ((cp - 0xD800) << 10) + (lo - 0xDC00);
+                }
+                utf8(v, cp);
+                break;
+            }
+            default: v += e;
+        }
+    }
+    r.expect('"');
+}
+template <class T> void read(Reader& r, std::vector<T>& v) {
+    r.expect('[');
+    v.clear();
+    if (r.peek(']')) { r.i++; return; }
+    while (true) {
+        T x;
+        read(r, x);
+        v.push_back(x);
+        if (r.peek(',')) { r.i++; continue; }
+        r.expect(']');
+        return;
+    }
+}
+
+template <class T> void arg(const std::vector<std::string>& lines, size_t i, T& v) {
+    if (i >= lines.size()) throw std::runtime_error("missing argument " + std::to_string(i + 1));
+    Reader r(lines[i]);
+    read(r, v);
+}
+
+inline void write(std::string& out, int v) { out += std::to_string(v); }
+inline void write(std::string& out, long long v) { out += std::to_string(v); }
+inline void write(std::string& out, double v) {
+    char buf[64];
+    snprintf(buf, sizeof buf, "%.5f", v);
+    out += std::string(buf) == "-0.00000" ? "0.00000" : buf;
+}
+inline void write(std::string& out, bool v) { out += v ? "true" : "false"; }
+inline void write(std::string& out, const std::string& v) {
+    static const char* hex = "0123456789abcdef";
+    out += '"';
+    for (unsigned char c : v) {
+        if (c == '"') out += "\\\"";
+        else if (c == '\\') out += "\\\\";
+        else if (c == '\n') out += "\\n";
+        else if (c == '\r') out += "\\r";
+        else if (c == '\t') out += "\\t";
+        else if (c < 0x20) { out += "\\u00"; out += hex[c >> 4]; out += hex[c & 15]; }
+        else out += (char)c;
+    }
+    out += '"';
+}
+template <class T> void write(std::string& out, const std::vector<T>& v) {
+    out += '[';
+    for (size_t i = 0; i < v.size(); i++) {
+        if (i > 0) out += ',';
+        write(out, v[i]);
+    }
+    out += ']';
+}
+
+inline std::vector<std::string> lines() {
+    std::stringstream in;
+    in << std::cin.rdbuf();
+    std::vector<std::string> out;
+    std::string l;
+    while (std::getline(in, l)) {
+        if (l.find_first_not_of(" \t\r") != std::string::npos) out.push_back(l);
+    }
+    return out;
+}
+
+// quiet points stdout at stderr while the solution runs, so that what it
+// prints cannot be mistaken for the result, and returns the real stdout.
+inline int quiet() {
+    std::cout.flush();
+    std::fflush(stdout);
+    int saved = dup(1);
+    dup2(2, 1);
+    return saved;
+}
+
+inline void restore(int saved) {
+    std::cout.flush();
+    std::fflush(stdout);
+    dup2(saved, 1);
+    close(saved);
+}
+
+}  // namespace _h
+`
+
+func (cpp) typ(t Type) string {
+	var s string
+	switch t.Base {
+	case BaseLong:
+		s = "long long"
+	case BaseDouble:
+		s = "double"
+	case BaseBool:
+		s = "bool"
+	case BaseString:
+		s = "string"
+	default:
+		s = "int"
+	}
+	for i := 0; i < t.Dims; i++ {
+		s = "vector<" + s + ">"
+	}
+	return s
+}
+
+// qualified spells a type without relying on "using namespace std".
+func (c cpp) qualified(t Type) string {
+	s := c.typ(t)
+	s = strings.ReplaceAll(s, "vector<", "std::vector<")
+	return strings.ReplaceAll(s, "string", "std::string")
+}
+
+func (c cpp) stub(sig *Signature) string {
+	params := make([]string, len(sig.Params))
+	for i, p := range sig.Params {
+		// Arrays are passed by reference as on most judges
+		if p.Type.Dims > 0 {
+			params[i] = c.typ(p.Type) + "& " + p.Name
+		} else {
+			params[i] = c.typ(p.Type) + " " + p.Name
+		}
+	}
+	return join(
+		"#include <bits/stdc++.h>",
+		"using namespace std;",
+		"",
+		"class Solution {",
+		"public:",
+		fmt.Sprintf("    %s %s(%s) {", c.typ(sig.Returns), sig.Name, strings.Join(params, ", ")),
+		"",
+		"    }",
+		"};",
+	)
+}
+
+func (c cpp) source(sig *Signature, code string) string {
+	lines := []string{"int main() {"}
+	if len(sig.Params) > 0 {
+		lines = append(lines, "    std::vector<std::string> _lines = _h::lines();")
+	}
+	for i, p := range sig.Params {
+		lines = append(lines,
+			fmt.Sprintf("    %s %s;", c.qualified(p.Type), p.Name),
+			fmt.Sprintf("    _h::arg(_lines, %d, %s);", i, p.Name),
+		)
+	}
+	lines = append(lines,
+		"    int _stdout = _h::quiet();",
+		"    Solution _sol;",
+		"    std::string _out;",
+		fmt.Sprintf("    _h::write(_out, (%s)_sol.%s(%s));", c.qualified(sig.Returns), sig.Name, strings.Join(sig.names(), ", ")),
+		"    _h::restore(_stdout);",
+		"    std::cout << _out << '\\n';",
+		"    return 0;",
+		"}",
+	)
+	return code + "\n\n// " + driverBanner + "\n" + cppDriver + "\n" + join(lines...)
+}
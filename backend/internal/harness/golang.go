@@ -0,0 +1,147 @@
+package harness
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type golang struct{}
+
+// golangImports are added on the package line, so the contestant's line
+// numbers in compiler errors stay the same.
+const golangImports = `; import (_hbufio "bufio"; _hjson "encoding/json"; _hos "os"; _hreflect "reflect"; _hstrconv "strconv"; _hstrings "strings")`
+
+const golangDriver = `func _hargs() []string {
+	data, _ := _hbufio.NewReader(_hos.Stdin).ReadString(0)
+	var lines []string
+	for _, l := range _hstrings.Split(data, "\n") {
+		if _hstrings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+func _hdecode(lines []string, i int, v any) {
+	if i >= len(lines) {
+		panic("missing argument " + _hstrconv.Itoa(i+1))
+	}
+	if err := _hjson.Unmarshal([]byte(lines[i]), v); err != nil {
+		panic(err)
+	}
+}
+
+func _hstr(b *_hstrings.Builder, s string) {
+	b.WriteByte('"')
+	for _, c := range s {
+		switch {
+		case c == '"':
+			b.WriteString("\\\"")
+		case c == '\\':
+			b.WriteString("\\\\")
+		case c == '\n':
+			b.WriteString("\\n")
+		case c == '\r':
+			b.WriteString("\\r")
+		case c == '\t':
+			b.WriteString("\\t")
+		case c < 0x20:
+			b.WriteString("\\u00")
+			b.WriteByte("0123456789abcdef"[c>>4])
+			b.WriteByte("0123456789abcdef"[c&15])
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('"')
+}
+
+func _hout(b *_hstrings.Builder, v _hreflect.Value) {
+	switch v.Kind() {
+	case _hreflect.Slice:
+		b.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			_hout(b, v.Index(i))
+		}
+		b.WriteByte(']')
+	case _hreflect.Float64:
+		s := _hstrconv.FormatFloat(v.Float(), 'f', 5, 64)
+		if s == "-0.00000" {
+			s = "0.00000"
+		}
+		b.WriteString(s)
+	case _hreflect.Bool:
+		b.WriteString(_hstrconv.FormatBool(v.Bool()))
+	case _hreflect.String:
+		_hstr(b, v.String())
+	default:
+		b.WriteString(_hstrconv.FormatInt(v.Int(), 10))
+	}
+}
+`
+
+var packageRe = regexp.MustCompile(`(?m)^package\s+[A-Za-z_][A-Za-z0-9_]*`)
+
+func (golang) typ(t Type) string {
+	var s string
+	switch t.Base {
+	case BaseLong:
+		s = "int64"
+	case BaseDouble:
+		s = "float64"
+	case BaseBool:
+		s = "bool"
+	case BaseString:
+		s = "string"
+	default:
+		s = "int"
+	}
+	return strings.Repeat("[]", t.Dims) + s
+}
+
+func (g golang) stub(sig *Signature) string {
+	params := make([]string, len(sig.Params))
+	for i, p := range sig.Params {
+		params[i] = p.Name + " " + g.typ(p.Type)
+	}
+	return join(
+		"package main",
+		"",
+		fmt.Sprintf("func %s(%s) %s {", sig.Name, strings.Join(params, ", "), g.typ(sig.Returns)),
+		"",
+		"}",
+	)
+}
+
+func (g golang) source(sig *Signature, code string) string {
+	if loc := packageRe.FindStringIndex(code); loc != nil {
+		code = code[:loc[1]] + golangImports + code[loc[1]:]
+	} else {
+		code = "package main" + golangImports + "\n" + code
+	}
+
+	lines := []string{"func main() {"}
+	if len(sig.Params) > 0 {
+		lines = append(lines, "\t_hlines := _hargs()")
+	}
+	for i, p := range sig.Params {
+		lines = append(lines,
+			fmt.Sprintf("\tvar %s %s", p.Name, g.typ(p.Type)),
+			fmt.Sprintf("\t_hdecode(_hlines, %d, &%s)", i, p.Name),
+		)
+	}
+	lines = append(lines,
+		"\t_hstdout := _hos.Stdout",
+		"\t_hos.Stdout = _hos.Stderr",
+		"\tvar _hb _hstrings.Builder",
+		fmt.Sprintf("\t_hout(&_hb, _hreflect.ValueOf(%s(%s)))", sig.Name, strings.Join(sig.names(), ", ")),
+		"\t_hb.WriteByte('\\n')",
+		"\t_hstdout.WriteString(_hb.String())",
+		"}",
+	)
+	return code + "\n\n// " + driverBanner + "\n\n" + golangDriver + "\n" + join(lines...)
+}
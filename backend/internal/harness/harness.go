@@ -0,0 +1,81 @@
+package harness
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ErrUnsupportedLanguage is returned for languages without a generator.
+var ErrUnsupportedLanguage = errors.New("language has no function harness")
+
+// driverBanner starts the generated driver so compiler errors in it are
+// recognisable.
+const driverBanner = "---- driver: reads the arguments and prints the result ----"
+
+// generator knows how one language calls a function with typed arguments.
+type generator interface {
+	// stub is the starting code shown to the contestant.
+	stub(sig *Signature) string
+	// source combines the contestant's code with the driver into the
+	// program that is compiled and run. Stdout only carries the result:
+	// whatever the contestant's code prints goes to stderr.
+	source(sig *Signature, code string) string
+}
+
+// generators are keyed by language registry id.
+var generators = map[string]generator{
+	"py":   python{},
+	"js":   javascript{},
+	"go":   golang{},
+	"cpp":  cpp{},
+	"java": java{},
+}
+
+// Supports reports whether function problems can be solved in a language.
+func Supports(language string) bool {
+	_, ok := generators[language]
+	return ok
+}
+
+// Languages lists the language ids that have a generator.
+func Languages() []string {
+	ids := make([]string, 0, len(generators))
+	for id := range generators {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Stub returns the code the editor starts with for a language.
+func Stub(sig *Signature, language string) (string, error) {
+	g, ok := generators[language]
+	if !ok {
+		return "", ErrUnsupportedLanguage
+	}
+	return g.stub(sig), nil
+}
+
+// Source returns the program to judge for a contestant's code.
+func Source(sig *Signature, language, code string) (string, error) {
+	g, ok := generators[language]
+	if !ok {
+		return "", ErrUnsupportedLanguage
+	}
+	return g.source(sig, code), nil
+}
+
+// names returns the parameter names of sig.
+func (s *Signature) names() []string {
+	names := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// join writes one line per item, for building generated code.
+func join(lines ...string) string {
+	return strings.Join(lines, "\n") + "\n"
+}
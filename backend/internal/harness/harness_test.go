@@ -0,0 +1,143 @@
+package harness
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolchains mirror the default language recipes of the judge.
+var toolchains = map[string]struct {
+	file    string
+	compile []string
+	run     []string
+}{
+	"py":   {"main.py", nil, []string{"python3", "main.py"}},
+	"js":   {"main.js", nil, []string{"node", "main.js"}},
+	"go":   {"main.go", []string{"go", "build", "-o", "main", "main.go"}, []string{"./main"}},
+	"cpp":  {"main.cpp", []string{"g++", "-O2", "-std=c++17", "-o", "main", "main.cpp"}, []string{"./main"}},
+	"java": {"Main.java", []string{"javac", "Main.java"}, []string{"java", "-cp", ".", "Main"}},
+}
+
+var harnessCases = []struct {
+	signature string
+	input     string
+	want      string
+	solutions map[string]string
+}{
+	{
+		signature: "twoSum(nums: int[], target: int) -> int[]",
+		input:     "[2,7,11,15]\n9\n",
+		want:      "[0,1]",
+		solutions: map[string]string{
+			"py":   "def twoSum(nums, target):\n    seen = {}\n    for i, n in enumerate(nums):\n        if target - n in seen:\n            return [seen[target - n], i]\n        seen[n] = i\n",
+			"js":   "function twoSum(nums, target) {\n  const seen = new Map();\n  for (let i = 0; i < nums.length; i++) {\n    if (seen.has(target - nums[i])) return [seen.get(target - nums[i]), i];\n    seen.set(nums[i], i);\n  }\n}\n",
+			"go":   "package main\n\nfunc twoSum(nums []int, target int) []int {\n\tseen := map[int]int{}\n\tfor i, n := range nums {\n\t\tif j, ok := seen[target-n]; ok {\n\t\t\treturn []int{j, i}\n\t\t}\n\t\tseen[n] = i\n\t}\n\treturn nil\n}\n",
+			"cpp":  "#include <bits/stdc++.h>\nusing namespace std;\n\nclass Solution {\npublic:\n    vector<int> twoSum(vector<int>& nums, int target) {\n        unordered_map<int, int> seen;\n        for (int i = 0; i < (int)nums.size(); i++) {\n            if (seen.count(target - nums[i])) return {seen[target - nums[i]], i};\n            seen[nums[i]] = i;\n        }\n        return {};\n    }\n};\n",
+			"java": "import java.util.*;\n\nclass Solution {\n    public int[] twoSum(int[] nums, int target) {\n        Map<Integer, Integer> seen = new HashMap<>();\n        for (int i = 0; i < nums.length; i++) {\n            if (seen.containsKey(target - nums[i])) return new int[]{seen.get(target - nums[i]), i};\n            seen.put(nums[i], i);\n        }\n        return new int[0];\n    }\n}\n",
+		},
+	},
+	{
+		signature: "shout(words: string[], loud: bool) -> string[]",
+		input:     "[\"say \\\"hi\\\"\",\"tab\\there\",\"é\"]\ntrue\n",
+		want:      "[\"say \\\"hi\\\"!\",\"tab\\there!\",\"é!\"]",
+		solutions: map[string]string{
+			"py":   "def shout(words, loud):\n    return [w + '!' if loud else w for w in words]\n",
+			"js":   "function shout(words, loud) {\n  return words.map((w) => (loud ? w + \"!\" : w));\n}\n",
+			"go":   "package main\n\nimport \"strings\"\n\nfunc shout(words []string, loud bool) []string {\n\tvar out []string\n\tfor _, w := range words {\n\t\tif loud {\n\t\t\tw = strings.TrimSpace(w) + \"!\"\n\t\t}\n\t\tout = append(out, w)\n\t}\n\treturn out\n}\n",
+			"cpp":  "#include <bits/stdc++.h>\nusing namespace std;\n\nclass Solution {\npublic:\n    vector<string> shout(vector<string>& words, bool loud) {\n        for (auto& w : words) if (loud) w += \"!\";\n        return words;\n    }\n};\n",
+			"java": "class Solution {\n    public String[] shout(String[] words, boolean loud) {\n        String[] out = new String[words.length];\n        for (int i = 0; i < words.length; i++) out[i] = loud ? words[i] + \"!\" : words[i];\n        return out;\n    }\n}\n",
+		},
+	},
+	{
+		signature: "scale(grid: long[][], factor: double) -> double[][]",
+		input:     "[[1,-2],[],[3000000000]]\n0.5\n",
+		want:      "[[0.50000,-1.00000],[],[1500000000.00000]]",
+		solutions: map[string]string{
+			"py":   "def scale(grid, factor):\n    return [[x * factor for x in row] for row in grid]\n",
+			"js":   "function scale(grid, factor) {\n  return grid.map((row) => row.map((x) => x * factor));\n}\n",
+			"go":   "package main\n\nfunc scale(grid [][]int64, factor float64) [][]float64 {\n\tout := make([][]float64, len(grid))\n\tfor i, row := range grid {\n\t\tout[i] = []float64{}\n\t\tfor _, x := range row {\n\t\t\tout[i] = append(out[i], float64(x)*factor)\n\t\t}\n\t}\n\treturn out\n}\n",
+			"cpp":  "#include <bits/stdc++.h>\nusing namespace std;\n\nclass Solution {\npublic:\n    vector<vector<double>> scale(vector<vector<long long>>& grid, double factor) {\n        vector<vector<double>> out;\n        for (auto& row : grid) {\n            out.emplace_back();\n            for (long long x : row) out.back().push_back(x * factor);\n        }\n        return out;\n    }\n};\n",
+			"java": "class Solution {\n    public double[][] scale(long[][] grid, double factor) {\n        double[][] out = new double[grid.length][];\n        for (int i = 0; i < grid.length; i++) {\n            out[i] = new double[grid[i].length];\n            for (int j = 0; j < grid[i].length; j++) out[i][j] = grid[i][j] * factor;\n        }\n        return out;\n    }\n}\n",
+		},
+	},
+	{
+		signature: "add(a: int, b: int) -> int",
+		input:     "2\n3\n",
+		want:      "5",
+		solutions: map[string]string{
+			"py":   "def add(a, b):\n    print('debug', a, b)\n    return a + b\n",
+			"js":   "function add(a, b) {\n  console.log(\"debug\", a, b);\n  return a + b;\n}\n",
+			"go":   "package main\n\nimport \"fmt\"\n\nfunc add(a int, b int) int {\n\tfmt.Println(\"debug\", a, b)\n\treturn a + b\n}\n",
+			"cpp":  "#include <bits/stdc++.h>\nusing namespace std;\n\nclass Solution {\npublic:\n    int add(int a, int b) {\n        cout << \"debug \" << a << endl;\n        printf(\"debug %d\\n\", b);\n        return a + b;\n    }\n};\n",
+			"java": "class Solution {\n    public int add(int a, int b) {\n        System.out.println(\"debug \" + a + \" \" + b);\n        return a + b;\n    }\n}\n",
+		},
+	},
+}
+
+// TestSource builds the generated programs with the toolchains installed on
+// the machine and checks their output.
+func TestSource(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles programs")
+	}
+	for _, lang := range Languages() {
+		tc := toolchains[lang]
+		tool := tc.run[0]
+		if tc.compile != nil {
+			tool = tc.compile[0]
+		}
+		t.Run(lang, func(t *testing.T) {
+			if _, err := exec.LookPath(tool); err != nil {
+				t.Skipf("%s is not installed", tool)
+			}
+			for _, c := range harnessCases {
+				sig, err := Parse(c.signature)
+				require.NoError(t, err)
+				src, err := Source(sig, lang, c.solutions[lang])
+				require.NoError(t, err)
+
+				dir := t.TempDir()
+				require.NoError(t, os.WriteFile(filepath.Join(dir, tc.file), []byte(src), 0o644))
+				if tc.compile != nil {
+					cmd := exec.Command(tc.compile[0], tc.compile[1:]...)
+					cmd.Dir = dir
+					out, err := cmd.CombinedOutput()
+					require.NoError(t, err, "%s\n%s", out, src)
+				}
+				cmd := exec.Command(tc.run[0], tc.run[1:]...)
+				cmd.Dir = dir
+				cmd.Stdin = strings.NewReader(c.input)
+				out, err := cmd.Output()
+				require.NoError(t, err, src)
+				assert.Equal(t, c.want+"\n", string(out), c.signature)
+			}
+		})
+	}
+}
+
+func TestStub(t *testing.T) {
+	sig, err := Parse("twoSum(nums: int[], target: int) -> int[]")
+	require.NoError(t, err)
+
+	want := map[string]string{
+		"py":   "def twoSum(nums: List[int], target: int) -> List[int]:",
+		"js":   "function twoSum(nums, target) {",
+		"go":   "func twoSum(nums []int, target int) []int {",
+		"cpp":  "    vector<int> twoSum(vector<int>& nums, int target) {",
+		"java": "    public int[] twoSum(int[] nums, int target) {",
+	}
+	for lang, line := range want {
+		stub, err := Stub(sig, lang)
+		require.NoError(t, err)
+		assert.Contains(t, stub, line+"\n", lang)
+	}
+
+	_, err = Stub(sig, "brainfuck")
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+}
@@ -0,0 +1,214 @@
+package harness
+
+import (
+	"fmt"
+	"strings"
+)
+
+type java struct{}
+
+// javaDriver is the body of the Main class. It parses every argument into
+// Long, Double, Boolean, String or List values, which the generated
+// converters turn into the declared types.
+const javaDriver = `    private static String _src;
+    private static int _pos;
+
+    private static void _ws() {
+        while (_pos < _src.length() && Character.isWhitespace(_src.charAt(_pos))) _pos++;
+    }
+
+    private static Object _parse() {
+        _ws();
+        char c = _src.charAt(_pos);
+        if (c == '[') {
+            _pos++;
+            java.util.List<Object> list = new java.util.ArrayList<>();
+            _ws();
+            if (_src.charAt(_pos) == ']') { _pos++; return list; }
+            while (true) {
+                list.add(_parse());
+                _ws();
+                if (_src.charAt(_pos++) == ']') return list;
+            }
+        }
+        if (c == '"') {
+            _pos++;
+            StringBuilder sb = new StringBuilder();
+            while (_src.charAt(_pos) != '"') {
+                char ch = _src.charAt(_pos++);
+                if (ch != '\\') { sb.append(ch); continue; }
+                char e = _src.charAt(_pos++);
+                switch (e) {
+                    case 'n': sb.append('\n'); break;
+                    case 't': sb.append('\t'); break;
+                    case 'r': sb.append('\r'); break;
+                    case 'b': sb.append('\b'); break;
+                    case 'f': sb.append('\f'); break;
+                    case 'u': sb.append((char) Integer.parseInt(_src.substring(_pos, _pos + 4), 16)); _pos += 4; break;
+                    default: sb.append(e);
+                }
+            }
+            _pos++;
+            return sb.toString();
+        }
+        if (_src.startsWith("true", _pos)) { _pos += 4; return Boolean.TRUE; }
+        if (_src.startsWith("false", _pos)) { _pos += 5; return Boolean.FALSE; }
+        int start = _pos;
+        while (_pos < _src.length() && "+-0123456789.eE".indexOf(_src.charAt(_pos)) >= 0) _pos++;
+        String num = _src.substring(start, _pos);
+        if (num.contains(".") || num.contains("e") || num.contains("E")) return Double.parseDouble(num);
+        return Long.parseLong(num);
+    }
+
+    private static Object _arg(java.util.List<String> lines, int i) {
+        if (i >= lines.size()) throw new IllegalArgumentException("missing argument " + (i + 1));
+        _src = lines.get(i);
+        _pos = 0;
+        return _parse();
+    }
+
+    private static java.util.List<?> _list(Object o) { return (java.util.List<?>) o; }
+    private static int _int(Object o) { return ((Number) o).intValue(); }
+    private static long _long(Object o) { return ((Number) o).longValue(); }
+    private static double _double(Object o) { return ((Number) o).doubleValue(); }
+    private static boolean _bool(Object o) { return (Boolean) o; }
+    private static String _string(Object o) { return (String) o; }
+
+    private static void _str(StringBuilder sb, String s) {
+        sb.append('"');
+        for (int i = 0; i < s.length(); i++) {
+            char c = s.charAt(i);
+            if (c == '"') sb.append("\\\"");
+            else if (c == '\\') sb.append("\\\\");
+            else if (c == '\n') sb.append("\\n");
+            else if (c == '\r') sb.append("\\r");
+            else if (c == '\t') sb.append("\\t");
+            else if (c < 0x20) sb.append(String.format("\\u%04x", (int) c));
+            else sb.append(c);
+        }
+        sb.append('"');
+    }
+
+    private static void _out(StringBuilder sb, Object v) {
+        if (v instanceof int[]) { int[] a = (int[]) v; Object[] b = new Object[a.length]; for (int i = 0; i < a.length; i++) b[i] = a[i]; _out(sb, b); }
+        else if (v instanceof long[]) { long[] a = (long[]) v; Object[] b = new Object[a.length]; for (int i = 0; i < a.length; i++) b[i] = a[i]; _out(sb, b); }
+        else if (v instanceof double[]) { double[] a = (double[]) v; Object[] b = new Object[a.length]; for (int i = 0; i < a.length; i++) b[i] = a[i]; _out(sb, b); }
+        else if (v instanceof boolean[]) { boolean[] a = (boolean[]) v; Object[] b = new Object[a.length]; for (int i = 0; i < a.length; i++) b[i] = a[i]; _out(sb, b); }
+        else if (v instanceof Object[]) {
+            Object[] a = (Object[]) v;
+            sb.append('[');
+            for (int i = 0; i < a.length; i++) {
+                if (i > 0) sb.append(',');
+                _out(sb, a[i]);
+            }
+            sb.append(']');
+        }
+        else if (v instanceof Double) {
+            String s = new java.math.BigDecimal((Double) v).setScale(5, java.math.RoundingMode.HALF_EVEN).toPlainString();
+            sb.append(s.equals("-0.00000") ? "0.00000" : s);
+        }
+        else if (v instanceof String) _str(sb, (String) v);
+        else sb.append(v);
+    }
+`
+
+func (java) typ(t Type) string {
+	var s string
+	switch t.Base {
+	case BaseLong:
+		s = "long"
+	case BaseDouble:
+		s = "double"
+	case BaseBool:
+		s = "boolean"
+	case BaseString:
+		s = "String"
+	default:
+		s = "int"
+	}
+	return s + strings.Repeat("[]", t.Dims)
+}
+
+func (j java) stub(sig *Signature) string {
+	params := make([]string, len(sig.Params))
+	for i, p := range sig.Params {
+		params[i] = j.typ(p.Type) + " " + p.Name
+	}
+	return join(
+		"import java.util.*;",
+		"",
+		"class Solution {",
+		fmt.Sprintf("    public %s %s(%s) {", j.typ(sig.Returns), sig.Name, strings.Join(params, ", ")),
+		"",
+		"    }",
+		"}",
+	)
+}
+
+// converter is the name of the generated method turning a parsed value into
+// type t.
+func (java) converter(t Type) string {
+	return fmt.Sprintf("_to_%s%d", t.Base, t.Dims)
+}
+
+// converters generates a conversion method for every array type needed by
+// the parameters; scalars use the helpers in javaDriver.
+func (j java) converters(sig *Signature) []string {
+	var lines []string
+	done := map[Type]bool{}
+	var add func(t Type)
+	add = func(t Type) {
+		if t.Dims == 0 || done[t] {
+			return
+		}
+		done[t] = true
+		add(t.Elem())
+		elem := "_" + t.Base
+		if t.Dims > 1 {
+			elem = j.converter(t.Elem())
+		}
+		// new int[n][] rather than new int[][n]
+		alloc := strings.Replace(j.typ(t), "[]", "[l.size()]", 1)
+		lines = append(lines,
+			fmt.Sprintf("    private static %s %s(Object o) {", j.typ(t), j.converter(t)),
+			"        java.util.List<?> l = _list(o);",
+			fmt.Sprintf("        %s a = new %s;", j.typ(t), alloc),
+			fmt.Sprintf("        for (int i = 0; i < a.length; i++) a[i] = %s(l.get(i));", elem),
+			"        return a;",
+			"    }",
+			"",
+		)
+	}
+	for _, p := range sig.Params {
+		add(p.Type)
+	}
+	return lines
+}
+
+func (j java) source(sig *Signature, code string) string {
+	lines := []string{"public class Main {", javaDriver}
+	lines = append(lines, j.converters(sig)...)
+	lines = append(lines,
+		"    public static void main(String[] args) throws Exception {",
+		"        String _in = new String(System.in.readAllBytes(), java.nio.charset.StandardCharsets.UTF_8);",
+		"        java.util.List<String> _lines = new java.util.ArrayList<>();",
+		"        for (String l : _in.split(\"\\n\")) if (!l.trim().isEmpty()) _lines.add(l);",
+	)
+	for i, p := range sig.Params {
+		conv := "_" + p.Type.Base
+		if p.Type.Dims > 0 {
+			conv = j.converter(p.Type)
+		}
+		lines = append(lines, fmt.Sprintf("        %s %s = %s(_arg(_lines, %d));", j.typ(p.Type), p.Name, conv, i))
+	}
+	lines = append(lines,
+		"        System.setOut(System.err);",
+		"        StringBuilder _sb = new StringBuilder();",
+		fmt.Sprintf("        _out(_sb, new Solution().%s(%s));", sig.Name, strings.Join(sig.names(), ", ")),
+		"        java.io.PrintStream _ps = new java.io.PrintStream(new java.io.FileOutputStream(java.io.FileDescriptor.out), true, \"UTF-8\");",
+		"        _ps.println(_sb);",
+		"    }",
+		"}",
+	)
+	return code + "\n\n// " + driverBanner + "\n" + join(lines...)
+}
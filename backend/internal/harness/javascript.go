@@ -0,0 +1,78 @@
+package harness
+
+import (
+	"fmt"
+	"strings"
+)
+
+type javascript struct{}
+
+const javascriptDriver = `(function () {
+  const _hArgs = require("fs").readFileSync(0, "utf8").split("\n")
+    .filter((l) => l.trim() !== "").map((l) => JSON.parse(l));
+  function _hStr(s) {
+    let out = '"';
+    for (const c of s) {
+      const code = c.codePointAt(0);
+      if (c === '"') out += '\\"';
+      else if (c === "\\") out += "\\\\";
+      else if (c === "\n") out += "\\n";
+      else if (c === "\r") out += "\\r";
+      else if (c === "\t") out += "\\t";
+      else if (code < 0x20) out += "\\u" + code.toString(16).padStart(4, "0");
+      else out += c;
+    }
+    return out + '"';
+  }
+  function _hOut(v, base, dims) {
+    if (dims > 0) return "[" + v.map((x) => _hOut(x, base, dims - 1)).join(",") + "]";
+    if (base === "double") {
+      const s = Number(v).toFixed(5);
+      return s === "-0.00000" ? "0.00000" : s;
+    }
+    if (base === "bool") return v ? "true" : "false";
+    if (base === "string") return _hStr(v);
+    return String(Math.trunc(v));
+  }
+`
+
+func (javascript) typ(t Type) string {
+	var s string
+	switch t.Base {
+	case BaseBool:
+		s = "boolean"
+	case BaseString:
+		s = "string"
+	default:
+		s = "number"
+	}
+	return s + strings.Repeat("[]", t.Dims)
+}
+
+func (j javascript) stub(sig *Signature) string {
+	lines := []string{"/**"}
+	for _, p := range sig.Params {
+		lines = append(lines, fmt.Sprintf(" * @param {%s} %s", j.typ(p.Type), p.Name))
+	}
+	lines = append(lines,
+		fmt.Sprintf(" * @return {%s}", j.typ(sig.Returns)),
+		" */",
+		fmt.Sprintf("function %s(%s) {", sig.Name, strings.Join(sig.names(), ", ")),
+		"",
+		"}",
+	)
+	return join(lines...)
+}
+
+func (javascript) source(sig *Signature, code string) string {
+	args := make([]string, len(sig.Params))
+	for i := range sig.Params {
+		args[i] = fmt.Sprintf("_hArgs[%d]", i)
+	}
+	return code + "\n\n// " + driverBanner + "\n" + javascriptDriver +
+		"  const _hWrite = process.stdout.write;\n" +
+		"  process.stdout.write = process.stderr.write.bind(process.stderr);\n" +
+		fmt.Sprintf("  const _hRes = %s(%s);\n", sig.Name, strings.Join(args, ", ")) +
+		"  process.stdout.write = _hWrite;\n" +
+		fmt.Sprintf("  process.stdout.write(_hOut(_hRes, %q, %d) + \"\\n\");\n})();\n", sig.Returns.Base, sig.Returns.Dims)
+}
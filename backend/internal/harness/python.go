@@ -0,0 +1,90 @@
+package harness
+
+import (
+	"fmt"
+	"strings"
+)
+
+type python struct{}
+
+const pythonDriver = `import sys as _h_sys, json as _h_json
+
+
+def _h_str(s):
+    out = ['"']
+    for c in s:
+        if c == '"':
+            out.append('\\"')
+        elif c == '\\':
+            out.append('\\\\')
+        elif c == '\n':
+            out.append('\\n')
+        elif c == '\r':
+            out.append('\\r')
+        elif c == '\t':
+            out.append('\\t')
+        elif ord(c) < 0x20:
+            out.append('\\u%04x' % ord(c))
+        else:
+            out.append(c)
+    out.append('"')
+    return ''.join(out)
+
+
+def _h_out(v, base, dims):
+    if dims > 0:
+        return '[' + ','.join(_h_out(x, base, dims - 1) for x in v) + ']'
+    if base == 'double':
+        s = '%.5f' % v
+        return '0.00000' if s == '-0.00000' else s
+    if base == 'bool':
+        return 'true' if v else 'false'
+    if base == 'string':
+        return _h_str(v)
+    return str(int(v))
+
+
+_h_sys.stdout.reconfigure(encoding='utf-8')
+_h_args = [_h_json.loads(l) for l in _h_sys.stdin.buffer.read().decode('utf-8').split('\n') if l.strip()]
+`
+
+func (python) typ(t Type) string {
+	if t.Dims > 0 {
+		return "List[" + python{}.typ(t.Elem()) + "]"
+	}
+	switch t.Base {
+	case BaseDouble:
+		return "float"
+	case BaseBool:
+		return "bool"
+	case BaseString:
+		return "str"
+	default:
+		return "int"
+	}
+}
+
+func (p python) stub(sig *Signature) string {
+	params := make([]string, len(sig.Params))
+	for i, param := range sig.Params {
+		params[i] = param.Name + ": " + p.typ(param.Type)
+	}
+	return join(
+		"from typing import List",
+		"",
+		"",
+		fmt.Sprintf("def %s(%s) -> %s:", sig.Name, strings.Join(params, ", "), p.typ(sig.Returns)),
+		"    pass",
+	)
+}
+
+func (python) source(sig *Signature, code string) string {
+	args := make([]string, len(sig.Params))
+	for i := range sig.Params {
+		args[i] = fmt.Sprintf("_h_args[%d]", i)
+	}
+	return code + "\n\n# " + driverBanner + "\n" + pythonDriver +
+		"_h_stdout, _h_sys.stdout = _h_sys.stdout, _h_sys.stderr\n" +
+		fmt.Sprintf("_h_res = %s(%s)\n", sig.Name, strings.Join(args, ", ")) +
+		fmt.Sprintf("print(_h_out(_h_res, '%s', %d), file=_h_stdout)\n", sig.Returns.Base, sig.Returns.Dims)
+}
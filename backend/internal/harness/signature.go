@@ -0,0 +1,167 @@
+// Package harness turns a typed function signature into the code around a
+// contestant's function: the stub shown in the editor and the hidden driver
+// that reads the arguments from stdin, calls the function and prints its
+// result.
+//
+// Test input has one line per parameter, each holding the argument as JSON,
+// for example "[2,7,11,15]" and "9". The driver prints the return value as
+// compact JSON on one line: arrays without spaces, strings quoted and
+// escaped, and doubles with exactly five decimals.
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidSignature = errors.New("invalid function signature")
+
+// Base types a parameter or return value can have.
+const (
+	BaseInt    = "int"    // 32-bit integer
+	BaseLong   = "long"   // 64-bit integer; JavaScript only keeps 53 bits
+	BaseDouble = "double" // printed with five decimals
+	BaseBool   = "bool"
+	BaseString = "string"
+)
+
+// MaxDims is the deepest array nesting a type may have, e.g. int[][].
+const MaxDims = 2
+
+// Type is a base type, possibly wrapped in arrays.
+type Type struct {
+	Base string
+	Dims int // 0 for scalars, 1 for arrays, 2 for arrays of arrays
+}
+
+func (t Type) String() string {
+	return t.Base + strings.Repeat("[]", t.Dims)
+}
+
+// Elem is the element type of an array type.
+func (t Type) Elem() Type {
+	return Type{Base: t.Base, Dims: t.Dims - 1}
+}
+
+type Param struct {
+	Name string
+	Type Type
+}
+
+// Signature is a function a problem asks contestants to implement.
+type Signature struct {
+	Name    string
+	Params  []Param
+	Returns Type
+}
+
+func (s Signature) String() string {
+	params := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		params[i] = p.Name + ": " + p.Type.String()
+	}
+	return fmt.Sprintf("%s(%s) -> %s", s.Name, strings.Join(params, ", "), s.Returns)
+}
+
+var (
+	signatureRe  = regexp.MustCompile(`^\s*(\w+)\s*\((.*)\)\s*->\s*(.+?)\s*$`)
+	identifierRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+)
+
+// reserved holds names that are keywords or would clash with the driver in
+// one of the supported languages.
+var reserved = map[string]bool{
+	"and": true, "args": true, "as": true, "assert": true, "auto": true,
+	"bool": true, "boolean": true, "break": true, "byte": true, "case": true, "catch": true,
+	"char": true, "class": true, "const": true, "continue": true, "def": true,
+	"default": true, "defer": true, "del": true, "delete": true, "do": true,
+	"double": true, "elif": true, "else": true, "enum": true, "except": true,
+	"export": true, "extends": true, "false": true, "final": true,
+	"finally": true, "float": true, "for": true, "from": true, "func": true,
+	"function": true, "global": true, "go": true, "goto": true, "if": true,
+	"implements": true, "import": true, "in": true, "instanceof": true,
+	"int": true, "interface": true, "is": true, "lambda": true, "let": true,
+	"long": true, "main": true, "map": true, "namespace": true, "new": true,
+	"nonlocal": true, "not": true, "null": true, "or": true, "package": true,
+	"pass": true, "private": true, "protected": true, "public": true,
+	"raise": true, "range": true, "return": true, "select": true,
+	"short": true, "signed": true, "sizeof": true, "static": true,
+	"std": true, "string": true, "struct": true, "super": true, "switch": true,
+	"template": true, "this": true, "throw": true, "throws": true,
+	"true": true, "try": true, "type": true, "typeof": true, "union": true,
+	"unsigned": true, "using": true, "var": true, "void": true,
+	"volatile": true, "while": true, "with": true, "yield": true,
+	"Solution": true, "Main": true, "List": true,
+}
+
+// Parse reads a signature such as "twoSum(nums: int[], target: int) -> int[]".
+func Parse(s string) (*Signature, error) {
+	m := signatureRe.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("%w: expected name(param: type, ...) -> type", ErrInvalidSignature)
+	}
+	if err := checkName(m[1]); err != nil {
+		return nil, err
+	}
+	sig := &Signature{Name: m[1]}
+
+	seen := map[string]bool{sig.Name: true}
+	if strings.TrimSpace(m[2]) != "" {
+		for _, part := range strings.Split(m[2], ",") {
+			name, typ, ok := strings.Cut(part, ":")
+			if !ok {
+				return nil, fmt.Errorf("%w: parameter %q has no type", ErrInvalidSignature, strings.TrimSpace(part))
+			}
+			name = strings.TrimSpace(name)
+			if err := checkName(name); err != nil {
+				return nil, err
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("%w: %q is used twice", ErrInvalidSignature, name)
+			}
+			seen[name] = true
+			t, err := parseType(typ)
+			if err != nil {
+				return nil, err
+			}
+			sig.Params = append(sig.Params, Param{Name: name, Type: t})
+		}
+	}
+
+	t, err := parseType(m[3])
+	if err != nil {
+		return nil, err
+	}
+	sig.Returns = t
+	return sig, nil
+}
+
+func checkName(name string) error {
+	if !identifierRe.MatchString(name) {
+		return fmt.Errorf("%w: %q is not a valid name", ErrInvalidSignature, name)
+	}
+	if reserved[name] {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidSignature, name)
+	}
+	return nil
+}
+
+func parseType(s string) (Type, error) {
+	s = strings.TrimSpace(s)
+	base := strings.TrimRight(s, "[]")
+	dims := (len(s) - len(base)) / 2
+	if strings.Repeat("[]", dims) != s[len(base):] {
+		return Type{}, fmt.Errorf("%w: malformed type %q", ErrInvalidSignature, s)
+	}
+	switch base {
+	case BaseInt, BaseLong, BaseDouble, BaseBool, BaseString:
+	default:
+		return Type{}, fmt.Errorf("%w: unknown type %q, expected int, long, double, bool or string", ErrInvalidSignature, base)
+	}
+	if dims > MaxDims {
+		return Type{}, fmt.Errorf("%w: %q nests arrays deeper than %d", ErrInvalidSignature, s, MaxDims)
+	}
+	return Type{Base: base, Dims: dims}, nil
+}
@@ -0,0 +1,43 @@
+package harness
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	sig, err := Parse(" twoSum(nums: int[], target: int) -> int[] ")
+	require.NoError(t, err)
+	assert.Equal(t, &Signature{
+		Name: "twoSum",
+		Params: []Param{
+			{Name: "nums", Type: Type{Base: BaseInt, Dims: 1}},
+			{Name: "target", Type: Type{Base: BaseInt}},
+		},
+		Returns: Type{Base: BaseInt, Dims: 1},
+	}, sig)
+	assert.Equal(t, "twoSum(nums: int[], target: int) -> int[]", sig.String())
+
+	sig, err = Parse("answer() -> string[][]")
+	require.NoError(t, err)
+	assert.Empty(t, sig.Params)
+	assert.Equal(t, Type{Base: BaseString, Dims: 2}, sig.Returns)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"twoSum(nums: int[], target: int)",
+		"twoSum(nums int[]) -> int",
+		"twoSum(nums: int[], nums: int) -> int",
+		"twoSum(nums: float[]) -> int",
+		"twoSum(nums: int[][][]) -> int",
+		"twoSum(nums: int[) -> int",
+		"class(x: int) -> int",
+		"f(_x: int) -> int",
+	} {
+		_, err := Parse(s)
+		assert.ErrorIs(t, err, ErrInvalidSignature, s)
+	}
+}
@@ -125,6 +125,34 @@ func (a Auth) Authorize(ctx *fiber.Ctx) error {
 	return ctx.Next()
 }
 
+// OptionalAuth is Authorize for endpoints that anonymous users may use too:
+// it sets the user when the request carries a valid token and lets the
+// request through either way.
+func (a Auth) OptionalAuth(ctx *fiber.Ctx) error {
+	if token := ctx.Cookies("token"); token != "" {
+		if user, err := a.VerifyToken("Bearer " + token); err == nil {
+			ctx.Locals("user", user)
+		}
+	}
+	return ctx.Next()
+}
+
+// IsAdmin reports whether the request was made by an admin. It needs
+// Authorize or OptionalAuth to have run.
+func (a Auth) IsAdmin(ctx *fiber.Ctx) bool {
+	user, ok := ctx.Locals("user").(domain.User)
+	return ok && user.Role == domain.ADMIN
+}
+
+// AdminOnly must run after Authorize and rejects non-admin users.
+func (a Auth) AdminOnly(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(domain.User)
+	if !ok || user.Role != domain.ADMIN {
+		return ctx.Status(403).JSON(fiber.Map{"message": "Forbidden"})
+	}
+	return ctx.Next()
+}
+
 func (a Auth) CurrentUserInfo(ctx *fiber.Ctx) (domain.User, error) {
 	user := ctx.Locals("user")
 	return user.(domain.User), nil
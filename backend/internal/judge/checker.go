@@ -0,0 +1,184 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+var ErrUnsupportedChecker = errors.New("unsupported checker")
+
+const (
+	DefaultCheckerLimit     = 10 * time.Second
+	DefaultCheckerMemoryKB  = 512 * 1024
+	DefaultFloatAbsEpsilon  = 1e-6
+	checkerExitAccepted     = 0
+	checkerExitWrongAnswer  = 1
+	checkerExitPresentation = 2
+)
+
+// CheckerSpec selects how outputs are checked. Program and Language are only
+// used by custom checkers.
+type CheckerSpec struct {
+	Type       string
+	AbsEpsilon float64
+	RelEpsilon float64
+	Program    string
+	Language   domain.Language
+}
+
+// CheckResult is a checker's decision for one test.
+type CheckResult struct {
+	Status  string
+	Score   float64 // 0..1
+	Message string
+}
+
+// ValidateChecker reports whether spec can be used to judge a problem.
+func ValidateChecker(spec CheckerSpec) error {
+	if spec.AbsEpsilon < 0 || spec.RelEpsilon < 0 {
+		return errors.New("checker epsilons must not be negative")
+	}
+	if spec.Type == domain.CHECKER_CUSTOM {
+		if strings.TrimSpace(spec.Program) == "" {
+			return errors.New("custom checker needs a program")
+		}
+		if len(spec.Language.RunCmd) == 0 {
+			return ErrUnsupportedLanguage
+		}
+		return nil
+	}
+	_, err := builtinCompare(spec)
+	return err
+}
+
+type checker interface {
+	check(ctx context.Context, input, expected, actual string) (CheckResult, error)
+	close()
+}
+
+func builtinCompare(spec CheckerSpec) (compareFunc, error) {
+	switch spec.Type {
+	case "", domain.CHECKER_WHITESPACE:
+		return compareTokens(func(a, e string) bool { return a == e }), nil
+	case domain.CHECKER_EXACT:
+		return compareExact, nil
+	case domain.CHECKER_LINES:
+		return compareLines, nil
+	case domain.CHECKER_CASE_INSENSITIVE:
+		return compareTokens(strings.EqualFold), nil
+	case domain.CHECKER_FLOAT:
+		abs := spec.AbsEpsilon
+		if abs == 0 && spec.RelEpsilon == 0 {
+			abs = DefaultFloatAbsEpsilon
+		}
+		return compareTokens(floatsEqual(abs, spec.RelEpsilon)), nil
+	case domain.CHECKER_UNORDERED_LINES:
+		return compareUnorderedLines, nil
+	default:
+		return nil, ErrUnsupportedChecker
+	}
+}
+
+// newChecker prepares the checker for one evaluation. A non-empty message
+// means the setter's checker program does not compile.
+func (j *Judge) newChecker(ctx context.Context, spec CheckerSpec) (checker, string, error) {
+	if spec.Type != domain.CHECKER_CUSTOM {
+		compare, err := builtinCompare(spec)
+		if err != nil {
+			return nil, "", err
+		}
+		return builtinChecker{compare: compare}, "", nil
+	}
+
+	if len(spec.Language.RunCmd) == 0 {
+		return nil, "", ErrUnsupportedLanguage
+	}
+	prog, failure, err := j.build(ctx, spec.Language, spec.Program)
+	if err != nil || failure != "" {
+		return nil, failure, err
+	}
+	return &programChecker{judge: j, prog: prog}, "", nil
+}
+
+type builtinChecker struct {
+	compare compareFunc
+}
+
+func (b builtinChecker) check(_ context.Context, _, expected, actual string) (CheckResult, error) {
+	if ok, message := b.compare(actual, expected); !ok {
+		return CheckResult{Status: domain.STATUS_WRONG_ANSWER, Message: message}, nil
+	}
+	return CheckResult{Status: domain.STATUS_ACCEPTED, Score: 1}, nil
+}
+
+func (builtinChecker) close() {}
+
+// programChecker runs the setter's checker as
+//
+//	<run command> input.txt expected.txt output.txt
+//
+// Exit code 0 accepts the output, 1 or 2 reject it and anything else is a
+// judge error. An optional number in [0, 1] on the first line of stdout is
+// the score; stderr becomes the checker message.
+//
+// The files live in the checker's own directory, which the sandbox hands to
+// the checker's run only. The submission never runs under the same uid and
+// cannot see the directory, so it can neither read expected.txt nor replace
+// the checker.
+type programChecker struct {
+	judge *Judge
+	prog  *program
+}
+
+func (c *programChecker) check(ctx context.Context, input, expected, actual string) (CheckResult, error) {
+	files := []struct{ name, data string }{
+		{"input.txt", input},
+		{"expected.txt", expected},
+		{"output.txt", actual},
+	}
+	args := make([]string, 0, len(files))
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(c.prog.dir, f.name), []byte(f.data), 0o600); err != nil {
+			return CheckResult{}, err
+		}
+		args = append(args, f.name)
+	}
+
+	spec := c.prog.spec(args...)
+	spec.TimeLimit = c.judge.CheckerLimit
+	spec.MemoryLimitKB = DefaultCheckerMemoryKB
+	run, err := c.judge.Runner.Run(ctx, spec)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	message := truncate(strings.TrimSpace(run.Stderr), maxMessageLength)
+	var res CheckResult
+	switch {
+	case run.TimedOut || run.MemoryExceeded || run.Signal != "":
+		return CheckResult{Status: domain.STATUS_JUDGE_ERROR, Message: "checker crashed or exceeded its limits"}, nil
+	case run.ExitCode == checkerExitAccepted:
+		res = CheckResult{Status: domain.STATUS_ACCEPTED, Score: 1, Message: message}
+	case run.ExitCode == checkerExitWrongAnswer || run.ExitCode == checkerExitPresentation:
+		res = CheckResult{Status: domain.STATUS_WRONG_ANSWER, Message: message}
+	default:
+		return CheckResult{Status: domain.STATUS_JUDGE_ERROR, Message: "checker failed: " + message}, nil
+	}
+
+	firstLine, _, _ := strings.Cut(run.Stdout, "\n")
+	if score, err := strconv.ParseFloat(strings.TrimSpace(firstLine), 64); err == nil && score >= 0 && score <= 1 {
+		res.Score = score
+	}
+	return res, nil
+}
+
+func (c *programChecker) close() {
+	c.prog.close()
+}
@@ -0,0 +1,112 @@
+package judge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/sandbox"
+)
+
+func TestBuiltinCheckers(t *testing.T) {
+	cases := []struct {
+		name     string
+		spec     CheckerSpec
+		actual   string
+		expected string
+		ok       bool
+	}{
+		{"whitespace ignores spacing", CheckerSpec{}, "1  2\n3\n\n", "1 2 3", true},
+		{"whitespace is case sensitive", CheckerSpec{Type: domain.CHECKER_WHITESPACE}, "YES", "yes", false},
+		{"exact keeps spacing", CheckerSpec{Type: domain.CHECKER_EXACT}, "1  2\n", "1 2\n", false},
+		{"exact ignores crlf and final newline", CheckerSpec{Type: domain.CHECKER_EXACT}, "a\r\nb\r\n", "a\nb", true},
+		{"lines ignores trailing spaces", CheckerSpec{Type: domain.CHECKER_LINES}, "1 2 \n3\n\n", "1 2\n3", true},
+		{"lines keeps inner spacing", CheckerSpec{Type: domain.CHECKER_LINES}, "1  2\n3", "1 2\n3", false},
+		{"lines keeps line breaks", CheckerSpec{Type: domain.CHECKER_LINES}, "1 2 3", "1 2\n3", false},
+		{"case insensitive", CheckerSpec{Type: domain.CHECKER_CASE_INSENSITIVE}, "Yes\nNO", "YES no", true},
+		{"float within absolute epsilon", CheckerSpec{Type: domain.CHECKER_FLOAT, AbsEpsilon: 1e-3}, "3.1415", "3.1416", true},
+		{"float outside absolute epsilon", CheckerSpec{Type: domain.CHECKER_FLOAT, AbsEpsilon: 1e-6}, "3.1415", "3.1416", false},
+		{"float within relative epsilon", CheckerSpec{Type: domain.CHECKER_FLOAT, RelEpsilon: 1e-3}, "1000000.5", "1000000", true},
+		{"float compares words exactly", CheckerSpec{Type: domain.CHECKER_FLOAT}, "impossible", "IMPOSSIBLE", false},
+		{"unordered lines", CheckerSpec{Type: domain.CHECKER_UNORDERED_LINES}, "b\na\nc\n", "a\nb\nc", true},
+		{"unordered lines counts duplicates", CheckerSpec{Type: domain.CHECKER_UNORDERED_LINES}, "a\na\nb", "a\nb\nb", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			compare, err := builtinCompare(tc.spec)
+			require.NoError(t, err)
+
+			ok, message := compare(tc.actual, tc.expected)
+			assert.Equal(t, tc.ok, ok, message)
+			assert.Equal(t, tc.ok, message == "")
+		})
+	}
+
+	_, err := builtinCompare(CheckerSpec{Type: "fuzzy"})
+	assert.ErrorIs(t, err, ErrUnsupportedChecker)
+}
+
+// runnerFunc adapts a function to the Runner interface.
+type runnerFunc func(ctx context.Context, spec sandbox.Spec) (*sandbox.Result, error)
+
+func (f runnerFunc) Run(ctx context.Context, spec sandbox.Spec) (*sandbox.Result, error) {
+	return f(ctx, spec)
+}
+
+func TestProgramChecker(t *testing.T) {
+	checkerLang := domain.Language{ID: "py", SourceFile: "check.py", RunCmd: []string{"python3", "check.py"}}
+	var checkerArgs []string
+	var checkerDir, submissionDir string
+	var expectedMode os.FileMode
+	runner := runnerFunc(func(ctx context.Context, spec sandbox.Spec) (*sandbox.Result, error) {
+		if spec.Args[1] == "check.py" {
+			checkerArgs = spec.Args[2:]
+			checkerDir = spec.Dir
+			fi, err := os.Stat(filepath.Join(spec.Dir, "expected.txt"))
+			require.NoError(t, err)
+			expectedMode = fi.Mode().Perm()
+			return &sandbox.Result{ExitCode: 1, Stdout: "0.5\n", Stderr: "path is not shortest\n"}, nil
+		}
+		submissionDir = spec.Dir
+		return &sandbox.Result{Stdout: "1 2 3\n"}, nil
+	})
+
+	res, err := New(runner).Evaluate(context.Background(), Request{
+		Language: python,
+		Code:     "x",
+		Tests:    []domain.TestCases{{Input: "3", Expected: "1 3"}},
+		Checker:  CheckerSpec{Type: domain.CHECKER_CUSTOM, Program: "y", Language: checkerLang},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"input.txt", "expected.txt", "output.txt"}, checkerArgs)
+	assert.NotEqual(t, submissionDir, checkerDir, "the checker needs its own directory")
+	assert.Equal(t, os.FileMode(0o600), expectedMode)
+	assert.Equal(t, domain.STATUS_WRONG_ANSWER, res.Status)
+	assert.Equal(t, 0.5, res.Tests[0].Score)
+	assert.Equal(t, "path is not shortest", res.Tests[0].CheckerMessage)
+}
+
+func TestProgramChecker_Failure(t *testing.T) {
+	runner := runnerFunc(func(ctx context.Context, spec sandbox.Spec) (*sandbox.Result, error) {
+		if filepath.Base(spec.Args[len(spec.Args)-1]) == "output.txt" {
+			return &sandbox.Result{ExitCode: 3, Stderr: "bad input"}, nil
+		}
+		return &sandbox.Result{Stdout: "1"}, nil
+	})
+	checkerLang := domain.Language{ID: "py", SourceFile: "check.py", RunCmd: []string{"python3", "check.py"}}
+
+	res, err := New(runner).Evaluate(context.Background(), Request{
+		Language: python,
+		Code:     "x",
+		Tests:    []domain.TestCases{{Input: "1", Expected: "1"}},
+		Checker:  CheckerSpec{Type: domain.CHECKER_CUSTOM, Program: "y", Language: checkerLang},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.STATUS_JUDGE_ERROR, res.Status)
+}
@@ -0,0 +1,129 @@
+package judge
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ExcerptLength is how much of each test's output is kept with a submission.
+const ExcerptLength = 1024
+
+// compareFunc compares program output with the expected answer. It returns
+// an empty message when they match and otherwise says where they differ.
+type compareFunc func(actual, expected string) (bool, string)
+
+func compareExact(actual, expected string) (bool, string) {
+	a := strings.TrimSuffix(strings.ReplaceAll(actual, "\r\n", "\n"), "\n")
+	e := strings.TrimSuffix(strings.ReplaceAll(expected, "\r\n", "\n"), "\n")
+	if a == e {
+		return true, ""
+	}
+	return false, firstLineDifference(strings.Split(a, "\n"), strings.Split(e, "\n"))
+}
+
+// compareLines ignores trailing whitespace on every line and trailing blank
+// lines. It is how outputs were compared before problems chose a checker.
+func compareLines(actual, expected string) (bool, string) {
+	a, e := normalizeOutput(actual), normalizeOutput(expected)
+	if a == e {
+		return true, ""
+	}
+	return false, firstLineDifference(strings.Split(a, "\n"), strings.Split(e, "\n"))
+}
+
+func compareTokens(equal func(a, e string) bool) compareFunc {
+	return func(actual, expected string) (bool, string) {
+		got, want := strings.Fields(actual), strings.Fields(expected)
+		for i := 0; i < len(got) || i < len(want); i++ {
+			switch {
+			case i >= len(got):
+				return false, fmt.Sprintf("token %d: expected %q, output ended", i+1, excerptLine(want[i]))
+			case i >= len(want):
+				return false, fmt.Sprintf("token %d: expected end of output, got %q", i+1, excerptLine(got[i]))
+			case !equal(got[i], want[i]):
+				return false, fmt.Sprintf("token %d: expected %q, got %q", i+1, excerptLine(want[i]), excerptLine(got[i]))
+			}
+		}
+		return true, ""
+	}
+}
+
+// floatsEqual accepts a numeric token within either epsilon of the expected
+// value; tokens that are not numbers must match exactly.
+func floatsEqual(absEps, relEps float64) func(a, e string) bool {
+	return func(a, e string) bool {
+		if a == e {
+			return true
+		}
+		x, errA := strconv.ParseFloat(a, 64)
+		y, errE := strconv.ParseFloat(e, 64)
+		if errA != nil || errE != nil || math.IsNaN(x) || math.IsNaN(y) {
+			return false
+		}
+		diff := math.Abs(x - y)
+		return diff <= absEps || diff <= relEps*math.Abs(y)
+	}
+}
+
+func compareUnorderedLines(actual, expected string) (bool, string) {
+	got := strings.Split(normalizeOutput(actual), "\n")
+	want := strings.Split(normalizeOutput(expected), "\n")
+	if len(got) != len(want) {
+		return false, fmt.Sprintf("expected %d lines, got %d", len(want), len(got))
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	for i := range want {
+		if got[i] != want[i] {
+			return false, fmt.Sprintf("line %q is missing from the output", excerptLine(want[i]))
+		}
+	}
+	return true, ""
+}
+
+func normalizeOutput(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+func firstLineDifference(got, want []string) string {
+	for i := 0; i < len(got) || i < len(want); i++ {
+		switch {
+		case i >= len(got):
+			return fmt.Sprintf("line %d: expected %q, output ended", i+1, excerptLine(want[i]))
+		case i >= len(want):
+			return fmt.Sprintf("line %d: expected end of output, got %q", i+1, excerptLine(got[i]))
+		case got[i] != want[i]:
+			return fmt.Sprintf("line %d: expected %q, got %q", i+1, excerptLine(want[i]), excerptLine(got[i]))
+		}
+	}
+	return ""
+}
+
+func excerptLine(s string) string {
+	const n = 64
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// Excerpt shortens program output for storage with a submission.
+func Excerpt(s string) string {
+	return truncate(s, ExcerptLength)
+}
+
+// truncate shortens s to at most n bytes for storage in error messages.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "\n... (truncated)"
+}
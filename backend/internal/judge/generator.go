@@ -0,0 +1,151 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/sandbox"
+)
+
+// Limits of a generator run. Generators write whole test inputs, so they
+// get more room than submissions.
+const (
+	DefaultGeneratorLimit    = 10 * time.Second
+	DefaultGeneratorMemoryKB = 1024 * 1024
+)
+
+var ErrGenerationFailed = errors.New("test generation failed")
+
+// GeneratorSpec is a setter's test generator. Scripts refer to it by Name;
+// it gets its arguments on the command line and writes one test input to
+// stdout.
+type GeneratorSpec struct {
+	Name     string
+	Program  string
+	Language domain.Language
+}
+
+// GenerateStep runs a generator once. Line is the script line, used in
+// errors.
+type GenerateStep struct {
+	Line      int
+	Generator string
+	Args      []string
+}
+
+// GenerateRequest produces test data. Each generator is built once and
+// every step makes the input of one test. When Solution is set, its Code
+// is run on each input, with its limits, to produce the expected output.
+type GenerateRequest struct {
+	Generators []GeneratorSpec
+	Steps      []GenerateStep
+	Solution   *Request
+}
+
+// Generate runs the steps of req in order and passes every test it makes
+// to emit as soon as it is ready, so inputs need not all be held at once.
+// A generator or solution that does not compile or fails on a step gives
+// an error wrapping ErrGenerationFailed.
+func (j *Judge) Generate(ctx context.Context, req GenerateRequest, emit func(step int, input, expected string) error) error {
+	generators := make(map[string]*program, len(req.Generators))
+	defer func() {
+		for _, prog := range generators {
+			prog.close()
+		}
+	}()
+	for _, step := range req.Steps {
+		if _, ok := generators[step.Generator]; ok {
+			continue
+		}
+		i := findGenerator(req.Generators, step.Generator)
+		if i < 0 {
+			return fmt.Errorf("%w: line %d: unknown generator %s", ErrGenerationFailed, step.Line, step.Generator)
+		}
+		spec := req.Generators[i]
+		if len(spec.Language.RunCmd) == 0 {
+			return ErrUnsupportedLanguage
+		}
+		prog, failure, err := j.build(ctx, spec.Language, spec.Program)
+		if err != nil {
+			return err
+		}
+		if failure != "" {
+			return fmt.Errorf("%w: generator %s does not compile:\n%s", ErrGenerationFailed, spec.Name, failure)
+		}
+		generators[step.Generator] = prog
+	}
+
+	var solution *program
+	if req.Solution != nil {
+		if len(req.Solution.Language.RunCmd) == 0 {
+			return ErrUnsupportedLanguage
+		}
+		prog, failure, err := j.build(ctx, req.Solution.Language, req.Solution.Code)
+		if err != nil {
+			return err
+		}
+		if failure != "" {
+			return fmt.Errorf("%w: solution does not compile:\n%s", ErrGenerationFailed, failure)
+		}
+		defer prog.close()
+		solution = prog
+	}
+
+	for i, step := range req.Steps {
+		spec := generators[step.Generator].spec(step.Args...)
+		spec.TimeLimit = DefaultGeneratorLimit
+		spec.MemoryLimitKB = DefaultGeneratorMemoryKB
+		run, err := j.Runner.Run(ctx, spec)
+		if err != nil {
+			return err
+		}
+		if run.Status() != "" {
+			return fmt.Errorf("%w: line %d: generator %s: %s", ErrGenerationFailed, step.Line, step.Generator, failureMessage(run))
+		}
+		input := run.Stdout
+
+		var expected string
+		if solution != nil {
+			spec := solution.spec()
+			spec.Stdin = strings.NewReader(input)
+			spec.TimeLimit, spec.MemoryLimitKB = j.limits(*req.Solution)
+			run, err := j.Runner.Run(ctx, spec)
+			if err != nil {
+				return err
+			}
+			if run.Status() != "" {
+				return fmt.Errorf("%w: line %d: solution: %s", ErrGenerationFailed, step.Line, failureMessage(run))
+			}
+			expected = run.Stdout
+		}
+		if err := emit(i, input, expected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findGenerator(generators []GeneratorSpec, name string) int {
+	for i, g := range generators {
+		if g.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// failureMessage describes a failed generator or solution run.
+func failureMessage(run *sandbox.Result) string {
+	msg := run.Status()
+	if msg == domain.STATUS_RUNTIME_ERROR {
+		msg = runtimeMessage(run)
+	}
+	if stderr := strings.TrimSpace(run.Stderr); stderr != "" {
+		msg += ": " + truncate(stderr, maxMessageLength)
+	}
+	return msg
+}
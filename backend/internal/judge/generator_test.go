@@ -0,0 +1,58 @@
+package judge
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/sandbox"
+)
+
+func TestGenerate(t *testing.T) {
+	// The generator prints its arguments; the solution sums its input.
+	runner := runnerFunc(func(ctx context.Context, spec sandbox.Spec) (*sandbox.Result, error) {
+		if spec.Stdin == nil {
+			args := spec.Args[2:]
+			if len(args) > 0 && args[0] == "fail" {
+				return &sandbox.Result{ExitCode: 1, Stderr: "bad arguments"}, nil
+			}
+			return &sandbox.Result{Stdout: strings.Join(args, " ") + "\n"}, nil
+		}
+		data, _ := io.ReadAll(spec.Stdin)
+		sum := 0
+		for _, f := range strings.Fields(string(data)) {
+			n, _ := strconv.Atoi(f)
+			sum += n
+		}
+		return &sandbox.Result{Stdout: strconv.Itoa(sum) + "\n"}, nil
+	})
+	gens := []GeneratorSpec{{Name: "gen", Program: "g", Language: python}}
+	req := GenerateRequest{
+		Generators: gens,
+		Steps:      []GenerateStep{{Line: 1, Generator: "gen", Args: []string{"1", "2"}}, {Line: 2, Generator: "gen", Args: []string{"5"}}},
+		Solution:   &Request{Language: python, Code: "s"},
+	}
+
+	var got [][2]string
+	err := New(runner).Generate(context.Background(), req, func(step int, input, expected string) error {
+		assert.Equal(t, len(got), step)
+		got = append(got, [2]string{input, expected})
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][2]string{{"1 2\n", "3\n"}, {"5\n", "5\n"}}, got)
+
+	req.Solution = nil
+	req.Steps = []GenerateStep{{Line: 3, Generator: "gen", Args: []string{"fail"}}}
+	err = New(runner).Generate(context.Background(), req, func(int, string, string) error { return nil })
+	assert.ErrorIs(t, err, ErrGenerationFailed)
+	assert.ErrorContains(t, err, "line 3: generator gen: exit code 1: bad arguments")
+
+	req.Steps = []GenerateStep{{Line: 4, Generator: "other"}}
+	err = New(runner).Generate(context.Background(), req, func(int, string, string) error { return nil })
+	assert.ErrorIs(t, err, ErrGenerationFailed)
+}
@@ -0,0 +1,120 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/sandbox"
+)
+
+// InteractorSpec is the setter's interactor for an interactive problem.
+type InteractorSpec struct {
+	Program  string
+	Language domain.Language
+}
+
+// ValidateInteractor reports whether spec can be used to judge a problem.
+func ValidateInteractor(spec InteractorSpec) error {
+	if strings.TrimSpace(spec.Program) == "" {
+		return errors.New("interactive problem needs an interactor program")
+	}
+	if len(spec.Language.RunCmd) == 0 {
+		return ErrUnsupportedLanguage
+	}
+	return nil
+}
+
+// interact runs the submission against the interactor, which is started as
+//
+//	<run command> input.txt answer.txt
+//
+// with its stdin connected to the submission's stdout and its stdout to the
+// submission's stdin. The test's input and expected output are only visible
+// to the interactor: they are written into its own directory, which the
+// sandbox hands to the interactor's run under a uid of its own, and the two
+// programs only share the pipes. Exit codes follow the checker protocol: 0
+// accepts, 1 or 2 reject and anything else is a judge error; stderr becomes
+// the message.
+//
+// The submission's limits decide TLE, MLE and runtime errors, except that a
+// wrong answer wins over a crash caused by the interactor hanging up early.
+func (j *Judge) interact(ctx context.Context, interactor *program, tc domain.TestCases, spec sandbox.Spec) (*sandbox.Result, CheckResult, error) {
+	files := []struct{ name, data string }{
+		{"input.txt", tc.Input},
+		{"answer.txt", tc.Expected},
+	}
+	args := make([]string, 0, len(files))
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(interactor.dir, f.name), []byte(f.data), 0o600); err != nil {
+			return nil, CheckResult{}, err
+		}
+		args = append(args, f.name)
+	}
+
+	// The parent's copies of the pipe ends are closed once both processes
+	// have started so that each side sees EOF when the other exits.
+	progIn, interactorOut, err := os.Pipe()
+	if err != nil {
+		return nil, CheckResult{}, err
+	}
+	interactorIn, progOut, err := os.Pipe()
+	if err != nil {
+		progIn.Close()
+		interactorOut.Close()
+		return nil, CheckResult{}, err
+	}
+
+	spec.Stdin = progIn
+	spec.Stdout = progOut
+	spec.CloseAfterStart = []io.Closer{progIn, progOut}
+
+	ispec := interactor.spec(args...)
+	ispec.Stdin = interactorIn
+	ispec.Stdout = interactorOut
+	ispec.CloseAfterStart = []io.Closer{interactorIn, interactorOut}
+	ispec.TimeLimit = j.CheckerLimit
+	ispec.WallTimeLimit = 2*max(j.CheckerLimit, spec.TimeLimit) + time.Second
+	ispec.MemoryLimitKB = DefaultCheckerMemoryKB
+
+	type outcome struct {
+		run *sandbox.Result
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		run, err := j.Runner.Run(ctx, ispec)
+		done <- outcome{run, err}
+	}()
+	run, err := j.Runner.Run(ctx, spec)
+	other := <-done
+	if err != nil {
+		return nil, CheckResult{}, err
+	}
+	if other.err != nil {
+		return nil, CheckResult{}, other.err
+	}
+	irun := other.run
+
+	if run.TimedOut || run.MemoryExceeded {
+		return run, CheckResult{Status: run.Status()}, nil
+	}
+
+	message := truncate(strings.TrimSpace(irun.Stderr), maxMessageLength)
+	switch {
+	case irun.TimedOut || irun.MemoryExceeded || irun.Signal != "":
+		return run, CheckResult{Status: domain.STATUS_JUDGE_ERROR, Message: "interactor crashed or exceeded its limits"}, nil
+	case irun.ExitCode == checkerExitWrongAnswer || irun.ExitCode == checkerExitPresentation:
+		return run, CheckResult{Status: domain.STATUS_WRONG_ANSWER, Message: message}, nil
+	case irun.ExitCode != checkerExitAccepted:
+		return run, CheckResult{Status: domain.STATUS_JUDGE_ERROR, Message: "interactor failed: " + message}, nil
+	case run.Status() != "":
+		return run, CheckResult{Status: domain.STATUS_RUNTIME_ERROR, Message: runtimeMessage(run)}, nil
+	}
+	return run, CheckResult{Status: domain.STATUS_ACCEPTED, Score: 1, Message: message}, nil
+}
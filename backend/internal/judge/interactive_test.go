@@ -0,0 +1,84 @@
+package judge
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/sandbox"
+)
+
+// guessingRunner plays both sides of a guessing game over the judge's pipes:
+// the interactor answers "ok" when the submission prints the number from
+// input.txt, and the submission prints guess.
+func guessingRunner(guess string, timedOut bool) runnerFunc {
+	return func(ctx context.Context, spec sandbox.Spec) (*sandbox.Result, error) {
+		defer func() {
+			for _, c := range spec.CloseAfterStart {
+				c.Close()
+			}
+		}()
+		if spec.Args[1] != "interactor.py" {
+			if _, err := os.Stat(filepath.Join(spec.Dir, "answer.txt")); err == nil {
+				return nil, errors.New("the answer is in the submission's directory")
+			}
+			fmt.Fprintln(spec.Stdout, guess)
+			io.ReadAll(spec.Stdin)
+			return &sandbox.Result{TimedOut: timedOut}, nil
+		}
+
+		answer := filepath.Join(spec.Dir, spec.Args[3])
+		if fi, err := os.Stat(answer); err != nil || fi.Mode().Perm() != 0o600 {
+			return nil, fmt.Errorf("answer.txt must be private to the interactor: %v", err)
+		}
+		secret, err := os.ReadFile(filepath.Join(spec.Dir, spec.Args[2]))
+		if err != nil {
+			return nil, err
+		}
+		line, _ := bufio.NewReader(spec.Stdin).ReadString('\n')
+		if strings.TrimSpace(line) != string(secret) {
+			return &sandbox.Result{ExitCode: 1, Stderr: "wrong guess " + strings.TrimSpace(line)}, nil
+		}
+		fmt.Fprintln(spec.Stdout, "ok")
+		return &sandbox.Result{}, nil
+	}
+}
+
+func TestInteractive(t *testing.T) {
+	interactorLang := domain.Language{ID: "py", SourceFile: "interactor.py", RunCmd: []string{"python3", "interactor.py"}}
+	tests := []struct {
+		name     string
+		guess    string
+		timedOut bool
+		status   string
+		message  string
+	}{
+		{"accepted", "42", false, domain.STATUS_ACCEPTED, ""},
+		{"wrong answer", "7", false, domain.STATUS_WRONG_ANSWER, "wrong guess 7"},
+		{"time limit beats interactor", "7", true, domain.STATUS_TIME_LIMIT, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := New(guessingRunner(tt.guess, tt.timedOut)).Evaluate(context.Background(), Request{
+				Language:   python,
+				Code:       "x",
+				Tests:      []domain.TestCases{{Input: "42", Expected: "42"}},
+				Interactor: &InteractorSpec{Program: "y", Language: interactorLang},
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, res.Status)
+			require.Len(t, res.Tests, 1)
+			assert.Equal(t, tt.message, res.Tests[0].CheckerMessage)
+		})
+	}
+}
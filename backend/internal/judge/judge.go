@@ -0,0 +1,459 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/sandbox"
+)
+
+var (
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	ErrNoTestCases         = errors.New("problem has no test cases")
+)
+
+const (
+	DefaultTimeLimit     = 2 * time.Second
+	DefaultMemoryLimitKB = 256 * 1024
+	DefaultCompileLimit  = 30 * time.Second
+	// DefaultCompileMemoryKB bounds compilers, which template or constexpr
+	// heavy code can otherwise drive to use all of the host's memory.
+	DefaultCompileMemoryKB = 1024 * 1024
+
+	maxMessageLength = 4096
+)
+
+// Judge compiles a submission once and runs it against every test case of a
+// problem, deciding the verdict on the server.
+type Judge struct {
+	Runner        Runner
+	TimeLimit     time.Duration
+	MemoryLimitKB int
+	CompileLimit  time.Duration
+	// CompileMemoryKB is the memory limit of compilers.
+	CompileMemoryKB int
+	CheckerLimit    time.Duration
+
+	// GoCache is an optional warmed Go build cache, filled as root with e.g.
+	// GOCACHE=<dir> go build std. Go builds start from a hard-linked copy,
+	// so they can add entries but never change the shared ones.
+	GoCache string
+}
+
+func New(runner Runner) *Judge {
+	return &Judge{
+		Runner:          runner,
+		TimeLimit:       DefaultTimeLimit,
+		MemoryLimitKB:   DefaultMemoryLimitKB,
+		CompileLimit:    DefaultCompileLimit,
+		CompileMemoryKB: DefaultCompileMemoryKB,
+		CheckerLimit:    DefaultCheckerLimit,
+	}
+}
+
+// Request is a single piece of code to evaluate. Language is the registry
+// entry the caller resolved for the submission. TimeLimit and MemoryLimitKB
+// are the final limits, usually from [Limits]; zero means the judge defaults
+// scaled by the language multipliers. Interactor is set for interactive
+// problems and replaces the checker. Progress, if set, is told when the judge
+// starts compiling and running the submission.
+//
+// Tests that record a hash for their input or expected output are read from
+// Data; the others use their Input and Expected as they are.
+type Request struct {
+	Language      domain.Language
+	Code          string
+	Tests         []domain.TestCases
+	Data          TestData
+	TimeLimit     time.Duration
+	MemoryLimitKB int
+	Checker       CheckerSpec
+	Interactor    *InteractorSpec
+	Progress      func(status string)
+}
+
+// TestResult is the outcome of running the submission on one test case.
+type TestResult struct {
+	TestCaseID     uuid.UUID
+	Status         string
+	Score          float64 // 0..1 from the checker
+	ExecutionTime  int     // in milliseconds
+	MemoryUsed     int     // in KB
+	Stdout         string
+	Stderr         string
+	CheckerMessage string
+}
+
+// Result is the overall verdict for a submission.
+type Result struct {
+	Status          string
+	ExecutionTime   int // slowest test, in milliseconds
+	MemoryUsed      int // peak across tests, in KB
+	TestCasesPassed int
+	TotalTestCases  int
+	ErrorMessage    string
+	Tests           []TestResult
+}
+
+// Evaluate builds the submission and runs it against all test cases. The
+// overall status is accepted only if every test passes; otherwise it is the
+// status of the first failing test.
+func (j *Judge) Evaluate(ctx context.Context, req Request) (*Result, error) {
+	lang := req.Language
+	if len(lang.RunCmd) == 0 {
+		return nil, ErrUnsupportedLanguage
+	}
+	if len(req.Tests) == 0 {
+		return nil, ErrNoTestCases
+	}
+
+	result := &Result{TotalTestCases: len(req.Tests)}
+
+	var check checker
+	var interactor *program
+	var failure string
+	var err error
+	if req.Interactor != nil {
+		if len(req.Interactor.Language.RunCmd) == 0 {
+			return nil, ErrUnsupportedLanguage
+		}
+		interactor, failure, err = j.build(ctx, req.Interactor.Language, req.Interactor.Program)
+		if err != nil {
+			return nil, err
+		}
+		if failure != "" {
+			result.Status = domain.STATUS_JUDGE_ERROR
+			result.ErrorMessage = "interactor does not compile:\n" + failure
+			return result, nil
+		}
+		defer interactor.close()
+	} else {
+		check, failure, err = j.newChecker(ctx, req.Checker)
+		if err != nil {
+			return nil, err
+		}
+		if failure != "" {
+			result.Status = domain.STATUS_JUDGE_ERROR
+			result.ErrorMessage = "checker does not compile:\n" + failure
+			return result, nil
+		}
+		defer check.close()
+	}
+
+	req.progress(domain.STATUS_COMPILING)
+	prog, failure, err := j.build(ctx, lang, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		result.Status = domain.STATUS_COMPILE_ERROR
+		result.ErrorMessage = failure
+		return result, nil
+	}
+	defer prog.close()
+
+	timeLimit, memoryLimitKB := j.limits(req)
+	req.progress(domain.STATUS_RUNNING)
+	for _, tc := range req.Tests {
+		spec := prog.spec()
+		spec.TimeLimit = timeLimit
+		spec.MemoryLimitKB = memoryLimitKB
+
+		var run *sandbox.Result
+		var verdict CheckResult
+		if interactor != nil {
+			if tc, err = req.load(ctx, tc); err == nil {
+				run, verdict, err = j.interact(ctx, interactor, tc, spec)
+			}
+		} else {
+			var stdin io.ReadCloser
+			if stdin, err = req.open(ctx, tc.InputHash, tc.Input); err != nil {
+				return nil, err
+			}
+			spec.Stdin = stdin
+			run, err = j.Runner.Run(ctx, spec)
+			stdin.Close()
+			if err == nil {
+				verdict, err = j.verdict(ctx, req, check, run, tc)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		tr := TestResult{
+			TestCaseID:     tc.ID,
+			Status:         verdict.Status,
+			Score:          verdict.Score,
+			ExecutionTime:  int(run.CPUTime.Milliseconds()),
+			MemoryUsed:     run.MemoryKB,
+			Stdout:         run.Stdout,
+			Stderr:         run.Stderr,
+			CheckerMessage: verdict.Message,
+		}
+		result.Tests = append(result.Tests, tr)
+
+		result.ExecutionTime = max(result.ExecutionTime, tr.ExecutionTime)
+		result.MemoryUsed = max(result.MemoryUsed, tr.MemoryUsed)
+
+		if tr.Status == domain.STATUS_ACCEPTED {
+			result.TestCasesPassed++
+		} else if result.Status == "" {
+			result.Status = tr.Status
+			result.ErrorMessage = truncate(run.Stderr, maxMessageLength)
+		}
+	}
+
+	if result.Status == "" {
+		result.Status = domain.STATUS_ACCEPTED
+	}
+	return result, nil
+}
+
+// Execute builds the code of req and runs it once on stdin without checking
+// its output, for trying code out on custom input. Tests, Checker and
+// Interactor are ignored. The single test result has status ok if the
+// program finished normally.
+func (j *Judge) Execute(ctx context.Context, req Request, stdin string) (*Result, error) {
+	if len(req.Language.RunCmd) == 0 {
+		return nil, ErrUnsupportedLanguage
+	}
+
+	result := &Result{TotalTestCases: 1}
+	prog, failure, err := j.build(ctx, req.Language, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		result.Status = domain.STATUS_COMPILE_ERROR
+		result.ErrorMessage = failure
+		return result, nil
+	}
+	defer prog.close()
+
+	spec := prog.spec()
+	spec.Stdin = strings.NewReader(stdin)
+	spec.TimeLimit, spec.MemoryLimitKB = j.limits(req)
+	run, err := j.Runner.Run(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := TestResult{
+		Status:        run.Status(),
+		ExecutionTime: int(run.CPUTime.Milliseconds()),
+		MemoryUsed:    run.MemoryKB,
+		Stdout:        run.Stdout,
+		Stderr:        run.Stderr,
+	}
+	if tr.Status == "" {
+		tr.Status = domain.STATUS_OK
+		result.TestCasesPassed = 1
+	} else if tr.Status == domain.STATUS_RUNTIME_ERROR {
+		tr.CheckerMessage = runtimeMessage(run)
+	}
+	result.Status = tr.Status
+	result.ExecutionTime = tr.ExecutionTime
+	result.MemoryUsed = tr.MemoryUsed
+	result.Tests = []TestResult{tr}
+	return result, nil
+}
+
+// limits returns the final limits of req, falling back to the judge's
+// defaults scaled by the language multipliers.
+func (j *Judge) limits(req Request) (time.Duration, int) {
+	timeLimit := req.TimeLimit
+	if timeLimit <= 0 {
+		timeLimit = scaleDuration(j.TimeLimit, req.Language.TimeMultiplier)
+	}
+	memoryLimitKB := req.MemoryLimitKB
+	if memoryLimitKB <= 0 {
+		memoryLimitKB = scaleInt(j.MemoryLimitKB, req.Language.MemoryMultiplier)
+	}
+	return timeLimit, memoryLimitKB
+}
+
+func (r Request) progress(status string) {
+	if r.Progress != nil {
+		r.Progress(status)
+	}
+}
+
+// TestData opens test files kept outside the database by their hash.
+type TestData interface {
+	Open(ctx context.Context, hash string) (io.ReadCloser, error)
+}
+
+// open streams a test file from Data when hash is set, and inline otherwise.
+func (r Request) open(ctx context.Context, hash, inline string) (io.ReadCloser, error) {
+	if hash == "" || r.Data == nil {
+		return io.NopCloser(strings.NewReader(inline)), nil
+	}
+	return r.Data.Open(ctx, hash)
+}
+
+// read returns the whole test file that open streams.
+func (r Request) read(ctx context.Context, hash, inline string) (string, error) {
+	f, err := r.open(ctx, hash, inline)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	return string(data), err
+}
+
+// load fills in the input and expected output of tc, which checkers and
+// interactors need in full.
+func (r Request) load(ctx context.Context, tc domain.TestCases) (domain.TestCases, error) {
+	var err error
+	if tc.Input, err = r.read(ctx, tc.InputHash, tc.Input); err != nil {
+		return tc, err
+	}
+	tc.Expected, err = r.read(ctx, tc.ExpectedHash, tc.Expected)
+	return tc, err
+}
+
+// verdict decides a test's status from the run and, if the program exited
+// cleanly, from the checker.
+func (j *Judge) verdict(ctx context.Context, req Request, check checker, run *sandbox.Result, tc domain.TestCases) (CheckResult, error) {
+	switch run.Status() {
+	case "":
+		tc, err := req.load(ctx, tc)
+		if err != nil {
+			return CheckResult{}, err
+		}
+		return check.check(ctx, tc.Input, tc.Expected, run.Stdout)
+	case domain.STATUS_RUNTIME_ERROR:
+		return CheckResult{Status: domain.STATUS_RUNTIME_ERROR, Message: runtimeMessage(run)}, nil
+	default:
+		return CheckResult{Status: run.Status()}, nil
+	}
+}
+
+func runtimeMessage(run *sandbox.Result) string {
+	switch {
+	case run.OutputExceeded:
+		return "output limit exceeded"
+	case run.Signal != "":
+		return "killed by signal: " + run.Signal
+	default:
+		return fmt.Sprintf("exit code %d", run.ExitCode)
+	}
+}
+
+// program is source code built in its own working directory.
+type program struct {
+	dir  string
+	lang domain.Language
+	env  []string
+}
+
+// build writes code into a fresh directory and compiles it. A non-empty
+// message is the compiler output of a failed build.
+func (j *Judge) build(ctx context.Context, lang domain.Language, code string) (*program, string, error) {
+	dir, err := os.MkdirTemp("", "codearena-judge-")
+	if err != nil {
+		return nil, "", err
+	}
+	prog := &program{dir: dir, lang: lang, env: sandboxEnv(dir)}
+	if j.GoCache != "" && len(lang.CompileCmd) > 0 && filepath.Base(lang.CompileCmd[0]) == "go" {
+		// Best effort: a build without the cache is only slower.
+		linkTree(j.GoCache, filepath.Join(dir, ".gocache"))
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, lang.SourceFile), []byte(code), 0o644); err != nil {
+		prog.close()
+		return nil, "", err
+	}
+
+	if len(lang.CompileCmd) > 0 {
+		compiled, err := j.Runner.Run(ctx, sandbox.Spec{
+			Dir:           dir,
+			Args:          lang.CompileCmd,
+			Env:           prog.env,
+			TimeLimit:     j.CompileLimit,
+			MemoryLimitKB: j.CompileMemoryKB,
+		})
+		if err != nil {
+			prog.close()
+			return nil, "", err
+		}
+		if compiled.CompileStatus() != "" {
+			prog.close()
+			return nil, truncate(compiled.Stderr+compiled.Stdout, maxMessageLength), nil
+		}
+	}
+	return prog, "", nil
+}
+
+// spec runs the program with extra command line arguments.
+func (p *program) spec(args ...string) sandbox.Spec {
+	return sandbox.Spec{
+		Dir:  p.dir,
+		Args: append(slices.Clone(p.lang.RunCmd), args...),
+		Env:  p.env,
+	}
+}
+
+func (p *program) close() {
+	os.RemoveAll(p.dir)
+}
+
+// scaleDuration and scaleInt apply a language multiplier; zero means none.
+func scaleDuration(d time.Duration, m float64) time.Duration {
+	if m <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * m)
+}
+
+func scaleInt(n int, m float64) int {
+	if m <= 0 {
+		return n
+	}
+	return int(float64(n) * m)
+}
+
+// sandboxEnv is the minimal environment given to compilers and submissions.
+func sandboxEnv(dir string) []string {
+	return []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		"TMPDIR=" + dir,
+		"GOCACHE=" + filepath.Join(dir, ".gocache"),
+		"GOPATH=" + filepath.Join(dir, ".gopath"),
+		"LANG=C.UTF-8",
+	}
+}
+
+// linkTree recreates the directories of src under dst and hard-links the
+// files into them.
+func linkTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return os.Link(path, target)
+	})
+}
@@ -0,0 +1,183 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/sandbox"
+)
+
+// fakeRunner answers runs from a table keyed by stdin.
+type fakeRunner struct {
+	compile     *sandbox.Result
+	compileSpec sandbox.Spec
+	runs        map[string]*sandbox.Result
+	calls       int
+}
+
+func (f *fakeRunner) Run(ctx context.Context, spec sandbox.Spec) (*sandbox.Result, error) {
+	f.calls++
+	if spec.Stdin == nil {
+		f.compileSpec = spec
+		return f.compile, nil
+	}
+	stdin, _ := io.ReadAll(spec.Stdin)
+	res := *f.runs[string(stdin)]
+	res.MemoryExceeded = spec.MemoryLimitKB > 0 && res.MemoryKB > spec.MemoryLimitKB
+	return &res, nil
+}
+
+// mapData is TestData kept in memory, keyed by hash.
+type mapData map[string]string
+
+func (m mapData) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	data, ok := m[hash]
+	if !ok {
+		return nil, errors.New("no such test file")
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+var (
+	python = domain.Language{ID: "py", SourceFile: "main.py", RunCmd: []string{"python3", "main.py"}}
+	cpp    = domain.Language{ID: "cpp", SourceFile: "main.cpp", CompileCmd: []string{"g++", "main.cpp"}, RunCmd: []string{"./a.out"}}
+)
+
+func TestJudge_Evaluate(t *testing.T) {
+	tests := []domain.TestCases{
+		{Input: "1 2", Expected: "3\n"},
+		{Input: "2 2", Expected: "4"},
+		{Input: "5 5", Expected: "10"},
+	}
+
+	t.Run("all tests pass", func(t *testing.T) {
+		runner := &fakeRunner{runs: map[string]*sandbox.Result{
+			"1 2": {Stdout: "3", CPUTime: 10 * time.Millisecond, MemoryKB: 100},
+			"2 2": {Stdout: "4  \n", CPUTime: 30 * time.Millisecond, MemoryKB: 300},
+			"5 5": {Stdout: "10\n\n", CPUTime: 20 * time.Millisecond, MemoryKB: 200},
+		}}
+		res, err := New(runner).Evaluate(context.Background(), Request{Language: python, Code: "x", Tests: tests})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.STATUS_ACCEPTED, res.Status)
+		assert.Equal(t, 3, res.TestCasesPassed)
+		assert.Equal(t, 3, res.TotalTestCases)
+		assert.Equal(t, 30, res.ExecutionTime)
+		assert.Equal(t, 300, res.MemoryUsed)
+	})
+
+	t.Run("first failing test decides the verdict", func(t *testing.T) {
+		runner := &fakeRunner{runs: map[string]*sandbox.Result{
+			"1 2": {Stdout: "3"},
+			"2 2": {Stdout: "5"},
+			"5 5": {TimedOut: true},
+		}}
+		res, err := New(runner).Evaluate(context.Background(), Request{Language: python, Code: "x", Tests: tests})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.STATUS_WRONG_ANSWER, res.Status)
+		assert.Equal(t, `token 1: expected "4", got "5"`, res.Tests[1].CheckerMessage)
+		assert.Equal(t, 1, res.TestCasesPassed)
+		assert.Equal(t, domain.STATUS_TIME_LIMIT, res.Tests[2].Status)
+	})
+
+	t.Run("runtime error and memory limit", func(t *testing.T) {
+		runner := &fakeRunner{runs: map[string]*sandbox.Result{
+			"1 2": {ExitCode: 1, Stderr: "boom"},
+			"2 2": {Stdout: "4", MemoryKB: DefaultMemoryLimitKB + 1},
+			"5 5": {Stdout: "10"},
+		}}
+		res, err := New(runner).Evaluate(context.Background(), Request{Language: python, Code: "x", Tests: tests})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.STATUS_RUNTIME_ERROR, res.Status)
+		assert.Equal(t, "boom", res.ErrorMessage)
+		assert.Equal(t, "exit code 1", res.Tests[0].CheckerMessage)
+		assert.Equal(t, domain.STATUS_MEMORY_LIMIT, res.Tests[1].Status)
+	})
+
+	t.Run("compile error stops judging", func(t *testing.T) {
+		runner := &fakeRunner{compile: &sandbox.Result{ExitCode: 1, Stderr: "syntax error"}}
+		res, err := New(runner).Evaluate(context.Background(), Request{Language: cpp, Code: "x", Tests: tests})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.STATUS_COMPILE_ERROR, res.Status)
+		assert.Equal(t, "syntax error", res.ErrorMessage)
+		assert.Equal(t, 1, runner.calls)
+		assert.Equal(t, DefaultCompileMemoryKB, runner.compileSpec.MemoryLimitKB)
+	})
+
+	t.Run("language multipliers scale the limits", func(t *testing.T) {
+		runner := &fakeRunner{runs: map[string]*sandbox.Result{
+			"1 2": {Stdout: "3", MemoryKB: DefaultMemoryLimitKB + 1},
+			"2 2": {Stdout: "4"},
+			"5 5": {Stdout: "10"},
+		}}
+		slow := python
+		slow.MemoryMultiplier = 2
+		res, err := New(runner).Evaluate(context.Background(), Request{Language: slow, Code: "x", Tests: tests})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.STATUS_ACCEPTED, res.Status)
+	})
+
+	t.Run("request limits override the defaults", func(t *testing.T) {
+		runner := &fakeRunner{runs: map[string]*sandbox.Result{
+			"1 2": {Stdout: "3", MemoryKB: 2048},
+			"2 2": {Stdout: "4"},
+			"5 5": {Stdout: "10"},
+		}}
+		res, err := New(runner).Evaluate(context.Background(), Request{Language: python, Code: "x", Tests: tests, MemoryLimitKB: 1024})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.STATUS_MEMORY_LIMIT, res.Status)
+	})
+
+	t.Run("tests with hashes are read from the test data", func(t *testing.T) {
+		runner := &fakeRunner{runs: map[string]*sandbox.Result{
+			"7 8": {Stdout: "15"},
+		}}
+		data := mapData{"in": "7 8", "out": "15\n"}
+		stored := []domain.TestCases{{InputHash: "in", ExpectedHash: "out"}}
+		res, err := New(runner).Evaluate(context.Background(), Request{Language: python, Code: "x", Tests: stored, Data: data})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.STATUS_ACCEPTED, res.Status)
+
+		stored[0].ExpectedHash = "missing"
+		_, err = New(runner).Evaluate(context.Background(), Request{Language: python, Code: "x", Tests: stored, Data: data})
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects unknown language and empty test sets", func(t *testing.T) {
+		_, err := New(&fakeRunner{}).Evaluate(context.Background(), Request{Language: domain.Language{ID: "cobol"}, Tests: tests})
+		assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+
+		_, err = New(&fakeRunner{}).Evaluate(context.Background(), Request{Language: python})
+		assert.ErrorIs(t, err, ErrNoTestCases)
+	})
+}
+
+func TestJudge_Execute(t *testing.T) {
+	runner := &fakeRunner{runs: map[string]*sandbox.Result{
+		"hi":    {Stdout: "HI\n", CPUTime: 5 * time.Millisecond, MemoryKB: 100},
+		"crash": {ExitCode: 2, Stderr: "panic"},
+	}}
+
+	res, err := New(runner).Execute(context.Background(), Request{Language: python, Code: "x"}, "hi")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.STATUS_OK, res.Status)
+	assert.Equal(t, "HI\n", res.Tests[0].Stdout)
+	assert.Equal(t, 5, res.ExecutionTime)
+
+	res, err = New(runner).Execute(context.Background(), Request{Language: python, Code: "x"}, "crash")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.STATUS_RUNTIME_ERROR, res.Status)
+	assert.Equal(t, "exit code 2", res.Tests[0].CheckerMessage)
+}
@@ -0,0 +1,56 @@
+package judge
+
+import "github.com/sudankdk/codearena/internal/domain"
+
+// DefaultLanguages are the built-in recipes seeded into the language registry
+// on startup. Existing registry rows are never overwritten by them. Slower
+// runtimes get larger multipliers on the problem's limits.
+func DefaultLanguages() []domain.Language {
+	return []domain.Language{
+		{
+			ID:               "py",
+			Name:             "Python 3",
+			SourceFile:       "main.py",
+			RunCmd:           []string{"python3", "main.py"},
+			Version:          "3",
+			TimeMultiplier:   2,
+			MemoryMultiplier: 1,
+		},
+		{
+			ID:               "js",
+			Name:             "JavaScript (Node.js)",
+			SourceFile:       "main.js",
+			RunCmd:           []string{"node", "main.js"},
+			TimeMultiplier:   1.5,
+			MemoryMultiplier: 1.5,
+		},
+		{
+			ID:               "go",
+			Name:             "Go",
+			SourceFile:       "main.go",
+			CompileCmd:       []string{"go", "build", "-o", "main", "main.go"},
+			RunCmd:           []string{"./main"},
+			TimeMultiplier:   1,
+			MemoryMultiplier: 1,
+		},
+		{
+			ID:               "cpp",
+			Name:             "C++17 (g++)",
+			SourceFile:       "main.cpp",
+			CompileCmd:       []string{"g++", "-O2", "-std=c++17", "-o", "main", "main.cpp"},
+			RunCmd:           []string{"./main"},
+			Version:          "c++17",
+			TimeMultiplier:   1,
+			MemoryMultiplier: 1,
+		},
+		{
+			ID:               "java",
+			Name:             "Java",
+			SourceFile:       "Main.java",
+			CompileCmd:       []string{"javac", "Main.java"},
+			RunCmd:           []string{"java", "-cp", ".", "Main"},
+			TimeMultiplier:   2,
+			MemoryMultiplier: 2,
+		},
+	}
+}
@@ -0,0 +1,37 @@
+package judge
+
+import (
+	"time"
+
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+// Limits returns the time and memory limits for running problem in lang. A
+// per-language override wins; otherwise the problem limit is scaled by the
+// language multiplier. Missing problem limits fall back to the domain defaults.
+func Limits(problem *domain.Problem, lang domain.Language) (time.Duration, int) {
+	timeMs := problem.TimeLimitMs
+	if timeMs <= 0 {
+		timeMs = domain.DefaultTimeLimitMs
+	}
+	memoryMB := problem.MemoryLimitMB
+	if memoryMB <= 0 {
+		memoryMB = domain.DefaultMemoryLimitMB
+	}
+
+	timeLimit := scaleDuration(time.Duration(timeMs)*time.Millisecond, lang.TimeMultiplier)
+	memoryKB := scaleInt(memoryMB*1024, lang.MemoryMultiplier)
+
+	for _, override := range problem.LanguageLimits {
+		if override.LanguageID != lang.ID {
+			continue
+		}
+		if override.TimeLimitMs > 0 {
+			timeLimit = time.Duration(override.TimeLimitMs) * time.Millisecond
+		}
+		if override.MemoryLimitMB > 0 {
+			memoryKB = override.MemoryLimitMB * 1024
+		}
+	}
+	return timeLimit, memoryKB
+}
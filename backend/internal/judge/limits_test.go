@@ -0,0 +1,42 @@
+package judge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+func TestLimits(t *testing.T) {
+	lang := domain.Language{ID: "py", TimeMultiplier: 2, MemoryMultiplier: 1.5}
+
+	t.Run("problem limits scaled by the language", func(t *testing.T) {
+		timeLimit, memoryKB := Limits(&domain.Problem{TimeLimitMs: 1000, MemoryLimitMB: 64}, lang)
+
+		assert.Equal(t, 2*time.Second, timeLimit)
+		assert.Equal(t, 96*1024, memoryKB)
+	})
+
+	t.Run("defaults when the problem has none", func(t *testing.T) {
+		timeLimit, memoryKB := Limits(&domain.Problem{}, domain.Language{ID: "cpp"})
+
+		assert.Equal(t, time.Duration(domain.DefaultTimeLimitMs)*time.Millisecond, timeLimit)
+		assert.Equal(t, domain.DefaultMemoryLimitMB*1024, memoryKB)
+	})
+
+	t.Run("language override wins field by field", func(t *testing.T) {
+		problem := &domain.Problem{
+			TimeLimitMs:   1000,
+			MemoryLimitMB: 64,
+			LanguageLimits: []domain.ProblemLanguageLimit{
+				{LanguageID: "cpp", TimeLimitMs: 100},
+				{LanguageID: "py", TimeLimitMs: 5000},
+			},
+		}
+		timeLimit, memoryKB := Limits(problem, lang)
+
+		assert.Equal(t, 5*time.Second, timeLimit)
+		assert.Equal(t, 96*1024, memoryKB)
+	})
+}
@@ -0,0 +1,15 @@
+package judge
+
+import (
+	"context"
+
+	"github.com/sudankdk/codearena/internal/sandbox"
+)
+
+// Runner executes compilers and submissions on behalf of the judge.
+// [sandbox.Sandbox] is the production implementation.
+type Runner interface {
+	Run(ctx context.Context, spec sandbox.Spec) (*sandbox.Result, error)
+}
+
+var _ Runner = (*sandbox.Sandbox)(nil)
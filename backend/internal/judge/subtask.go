@@ -0,0 +1,120 @@
+package judge
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+// SubtaskResult is the score of one subtask of a submission.
+type SubtaskResult struct {
+	Index  int
+	Name   string
+	Status string // accepted, or why the subtask lost points
+	Points int
+	Score  float64 // points earned, 0..Points
+}
+
+// ValidateSubtasks reports whether subtasks can be used to score a problem.
+func ValidateSubtasks(subtasks []domain.Subtask) error {
+	seen := make(map[int]bool, len(subtasks))
+	for _, s := range subtasks {
+		if s.Index < 1 {
+			return errors.New("subtask indexes start at 1")
+		}
+		if seen[s.Index] {
+			return fmt.Errorf("duplicate subtask %d", s.Index)
+		}
+		seen[s.Index] = true
+		if s.Points < 0 {
+			return fmt.Errorf("subtask %d: points must not be negative", s.Index)
+		}
+		switch s.Aggregation {
+		case "", domain.SUBTASK_ALL, domain.SUBTASK_MIN:
+		default:
+			return fmt.Errorf("subtask %d: unknown aggregation %q", s.Index, s.Aggregation)
+		}
+	}
+	for _, s := range subtasks {
+		for _, dep := range s.Dependencies {
+			if dep >= s.Index || !seen[dep] {
+				return fmt.Errorf("subtask %d: dependencies must be earlier subtasks, got %d", s.Index, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// ScoreSubtasks turns the per-test results of res into subtask scores. A
+// subtask's fraction of its points is 1 or 0 for the "all" aggregation and
+// the lowest test score for "min", capped by the fractions of the subtasks
+// it depends on. Subtasks without any judged tests score nothing.
+func ScoreSubtasks(subtasks []domain.Subtask, tests []domain.TestCases, res *Result) []SubtaskResult {
+	subtaskOf := make(map[uuid.UUID]int, len(tests))
+	for _, tc := range tests {
+		subtaskOf[tc.ID] = tc.Subtask
+	}
+
+	sorted := slices.Clone(subtasks)
+	slices.SortFunc(sorted, func(a, b domain.Subtask) int { return a.Index - b.Index })
+
+	fractions := make(map[int]float64, len(sorted))
+	statuses := make(map[int]string, len(sorted))
+	out := make([]SubtaskResult, 0, len(sorted))
+	for _, s := range sorted {
+		fraction, status, judged := 1.0, "", false
+		for _, tr := range res.Tests {
+			if subtaskOf[tr.TestCaseID] != s.Index {
+				continue
+			}
+			judged = true
+			score := tr.Score
+			if s.Aggregation != domain.SUBTASK_MIN {
+				score = 0
+				if tr.Status == domain.STATUS_ACCEPTED {
+					score = 1
+				}
+			}
+			fraction = min(fraction, score)
+			if status == "" && tr.Status != domain.STATUS_ACCEPTED {
+				status = tr.Status
+			}
+		}
+		if !judged {
+			fraction, status = 0, res.Status
+		}
+		for _, dep := range s.Dependencies {
+			if fractions[dep] < fraction {
+				fraction = fractions[dep]
+			}
+			if status == "" && statuses[dep] != domain.STATUS_ACCEPTED {
+				status = statuses[dep]
+			}
+		}
+		if status == "" {
+			status = domain.STATUS_ACCEPTED
+		}
+		fractions[s.Index], statuses[s.Index] = fraction, status
+
+		out = append(out, SubtaskResult{
+			Index:  s.Index,
+			Name:   s.Name,
+			Status: status,
+			Points: s.Points,
+			Score:  fraction * float64(s.Points),
+		})
+	}
+	return out
+}
+
+// SubtaskPoints sums the points earned over results.
+func SubtaskPoints(results []SubtaskResult) float64 {
+	var total float64
+	for _, r := range results {
+		total += r.Score
+	}
+	return total
+}
@@ -0,0 +1,56 @@
+package judge
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+func TestScoreSubtasks(t *testing.T) {
+	tests := make([]domain.TestCases, 5)
+	for i := range tests {
+		tests[i].ID = uuid.New()
+	}
+	tests[0].Subtask, tests[1].Subtask = 1, 1
+	tests[2].Subtask, tests[3].Subtask = 2, 2
+	tests[4].Subtask = 3
+
+	subtasks := []domain.Subtask{
+		{Index: 3, Points: 50, Dependencies: []int{2}},
+		{Index: 1, Points: 20},
+		{Index: 2, Points: 30, Aggregation: domain.SUBTASK_MIN},
+	}
+	res := &Result{Status: domain.STATUS_WRONG_ANSWER, Tests: []TestResult{
+		{TestCaseID: tests[0].ID, Status: domain.STATUS_ACCEPTED, Score: 1},
+		{TestCaseID: tests[1].ID, Status: domain.STATUS_ACCEPTED, Score: 1},
+		{TestCaseID: tests[2].ID, Status: domain.STATUS_ACCEPTED, Score: 1},
+		{TestCaseID: tests[3].ID, Status: domain.STATUS_WRONG_ANSWER, Score: 0.5},
+		{TestCaseID: tests[4].ID, Status: domain.STATUS_ACCEPTED, Score: 1},
+	}}
+
+	got := ScoreSubtasks(subtasks, tests, res)
+
+	require.Len(t, got, 3)
+	assert.Equal(t, SubtaskResult{Index: 1, Status: domain.STATUS_ACCEPTED, Points: 20, Score: 20}, got[0])
+	assert.Equal(t, SubtaskResult{Index: 2, Status: domain.STATUS_WRONG_ANSWER, Points: 30, Score: 15}, got[1])
+	assert.Equal(t, SubtaskResult{Index: 3, Status: domain.STATUS_WRONG_ANSWER, Points: 50, Score: 25}, got[2])
+	assert.Equal(t, 60.0, SubtaskPoints(got))
+}
+
+func TestScoreSubtasks_CompileError(t *testing.T) {
+	got := ScoreSubtasks([]domain.Subtask{{Index: 1, Points: 10}}, nil, &Result{Status: domain.STATUS_COMPILE_ERROR})
+
+	require.Len(t, got, 1)
+	assert.Equal(t, domain.STATUS_COMPILE_ERROR, got[0].Status)
+	assert.Zero(t, got[0].Score)
+}
+
+func TestValidateSubtasks(t *testing.T) {
+	assert.NoError(t, ValidateSubtasks([]domain.Subtask{{Index: 1}, {Index: 2, Dependencies: []int{1}}}))
+	assert.Error(t, ValidateSubtasks([]domain.Subtask{{Index: 1, Dependencies: []int{2}}, {Index: 2}}))
+	assert.Error(t, ValidateSubtasks([]domain.Subtask{{Index: 1}, {Index: 1}}))
+	assert.Error(t, ValidateSubtasks([]domain.Subtask{{Index: 1, Aggregation: "sum"}}))
+}
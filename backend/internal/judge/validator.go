@@ -0,0 +1,95 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+// validatorExitValid is the exit code Kattis input validators use for valid
+// input; 0 is accepted as well.
+const validatorExitValid = 42
+
+// ValidatorSpec is the setter's input validator. It reads a test's input on
+// stdin and exits with 0 (or 42, as Kattis validators do) when the input is
+// valid. Any other exit rejects the input and stderr says why.
+type ValidatorSpec struct {
+	Program  string
+	Language domain.Language
+}
+
+// ValidateValidator reports whether spec can be used to check inputs.
+func ValidateValidator(spec ValidatorSpec) error {
+	if strings.TrimSpace(spec.Program) == "" {
+		return errors.New("input validator needs a program")
+	}
+	if len(spec.Language.RunCmd) == 0 {
+		return ErrUnsupportedLanguage
+	}
+	return nil
+}
+
+// InputCheck is the validator's decision on the input of one test.
+type InputCheck struct {
+	TestCaseID uuid.UUID
+	Valid      bool
+	Message    string
+}
+
+// Validation is the outcome of running the validator over a problem's
+// tests. CompileError is set, and Tests empty, when the validator does not
+// compile.
+type Validation struct {
+	CompileError string
+	Tests        []InputCheck
+}
+
+// ValidateInputs builds the validator and runs it on the input of every
+// test. Inputs with a hash are streamed from data.
+func (j *Judge) ValidateInputs(ctx context.Context, spec ValidatorSpec, tests []domain.TestCases, data TestData) (*Validation, error) {
+	if len(spec.Language.RunCmd) == 0 {
+		return nil, ErrUnsupportedLanguage
+	}
+	prog, failure, err := j.build(ctx, spec.Language, spec.Program)
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		return &Validation{CompileError: failure}, nil
+	}
+	defer prog.close()
+
+	req := Request{Data: data}
+	res := &Validation{Tests: make([]InputCheck, 0, len(tests))}
+	for _, tc := range tests {
+		var stdin io.ReadCloser
+		if stdin, err = req.open(ctx, tc.InputHash, tc.Input); err != nil {
+			return nil, err
+		}
+		run := prog.spec()
+		run.Stdin = stdin
+		run.TimeLimit = j.CheckerLimit
+		run.MemoryLimitKB = DefaultCheckerMemoryKB
+		result, err := j.Runner.Run(ctx, run)
+		stdin.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		check := InputCheck{TestCaseID: tc.ID, Message: truncate(strings.TrimSpace(result.Stderr), maxMessageLength)}
+		switch {
+		case result.TimedOut || result.MemoryExceeded || result.Signal != "":
+			check.Message = "validator crashed or exceeded its limits"
+		case result.ExitCode == 0 || result.ExitCode == validatorExitValid:
+			check.Valid = true
+		case check.Message == "":
+			check.Message = "input rejected"
+		}
+		res.Tests = append(res.Tests, check)
+	}
+	return res, nil
+}
@@ -0,0 +1,54 @@
+package judge
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/sandbox"
+)
+
+func TestValidateInputs(t *testing.T) {
+	// The validator accepts a single number, exiting 42 like Kattis
+	// validators, and accepts "0" with exit code 0.
+	runner := runnerFunc(func(ctx context.Context, spec sandbox.Spec) (*sandbox.Result, error) {
+		data, _ := io.ReadAll(spec.Stdin)
+		input := strings.TrimSpace(string(data))
+		switch _, err := strconv.Atoi(input); {
+		case input == "loop":
+			return &sandbox.Result{TimedOut: true}, nil
+		case input == "0":
+			return &sandbox.Result{}, nil
+		case err != nil:
+			return &sandbox.Result{ExitCode: 43, Stderr: "not a number: " + input}, nil
+		}
+		return &sandbox.Result{ExitCode: 42}, nil
+	})
+	tests := []domain.TestCases{
+		{Input: "12\n"},
+		{Input: "0"},
+		{Input: "twelve"},
+		{InputHash: "big"},
+		{Input: "loop"},
+	}
+
+	res, err := New(runner).ValidateInputs(context.Background(), ValidatorSpec{Program: "v", Language: python}, tests, mapData{"big": "x y"})
+	require.NoError(t, err)
+	require.Len(t, res.Tests, 5)
+	assert.True(t, res.Tests[0].Valid)
+	assert.True(t, res.Tests[1].Valid)
+	assert.Equal(t, InputCheck{Message: "not a number: twelve"}, res.Tests[2])
+	assert.Equal(t, InputCheck{Message: "not a number: x y"}, res.Tests[3])
+	assert.Equal(t, "validator crashed or exceeded its limits", res.Tests[4].Message)
+
+	broken := &fakeRunner{compile: &sandbox.Result{ExitCode: 1, Stderr: "syntax error"}}
+	res, err = New(broken).ValidateInputs(context.Background(), ValidatorSpec{Program: "v", Language: cpp}, tests, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "syntax error", res.CompileError)
+	assert.Empty(t, res.Tests)
+}
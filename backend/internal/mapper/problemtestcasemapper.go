@@ -12,11 +12,60 @@ func ToDomain(in dto.CreateProblemDTO) domain.Problem {
 		Description: in.Description,
 		Tag:         in.Tag,
 		Difficulty:  in.Difficulty,
+		Type:        in.Type,
+		Signature:   in.Signature,
+
+		TimeLimitMs:    in.TimeLimitMs,
+		MemoryLimitMB:  in.MemoryLimitMB,
+		LanguageLimits: ToLanguageLimits(in.LanguageLimits),
+		Subtasks:       ToSubtasks(in.Subtasks),
+
+		GenerationScript: in.GenerationScript,
+	}
+	if c := in.Checker; c != nil {
+		p.CheckerType = c.Type
+		p.CheckerAbsEpsilon = c.AbsEpsilon
+		p.CheckerRelEpsilon = c.RelEpsilon
+		if c.Type == domain.CHECKER_CUSTOM {
+			p.Programs = append(p.Programs, domain.ProblemProgram{
+				Role:     domain.PROGRAM_CHECKER,
+				Language: c.Language,
+				Code:     c.Code,
+			})
+		}
+	}
+	if in.Interactor != nil {
+		p.Programs = append(p.Programs, domain.ProblemProgram{
+			Role:     domain.PROGRAM_INTERACTOR,
+			Language: in.Interactor.Language,
+			Code:     in.Interactor.Code,
+		})
+	}
+	if in.Validator != nil {
+		p.Programs = append(p.Programs, domain.ProblemProgram{
+			Role:     domain.PROGRAM_VALIDATOR,
+			Language: in.Validator.Language,
+			Code:     in.Validator.Code,
+		})
+	}
+	for _, s := range in.Solutions {
+		p.Programs = append(p.Programs, ToSolution(s))
+	}
+	for _, g := range in.Generators {
+		p.Programs = append(p.Programs, ToGenerator(g))
 	}
-	for _, tc := range in.TestCases {
+	for i, tc := range in.TestCases {
+		order := i
+		if tc.OrderIndex != nil {
+			order = *tc.OrderIndex
+		}
 		p.TestCases = append(p.TestCases, domain.TestCases{
-			Input:    tc.Input,
-			Expected: tc.Expected,
+			Input:       tc.Input,
+			Expected:    tc.Expected,
+			IsSample:    tc.IsSample,
+			OrderIndex:  order,
+			Explanation: tc.Explanation,
+			Subtask:     tc.Subtask,
 		})
 	}
 
@@ -37,8 +86,21 @@ func ToProblemResponse(p domain.Problem) dto.ProblemResponseDTO {
 		Description: p.Description,
 		Tag:         p.Tag,
 		Difficulty:  p.Difficulty,
+		Type:        p.Type,
+		Signature:   p.Signature,
 		CreatedAt:   p.CreatedAt,
 		UpdatedAt:   p.UpdatedAt,
+
+		TimeLimitMs:   p.TimeLimitMs,
+		MemoryLimitMB: p.MemoryLimitMB,
+
+		CheckerType:       p.CheckerType,
+		CheckerAbsEpsilon: p.CheckerAbsEpsilon,
+		CheckerRelEpsilon: p.CheckerRelEpsilon,
+
+		TestCaseCount: p.TestCaseCount,
+
+		Status: p.Status,
 	}
 	for _, tc := range p.TestCases {
 		out.TestCases = append(out.TestCases, dto.TestCaseResponseDTO{
@@ -48,6 +110,11 @@ func ToProblemResponse(p domain.Problem) dto.ProblemResponseDTO {
 			ProblemID: tc.ProblemID.String(),
 			CreatedAt: tc.CreatedAt,
 			UpdatedAt: tc.UpdatedAt,
+
+			IsSample:    tc.IsSample,
+			OrderIndex:  tc.OrderIndex,
+			Explanation: tc.Explanation,
+			Subtask:     tc.Subtask,
 		})
 	}
 
@@ -61,5 +128,70 @@ func ToProblemResponse(p domain.Problem) dto.ProblemResponseDTO {
 			UpdatedAt: bp.UpdatedAt,
 		})
 	}
+
+	for _, l := range p.LanguageLimits {
+		out.LanguageLimits = append(out.LanguageLimits, dto.LanguageLimitDTO{
+			Language:      l.LanguageID,
+			TimeLimitMs:   l.TimeLimitMs,
+			MemoryLimitMB: l.MemoryLimitMB,
+		})
+	}
+
+	for _, s := range p.Subtasks {
+		out.Subtasks = append(out.Subtasks, dto.SubtaskDTO{
+			Index:        s.Index,
+			Name:         s.Name,
+			Points:       s.Points,
+			Aggregation:  s.Aggregation,
+			Dependencies: s.Dependencies,
+		})
+	}
+	return out
+}
+
+func ToLanguageLimits(in []dto.LanguageLimitDTO) []domain.ProblemLanguageLimit {
+	var out []domain.ProblemLanguageLimit
+	for _, l := range in {
+		out = append(out, domain.ProblemLanguageLimit{
+			LanguageID:    l.Language,
+			TimeLimitMs:   l.TimeLimitMs,
+			MemoryLimitMB: l.MemoryLimitMB,
+		})
+	}
+	return out
+}
+
+func ToSubtasks(in []dto.SubtaskDTO) []domain.Subtask {
+	var out []domain.Subtask
+	for _, s := range in {
+		out = append(out, domain.Subtask{
+			Index:        s.Index,
+			Name:         s.Name,
+			Points:       s.Points,
+			Aggregation:  s.Aggregation,
+			Dependencies: s.Dependencies,
+		})
+	}
 	return out
 }
+
+// ToSolution maps a reference solution to its program.
+func ToSolution(s dto.SolutionDTO) domain.ProblemProgram {
+	return domain.ProblemProgram{
+		Role:     domain.PROGRAM_SOLUTION,
+		Name:     s.Name,
+		Language: s.Language,
+		Code:     s.Code,
+		Expected: s.Expected,
+	}
+}
+
+// ToGenerator maps a test generator to its program.
+func ToGenerator(g dto.GeneratorDTO) domain.ProblemProgram {
+	return domain.ProblemProgram{
+		Role:     domain.PROGRAM_GENERATOR,
+		Name:     g.Name,
+		Language: g.Language,
+		Code:     g.Code,
+	}
+}
@@ -0,0 +1,193 @@
+// Package problempkg reads and writes problem packages: zip archives laid out
+// like Kattis/ICPC problem packages, with a "codearena" section in
+// problem.yaml for what that format has no place for. The layout is
+// described in PROBLEM_PACKAGE.md.
+package problempkg
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+var ErrInvalidPackage = errors.New("invalid problem package")
+
+// Limits on the archives Read accepts.
+const (
+	MaxFiles     = 10000
+	MaxTotalSize = 512 << 20 // uncompressed bytes
+)
+
+// Paths inside a package.
+const (
+	metadataFile    = "problem.yaml"
+	statementDir    = "problem_statement"
+	statementFile   = "problem_statement/problem.en.md"
+	sampleDir       = "data/sample"
+	secretDir       = "data/secret"
+	validatorsDir   = "output_validators"
+	inputDir        = "input_format_validators"
+	submissionsDir  = "submissions"
+	generatorsDir   = "generators"
+	boilerplatesDir = "codearena/boilerplates"
+)
+
+// Values of the validation key of problem.yaml.
+const (
+	validationDefault     = "default"
+	validationCustom      = "custom"
+	validationInteractive = "custom interactive"
+)
+
+// Verdicts are the submissions/ directories; a reference solution is
+// expected to get the verdict of the directory it is in.
+var Verdicts = []string{"accepted", "wrong_answer", "time_limit_exceeded", "run_time_error"}
+
+// verdictOutcomes maps each of Verdicts to its domain.SolutionOutcomes entry.
+var verdictOutcomes = map[string]string{
+	"accepted":            domain.STATUS_ACCEPTED,
+	"wrong_answer":        domain.STATUS_WRONG_ANSWER,
+	"time_limit_exceeded": domain.STATUS_TIME_LIMIT,
+	"run_time_error":      domain.STATUS_RUNTIME_ERROR,
+}
+
+// Metadata is the content of problem.yaml.
+type Metadata struct {
+	Name           string     `yaml:"name"`
+	Source         string     `yaml:"source,omitempty"`
+	Type           string     `yaml:"type,omitempty"` // pass-fail or scoring
+	Validation     string     `yaml:"validation,omitempty"`
+	ValidatorFlags string     `yaml:"validator_flags,omitempty"`
+	Limits         Limits     `yaml:"limits,omitempty"`
+	CodeArena      *Extension `yaml:"codearena,omitempty"`
+}
+
+type Limits struct {
+	TimeLimit float64 `yaml:"time_limit,omitempty"` // seconds
+	Memory    int     `yaml:"memory,omitempty"`     // MiB
+}
+
+// Extension holds the problem settings the Kattis format does not cover.
+type Extension struct {
+	Slug           string          `yaml:"slug,omitempty"`
+	Difficulty     string          `yaml:"difficulty,omitempty"`
+	Tag            string          `yaml:"tag,omitempty"`
+	Type           string          `yaml:"type,omitempty"` // standard, interactive or function
+	Signature      string          `yaml:"signature,omitempty"`
+	Checker        string          `yaml:"checker,omitempty"`
+	LanguageLimits []LanguageLimit `yaml:"language_limits,omitempty"`
+	Subtasks       []Subtask       `yaml:"subtasks,omitempty"`
+
+	GenerationScript string `yaml:"generation_script,omitempty"` // see ParseScript
+}
+
+type LanguageLimit struct {
+	Language      string `yaml:"language"`
+	TimeLimitMs   int    `yaml:"time_limit_ms,omitempty"`
+	MemoryLimitMB int    `yaml:"memory_limit_mb,omitempty"`
+}
+
+type Subtask struct {
+	Index        int    `yaml:"index"`
+	Name         string `yaml:"name,omitempty"`
+	Points       int    `yaml:"points"`
+	Aggregation  string `yaml:"aggregation,omitempty"`
+	Dependencies []int  `yaml:"dependencies,flow,omitempty"`
+}
+
+// Verdict is the directory of a solution name.
+func Verdict(name string) string {
+	verdict, _, _ := strings.Cut(name, "/")
+	return verdict
+}
+
+// VerdictDir is the submissions/ directory of solutions expected to get
+// outcome.
+func VerdictDir(outcome string) string {
+	for dir, o := range verdictOutcomes {
+		if o == outcome {
+			return dir
+		}
+	}
+	return ""
+}
+
+// languageFor finds the language of a source file by its extension.
+func languageFor(file string, languages []domain.Language) (string, bool) {
+	ext := path.Ext(file)
+	if ext == "" {
+		return "", false
+	}
+	for _, lang := range languages {
+		if path.Ext(lang.SourceFile) == ext {
+			return lang.ID, true
+		}
+	}
+	return "", false
+}
+
+// slugify turns a problem name into a slug.
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// naturalLess orders file names with numbers by value, so that 2.in comes
+// before 10.in.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := isDigit(a[0]), isDigit(b[0])
+		if da && db {
+			na, ra := splitNumber(a)
+			nb, rb := splitNumber(b)
+			na, nb = strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = ra, rb
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// naturalCompare is naturalLess as a comparison function for sorting.
+func naturalCompare(a, b string) int {
+	switch {
+	case naturalLess(a, b):
+		return -1
+	case naturalLess(b, a):
+		return 1
+	}
+	return 0
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func splitNumber(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
@@ -0,0 +1,212 @@
+package problempkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/judge"
+)
+
+func TestWriteRead(t *testing.T) {
+	problem := &domain.Problem{
+		MainHeading:       "Two Sum",
+		Slug:              "two-sum",
+		Description:       "Find two numbers.",
+		Tag:               "arrays",
+		Difficulty:        domain.EASY,
+		Type:              domain.PROBLEM_STANDARD,
+		TimeLimitMs:       1500,
+		MemoryLimitMB:     128,
+		CheckerType:       domain.CHECKER_FLOAT,
+		CheckerAbsEpsilon: 1e-6,
+		LanguageLimits:    []domain.ProblemLanguageLimit{{LanguageID: "py", TimeLimitMs: 4000}},
+		Subtasks:          []domain.Subtask{{Index: 1, Name: "small", Points: 40, Aggregation: domain.SUBTASK_ALL}, {Index: 2, Points: 60, Aggregation: domain.SUBTASK_MIN, Dependencies: []int{1}}},
+		TestCases: []domain.TestCases{
+			{Input: "1 2\n", Expected: "3\n", IsSample: true, Explanation: "1 + 2 = 3", Subtask: 1},
+			{Input: "2 2\n", Expected: "4\n", Subtask: 1},
+			{Input: "5 5\n", Expected: "10\n", Subtask: 2},
+		},
+		Boilerplates:     []domain.BoilerPlate{{Language: "cpp", Code: "int main() {}\n"}},
+		GenerationScript: "gen 10 > 04.in\n",
+	}
+	programs := []domain.ProblemProgram{
+		{Role: domain.PROGRAM_SOLUTION, Name: "accepted/main.py", Language: "py", Code: "print(sum(map(int, input().split())))\n", Expected: domain.STATUS_ACCEPTED},
+		{Role: domain.PROGRAM_SOLUTION, Name: "zero.go", Language: "go", Code: "package main\n", Expected: domain.STATUS_WRONG_ANSWER},
+		{Role: domain.PROGRAM_VALIDATOR, Language: "py", Code: "import sys\n"},
+		{Role: domain.PROGRAM_GENERATOR, Name: "gen", Language: "cpp", Code: "int main() {}\n"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, problem, programs, judge.DefaultLanguages()))
+	pkg, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()), judge.DefaultLanguages())
+	require.NoError(t, err)
+
+	p := pkg.Problem
+	assert.Equal(t, "Two Sum", p.MainHeading)
+	assert.Equal(t, "two-sum", p.Slug)
+	assert.Equal(t, "Find two numbers.", p.Description)
+	assert.Equal(t, "arrays", p.Tag)
+	assert.Equal(t, domain.EASY, p.Difficulty)
+	assert.Equal(t, 1500, p.TimeLimitMs)
+	assert.Equal(t, 128, p.MemoryLimitMB)
+	assert.Equal(t, domain.CHECKER_FLOAT, p.Checker.Type)
+	assert.Equal(t, 1e-6, p.Checker.AbsEpsilon)
+	assert.Zero(t, p.Checker.RelEpsilon)
+	assert.Equal(t, "py", p.LanguageLimits[0].Language)
+	assert.Equal(t, 4000, p.LanguageLimits[0].TimeLimitMs)
+	require.Len(t, p.Subtasks, 2)
+	assert.Equal(t, []int{1}, p.Subtasks[1].Dependencies)
+	assert.Equal(t, domain.SUBTASK_MIN, p.Subtasks[1].Aggregation)
+
+	require.Len(t, p.TestCases, 3)
+	for i, tc := range p.TestCases {
+		want := problem.TestCases[i]
+		assert.Equal(t, want.Input, tc.Input)
+		assert.Equal(t, want.Expected, tc.Expected)
+		assert.Equal(t, want.IsSample, tc.IsSample)
+		assert.Equal(t, want.Explanation, tc.Explanation)
+		assert.Equal(t, want.Subtask, tc.Subtask)
+		assert.Equal(t, i, *tc.OrderIndex)
+	}
+
+	require.Len(t, p.Boilerplates, 1)
+	assert.Equal(t, "cpp", p.Boilerplates[0].Language)
+	assert.Equal(t, []dto.SolutionDTO{
+		{Name: "accepted/main.py", Language: "py", Code: programs[0].Code, Expected: domain.STATUS_ACCEPTED},
+		{Name: "wrong_answer/zero.go", Language: "go", Code: programs[1].Code, Expected: domain.STATUS_WRONG_ANSWER},
+	}, p.Solutions)
+	require.NotNil(t, p.Validator)
+	assert.Equal(t, "py", p.Validator.Language)
+	assert.Equal(t, programs[2].Code, p.Validator.Code)
+	assert.Equal(t, []dto.GeneratorDTO{{Name: "gen", Language: "cpp", Code: programs[3].Code}}, p.Generators)
+	assert.Equal(t, problem.GenerationScript, p.GenerationScript)
+}
+
+// zipOf builds an archive from file names and contents.
+func zipOf(t *testing.T, files map[string]string) *bytes.Reader {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestRead_KattisPackage(t *testing.T) {
+	r := zipOf(t, map[string]string{
+		"hello/problem.yaml":                      "name: Hello World\nvalidation: custom\nauthor: Someone\n",
+		"hello/.timelimit":                        "3\n",
+		"hello/problem_statement/problem.en.tex":  "\\problemname{Hello}",
+		"hello/data/sample/1.in":                  "",
+		"hello/data/sample/1.ans":                 "Hello World!\n",
+		"hello/data/secret/2.in":                  "a",
+		"hello/data/secret/2.ans":                 "b",
+		"hello/data/secret/10.in":                 "c",
+		"hello/data/secret/10.ans":                "d",
+		"hello/output_validators/check/check.cpp": "int main() {}",
+		"hello/submissions/accepted/hello.py":     "print('Hello World!')",
+	})
+	pkg, err := Read(r, r.Size(), judge.DefaultLanguages())
+	require.NoError(t, err)
+
+	p := pkg.Problem
+	assert.Equal(t, "hello", p.Slug)
+	assert.Equal(t, "\\problemname{Hello}", p.Description)
+	assert.Equal(t, 3000, p.TimeLimitMs)
+	assert.Equal(t, domain.PROBLEM_STANDARD, p.Type)
+	assert.Equal(t, domain.CHECKER_CUSTOM, p.Checker.Type)
+	assert.Equal(t, "cpp", p.Checker.Language)
+	require.Len(t, p.TestCases, 3)
+	assert.True(t, p.TestCases[0].IsSample)
+	assert.Equal(t, "a", p.TestCases[1].Input)
+	assert.Equal(t, "c", p.TestCases[2].Input)
+	assert.Nil(t, p.Validator)
+	require.Len(t, p.Solutions, 1)
+	assert.Equal(t, "py", p.Solutions[0].Language)
+	assert.Equal(t, domain.STATUS_ACCEPTED, p.Solutions[0].Expected)
+}
+
+func TestRead_Invalid(t *testing.T) {
+	cases := map[string]map[string]string{
+		"no metadata":    {"data/secret/1.in": "", "data/secret/1.ans": ""},
+		"unpaired input": {"problem.yaml": "name: X\n", "data/secret/1.in": ""},
+		"outside data":   {"problem.yaml": "name: X\n", "data/1.in": "", "data/1.ans": ""},
+		"bad verdict":    {"problem.yaml": "name: X\n", "submissions/slow/a.py": ""},
+		"two validators": {"problem.yaml": "name: X\nvalidation: custom\n", "output_validators/a/a.cpp": "", "output_validators/a/b.cpp": ""},
+		"two inputs":     {"problem.yaml": "name: X\n", "input_format_validators/a/a.cpp": "", "input_format_validators/b/b.py": ""},
+	}
+	for name, files := range cases {
+		r := zipOf(t, files)
+		_, err := Read(r, r.Size(), judge.DefaultLanguages())
+		assert.ErrorIs(t, err, ErrInvalidPackage, name)
+	}
+}
+
+func TestReadTests(t *testing.T) {
+	r := zipOf(t, map[string]string{
+		"sample/1.in":      "s",
+		"sample/1.out":     "S",
+		"10.in":            "j",
+		"10.ans":           "J",
+		"2.in":             "b",
+		"2.out":            "B",
+		"subtask3/01.in":   "x",
+		"subtask3/01.out":  "X",
+		"__MACOSX/._2.in":  "junk",
+		"nested/.DS_Store": "junk",
+	})
+	tests, err := ReadTests(r, r.Size())
+	require.NoError(t, err)
+	require.Len(t, tests, 4)
+	assert.Equal(t, TestFile{Name: "2", Input: "b", Expected: "B"}, tests[0])
+	assert.Equal(t, TestFile{Name: "10", Input: "j", Expected: "J"}, tests[1])
+	assert.Equal(t, TestFile{Name: "sample/1", Input: "s", Expected: "S", IsSample: true}, tests[2])
+	assert.Equal(t, TestFile{Name: "subtask3/01", Input: "x", Expected: "X", Subtask: 3}, tests[3])
+
+	cases := map[string]map[string]string{
+		"missing output": {"1.in": "", "2.in": "", "2.out": ""},
+		"missing input":  {"1.out": ""},
+		"both outputs":   {"1.in": "", "1.out": "", "1.ans": ""},
+		"stray file":     {"1.in": "", "1.out": "", "notes.txt": ""},
+		"empty":          {},
+	}
+	for name, files := range cases {
+		r := zipOf(t, files)
+		_, err := ReadTests(r, r.Size())
+		assert.ErrorIs(t, err, ErrInvalidPackage, name)
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	cmds, err := ParseScript("# large tests\ngen 1000 42 > 10.in\n\ngen 5 >2.in\nrand-tree 7 > subtask2/01.in\nsmall > sample/01.in\n")
+	require.NoError(t, err)
+	require.Len(t, cmds, 4)
+	assert.Equal(t, Command{Line: 4, Generator: "gen", Args: []string{"5"}, Name: "2"}, cmds[0])
+	assert.Equal(t, "gen 1000 42", cmds[1].String())
+	assert.Equal(t, Command{Line: 6, Generator: "small", Args: []string{}, Name: "sample/01", IsSample: true}, cmds[2])
+	assert.Equal(t, 2, cmds[3].Subtask)
+
+	cases := map[string]string{
+		"no target":        "gen 1 2\n",
+		"no generator":     "> 1.in\n",
+		"not an input":     "gen > 1.out\n",
+		"two targets":      "gen > 1.in 2.in\n",
+		"outside":          "gen > ../1.in\n",
+		"duplicate target": "gen 1 > 1.in\ngen 2 > 1.in\n",
+		"bad name":         "gen/x > 1.in\n",
+		"empty":            "# nothing\n",
+	}
+	for name, script := range cases {
+		_, err := ParseScript(script)
+		assert.ErrorIs(t, err, ErrInvalidScript, name)
+	}
+}
@@ -0,0 +1,395 @@
+package problempkg
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/dto"
+	"gopkg.in/yaml.v3"
+)
+
+// Package is a problem read from a package, ready to be created.
+type Package struct {
+	Problem dto.CreateProblemDTO
+}
+
+var subtaskDirRe = regexp.MustCompile(`^subtask(\d+)$`)
+
+// Read parses the package in r. Programs are matched to the languages of
+// the registry by file extension.
+func Read(r io.ReaderAt, size int64, languages []domain.Language) (*Package, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+	files, root, err := readFiles(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ok := files[metadataFile]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidPackage, metadataFile)
+	}
+	var meta Metadata
+	if err := yaml.Unmarshal([]byte(raw), &meta); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPackage, metadataFile, err)
+	}
+	if strings.TrimSpace(meta.Name) == "" {
+		return nil, fmt.Errorf("%w: %s has no name", ErrInvalidPackage, metadataFile)
+	}
+	ext := meta.CodeArena
+	if ext == nil {
+		ext = &Extension{}
+	}
+
+	p := dto.CreateProblemDTO{
+		MainHeading:   meta.Name,
+		Slug:          ext.Slug,
+		Description:   statement(files),
+		Tag:           ext.Tag,
+		Difficulty:    ext.Difficulty,
+		Type:          ext.Type,
+		Signature:     ext.Signature,
+		TimeLimitMs:   int(math.Round(meta.Limits.TimeLimit * 1000)),
+		MemoryLimitMB: meta.Limits.Memory,
+	}
+	if p.Slug == "" {
+		p.Slug = root
+	}
+	if p.Slug == "" {
+		p.Slug = slugify(meta.Name)
+	}
+	if p.TimeLimitMs == 0 {
+		// Legacy packages keep the time limit in a file of its own
+		if s, ok := files[".timelimit"]; ok {
+			if seconds, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				p.TimeLimitMs = int(math.Round(seconds * 1000))
+			}
+		}
+	}
+	if p.Type == "" {
+		p.Type = domain.PROBLEM_STANDARD
+		if meta.Validation == validationInteractive {
+			p.Type = domain.PROBLEM_INTERACTIVE
+		}
+	}
+	for _, l := range ext.LanguageLimits {
+		p.LanguageLimits = append(p.LanguageLimits, dto.LanguageLimitDTO{
+			Language:      l.Language,
+			TimeLimitMs:   l.TimeLimitMs,
+			MemoryLimitMB: l.MemoryLimitMB,
+		})
+	}
+	for _, s := range ext.Subtasks {
+		p.Subtasks = append(p.Subtasks, dto.SubtaskDTO{
+			Index:        s.Index,
+			Name:         s.Name,
+			Points:       s.Points,
+			Aggregation:  s.Aggregation,
+			Dependencies: s.Dependencies,
+		})
+	}
+
+	if p.Checker, err = checker(meta, ext.Checker); err != nil {
+		return nil, err
+	}
+	if meta.Validation == validationCustom || meta.Validation == validationInteractive {
+		program, err := singleProgram(files, languages, "output validator", validatorsDir, "output_validator")
+		if err != nil {
+			return nil, err
+		}
+		if program == nil {
+			return nil, fmt.Errorf("%w: the output validator must be a single source file, found none", ErrInvalidPackage)
+		}
+		if meta.Validation == validationInteractive {
+			p.Interactor = program
+		} else {
+			p.Checker.Language = program.Language
+			p.Checker.Code = program.Code
+		}
+	}
+
+	if p.TestCases, err = tests(files); err != nil {
+		return nil, err
+	}
+	if p.Validator, err = singleProgram(files, languages, "input validator", inputDir, "input_validators"); err != nil {
+		return nil, err
+	}
+	p.Boilerplates = boilerplates(files)
+	if p.Solutions, err = solutions(files, languages); err != nil {
+		return nil, err
+	}
+	p.Generators = generators(files, languages)
+	p.GenerationScript = ext.GenerationScript
+	return &Package{Problem: p}, nil
+}
+
+// readFiles reads every file of the archive. Packages zipped together with
+// their directory are accepted too; root is then the directory name.
+func readFiles(zr *zip.Reader) (files map[string]string, root string, err error) {
+	files = make(map[string]string)
+	var total int64
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name := path.Clean(f.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, "", fmt.Errorf("%w: bad file name %q", ErrInvalidPackage, f.Name)
+		}
+		if strings.HasPrefix(name, "__MACOSX/") || path.Base(name) == ".DS_Store" {
+			continue
+		}
+		if len(files) == MaxFiles {
+			return nil, "", fmt.Errorf("%w: more than %d files", ErrInvalidPackage, MaxFiles)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %s: %v", ErrInvalidPackage, name, err)
+		}
+		// The sizes in the archive can lie, so count what is read
+		data, err := io.ReadAll(io.LimitReader(rc, MaxTotalSize-total+1))
+		rc.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %s: %v", ErrInvalidPackage, name, err)
+		}
+		total += int64(len(data))
+		if total > MaxTotalSize {
+			return nil, "", fmt.Errorf("%w: more than %d MB uncompressed", ErrInvalidPackage, MaxTotalSize>>20)
+		}
+		files[name] = string(data)
+	}
+
+	if _, ok := files[metadataFile]; ok {
+		return files, "", nil
+	}
+	for name := range files {
+		dir, _, _ := strings.Cut(name, "/")
+		if _, ok := files[dir+"/"+metadataFile]; !ok {
+			continue
+		}
+		stripped := make(map[string]string, len(files))
+		for name, data := range files {
+			if rest, ok := strings.CutPrefix(name, dir+"/"); ok {
+				stripped[rest] = data
+			}
+		}
+		return stripped, dir, nil
+	}
+	return files, "", nil
+}
+
+// statement returns the Markdown statement, or the LaTeX source when there
+// is none.
+func statement(files map[string]string) string {
+	if s, ok := files[statementFile]; ok {
+		return s
+	}
+	var names []string
+	for name := range files {
+		if path.Dir(name) == statementDir && strings.HasPrefix(path.Base(name), "problem.") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, ext := range []string{".md", ".tex"} {
+		for _, name := range names {
+			if path.Ext(name) == ext {
+				return files[name]
+			}
+		}
+	}
+	return ""
+}
+
+// checker maps the output validation of problem.yaml to a checker. The
+// checker type from the codearena section wins over the one implied by the
+// validator flags.
+func checker(meta Metadata, typ string) (*dto.CheckerDTO, error) {
+	c := &dto.CheckerDTO{Type: typ}
+	caseSensitive, spaceSensitive, float := false, false, false
+	flags := strings.Fields(meta.ValidatorFlags)
+	for i := 0; i < len(flags); i++ {
+		switch flags[i] {
+		case "case_sensitive":
+			caseSensitive = true
+		case "space_change_sensitive":
+			spaceSensitive = true
+		case "float_tolerance", "float_absolute_tolerance", "float_relative_tolerance":
+			if i+1 == len(flags) {
+				return nil, fmt.Errorf("%w: %s needs a value", ErrInvalidPackage, flags[i])
+			}
+			eps, err := strconv.ParseFloat(flags[i+1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPackage, flags[i], err)
+			}
+			if flags[i] != "float_relative_tolerance" {
+				c.AbsEpsilon = eps
+			}
+			if flags[i] != "float_absolute_tolerance" {
+				c.RelEpsilon = eps
+			}
+			float = true
+			i++
+		}
+	}
+	if c.Type != "" {
+		return c, nil
+	}
+	switch {
+	case meta.Validation == validationCustom:
+		c.Type = domain.CHECKER_CUSTOM
+	case float:
+		c.Type = domain.CHECKER_FLOAT
+	case spaceSensitive:
+		c.Type = domain.CHECKER_EXACT
+	case caseSensitive:
+		c.Type = domain.CHECKER_WHITESPACE
+	default:
+		c.Type = domain.CHECKER_CASE_INSENSITIVE
+	}
+	return c, nil
+}
+
+// singleProgram returns the single source file below one of dirs, or nil
+// when there is none. what names the program in errors.
+func singleProgram(files map[string]string, languages []domain.Language, what string, dirs ...string) (*dto.ProgramDTO, error) {
+	var names []string
+	for name := range files {
+		for _, dir := range dirs {
+			if strings.HasPrefix(name, dir+"/") {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if len(names) != 1 {
+		return nil, fmt.Errorf("%w: the %s must be a single source file, found %d files", ErrInvalidPackage, what, len(names))
+	}
+	lang, ok := languageFor(names[0], languages)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s: unknown language", ErrInvalidPackage, names[0])
+	}
+	return &dto.ProgramDTO{Language: lang, Code: files[names[0]]}, nil
+}
+
+// tests pairs the .in and .ans files below data/. Samples come first and
+// tests are ordered by file name; a subtaskN directory puts tests in
+// subtask N.
+func tests(files map[string]string) ([]dto.CreateTestCaseDTO, error) {
+	var inputs []string
+	for name := range files {
+		if !strings.HasPrefix(name, "data/") {
+			continue
+		}
+		base := strings.TrimSuffix(name, path.Ext(name))
+		switch path.Ext(name) {
+		case ".in":
+			if _, ok := files[base+".ans"]; !ok {
+				return nil, fmt.Errorf("%w: %s has no %s.ans", ErrInvalidPackage, name, path.Base(base))
+			}
+			inputs = append(inputs, name)
+		case ".ans":
+			if _, ok := files[base+".in"]; !ok {
+				return nil, fmt.Errorf("%w: %s has no %s.in", ErrInvalidPackage, name, path.Base(base))
+			}
+		}
+	}
+	slices.SortFunc(inputs, naturalCompare)
+
+	out := make([]dto.CreateTestCaseDTO, 0, len(inputs))
+	for i, name := range inputs {
+		sample := strings.HasPrefix(name, sampleDir+"/")
+		if !sample && !strings.HasPrefix(name, secretDir+"/") {
+			return nil, fmt.Errorf("%w: %s is not below %s or %s", ErrInvalidPackage, name, sampleDir, secretDir)
+		}
+		subtask := 0
+		for _, dir := range strings.Split(path.Dir(name), "/") {
+			if m := subtaskDirRe.FindStringSubmatch(dir); m != nil {
+				subtask, _ = strconv.Atoi(m[1])
+			}
+		}
+		base := strings.TrimSuffix(name, ".in")
+		order := i
+		out = append(out, dto.CreateTestCaseDTO{
+			Input:       files[name],
+			Expected:    files[base+".ans"],
+			IsSample:    sample,
+			OrderIndex:  &order,
+			Explanation: files[base+".desc"],
+			Subtask:     subtask,
+		})
+	}
+	return out, nil
+}
+
+// boilerplates reads codearena/boilerplates/<language id>.<extension>.
+func boilerplates(files map[string]string) []dto.CreateBoilerplateDTO {
+	var out []dto.CreateBoilerplateDTO
+	for name, code := range files {
+		if path.Dir(name) != boilerplatesDir {
+			continue
+		}
+		base := path.Base(name)
+		out = append(out, dto.CreateBoilerplateDTO{
+			Language: strings.TrimSuffix(base, path.Ext(base)),
+			Code:     code,
+		})
+	}
+	slices.SortFunc(out, func(a, b dto.CreateBoilerplateDTO) int { return strings.Compare(a.Language, b.Language) })
+	return out
+}
+
+// solutions reads submissions/<verdict>/<file>. Solutions are named by
+// their path below submissions/, e.g. "accepted/fast.cpp".
+func solutions(files map[string]string, languages []domain.Language) ([]dto.SolutionDTO, error) {
+	var out []dto.SolutionDTO
+	for name, code := range files {
+		rest, ok := strings.CutPrefix(name, submissionsDir+"/")
+		if !ok {
+			continue
+		}
+		if !slices.Contains(Verdicts, Verdict(rest)) || !strings.Contains(rest, "/") {
+			return nil, fmt.Errorf("%w: %s is not below one of %s/%s", ErrInvalidPackage, name, submissionsDir, strings.Join(Verdicts, ", "))
+		}
+		lang, ok := languageFor(name, languages)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: unknown language", ErrInvalidPackage, name)
+		}
+		out = append(out, dto.SolutionDTO{Name: rest, Language: lang, Code: code, Expected: verdictOutcomes[Verdict(rest)]})
+	}
+	slices.SortFunc(out, func(a, b dto.SolutionDTO) int { return strings.Compare(a.Name, b.Name) })
+	return out, nil
+}
+
+// generators reads generators/<name>.<extension>. Files in a language the
+// registry does not know are skipped, as Kattis packages keep all kinds of
+// generator files there.
+func generators(files map[string]string, languages []domain.Language) []dto.GeneratorDTO {
+	var out []dto.GeneratorDTO
+	for name, code := range files {
+		if path.Dir(name) != generatorsDir {
+			continue
+		}
+		lang, ok := languageFor(name, languages)
+		base := path.Base(name)
+		base = strings.TrimSuffix(base, path.Ext(base))
+		if !ok || !ValidGeneratorName(base) {
+			continue
+		}
+		out = append(out, dto.GeneratorDTO{Name: base, Language: lang, Code: code})
+	}
+	slices.SortFunc(out, func(a, b dto.GeneratorDTO) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
@@ -0,0 +1,101 @@
+package problempkg
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidScript = errors.New("invalid generation script")
+
+var generatorNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Command is one line of a generation script: run Generator with Args and
+// write its output to the input of test Name.
+type Command struct {
+	Line      int // 1-based line of the script
+	Generator string
+	Args      []string
+	Name      string // target without .in, e.g. "subtask2/17"
+	IsSample  bool
+	Subtask   int
+}
+
+// String is the command as it appears in the script, without its target.
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Generator}, c.Args...), " ")
+}
+
+// ValidGeneratorName reports whether name can be used for a generator in
+// scripts.
+func ValidGeneratorName(name string) bool {
+	return generatorNameRe.MatchString(name)
+}
+
+// ParseScript parses a generation script. Each line has the form
+//
+//	gen 1000 42 > 17.in
+//
+// where gen is a generator of the problem and the arguments are split on
+// white space. Targets are named like the files of a test archive, so
+// sample/01.in makes a sample and subtask2/05.in a test of subtask 2.
+// Blank lines and lines starting with # are skipped. Commands come back
+// ordered by target, with numbers compared by value.
+func ParseScript(script string) ([]Command, error) {
+	var cmds []Command
+	seen := make(map[string]int)
+	for i, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		n := i + 1
+		command, target, ok := strings.Cut(line, ">")
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d: missing > target", ErrInvalidScript, n)
+		}
+		fields := strings.Fields(command)
+		target = strings.TrimSpace(target)
+		switch {
+		case len(fields) == 0:
+			return nil, fmt.Errorf("%w: line %d: missing generator", ErrInvalidScript, n)
+		case !ValidGeneratorName(fields[0]):
+			return nil, fmt.Errorf("%w: line %d: invalid generator name %q", ErrInvalidScript, n, fields[0])
+		case !strings.HasSuffix(target, ".in") || strings.ContainsAny(target, " \t>"):
+			return nil, fmt.Errorf("%w: line %d: target must be a single .in file", ErrInvalidScript, n)
+		case path.IsAbs(target) || path.Clean(target) != target || strings.HasPrefix(target, "../"):
+			return nil, fmt.Errorf("%w: line %d: invalid target %s", ErrInvalidScript, n, target)
+		}
+		if prev, dup := seen[target]; dup {
+			return nil, fmt.Errorf("%w: line %d: %s is already written on line %d", ErrInvalidScript, n, target, prev)
+		}
+		seen[target] = n
+
+		cmd := Command{Line: n, Generator: fields[0], Args: fields[1:], Name: strings.TrimSuffix(target, ".in")}
+		cmd.IsSample, cmd.Subtask = placement(cmd.Name)
+		cmds = append(cmds, cmd)
+	}
+	if len(cmds) == 0 {
+		return nil, fmt.Errorf("%w: no commands", ErrInvalidScript)
+	}
+	slices.SortFunc(cmds, func(a, b Command) int { return naturalCompare(a.Name, b.Name) })
+	return cmds, nil
+}
+
+// placement tells from the directories of a test name whether the test is
+// a sample and which subtask it belongs to.
+func placement(name string) (sample bool, subtask int) {
+	for _, dir := range strings.Split(path.Dir(name), "/") {
+		if dir == "sample" {
+			sample = true
+		}
+		if m := subtaskDirRe.FindStringSubmatch(dir); m != nil {
+			subtask, _ = strconv.Atoi(m[1])
+		}
+	}
+	return sample, subtask
+}
@@ -0,0 +1,73 @@
+package problempkg
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// TestFile is a test read from a test archive.
+type TestFile struct {
+	Name     string // path of the input without its extension, e.g. "sample/01"
+	Input    string
+	Expected string
+	IsSample bool
+	Subtask  int
+}
+
+// ReadTests parses a test archive: pairs of NN.in and NN.out files, where
+// NN.ans may stand in for NN.out. Tests below a directory named sample are
+// samples and tests below a subtaskN directory belong to subtask N. Tests
+// come back in path order, with numbers compared by value.
+func ReadTests(r io.ReaderAt, size int64) ([]TestFile, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+	files, _, err := readFiles(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range files {
+		base := strings.TrimSuffix(name, path.Ext(name))
+		_, hasOut := files[base+".out"]
+		_, hasAns := files[base+".ans"]
+		switch path.Ext(name) {
+		case ".in":
+			if !hasOut && !hasAns {
+				return nil, fmt.Errorf("%w: %s has no %s.out", ErrInvalidPackage, name, path.Base(base))
+			}
+			if hasOut && hasAns {
+				return nil, fmt.Errorf("%w: %s has both %s.out and %s.ans", ErrInvalidPackage, name, path.Base(base), path.Base(base))
+			}
+			names = append(names, base)
+		case ".out", ".ans":
+			if _, ok := files[base+".in"]; !ok {
+				return nil, fmt.Errorf("%w: %s has no %s.in", ErrInvalidPackage, name, path.Base(base))
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s is not a .in, .out or .ans file", ErrInvalidPackage, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: no tests found", ErrInvalidPackage)
+	}
+	slices.SortFunc(names, naturalCompare)
+
+	tests := make([]TestFile, 0, len(names))
+	for _, name := range names {
+		expected, ok := files[name+".out"]
+		if !ok {
+			expected = files[name+".ans"]
+		}
+		tf := TestFile{Name: name, Input: files[name+".in"], Expected: expected}
+		tf.IsSample, tf.Subtask = placement(name)
+		tests = append(tests, tf)
+	}
+	return tests, nil
+}
@@ -0,0 +1,187 @@
+package problempkg
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/sudankdk/codearena/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// Write writes problem p, loaded with its tests, boilerplates and subtasks,
+// as a package. programs are the checker, interactor, input validator,
+// reference solutions and test generators of the problem.
+func Write(w io.Writer, p *domain.Problem, programs []domain.ProblemProgram, languages []domain.Language) error {
+	sourceFile := func(id string) (string, error) {
+		for _, lang := range languages {
+			if lang.ID == id {
+				return lang.SourceFile, nil
+			}
+		}
+		return "", fmt.Errorf("unknown language %q", id)
+	}
+
+	meta := Metadata{
+		Name:       p.MainHeading,
+		Source:     "CodeArena",
+		Type:       "pass-fail",
+		Validation: validationDefault,
+		Limits: Limits{
+			TimeLimit: float64(p.TimeLimitMs) / 1000,
+			Memory:    p.MemoryLimitMB,
+		},
+		CodeArena: &Extension{
+			Slug:       p.Slug,
+			Difficulty: p.Difficulty,
+			Tag:        p.Tag,
+			Type:       p.Type,
+			Signature:  p.Signature,
+			Checker:    p.CheckerType,
+
+			GenerationScript: p.GenerationScript,
+		},
+	}
+	if len(p.Subtasks) > 0 {
+		meta.Type = "scoring"
+	}
+	switch {
+	case p.Type == domain.PROBLEM_INTERACTIVE:
+		meta.Validation = validationInteractive
+	case p.CheckerType == domain.CHECKER_CUSTOM:
+		meta.Validation = validationCustom
+	}
+	meta.ValidatorFlags = validatorFlags(p)
+	for _, l := range p.LanguageLimits {
+		meta.CodeArena.LanguageLimits = append(meta.CodeArena.LanguageLimits, LanguageLimit{
+			Language:      l.LanguageID,
+			TimeLimitMs:   l.TimeLimitMs,
+			MemoryLimitMB: l.MemoryLimitMB,
+		})
+	}
+	for _, s := range p.Subtasks {
+		meta.CodeArena.Subtasks = append(meta.CodeArena.Subtasks, Subtask{
+			Index:        s.Index,
+			Name:         s.Name,
+			Points:       s.Points,
+			Aggregation:  s.Aggregation,
+			Dependencies: s.Dependencies,
+		})
+	}
+	rawMeta, err := yaml.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	add := func(name, content string) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, content)
+		return err
+	}
+
+	if err := add(metadataFile, string(rawMeta)); err != nil {
+		return err
+	}
+	if err := add(statementFile, p.Description); err != nil {
+		return err
+	}
+
+	// Tests are numbered across the problem so their order survives a
+	// round trip when the samples come first.
+	width := max(2, len(strconv.Itoa(len(p.TestCases))))
+	for i, tc := range p.TestCases {
+		dir := secretDir
+		if tc.IsSample {
+			dir = sampleDir
+		}
+		if tc.Subtask > 0 {
+			dir = path.Join(dir, fmt.Sprintf("subtask%d", tc.Subtask))
+		}
+		base := path.Join(dir, fmt.Sprintf("%0*d", width, i+1))
+		if err := add(base+".in", tc.Input); err != nil {
+			return err
+		}
+		if err := add(base+".ans", tc.Expected); err != nil {
+			return err
+		}
+		if tc.Explanation != "" {
+			if err := add(base+".desc", tc.Explanation); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, program := range programs {
+		var name string
+		switch program.Role {
+		case domain.PROGRAM_CHECKER, domain.PROGRAM_INTERACTOR:
+			file, err := sourceFile(program.Language)
+			if err != nil {
+				return err
+			}
+			name = path.Join(validatorsDir, program.Role, file)
+		case domain.PROGRAM_VALIDATOR:
+			file, err := sourceFile(program.Language)
+			if err != nil {
+				return err
+			}
+			name = path.Join(inputDir, program.Role, file)
+		case domain.PROGRAM_SOLUTION:
+			// Imported solutions are already named after their directory
+			dir := VerdictDir(program.Expected)
+			if dir == "" {
+				return fmt.Errorf("solution %s: unknown expected verdict %q", program.Name, program.Expected)
+			}
+			name = path.Join(submissionsDir, dir, strings.TrimPrefix(program.Name, dir+"/"))
+		case domain.PROGRAM_GENERATOR:
+			file, err := sourceFile(program.Language)
+			if err != nil {
+				return err
+			}
+			name = path.Join(generatorsDir, program.Name+path.Ext(file))
+		default:
+			continue
+		}
+		if err := add(name, program.Code); err != nil {
+			return err
+		}
+	}
+
+	for _, bp := range p.Boilerplates {
+		file, err := sourceFile(bp.Language)
+		if err != nil {
+			return err
+		}
+		if err := add(path.Join(boilerplatesDir, bp.Language+path.Ext(file)), bp.Code); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// validatorFlags describes the checker of p with the flags of the Kattis
+// default output validator, which is case insensitive unless told otherwise.
+func validatorFlags(p *domain.Problem) string {
+	var flags []string
+	switch p.CheckerType {
+	case domain.CHECKER_EXACT:
+		flags = append(flags, "case_sensitive", "space_change_sensitive")
+	case domain.CHECKER_LINES, domain.CHECKER_WHITESPACE, domain.CHECKER_UNORDERED_LINES:
+		flags = append(flags, "case_sensitive")
+	case domain.CHECKER_FLOAT:
+		if p.CheckerAbsEpsilon > 0 {
+			flags = append(flags, "float_absolute_tolerance", strconv.FormatFloat(p.CheckerAbsEpsilon, 'g', -1, 64))
+		}
+		if p.CheckerRelEpsilon > 0 {
+			flags = append(flags, "float_relative_tolerance", strconv.FormatFloat(p.CheckerRelEpsilon, 'g', -1, 64))
+		}
+	}
+	return strings.Join(flags, " ")
+}
@@ -8,15 +8,22 @@ import (
 	"github.com/sudankdk/codearena/internal/domain"
 	"github.com/sudankdk/codearena/internal/dto"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrRatingChanged is returned when finalizing or rolling back a contest
+// would overwrite a user's rating that something else changed meanwhile,
+// such as another contest finalized since.
+var ErrRatingChanged = errors.New("user rating has changed meanwhile")
+
 type ContestRepo interface {
 	Create(contest *domain.Contest) error
 	GetByID(id uuid.UUID) (*domain.Contest, error)
 	Update(contest *domain.Contest) error
 	Delete(id uuid.UUID) error
 	List(query dto.ListQuery) ([]*domain.Contest, error)
-	AddProblem(contestID, problemID uuid.UUID, orderIndex int, maxPoints int, partialCredit bool, timeMultiplier float64) error
+	AddProblem(problem *domain.ContestProblem) error
+	UpdateProblemScoring(problem *domain.ContestProblem) error
 	RemoveProblem(contestID, problemID uuid.UUID) error
 	GetProblems(contestID uuid.UUID) ([]*domain.ContestProblem, error)
 	RegisterParticipant(contestID, userID uuid.UUID) error
@@ -24,11 +31,19 @@ type ContestRepo interface {
 	IsUserRegistered(contestID, userID uuid.UUID) (bool, error)
 	GetParticipants(contestID uuid.UUID) ([]*domain.ContestParticipant, error)
 	UpdateParticipantScore(contestID, userID uuid.UUID, points int, problemsSolved int, penaltyTime int) error
-	UpdateParticipantActivity(contestID, userID uuid.UUID, startedAt, lastSubmissionAt *time.Time, problemsAttempted int) error
+	RescoreParticipant(contestID, userID uuid.UUID, score func(submissions []domain.Submission) (*domain.ContestTotals, error)) error
+	GetRatingHistory(userID uuid.UUID) ([]*domain.ContestParticipant, error)
 	GetLeaderboard(contestID uuid.UUID) ([]*domain.ContestLeaderboardEntry, error)
 	UpdateLeaderboardEntry(contestID, userID uuid.UUID, score int, rating float64, rank int) error
 	UpdateGlobalLeaderboardEntry(userID uuid.UUID, rating float64, solvedCount int) error
 	GetGlobalLeaderboard(limit int) ([]*domain.GlobalLeaderboardEntry, error)
+	AdvanceStatuses(now time.Time) (started, ended int64, err error)
+	ListByStatus(status string) ([]*domain.Contest, error)
+	ClaimFinalize(contestID uuid.UUID, now time.Time, retryStartedBefore *time.Time) (bool, error)
+	FailFinalize(contestID uuid.UUID, finalizeErr error) error
+	SaveFinalization(finalization *domain.ContestFinalization, now time.Time) error
+	RollbackFinalization(contestID, by uuid.UUID, now time.Time) error
+	MarkFinalizedContests() (int64, error)
 }
 
 type contestRepoImpl struct {
@@ -36,18 +51,23 @@ type contestRepoImpl struct {
 }
 
 // AddProblem implements [ContestRepo].
-func (c *contestRepoImpl) AddProblem(contestID uuid.UUID, problemID uuid.UUID, orderIndex int, maxPoints int, partialCredit bool, timeMultiplier float64) error {
-	p := &domain.ContestProblem{
-		ContestID:      contestID,
-		ProblemID:      problemID,
-		OrderIndex:     orderIndex,
-		MaxPoints:      maxPoints,
-		PartialCredit:  partialCredit,
-		TimeMultiplier: timeMultiplier,
+func (c *contestRepoImpl) AddProblem(problem *domain.ContestProblem) error {
+	if err := c.db.Create(problem).Error; err != nil {
+		return err
 	}
+	return nil
+}
 
-	if err := c.db.Create(p).Error; err != nil {
-		return err
+// UpdateProblemScoring writes the scoring fields of a contest problem.
+func (c *contestRepoImpl) UpdateProblemScoring(problem *domain.ContestProblem) error {
+	res := c.db.Model(problem).
+		Select("max_points", "partial_credit", "time_multiplier", "time_penalty_minutes").
+		Updates(problem)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("contest problem not found")
 	}
 	return nil
 }
@@ -76,12 +96,11 @@ func (c *contestRepoImpl) Delete(id uuid.UUID) error {
 
 // GetByID implements [ContestRepo].
 func (c *contestRepoImpl) GetByID(id uuid.UUID) (*domain.Contest, error) {
-	db := c.db.Preload("Problems").Preload("Participants").Preload("Leaderboard").First(&domain.Contest{}, "id = ?", id)
-	if db.Error != nil {
-		return nil, db.Error
-	}
 	var contest domain.Contest
-	if err := db.Scan(&contest).Error; err != nil {
+	err := c.db.Preload("Problems", func(db *gorm.DB) *gorm.DB {
+		return db.Order("order_index ASC")
+	}).Preload("Participants").Preload("Leaderboard").First(&contest, "id = ?", id).Error
+	if err != nil {
 		return nil, err
 	}
 	return &contest, nil
@@ -200,15 +219,11 @@ func (c *contestRepoImpl) UnregisterParticipant(contestID uuid.UUID, userID uuid
 // Update implements [ContestRepo].
 func (c *contestRepoImpl) Update(contest *domain.Contest) error {
 	// Only allow updating certain fields (e.g. name, description, start/end time)
-	updates := map[string]interface{}{
-		"name":        contest.Name,
-		"description": contest.Description,
-		"start_time":  contest.StartTime,
-		"end_time":    contest.EndTime,
-		"duration":    contest.Duration,
-		"is_active":   contest.IsActive,
-	}
-	if err := c.db.Model(&domain.Contest{}).Where("id = ?", contest.ID).Updates(updates).Error; err != nil {
+	err := c.db.Model(contest).
+		Select("name", "description", "start_time", "end_time", "duration", "is_active", "status",
+			"is_rated", "scoring_mode", "scoring").
+		Updates(contest).Error
+	if err != nil {
 		return err
 	}
 	return nil
@@ -250,6 +265,15 @@ func (c *contestRepoImpl) UpdateLeaderboardEntry(contestID uuid.UUID, userID uui
 
 // UpdateGlobalLeaderboardEntry implements [ContestRepo].
 func (c *contestRepoImpl) UpdateGlobalLeaderboardEntry(userID uuid.UUID, rating float64, solvedCount int) error {
+	if err := c.upsertGlobalEntry(userID, rating, solvedCount); err != nil {
+		return err
+	}
+	return c.recalculateGlobalRanks()
+}
+
+// upsertGlobalEntry writes a user's global leaderboard entry without
+// updating the ranks.
+func (c *contestRepoImpl) upsertGlobalEntry(userID uuid.UUID, rating float64, solvedCount int) error {
 	// Get user for username
 	var user domain.User
 	if err := c.db.First(&user, "id = ?", userID).Error; err != nil {
@@ -285,9 +309,7 @@ func (c *contestRepoImpl) UpdateGlobalLeaderboardEntry(userID uuid.UUID, rating
 			return err
 		}
 	}
-
-	// Recalculate global ranks
-	return c.recalculateGlobalRanks()
+	return nil
 }
 
 func (c *contestRepoImpl) GetGlobalLeaderboard(limit int) ([]*domain.GlobalLeaderboardEntry, error) {
@@ -302,19 +324,17 @@ func (c *contestRepoImpl) GetGlobalLeaderboard(limit int) ([]*domain.GlobalLeade
 	return entries, nil
 }
 
+// recalculateGlobalRanks ranks the global leaderboard by rating in a single
+// statement, touching only the entries whose rank changes.
 func (c *contestRepoImpl) recalculateGlobalRanks() error {
-	var entries []domain.GlobalLeaderboardEntry
-	if err := c.db.Order("rating DESC").Find(&entries).Error; err != nil {
-		return err
-	}
-
-	for i, entry := range entries {
-		rank := i + 1
-		if err := c.db.Model(&entry).Where("id = ?", entry.ID).Update("rank", rank).Error; err != nil {
-			return err
-		}
-	}
-	return nil
+	return c.db.Exec(`
+		UPDATE global_leaderboard_entries g
+		SET rank = r.rank
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY rating DESC) AS rank
+			FROM global_leaderboard_entries
+		) r
+		WHERE g.id = r.id AND g.rank <> r.rank`).Error
 }
 
 // UpdateParticipantScore implements [ContestRepo].
@@ -336,39 +356,326 @@ func (c *contestRepoImpl) UpdateParticipantScore(contestID uuid.UUID, userID uui
 	return nil
 }
 
-// UpdateParticipantActivity updates participant timestamps and attempt count
-func (c *contestRepoImpl) UpdateParticipantActivity(contestID uuid.UUID, userID uuid.UUID, startedAt, lastSubmissionAt *time.Time, problemsAttempted int) error {
-	var participant domain.ContestParticipant
-	if err := c.db.Where("contest_id = ? AND user_id = ?", contestID, userID).First(&participant).Error; err != nil {
-		return err
-	}
+// RescoreParticipant implements [ContestRepo]. It hands the participant's
+// submissions to score and saves the totals and submission points it
+// returns, along with the times of their first and last submission. The
+// participant's row stays locked meanwhile, so rescores of one participant
+// run one after another and none of them overwrites totals with ones
+// computed from submissions that have changed since.
+func (c *contestRepoImpl) RescoreParticipant(contestID, userID uuid.UUID, score func(submissions []domain.Submission) (*domain.ContestTotals, error)) error {
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		var participant domain.ContestParticipant
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("contest_id = ? AND user_id = ?", contestID, userID).
+			First(&participant).Error
+		if err != nil {
+			return err
+		}
 
-	updates := map[string]interface{}{}
+		submissions, err := (&submissionRepo{db: tx}).ListContestSubmissions(contestID, userID)
+		if err != nil {
+			return err
+		}
+		totals, err := score(submissions)
+		if err != nil {
+			return err
+		}
 
-	// Set started_at only if it's null (first time)
-	if startedAt != nil && participant.StartedAt == nil {
-		updates["started_at"] = startedAt
-	}
+		for _, sub := range submissions {
+			points, ok := totals.SubmissionPoints[sub.ID]
+			if !ok || points == sub.PointsEarned {
+				continue
+			}
+			if err := tx.Model(&domain.Submission{}).Where("id = ?", sub.ID).
+				Update("points_earned", points).Error; err != nil {
+				return err
+			}
+		}
+		updates := map[string]interface{}{
+			"total_points":       totals.Points,
+			"problems_solved":    totals.ProblemsSolved,
+			"problems_attempted": totals.ProblemsAttempted,
+			"penalty_time":       totals.PenaltyTime,
+		}
+		// Submission times, not judging times, which a queue, retries or
+		// rejudges can push past the contest's end
+		if len(submissions) > 0 {
+			updates["started_at"] = submissions[0].CreatedAt
+			updates["last_submission_at"] = submissions[len(submissions)-1].CreatedAt
+		}
+		return tx.Model(&participant).Updates(updates).Error
+	})
+}
 
-	// Always update last submission time
-	if lastSubmissionAt != nil {
-		updates["last_submission_at"] = lastSubmissionAt
+// GetRatingHistory implements [ContestRepo]. It returns the user's results
+// in finalized rated contests, oldest first, with each contest preloaded.
+func (c *contestRepoImpl) GetRatingHistory(userID uuid.UUID) ([]*domain.ContestParticipant, error) {
+	var participants []*domain.ContestParticipant
+	if err := c.db.Joins("JOIN contests ON contests.id = contest_participants.contest_id").
+		Where("contest_participants.user_id = ? AND contests.is_rated AND contests.status = ?", userID, domain.CONTEST_FINALIZED).
+		Order("contests.end_time ASC").
+		Preload("Contest").
+		Find(&participants).Error; err != nil {
+		return nil, err
 	}
+	return participants, nil
+}
 
-	// Increment problems attempted
-	if problemsAttempted > 0 {
-		updates["problems_attempted"] = participant.ProblemsAttempted + problemsAttempted
+// AdvanceStatuses starts scheduled contests whose start time has passed and
+// ends running ones whose end time has passed. It only moves contests
+// forward, so running it from several servers at once is safe.
+func (c *contestRepoImpl) AdvanceStatuses(now time.Time) (int64, int64, error) {
+	started := c.db.Model(&domain.Contest{}).
+		Where("status = ? AND start_time <= ? AND end_time > ?", domain.CONTEST_SCHEDULED, now, now).
+		Updates(map[string]interface{}{"status": domain.CONTEST_RUNNING, "is_active": true})
+	if started.Error != nil {
+		return 0, 0, started.Error
+	}
+	ended := c.db.Model(&domain.Contest{}).
+		Where("status IN ? AND end_time <= ?", []string{domain.CONTEST_SCHEDULED, domain.CONTEST_RUNNING}, now).
+		Updates(map[string]interface{}{"status": domain.CONTEST_ENDED, "is_active": false})
+	if ended.Error != nil {
+		return started.RowsAffected, 0, ended.Error
+	}
+	return started.RowsAffected, ended.RowsAffected, nil
+}
+
+// ListByStatus returns the contests with a status, oldest end time first.
+func (c *contestRepoImpl) ListByStatus(status string) ([]*domain.Contest, error) {
+	var contests []*domain.Contest
+	if err := c.db.Where("status = ?", status).Order("end_time ASC").Find(&contests).Error; err != nil {
+		return nil, err
 	}
+	return contests, nil
+}
+
+// ClaimFinalize marks a contest that has ended as finalizing and reports
+// whether this call did so; only one caller can claim a contest. With
+// retryStartedBefore set, a contest that is already finalizing is claimed
+// again if finalizing it failed or started before that time.
+func (c *contestRepoImpl) ClaimFinalize(contestID uuid.UUID, now time.Time, retryStartedBefore *time.Time) (bool, error) {
+	claimable := c.db.Where("status IN ? AND end_time <= ?",
+		[]string{domain.CONTEST_SCHEDULED, domain.CONTEST_RUNNING, domain.CONTEST_ENDED}, now)
+	if retryStartedBefore != nil {
+		claimable = claimable.Or("status = ? AND (finalize_error <> '' OR finalize_started_at < ?)",
+			domain.CONTEST_FINALIZING, *retryStartedBefore)
+	}
+	res := c.db.Model(&domain.Contest{}).
+		Where("id = ?", contestID).
+		Where(claimable).
+		Updates(map[string]interface{}{
+			"status":              domain.CONTEST_FINALIZING,
+			"is_active":           false,
+			"finalize_started_at": now,
+			"finalize_error":      "",
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// FailFinalize records why finalizing a claimed contest failed. The contest
+// stays finalizing.
+func (c *contestRepoImpl) FailFinalize(contestID uuid.UUID, finalizeErr error) error {
+	return c.db.Model(&domain.Contest{}).
+		Where("id = ? AND status = ?", contestID, domain.CONTEST_FINALIZING).
+		Update("finalize_error", finalizeErr.Error()).Error
+}
 
-	if len(updates) > 0 {
-		if err := c.db.Model(&participant).Where("id = ?", participant.ID).Updates(updates).Error; err != nil {
+// SaveFinalization implements [ContestRepo]. In one transaction it records
+// every participant's result and new rating, updates the contest and global
+// leaderboards, saves finalization with each user's global leaderboard
+// entry as it was, and marks the contest finalized. The contest must be
+// finalizing. Nothing is changed if any of it fails.
+func (c *contestRepoImpl) SaveFinalization(finalization *domain.ContestFinalization, now time.Time) error {
+	contestID := finalization.ContestID
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockContest(tx, contestID, domain.CONTEST_FINALIZING); err != nil {
 			return err
 		}
+		txRepo := &contestRepoImpl{db: tx}
+
+		for i := range finalization.Results {
+			result := &finalization.Results[i]
+
+			var entry domain.GlobalLeaderboardEntry
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", result.UserID).First(&entry).Error
+			switch {
+			case err == nil:
+				result.PrevGlobalEntry = &domain.GlobalEntryState{
+					Rating:               entry.Rating,
+					Rank:                 entry.Rank,
+					SolvedCount:          entry.SolvedCount,
+					ContestsParticipated: entry.ContestsParticipated,
+				}
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				return err
+			}
+
+			res := tx.Model(&domain.ContestParticipant{}).
+				Where("contest_id = ? AND user_id = ?", contestID, result.UserID).
+				Updates(map[string]interface{}{
+					"rank":          result.Rank,
+					"old_rating":    result.OldRating,
+					"new_rating":    result.NewRating,
+					"rating_change": result.RatingChange,
+				})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errors.New("contest participant not found")
+			}
+			if err := setRating(tx, result.UserID, result.OldRating, result.NewRating); err != nil {
+				return err
+			}
+			if err := txRepo.UpdateLeaderboardEntry(contestID, result.UserID, result.Score, result.NewRating, result.Rank); err != nil {
+				return err
+			}
+			if err := txRepo.upsertGlobalEntry(result.UserID, result.NewRating, result.SolvedCount); err != nil {
+				return err
+			}
+		}
+		if err := txRepo.recalculateGlobalRanks(); err != nil {
+			return err
+		}
+
+		finalization.FinalizedAt = now
+		if err := tx.Create(finalization).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.Contest{}).Where("id = ?", contestID).Updates(map[string]interface{}{
+			"status":         domain.CONTEST_FINALIZED,
+			"finalized_at":   now,
+			"finalize_error": "",
+		}).Error
+	})
+}
+
+// RollbackFinalization implements [ContestRepo]. In one transaction it
+// restores every participant's rating and global leaderboard rating from
+// the contest's finalization, clears their results and the contest
+// leaderboard, marks the finalization rolled back and moves the contest
+// back to ended. The contest must be finalized.
+func (c *contestRepoImpl) RollbackFinalization(contestID, by uuid.UUID, now time.Time) error {
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockContest(tx, contestID, domain.CONTEST_FINALIZED); err != nil {
+			return err
+		}
+
+		var finalization domain.ContestFinalization
+		if err := tx.Where("contest_id = ? AND rolled_back_at IS NULL", contestID).First(&finalization).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("contest finalization not found")
+			}
+			return err
+		}
+
+		for _, result := range finalization.Results {
+			if err := setRating(tx, result.UserID, result.NewRating, result.OldRating); err != nil {
+				return err
+			}
+
+			// The solved count may have grown since with practice solves, so
+			// it is left alone; the ranks are recalculated below
+			entries := tx.Model(&domain.GlobalLeaderboardEntry{}).Where("user_id = ?", result.UserID)
+			if prev := result.PrevGlobalEntry; prev != nil {
+				err := entries.Updates(map[string]interface{}{
+					"rating":                prev.Rating,
+					"contests_participated": prev.ContestsParticipated,
+					"updated_at":            now,
+				}).Error
+				if err != nil {
+					return err
+				}
+			} else if err := entries.Delete(&domain.GlobalLeaderboardEntry{}).Error; err != nil {
+				return err
+			}
+
+			err := tx.Model(&domain.ContestParticipant{}).
+				Where("contest_id = ? AND user_id = ?", contestID, result.UserID).
+				Updates(map[string]interface{}{
+					"rank":          0,
+					"old_rating":    result.OldRating,
+					"new_rating":    result.OldRating,
+					"rating_change": 0,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("contest_id = ?", contestID).Delete(&domain.ContestLeaderboardEntry{}).Error; err != nil {
+			return err
+		}
+		if err := (&contestRepoImpl{db: tx}).recalculateGlobalRanks(); err != nil {
+			return err
+		}
+
+		err := tx.Model(&finalization).Updates(map[string]interface{}{
+			"rolled_back_at": now,
+			"rolled_back_by": by,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&domain.Contest{}).Where("id = ?", contestID).Updates(map[string]interface{}{
+			"status":              domain.CONTEST_ENDED,
+			"finalize_started_at": nil,
+			"finalized_at":        nil,
+			"rolled_back_at":      now,
+		}).Error
+	})
+}
+
+// lockContest locks a contest's row for the rest of tx and checks
+// that it has the given status.
+func lockContest(tx *gorm.DB, contestID uuid.UUID, status string) error {
+	var contest domain.Contest
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "status").
+		First(&contest, "id = ?", contestID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("contest not found")
+		}
+		return err
+	}
+	if contest.Status != status {
+		return errors.New("contest is " + contest.Status + ", not " + status)
 	}
+	return nil
+}
 
+// setRating moves a user's rating from one value to another. It fails with
+// ErrRatingChanged if the rating is no longer from.
+func setRating(tx *gorm.DB, userID uuid.UUID, from, to float64) error {
+	if from == to {
+		return nil
+	}
+	res := tx.Model(&domain.User{}).Where("id = ? AND rating = ?", userID, from).Update("rating", to)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRatingChanged
+	}
 	return nil
 }
 
+// MarkFinalizedContests marks contests whose rankings were finalized before
+// contests had a status, so that they are not finalized again.
+func (c *contestRepoImpl) MarkFinalizedContests() (int64, error) {
+	res := c.db.Model(&domain.Contest{}).
+		Where("EXISTS (SELECT 1 FROM contest_leaderboard_entries e WHERE e.contest_id = contests.id)").
+		Updates(map[string]interface{}{
+			"status":       domain.CONTEST_FINALIZED,
+			"is_active":    false,
+			"finalized_at": gorm.Expr("end_time"),
+		})
+	return res.RowsAffected, res.Error
+}
+
 func NewContestRepo(db *gorm.DB) ContestRepo {
 	return &contestRepoImpl{db: db}
 }
@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+	"gorm.io/gorm"
+)
+
+func TestRescoreParticipant(t *testing.T) {
+	db := testDB(t)
+	contest, user, problem := seedParticipant(t, db)
+	sub := &domain.Submission{UserID: user.ID, ProblemID: problem.ID, ContestID: &contest.ID,
+		Language: "py", Code: "x", Status: domain.STATUS_ACCEPTED}
+	require.NoError(t, db.Create(sub).Error)
+	contests := NewContestRepo(db)
+
+	// The first rescore holds the participant until it is released; the
+	// second must not read the submissions before then.
+	release := make(chan struct{})
+	firstStarted := make(chan struct{})
+	first := make(chan error, 1)
+	go func() {
+		first <- contests.RescoreParticipant(contest.ID, user.ID, func(submissions []domain.Submission) (*domain.ContestTotals, error) {
+			close(firstStarted)
+			<-release
+			return &domain.ContestTotals{Points: 50, SubmissionPoints: map[uuid.UUID]int{sub.ID: 50}}, nil
+		})
+	}()
+	<-firstStarted
+
+	secondRead := make(chan int, 1)
+	second := make(chan error, 1)
+	go func() {
+		second <- contests.RescoreParticipant(contest.ID, user.ID, func(submissions []domain.Submission) (*domain.ContestTotals, error) {
+			secondRead <- submissions[0].PointsEarned
+			return &domain.ContestTotals{Points: 100, ProblemsSolved: 1, ProblemsAttempted: 1,
+				SubmissionPoints: map[uuid.UUID]int{sub.ID: 100}}, nil
+		})
+	}()
+
+	select {
+	case <-secondRead:
+		t.Fatal("second rescore read the submissions while the participant was locked")
+	case <-time.After(200 * time.Millisecond):
+	}
+	close(release)
+	require.NoError(t, <-first)
+	assert.Equal(t, 50, <-secondRead, "the second rescore sees what the first one saved")
+	require.NoError(t, <-second)
+
+	var participant domain.ContestParticipant
+	require.NoError(t, db.Where("contest_id = ? AND user_id = ?", contest.ID, user.ID).First(&participant).Error)
+	assert.Equal(t, 100, participant.TotalPoints)
+	assert.Equal(t, 1, participant.ProblemsSolved)
+	// The times are when the user submitted, not when it was scored
+	require.NotNil(t, participant.LastSubmissionAt)
+	assert.WithinDuration(t, sub.CreatedAt, *participant.LastSubmissionAt, time.Millisecond)
+	assert.WithinDuration(t, sub.CreatedAt, *participant.StartedAt, time.Millisecond)
+	var saved domain.Submission
+	require.NoError(t, db.First(&saved, "id = ?", sub.ID).Error)
+	assert.Equal(t, 100, saved.PointsEarned)
+
+	err := contests.RescoreParticipant(contest.ID, problem.ID, func([]domain.Submission) (*domain.ContestTotals, error) {
+		t.Fatal("scored a user who is not a participant")
+		return nil, nil
+	})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestSaveFinalization_GlobalRanks(t *testing.T) {
+	db := testDB(t)
+	contest, alice, _ := seedParticipant(t, db)
+	require.NoError(t, db.Model(contest).Update("status", domain.CONTEST_FINALIZING).Error)
+	bob := &domain.User{Username: "bob", Email: "bob@example.com", Password: "x"}
+	carol := &domain.User{Username: "carol", Email: "carol@example.com", Password: "x"}
+	require.NoError(t, db.Create(bob).Error)
+	require.NoError(t, db.Create(carol).Error)
+	require.NoError(t, db.Create(&domain.ContestParticipant{ContestID: contest.ID, UserID: bob.ID}).Error)
+	require.NoError(t, db.Create(&domain.GlobalLeaderboardEntry{UserID: carol.ID, Username: carol.Username, Rating: 1100, Rank: 1}).Error)
+
+	err := NewContestRepo(db).SaveFinalization(&domain.ContestFinalization{
+		ContestID: contest.ID,
+		Results: []domain.FinalizedResult{
+			{UserID: alice.ID, Rank: 1, Score: 100, OldRating: 1000, NewRating: 1200},
+			{UserID: bob.ID, Rank: 2, Score: 50, OldRating: 1000, NewRating: 950},
+		},
+	}, time.Now())
+	require.NoError(t, err)
+
+	var entries []domain.GlobalLeaderboardEntry
+	require.NoError(t, db.Order("rank ASC").Find(&entries).Error)
+	var ranked []uuid.UUID
+	for _, entry := range entries {
+		ranked = append(ranked, entry.UserID)
+	}
+	assert.Equal(t, []uuid.UUID{alice.ID, carol.ID, bob.ID}, ranked)
+	assert.Equal(t, []int{1, 2, 3}, []int{entries[0].Rank, entries[1].Rank, entries[2].Rank})
+}
+
+func TestRollbackFinalization_KeepsSolvedCount(t *testing.T) {
+	db := testDB(t)
+	contest, alice, _ := seedParticipant(t, db)
+	require.NoError(t, db.Model(contest).Update("status", domain.CONTEST_FINALIZING).Error)
+	require.NoError(t, db.Create(&domain.GlobalLeaderboardEntry{UserID: alice.ID, Username: alice.Username,
+		Rating: 1000, Rank: 1, SolvedCount: 3, ContestsParticipated: 2}).Error)
+	contests := NewContestRepo(db)
+
+	require.NoError(t, contests.SaveFinalization(&domain.ContestFinalization{
+		ContestID: contest.ID,
+		Results:   []domain.FinalizedResult{{UserID: alice.ID, Rank: 1, OldRating: 1000, NewRating: 1200, SolvedCount: 3}},
+	}, time.Now()))
+	// A practice solve after the contest was finalized
+	require.NoError(t, db.Model(&domain.GlobalLeaderboardEntry{}).Where("user_id = ?", alice.ID).Update("solved_count", 4).Error)
+
+	require.NoError(t, contests.RollbackFinalization(contest.ID, alice.ID, time.Now()))
+
+	var entry domain.GlobalLeaderboardEntry
+	require.NoError(t, db.Where("user_id = ?", alice.ID).First(&entry).Error)
+	assert.Equal(t, 1000.0, entry.Rating)
+	assert.Equal(t, 2, entry.ContestsParticipated)
+	assert.Equal(t, 4, entry.SolvedCount)
+	assert.Equal(t, 1, entry.Rank)
+}
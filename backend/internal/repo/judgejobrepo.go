@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+	"gorm.io/gorm"
+)
+
+// ErrLeaseLost is returned when a worker no longer holds the job it works on.
+var ErrLeaseLost = errors.New("judge job lease lost")
+
+type JudgeJobRepo interface {
+	Claim(workerID string, lease time.Duration) (*domain.JudgeJob, error)
+	Renew(id uuid.UUID, workerID string, lease time.Duration) error
+	Finish(id uuid.UUID, workerID, status, lastError string) error
+	Release(id uuid.UUID, workerID, lastError string) error
+}
+
+type judgeJobRepo struct {
+	db *gorm.DB
+}
+
+var _ JudgeJobRepo = (*judgeJobRepo)(nil)
+
+// claimJob takes the oldest job that is queued or whose lease has run out.
+// SKIP LOCKED lets concurrent workers claim different jobs without waiting
+// on each other.
+const claimJob = `
+UPDATE judge_jobs
+SET status = ?, worker_id = ?, lease_until = ?, attempts = attempts + 1, updated_at = ?
+WHERE id = (
+	SELECT id FROM judge_jobs
+	WHERE status = ? OR (status = ? AND lease_until < ?)
+	ORDER BY created_at
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING *`
+
+// Claim implements [JudgeJobRepo]. It returns nil when the queue is empty.
+func (r *judgeJobRepo) Claim(workerID string, lease time.Duration) (*domain.JudgeJob, error) {
+	now := time.Now()
+	var jobs []domain.JudgeJob
+	err := r.db.Raw(claimJob,
+		domain.JOB_RUNNING, workerID, now.Add(lease), now,
+		domain.JOB_QUEUED, domain.JOB_RUNNING, now,
+	).Scan(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return &jobs[0], nil
+}
+
+// Renew implements [JudgeJobRepo].
+func (r *judgeJobRepo) Renew(id uuid.UUID, workerID string, lease time.Duration) error {
+	res := r.db.Model(&domain.JudgeJob{}).
+		Where("id = ? AND worker_id = ? AND status = ?", id, workerID, domain.JOB_RUNNING).
+		Update("lease_until", time.Now().Add(lease))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Finish implements [JudgeJobRepo].
+func (r *judgeJobRepo) Finish(id uuid.UUID, workerID, status, lastError string) error {
+	return r.update(id, workerID, map[string]interface{}{
+		"status":      status,
+		"lease_until": nil,
+		"last_error":  lastError,
+	})
+}
+
+// Release implements [JudgeJobRepo]. The job goes back to the queue to be
+// retried.
+func (r *judgeJobRepo) Release(id uuid.UUID, workerID, lastError string) error {
+	return r.update(id, workerID, map[string]interface{}{
+		"status":      domain.JOB_QUEUED,
+		"worker_id":   "",
+		"lease_until": nil,
+		"last_error":  lastError,
+	})
+}
+
+func (r *judgeJobRepo) update(id uuid.UUID, workerID string, updates map[string]interface{}) error {
+	res := r.db.Model(&domain.JudgeJob{}).
+		Where("id = ? AND worker_id = ?", id, workerID).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func NewJudgeJobRepo(db *gorm.DB) JudgeJobRepo {
+	return &judgeJobRepo{db: db}
+}
@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+	"gorm.io/gorm"
+)
+
+// queueJobs queues one judge job per new submission, oldest first.
+func queueJobs(t *testing.T, db *gorm.DB, n int) []*domain.JudgeJob {
+	t.Helper()
+	user := &domain.User{Username: "carol", Email: "carol@example.com", Password: "x"}
+	require.NoError(t, db.Create(user).Error)
+	problem := &domain.Problem{MainHeading: "Sum", Slug: "sum"}
+	require.NoError(t, db.Create(problem).Error)
+
+	jobs := make([]*domain.JudgeJob, n)
+	start := time.Now().Add(-time.Hour)
+	for i := range jobs {
+		sub := &domain.Submission{UserID: user.ID, ProblemID: problem.ID, Language: "py", Code: "x", Status: domain.STATUS_QUEUED}
+		require.NoError(t, db.Create(sub).Error)
+		jobs[i] = &domain.JudgeJob{SubmissionID: sub.ID, Status: domain.JOB_QUEUED, CreatedAt: start.Add(time.Duration(i) * time.Second)}
+		require.NoError(t, db.Create(jobs[i]).Error)
+	}
+	return jobs
+}
+
+func TestJudgeJobRepo_ClaimAndLease(t *testing.T) {
+	db := testDB(t)
+	jobs := queueJobs(t, db, 2)
+	queue := NewJudgeJobRepo(db)
+
+	first, err := queue.Claim("a", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	assert.Equal(t, jobs[0].ID, first.ID, "oldest job first")
+	assert.Equal(t, domain.JOB_RUNNING, first.Status)
+	assert.Equal(t, "a", first.WorkerID)
+	assert.Equal(t, 1, first.Attempts)
+	require.NotNil(t, first.LeaseUntil)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), *first.LeaseUntil, 10*time.Second)
+
+	second, err := queue.Claim("b", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, second)
+	assert.Equal(t, jobs[1].ID, second.ID)
+
+	none, err := queue.Claim("c", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, none, "leased jobs are not claimed again")
+
+	require.NoError(t, queue.Renew(first.ID, "a", time.Minute))
+	assert.ErrorIs(t, queue.Renew(first.ID, "b", time.Minute), ErrLeaseLost)
+
+	// Worker a stops renewing; its job is claimed again once the lease ran out
+	require.NoError(t, db.Model(&domain.JudgeJob{}).Where("id = ?", first.ID).
+		Update("lease_until", time.Now().Add(-time.Second)).Error)
+	retried, err := queue.Claim("c", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, retried)
+	assert.Equal(t, first.ID, retried.ID)
+	assert.Equal(t, 2, retried.Attempts)
+
+	assert.ErrorIs(t, queue.Renew(first.ID, "a", time.Minute), ErrLeaseLost)
+	assert.ErrorIs(t, queue.Finish(first.ID, "a", domain.JOB_DONE, ""), ErrLeaseLost)
+	require.NoError(t, queue.Finish(first.ID, "c", domain.JOB_DONE, ""))
+
+	require.NoError(t, queue.Release(second.ID, "b", "sandbox setup failed"))
+	released, err := queue.Claim("d", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, released)
+	assert.Equal(t, second.ID, released.ID)
+	assert.Equal(t, "sandbox setup failed", released.LastError)
+
+	none, err = queue.Claim("e", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, none, "finished jobs are not claimed again")
+}
+
+func TestJudgeJobRepo_ConcurrentClaims(t *testing.T) {
+	db := testDB(t)
+	jobs := queueJobs(t, db, 20)
+	queue := NewJudgeJobRepo(db)
+
+	var mu sync.Mutex
+	claimed := map[uuid.UUID]int{}
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job, err := queue.Claim(fmt.Sprintf("worker-%d", w), time.Minute)
+				if !assert.NoError(t, err) || job == nil {
+					return
+				}
+				mu.Lock()
+				claimed[job.ID]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, claimed, len(jobs))
+	for id, n := range claimed {
+		assert.Equal(t, 1, n, "job %s claimed more than once", id)
+	}
+}
@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"errors"
+
+	"github.com/sudankdk/codearena/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrLanguageNotFound is returned for a language id that is not registered.
+var ErrLanguageNotFound = errors.New("language not found")
+
+type LanguageRepo interface {
+	CreateLanguage(lang *domain.Language) error
+	GetLanguage(id string) (*domain.Language, error)
+	ListLanguages(includeDisabled bool) ([]domain.Language, error)
+	UpdateLanguage(id string, updates map[string]interface{}) error
+	SeedLanguages(langs []domain.Language) error
+}
+
+type languageRepo struct {
+	db *gorm.DB
+}
+
+var _ LanguageRepo = (*languageRepo)(nil) // compile-time interface check
+
+// CreateLanguage implements [LanguageRepo].
+func (l *languageRepo) CreateLanguage(lang *domain.Language) error {
+	var count int64
+	if err := l.db.Model(&domain.Language{}).Where("id = ?", lang.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("language with this id already exists")
+	}
+	return l.db.Create(lang).Error
+}
+
+// GetLanguage implements [LanguageRepo].
+func (l *languageRepo) GetLanguage(id string) (*domain.Language, error) {
+	var lang domain.Language
+	if err := l.db.Where("id = ?", id).First(&lang).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLanguageNotFound
+		}
+		return nil, err
+	}
+	return &lang, nil
+}
+
+// ListLanguages implements [LanguageRepo].
+func (l *languageRepo) ListLanguages(includeDisabled bool) ([]domain.Language, error) {
+	var langs []domain.Language
+	query := l.db.Order("name ASC")
+	if !includeDisabled {
+		query = query.Where("enabled = ?", true)
+	}
+	if err := query.Find(&langs).Error; err != nil {
+		return nil, err
+	}
+	return langs, nil
+}
+
+// UpdateLanguage implements [LanguageRepo].
+func (l *languageRepo) UpdateLanguage(id string, updates map[string]interface{}) error {
+	res := l.db.Model(&domain.Language{}).Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLanguageNotFound
+	}
+	return nil
+}
+
+// SeedLanguages implements [LanguageRepo]. Languages that already exist are
+// left untouched so admin edits survive restarts.
+func (l *languageRepo) SeedLanguages(langs []domain.Language) error {
+	if len(langs) == 0 {
+		return nil
+	}
+	return l.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&langs).Error
+}
+
+func NewLanguageRepo(db *gorm.DB) LanguageRepo {
+	return &languageRepo{
+		db: db,
+	}
+}
@@ -2,11 +2,13 @@ package repo
 
 import (
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sudankdk/codearena/internal/domain"
 	"github.com/sudankdk/codearena/internal/dto"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProblemsRepo interface {
@@ -15,8 +17,22 @@ type ProblemsRepo interface {
 	GetProblemBySlug(slug string, includeTC bool) (*domain.Problem, error)
 	GetProblemByTitle(title string) (*domain.Problem, error)
 	ListProblems(opts dto.ProblemListQueryDTO) ([]domain.Problem, int64, error)
+	IsPublic(id uuid.UUID, now time.Time) (bool, error)
 	UpdateProblem(id uuid.UUID, updates map[string]interface{}) error
+	ReplaceLanguageLimits(id uuid.UUID, limits []domain.ProblemLanguageLimit) error
+	ReplaceSubtasks(id uuid.UUID, subtasks []domain.Subtask) error
+	ReplaceBoilerplates(id uuid.UUID, boilerplates []domain.BoilerPlate) error
+	SaveProgram(program *domain.ProblemProgram) error
+	GetProgram(problemID uuid.UUID, role, name string) (*domain.ProblemProgram, error)
+	ListPrograms(problemID uuid.UUID) ([]domain.ProblemProgram, error)
+	DeleteProgram(problemID uuid.UUID, role, name string) error
+	SaveAttachment(attachment *domain.ProblemAttachment) error
+	GetAttachment(problemID uuid.UUID, name string) (*domain.ProblemAttachment, error)
+	ListAttachments(problemID uuid.UUID) ([]domain.ProblemAttachment, error)
+	DeleteAttachment(problemID uuid.UUID, name string) (*domain.ProblemAttachment, error)
+	CountAttachmentsWithHash(hash string) (int64, error)
 	DeleteProblem(id uuid.UUID) error
+	UseLegacyChecker() (int64, error)
 }
 
 type problemsRepo struct {
@@ -49,9 +65,9 @@ func (pr *problemsRepo) CreateProblem(p *domain.Problem) error {
 // GetProblemByID implements [ProblemsRepo].
 func (p *problemsRepo) GetProblemByID(id uuid.UUID, includeTC bool) (*domain.Problem, error) {
 	var problem domain.Problem
-	query := p.db.Model(&problem)
+	query := p.db.Model(&problem).Preload("LanguageLimits").Preload("Subtasks", orderSubtasks)
 	if includeTC {
-		query = query.Preload("TestCases").Preload("Boilerplates")
+		query = query.Preload("TestCases", orderTestCases).Preload("Boilerplates")
 	}
 	if err := query.First(&problem, "id = ?", id).Error; err != nil {
 		return nil, err
@@ -61,9 +77,9 @@ func (p *problemsRepo) GetProblemByID(id uuid.UUID, includeTC bool) (*domain.Pro
 
 func (p *problemsRepo) GetProblemBySlug(slug string, includeTC bool) (*domain.Problem, error) {
 	var problem domain.Problem
-	query := p.db.Model(&problem)
+	query := p.db.Model(&problem).Preload("LanguageLimits").Preload("Subtasks", orderSubtasks)
 	if includeTC {
-		query = query.Preload("TestCases").Preload("Boilerplates")
+		query = query.Preload("TestCases", orderTestCases).Preload("Boilerplates")
 	}
 	if err := query.First(&problem, "slug = ?", slug).Error; err != nil {
 		return nil, err
@@ -86,6 +102,12 @@ func (p *problemsRepo) ListProblems(opts dto.ProblemListQueryDTO) ([]domain.Prob
 
 	// Count total records matching filters
 	countQuery := p.db.Model(&domain.Problem{})
+	if opts.Public {
+		countQuery = countQuery.Scopes(publicProblems(time.Now()))
+	}
+	if opts.Status != "" {
+		countQuery = countQuery.Where("status = ?", opts.Status)
+	}
 	if opts.Difficulty != "" {
 		countQuery = countQuery.Where("difficulty = ?", opts.Difficulty)
 	}
@@ -99,9 +121,18 @@ func (p *problemsRepo) ListProblems(opts dto.ProblemListQueryDTO) ([]domain.Prob
 	// Fetch paginated results
 	query := p.db.Model(&domain.Problem{})
 
-	// Always preload test cases and boilerplates for now
-	query = query.Preload("TestCases").Preload("Boilerplates")
+	// Lists only carry the samples; hidden tests are counted instead.
+	if opts.Testcases {
+		query = query.Preload("TestCases", sampleTestCases)
+	}
+	query = query.Preload("Boilerplates").Preload("LanguageLimits").Preload("Subtasks", orderSubtasks)
 
+	if opts.Public {
+		query = query.Scopes(publicProblems(time.Now()))
+	}
+	if opts.Status != "" {
+		query = query.Where("status = ?", opts.Status)
+	}
 	if opts.Difficulty != "" {
 		query = query.Where("difficulty = ?", opts.Difficulty)
 	}
@@ -118,10 +149,62 @@ func (p *problemsRepo) ListProblems(opts dto.ProblemListQueryDTO) ([]domain.Prob
 	if err := query.Order("created_at DESC").Find(&problems).Error; err != nil {
 		return nil, 0, err
 	}
+	if err := p.countTestCases(problems); err != nil {
+		return nil, 0, err
+	}
 
 	return problems, total, nil
 }
 
+// IsPublic implements [ProblemsRepo].
+func (p *problemsRepo) IsPublic(id uuid.UUID, now time.Time) (bool, error) {
+	var count int64
+	if err := p.db.Model(&domain.Problem{}).Scopes(publicProblems(now)).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// publicProblems limits a query to the problems everyone may see at now:
+// published ones that are not part of a contest starting after now.
+func publicProblems(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("problems.status = ?", domain.PROBLEM_PUBLISHED).
+			Where(`NOT EXISTS (SELECT 1 FROM contest_problems cp JOIN contests c ON c.id = cp.contest_id
+				WHERE cp.problem_id = problems.id AND c.start_time > ?)`, now)
+	}
+}
+
+// countTestCases fills in TestCaseCount with one grouped query.
+func (p *problemsRepo) countTestCases(problems []domain.Problem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(problems))
+	for i, problem := range problems {
+		ids[i] = problem.ID
+	}
+	var counts []struct {
+		ProblemID uuid.UUID
+		Count     int64
+	}
+	if err := p.db.Model(&domain.TestCases{}).
+		Select("problem_id, COUNT(*) AS count").
+		Where("problem_id IN ?", ids).
+		Group("problem_id").
+		Scan(&counts).Error; err != nil {
+		return err
+	}
+	byProblem := make(map[uuid.UUID]int64, len(counts))
+	for _, c := range counts {
+		byProblem[c.ProblemID] = c.Count
+	}
+	for i := range problems {
+		problems[i].TestCaseCount = byProblem[problems[i].ID]
+	}
+	return nil
+}
+
 // UpdateProblem implements [ProblemsRepo].
 func (pr *problemsRepo) UpdateProblem(id uuid.UUID, updates map[string]interface{}) error {
 	// First check if problem exists
@@ -141,6 +224,136 @@ func (pr *problemsRepo) UpdateProblem(id uuid.UUID, updates map[string]interface
 	return nil
 }
 
+// ReplaceLanguageLimits implements [ProblemsRepo].
+func (pr *problemsRepo) ReplaceLanguageLimits(id uuid.UUID, limits []domain.ProblemLanguageLimit) error {
+	return pr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("problem_id = ?", id).Delete(&domain.ProblemLanguageLimit{}).Error; err != nil {
+			return err
+		}
+		if len(limits) == 0 {
+			return nil
+		}
+		for i := range limits {
+			limits[i].ProblemID = id
+		}
+		return tx.Create(&limits).Error
+	})
+}
+
+// ReplaceSubtasks implements [ProblemsRepo]. Tests keep their subtask index.
+func (pr *problemsRepo) ReplaceSubtasks(id uuid.UUID, subtasks []domain.Subtask) error {
+	return pr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("problem_id = ?", id).Delete(&domain.Subtask{}).Error; err != nil {
+			return err
+		}
+		if len(subtasks) == 0 {
+			return nil
+		}
+		for i := range subtasks {
+			subtasks[i].ProblemID = id
+		}
+		return tx.Create(&subtasks).Error
+	})
+}
+
+// ReplaceBoilerplates implements [ProblemsRepo].
+func (pr *problemsRepo) ReplaceBoilerplates(id uuid.UUID, boilerplates []domain.BoilerPlate) error {
+	return pr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("problem_id = ?", id).Delete(&domain.BoilerPlate{}).Error; err != nil {
+			return err
+		}
+		if len(boilerplates) == 0 {
+			return nil
+		}
+		for i := range boilerplates {
+			boilerplates[i].ProblemID = id
+		}
+		return tx.Create(&boilerplates).Error
+	})
+}
+
+// SaveProgram implements [ProblemsRepo]. It replaces the program with the same
+// problem, role and name.
+func (pr *problemsRepo) SaveProgram(program *domain.ProblemProgram) error {
+	return pr.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "problem_id"}, {Name: "role"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"language", "code", "expected", "updated_at"}),
+	}).Create(program).Error
+}
+
+// GetProgram implements [ProblemsRepo].
+func (pr *problemsRepo) GetProgram(problemID uuid.UUID, role, name string) (*domain.ProblemProgram, error) {
+	var program domain.ProblemProgram
+	if err := pr.db.Where("problem_id = ? AND role = ? AND name = ?", problemID, role, name).First(&program).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("program not found")
+		}
+		return nil, err
+	}
+	return &program, nil
+}
+
+// ListPrograms implements [ProblemsRepo].
+func (pr *problemsRepo) ListPrograms(problemID uuid.UUID) ([]domain.ProblemProgram, error) {
+	var programs []domain.ProblemProgram
+	err := pr.db.Where("problem_id = ?", problemID).Order("role ASC, name ASC").Find(&programs).Error
+	return programs, err
+}
+
+// DeleteProgram implements [ProblemsRepo].
+func (pr *problemsRepo) DeleteProgram(problemID uuid.UUID, role, name string) error {
+	return pr.db.Where("problem_id = ? AND role = ? AND name = ?", problemID, role, name).
+		Delete(&domain.ProblemProgram{}).Error
+}
+
+// SaveAttachment implements [ProblemsRepo]. It replaces the attachment with
+// the same problem and name.
+func (pr *problemsRepo) SaveAttachment(attachment *domain.ProblemAttachment) error {
+	return pr.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "problem_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content_type", "hash", "size", "updated_at"}),
+	}).Create(attachment).Error
+}
+
+// GetAttachment implements [ProblemsRepo].
+func (pr *problemsRepo) GetAttachment(problemID uuid.UUID, name string) (*domain.ProblemAttachment, error) {
+	var attachment domain.ProblemAttachment
+	if err := pr.db.Where("problem_id = ? AND name = ?", problemID, name).First(&attachment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// ListAttachments implements [ProblemsRepo].
+func (pr *problemsRepo) ListAttachments(problemID uuid.UUID) ([]domain.ProblemAttachment, error) {
+	var attachments []domain.ProblemAttachment
+	err := pr.db.Where("problem_id = ?", problemID).Order("name ASC").Find(&attachments).Error
+	return attachments, err
+}
+
+// DeleteAttachment implements [ProblemsRepo]. It returns the deleted row so
+// the caller can clean up its blob.
+func (pr *problemsRepo) DeleteAttachment(problemID uuid.UUID, name string) (*domain.ProblemAttachment, error) {
+	attachment, err := pr.GetAttachment(problemID, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := pr.db.Delete(attachment).Error; err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// CountAttachmentsWithHash implements [ProblemsRepo].
+func (pr *problemsRepo) CountAttachmentsWithHash(hash string) (int64, error) {
+	var count int64
+	err := pr.db.Model(&domain.ProblemAttachment{}).Where("hash = ?", hash).Count(&count).Error
+	return count, err
+}
+
 // DeleteProblem implements [ProblemsRepo].
 func (pr *problemsRepo) DeleteProblem(id uuid.UUID) error {
 	// Check if problem exists
@@ -164,6 +377,31 @@ func (pr *problemsRepo) DeleteProblem(id uuid.UUID) error {
 			return err
 		}
 
+		// Delete per-language limit overrides
+		if err := tx.Where("problem_id = ?", id).Delete(&domain.ProblemLanguageLimit{}).Error; err != nil {
+			return err
+		}
+
+		// Delete subtasks
+		if err := tx.Where("problem_id = ?", id).Delete(&domain.Subtask{}).Error; err != nil {
+			return err
+		}
+
+		// Delete checker and other helper programs
+		if err := tx.Where("problem_id = ?", id).Delete(&domain.ProblemProgram{}).Error; err != nil {
+			return err
+		}
+
+		// Delete attachments; their blobs stay in the store
+		if err := tx.Where("problem_id = ?", id).Delete(&domain.ProblemAttachment{}).Error; err != nil {
+			return err
+		}
+
+		// Delete revision history
+		if err := tx.Where("problem_id = ?", id).Delete(&domain.ProblemRevision{}).Error; err != nil {
+			return err
+		}
+
 		// Delete the problem
 		if err := tx.Delete(&problem).Error; err != nil {
 			return err
@@ -173,6 +411,30 @@ func (pr *problemsRepo) DeleteProblem(id uuid.UUID) error {
 	})
 }
 
+// UseLegacyChecker gives every problem the lines checker, which compares
+// outputs the way they were compared before problems chose a checker.
+func (pr *problemsRepo) UseLegacyChecker() (int64, error) {
+	res := pr.db.Model(&domain.Problem{}).
+		Where("1 = 1").
+		Update("checker_type", domain.CHECKER_LINES)
+	return res.RowsAffected, res.Error
+}
+
+// orderTestCases sorts preloaded test cases into judging order.
+func orderTestCases(db *gorm.DB) *gorm.DB {
+	return db.Order("order_index ASC, created_at ASC")
+}
+
+// sampleTestCases preloads only the samples, in judging order.
+func sampleTestCases(db *gorm.DB) *gorm.DB {
+	return orderTestCases(db.Where("is_sample = ?", true))
+}
+
+// orderSubtasks sorts preloaded subtasks by index.
+func orderSubtasks(db *gorm.DB) *gorm.DB {
+	return db.Order(`"index" ASC`)
+}
+
 func NewProblemsRepo(db *gorm.DB) ProblemsRepo {
 	return &problemsRepo{
 		db: db,
@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to the Postgres database in CODEARENA_TEST_DSN and migrates
+// a schema of its own that is dropped when the test ends. Tests that need a
+// database are skipped without one.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("CODEARENA_TEST_DSN")
+	if dsn == "" {
+		t.Skip("CODEARENA_TEST_DSN is not set")
+	}
+	config := &gorm.Config{Logger: logger.Discard}
+
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	require.NoError(t, err)
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	require.NoError(t, admin.Exec("CREATE SCHEMA "+schema).Error)
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	switch {
+	case !strings.Contains(dsn, "://"):
+		dsn += " search_path=" + schema
+	case strings.Contains(dsn, "?"):
+		dsn += "&search_path=" + schema
+	default:
+		dsn += "?search_path=" + schema
+	}
+	db, err := gorm.Open(postgres.Open(dsn), config)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	require.NoError(t, db.AutoMigrate(
+		&domain.User{},
+		&domain.Problem{},
+		&domain.Submission{},
+		&domain.Contest{},
+		&domain.ContestProblem{},
+		&domain.ContestParticipant{},
+		&domain.ContestLeaderboardEntry{},
+		&domain.GlobalLeaderboardEntry{},
+		&domain.ContestFinalization{},
+		&domain.Subtask{},
+		&domain.SubmissionSubtaskResult{},
+		&domain.JudgeJob{},
+		&domain.SubmissionJudgement{},
+	))
+	return db
+}
+
+// seedParticipant creates a contest with one problem and one registered
+// participant.
+func seedParticipant(t *testing.T, db *gorm.DB) (*domain.Contest, *domain.User, *domain.Problem) {
+	t.Helper()
+	user := &domain.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	require.NoError(t, db.Create(user).Error)
+	problem := &domain.Problem{MainHeading: "Sum", Slug: "sum"}
+	require.NoError(t, db.Create(problem).Error)
+
+	now := time.Now()
+	contest := &domain.Contest{Name: "Round 1", StartTime: now.Add(-time.Hour), EndTime: now.Add(time.Hour), Duration: 120}
+	require.NoError(t, db.Create(contest).Error)
+	require.NoError(t, db.Create(&domain.ContestProblem{ContestID: contest.ID, ProblemID: problem.ID, OrderIndex: 1}).Error)
+	require.NoError(t, db.Create(&domain.ContestParticipant{ContestID: contest.ID, UserID: user.ID}).Error)
+	return contest, user, problem
+}
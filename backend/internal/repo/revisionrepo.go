@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevisionRepo interface {
+	CreateRevision(revision *domain.ProblemRevision) error
+	GetRevision(problemID uuid.UUID, number int) (*domain.ProblemRevision, error)
+	LatestRevision(problemID uuid.UUID) (*domain.ProblemRevision, error)
+	ListRevisions(problemID uuid.UUID) ([]domain.ProblemRevision, error)
+	RestoreRevision(revision *domain.ProblemRevision, tests []domain.TestCases) error
+	ProblemsWithoutRevisions() ([]uuid.UUID, error)
+}
+
+type revisionRepo struct {
+	db *gorm.DB
+}
+
+var _ RevisionRepo = (*revisionRepo)(nil) // compile-time interface check
+
+// CreateRevision implements RevisionRepo. It numbers the revision after the
+// problem's latest one and makes it the problem's current revision. The
+// problem row is locked meanwhile, so concurrent changes get one number each.
+func (r *revisionRepo) CreateRevision(revision *domain.ProblemRevision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var problem domain.Problem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "revision").
+			First(&problem, "id = ?", revision.ProblemID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("problem not found")
+			}
+			return err
+		}
+		revision.Number = problem.Revision + 1
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.Problem{}).Where("id = ?", revision.ProblemID).
+			UpdateColumn("revision", revision.Number).Error
+	})
+}
+
+// GetRevision implements RevisionRepo.
+func (r *revisionRepo) GetRevision(problemID uuid.UUID, number int) (*domain.ProblemRevision, error) {
+	var revision domain.ProblemRevision
+	if err := r.db.First(&revision, "problem_id = ? AND number = ?", problemID, number).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("revision not found")
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// LatestRevision implements RevisionRepo.
+func (r *revisionRepo) LatestRevision(problemID uuid.UUID) (*domain.ProblemRevision, error) {
+	var revision domain.ProblemRevision
+	if err := r.db.Where("problem_id = ?", problemID).Order("number DESC").First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("revision not found")
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// ListRevisions implements RevisionRepo. Newest revisions come first.
+func (r *revisionRepo) ListRevisions(problemID uuid.UUID) ([]domain.ProblemRevision, error) {
+	var revisions []domain.ProblemRevision
+	err := r.db.Where("problem_id = ?", problemID).Order("number DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+// RestoreRevision implements RevisionRepo. It writes the statement, limits
+// and subtasks of revision back onto its problem and replaces the
+// problem's tests with tests, all in one transaction.
+func (r *revisionRepo) RestoreRevision(revision *domain.ProblemRevision, tests []domain.TestCases) error {
+	problemID := revision.ProblemID
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&domain.Problem{}).Where("id = ?", problemID).Updates(map[string]interface{}{
+			"main_heading":    revision.MainHeading,
+			"description":     revision.Description,
+			"tag":             revision.Tag,
+			"difficulty":      revision.Difficulty,
+			"time_limit_ms":   revision.TimeLimitMs,
+			"memory_limit_mb": revision.MemoryLimitMB,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("problem not found")
+		}
+
+		if err := tx.Where("problem_id = ?", problemID).Delete(&domain.ProblemLanguageLimit{}).Error; err != nil {
+			return err
+		}
+		for _, l := range revision.LanguageLimits {
+			limit := domain.ProblemLanguageLimit{ProblemID: problemID, LanguageID: l.Language, TimeLimitMs: l.TimeLimitMs, MemoryLimitMB: l.MemoryLimitMB}
+			if err := tx.Create(&limit).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("problem_id = ?", problemID).Delete(&domain.Subtask{}).Error; err != nil {
+			return err
+		}
+		for _, s := range revision.Subtasks {
+			subtask := domain.Subtask{ProblemID: problemID, Index: s.Index, Name: s.Name, Points: s.Points, Aggregation: s.Aggregation, Dependencies: s.Dependencies}
+			if err := tx.Create(&subtask).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("problem_id = ?", problemID).Delete(&domain.TestCases{}).Error; err != nil {
+			return err
+		}
+		if len(tests) == 0 {
+			return nil
+		}
+		for i := range tests {
+			tests[i].ProblemID = problemID
+			tests[i].OrderIndex = i
+		}
+		return tx.CreateInBatches(&tests, 100).Error
+	})
+}
+
+// ProblemsWithoutRevisions implements RevisionRepo.
+func (r *revisionRepo) ProblemsWithoutRevisions() ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&domain.Problem{}).
+		Where("NOT EXISTS (SELECT 1 FROM problem_revisions pr WHERE pr.problem_id = problems.id)").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func NewRevisionRepo(db *gorm.DB) RevisionRepo {
+	return &revisionRepo{
+		db: db,
+	}
+}
@@ -7,14 +7,27 @@ import (
 	"github.com/sudankdk/codearena/internal/domain"
 	"github.com/sudankdk/codearena/internal/dto"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// requeueBatch bounds the number of submissions requeued per statement.
+const requeueBatch = 500
+
 type SubmissionRepo interface {
 	CreateSubmission(submission *domain.Submission) error
 	GetSubmissionByID(id uuid.UUID) (*domain.Submission, error)
 	UpdateSubmissionPoints(id uuid.UUID, points int) error
+	UpdateSubmissionStatus(id uuid.UUID, status string) error
+	SaveJudgement(submission *domain.Submission) error
+	GetSubmissionStatus(id uuid.UUID) (*domain.Submission, error)
+	ListJudgements(submissionID uuid.UUID) ([]domain.SubmissionJudgement, error)
+	ListSubmissionIDs(problemID, contestID *uuid.UUID) ([]uuid.UUID, error)
+	RequeueSubmissions(ids []uuid.UUID) error
+	ListContestSubmissions(contestID, userID uuid.UUID) ([]domain.Submission, error)
 	CountContestProblemAttempts(contestID, userID, problemID uuid.UUID) (int, error)
+	CountPendingContestSubmissions(contestID uuid.UUID) (int64, error)
 	HasUserSolvedContestProblem(contestID, userID, problemID, excludeSubmissionID uuid.UUID) (bool, error)
+	ListContestSubtaskResults(contestID, userID, problemID uuid.UUID) ([]domain.SubmissionSubtaskResult, error)
 	ListSubmissions(opts dto.SubmissionListQueryDTO) ([]domain.Submission, int64, error)
 	GetUserStats(userID uuid.UUID) (*dto.UserStatsDTO, error)
 	GetProblemStats(problemID uuid.UUID) (*dto.ProblemStatsDTO, error)
@@ -39,7 +52,15 @@ func (sr *submissionRepo) CreateSubmission(submission *domain.Submission) error
 
 func (sr *submissionRepo) GetSubmissionByID(id uuid.UUID) (*domain.Submission, error) {
 	var submission domain.Submission
-	if err := sr.db.Preload("Problem").Preload("User").First(&submission, "id = ?", id).Error; err != nil {
+	if err := sr.db.Preload("Problem").Preload("User").
+		Preload("TestResults", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).
+		Preload("TestResults.TestCase").
+		Preload("SubtaskResults", func(db *gorm.DB) *gorm.DB {
+			return db.Order("subtask_index ASC")
+		}).
+		First(&submission, "id = ?", id).Error; err != nil {
 		return nil, errors.New("submission not found")
 	}
 	return &submission, nil
@@ -52,6 +73,192 @@ func (sr *submissionRepo) UpdateSubmissionPoints(id uuid.UUID, points int) error
 	return nil
 }
 
+// ListContestSubtaskResults returns the subtask scores of all of a user's
+// submissions to a problem in a contest.
+func (sr *submissionRepo) ListContestSubtaskResults(contestID, userID, problemID uuid.UUID) ([]domain.SubmissionSubtaskResult, error) {
+	var results []domain.SubmissionSubtaskResult
+	err := sr.db.Joins("JOIN submissions ON submissions.id = submission_subtask_results.submission_id").
+		Where("submissions.contest_id = ? AND submissions.user_id = ? AND submissions.problem_id = ?", contestID, userID, problemID).
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (sr *submissionRepo) UpdateSubmissionStatus(id uuid.UUID, status string) error {
+	return sr.db.Model(&domain.Submission{}).Where("id = ?", id).Update("status", status).Error
+}
+
+// SaveJudgement stores the verdict of a submission together with its test
+// and subtask results, replacing the results of any earlier judgement. The
+// verdict is also added to the submission's judgement history. A zero
+// ProblemRevision, as with submissions that could not be judged, keeps the
+// revision recorded before.
+func (sr *submissionRepo) SaveJudgement(submission *domain.Submission) error {
+	return sr.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"status":            submission.Status,
+			"execution_time":    submission.ExecutionTime,
+			"memory_used":       submission.MemoryUsed,
+			"test_cases_passed": submission.TestCasesPassed,
+			"total_test_cases":  submission.TotalTestCases,
+			"error_message":     submission.ErrorMessage,
+		}
+		if submission.ProblemRevision > 0 {
+			updates["problem_revision"] = submission.ProblemRevision
+		}
+		err := tx.Model(&domain.Submission{}).Where("id = ?", submission.ID).Updates(updates).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(judgementOf(submission)).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("submission_id = ?", submission.ID).Delete(&domain.SubmissionTestResult{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("submission_id = ?", submission.ID).Delete(&domain.SubmissionSubtaskResult{}).Error; err != nil {
+			return err
+		}
+		for i := range submission.TestResults {
+			submission.TestResults[i].SubmissionID = submission.ID
+		}
+		for i := range submission.SubtaskResults {
+			submission.SubtaskResults[i].SubmissionID = submission.ID
+		}
+		if len(submission.TestResults) > 0 {
+			if err := tx.Create(&submission.TestResults).Error; err != nil {
+				return err
+			}
+		}
+		if len(submission.SubtaskResults) > 0 {
+			if err := tx.Create(&submission.SubtaskResults).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetSubmissionStatus loads only the fields needed to follow a submission
+// through the judge.
+func (sr *submissionRepo) GetSubmissionStatus(id uuid.UUID) (*domain.Submission, error) {
+	var submission domain.Submission
+	if err := sr.db.Select("id", "status", "test_cases_passed", "total_test_cases", "points_earned").
+		First(&submission, "id = ?", id).Error; err != nil {
+		return nil, errors.New("submission not found")
+	}
+	return &submission, nil
+}
+
+func judgementOf(submission *domain.Submission) *domain.SubmissionJudgement {
+	return &domain.SubmissionJudgement{
+		SubmissionID:    submission.ID,
+		Status:          submission.Status,
+		ExecutionTime:   submission.ExecutionTime,
+		MemoryUsed:      submission.MemoryUsed,
+		TestCasesPassed: submission.TestCasesPassed,
+		TotalTestCases:  submission.TotalTestCases,
+		ErrorMessage:    submission.ErrorMessage,
+		ProblemRevision: submission.ProblemRevision,
+	}
+}
+
+// ListJudgements returns the verdicts a submission received, oldest first.
+func (sr *submissionRepo) ListJudgements(submissionID uuid.UUID) ([]domain.SubmissionJudgement, error) {
+	var judgements []domain.SubmissionJudgement
+	if err := sr.db.Where("submission_id = ?", submissionID).
+		Order("created_at ASC").
+		Find(&judgements).Error; err != nil {
+		return nil, err
+	}
+	return judgements, nil
+}
+
+// ListSubmissionIDs returns the IDs of all submissions to a problem and/or
+// in a contest, oldest first.
+func (sr *submissionRepo) ListSubmissionIDs(problemID, contestID *uuid.UUID) ([]uuid.UUID, error) {
+	query := sr.db.Model(&domain.Submission{})
+	if problemID != nil {
+		query = query.Where("problem_id = ?", *problemID)
+	}
+	if contestID != nil {
+		query = query.Where("contest_id = ?", *contestID)
+	}
+	var ids []uuid.UUID
+	if err := query.Order("created_at ASC").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// RequeueSubmissions puts submissions back on the judge queue. Submissions
+// judged before verdicts were recorded get their current verdict added to
+// their history first, so it is not lost when they are judged again.
+func (sr *submissionRepo) RequeueSubmissions(ids []uuid.UUID) error {
+	for start := 0; start < len(ids); start += requeueBatch {
+		batch := ids[start:min(start+requeueBatch, len(ids))]
+		err := sr.db.Transaction(func(tx *gorm.DB) error {
+			var unrecorded []domain.Submission
+			err := tx.Select("id", "status", "execution_time", "memory_used", "test_cases_passed", "total_test_cases", "error_message").
+				Where("id IN ? AND status NOT IN ?", batch,
+					[]string{domain.STATUS_QUEUED, domain.STATUS_COMPILING, domain.STATUS_RUNNING}).
+				Where("NOT EXISTS (SELECT 1 FROM submission_judgements j WHERE j.submission_id = submissions.id)").
+				Find(&unrecorded).Error
+			if err != nil {
+				return err
+			}
+			for i := range unrecorded {
+				if err := tx.Create(judgementOf(&unrecorded[i])).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Model(&domain.Submission{}).Where("id IN ?", batch).
+				Update("status", domain.STATUS_QUEUED).Error; err != nil {
+				return err
+			}
+
+			// Submissions from before the judge queue have no job yet
+			jobs := make([]domain.JudgeJob, len(batch))
+			for i, id := range batch {
+				jobs[i] = domain.JudgeJob{SubmissionID: id, Status: domain.JOB_QUEUED}
+			}
+			return tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "submission_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"status":      domain.JOB_QUEUED,
+					"attempts":    0,
+					"worker_id":   "",
+					"lease_until": nil,
+					"last_error":  "",
+					"created_at":  gorm.Expr("EXCLUDED.created_at"),
+					"updated_at":  gorm.Expr("EXCLUDED.updated_at"),
+				}),
+			}).Create(&jobs).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListContestSubmissions returns a user's submissions in a contest in the
+// order they were made, with their subtask results.
+func (sr *submissionRepo) ListContestSubmissions(contestID, userID uuid.UUID) ([]domain.Submission, error) {
+	var submissions []domain.Submission
+	err := sr.db.Where("contest_id = ? AND user_id = ?", contestID, userID).
+		Preload("SubtaskResults").
+		Order("created_at ASC").
+		Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
 func (sr *submissionRepo) CountContestProblemAttempts(contestID, userID, problemID uuid.UUID) (int, error) {
 	var count int64
 	err := sr.db.Model(&domain.Submission{}).
@@ -63,6 +270,21 @@ func (sr *submissionRepo) CountContestProblemAttempts(contestID, userID, problem
 	return int(count), nil
 }
 
+// CountPendingContestSubmissions counts a contest's submissions that are
+// still waiting for a verdict or whose judge job has not finished. A verdict
+// is stored before the contest totals are updated and the job finishes only
+// after that, so totals are not final until the job is.
+func (sr *submissionRepo) CountPendingContestSubmissions(contestID uuid.UUID) (int64, error) {
+	var count int64
+	err := sr.db.Model(&domain.Submission{}).
+		Where("contest_id = ?", contestID).
+		Where("status IN ? OR EXISTS (SELECT 1 FROM judge_jobs j WHERE j.submission_id = submissions.id AND j.status IN ?)",
+			[]string{domain.STATUS_QUEUED, domain.STATUS_COMPILING, domain.STATUS_RUNNING},
+			[]string{domain.JOB_QUEUED, domain.JOB_RUNNING}).
+		Count(&count).Error
+	return count, err
+}
+
 func (sr *submissionRepo) ListSubmissions(opts dto.SubmissionListQueryDTO) ([]domain.Submission, int64, error) {
 	var submissions []domain.Submission
 	var total int64
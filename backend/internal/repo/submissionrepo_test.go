@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+func TestCountPendingContestSubmissions(t *testing.T) {
+	db := testDB(t)
+	contest, user, problem := seedParticipant(t, db)
+	submit := func(status, jobStatus string) *domain.JudgeJob {
+		sub := &domain.Submission{UserID: user.ID, ProblemID: problem.ID, ContestID: &contest.ID,
+			Language: "py", Code: "x", Status: status}
+		require.NoError(t, db.Create(sub).Error)
+		job := &domain.JudgeJob{SubmissionID: sub.ID, Status: jobStatus}
+		require.NoError(t, db.Create(job).Error)
+		return job
+	}
+	submissions := NewSubmissionRepo(db)
+
+	submit(domain.STATUS_ACCEPTED, domain.JOB_DONE)
+	submit(domain.STATUS_QUEUED, domain.JOB_QUEUED)
+	// Judged, but the contest totals may not be updated yet
+	scoring := submit(domain.STATUS_ACCEPTED, domain.JOB_RUNNING)
+
+	pending, err := submissions.CountPendingContestSubmissions(contest.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pending)
+
+	require.NoError(t, db.Model(scoring).Update("status", domain.JOB_DONE).Error)
+	pending, err = submissions.CountPendingContestSubmissions(contest.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending)
+}
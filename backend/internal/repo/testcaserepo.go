@@ -11,6 +11,12 @@ import (
 type TestcaseRepo interface {
 	CreateTestcase(testcases domain.TestCases) error
 	ListTestcase(id uuid.UUID) ([]domain.TestCases, error)
+	ListSampleTestcases(problemID uuid.UUID) ([]domain.TestCases, error)
+	GetTestcase(id uuid.UUID) (*domain.TestCases, error)
+	UpdateTestcase(id uuid.UUID, updates map[string]interface{}) error
+	NextOrderIndex(problemID uuid.UUID) (int, error)
+	ImportTestcases(problemID uuid.UUID, tests []domain.TestCases, replace bool) (int64, error)
+	MigrateInlineData(save func(tc *domain.TestCases) error) (int, error)
 }
 
 type testcaseRepo struct {
@@ -30,13 +36,130 @@ func (t *testcaseRepo) CreateTestcase(testcases domain.TestCases) error {
 // ListTestcase implements TestcaseRepo.
 func (t *testcaseRepo) ListTestcase(id uuid.UUID) ([]domain.TestCases, error) {
 	var testcases []domain.TestCases
-	if err := t.db.Where("problem_id = ?",id).Find(&testcases).Error; err != nil {
+	if err := t.db.Where("problem_id = ?",id).Order("order_index ASC, created_at ASC").Find(&testcases).Error; err != nil {
 		return []domain.TestCases{}, err
 	}
 	return testcases, nil
 
 }
 
+// ListSampleTestcases implements TestcaseRepo.
+func (t *testcaseRepo) ListSampleTestcases(problemID uuid.UUID) ([]domain.TestCases, error) {
+	var testcases []domain.TestCases
+	if err := t.db.Where("problem_id = ? AND is_sample = ?", problemID, true).
+		Order("order_index ASC, created_at ASC").
+		Find(&testcases).Error; err != nil {
+		return []domain.TestCases{}, err
+	}
+	return testcases, nil
+}
+
+// GetTestcase implements TestcaseRepo.
+func (t *testcaseRepo) GetTestcase(id uuid.UUID) (*domain.TestCases, error) {
+	var testcase domain.TestCases
+	if err := t.db.First(&testcase, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("testcase not found")
+		}
+		return nil, err
+	}
+	return &testcase, nil
+}
+
+// UpdateTestcase implements TestcaseRepo.
+func (t *testcaseRepo) UpdateTestcase(id uuid.UUID, updates map[string]interface{}) error {
+	res := t.db.Model(&domain.TestCases{}).Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("testcase not found")
+	}
+	return nil
+}
+
+// NextOrderIndex implements TestcaseRepo.
+func (t *testcaseRepo) NextOrderIndex(problemID uuid.UUID) (int, error) {
+	var next int
+	if err := t.db.Model(&domain.TestCases{}).
+		Where("problem_id = ?", problemID).
+		Select("COALESCE(MAX(order_index) + 1, 0)").
+		Scan(&next).Error; err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// ImportTestcases implements TestcaseRepo. With replace set the problem's
+// tests are deleted first; otherwise the new tests are ordered after the
+// existing ones. It returns the number of deleted tests.
+func (t *testcaseRepo) ImportTestcases(problemID uuid.UUID, tests []domain.TestCases, replace bool) (int64, error) {
+	var removed int64
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		next := 0
+		if replace {
+			res := tx.Where("problem_id = ?", problemID).Delete(&domain.TestCases{})
+			if res.Error != nil {
+				return res.Error
+			}
+			removed = res.RowsAffected
+		} else if err := tx.Model(&domain.TestCases{}).
+			Where("problem_id = ?", problemID).
+			Select("COALESCE(MAX(order_index) + 1, 0)").
+			Scan(&next).Error; err != nil {
+			return err
+		}
+		for i := range tests {
+			tests[i].ProblemID = problemID
+			tests[i].OrderIndex = next + i
+		}
+		return tx.CreateInBatches(&tests, 100).Error
+	})
+	return removed, err
+}
+
+// MigrateInlineData implements TestcaseRepo. Test data used to be kept in
+// the input and expected columns; save moves it into the blob store and the
+// columns are dropped once every test has been moved. It returns the number
+// of moved tests.
+func (t *testcaseRepo) MigrateInlineData(save func(tc *domain.TestCases) error) (int, error) {
+	m := t.db.Migrator()
+	if !m.HasColumn(&domain.TestCases{}, "input") || !m.HasColumn(&domain.TestCases{}, "expected") {
+		return 0, nil
+	}
+	var rows []struct {
+		ID       uuid.UUID
+		Input    string
+		Expected string
+	}
+	if err := t.db.Model(&domain.TestCases{}).
+		Select("id, COALESCE(input, '') AS input, COALESCE(expected, '') AS expected").
+		Where("input_hash IS NULL OR input_hash = '' OR expected_hash IS NULL OR expected_hash = ''").
+		Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+	for i, row := range rows {
+		tc := domain.TestCases{Input: row.Input, Expected: row.Expected}
+		if err := save(&tc); err != nil {
+			return i, err
+		}
+		if err := t.db.Model(&domain.TestCases{}).Where("id = ?", row.ID).Updates(map[string]interface{}{
+			"input_hash":       tc.InputHash,
+			"input_size":       tc.InputSize,
+			"input_preview":    tc.InputPreview,
+			"expected_hash":    tc.ExpectedHash,
+			"expected_size":    tc.ExpectedSize,
+			"expected_preview": tc.ExpectedPreview,
+		}).Error; err != nil {
+			return i, err
+		}
+	}
+	if err := m.DropColumn(&domain.TestCases{}, "input"); err != nil {
+		return len(rows), err
+	}
+	return len(rows), m.DropColumn(&domain.TestCases{}, "expected")
+}
+
 func NewTestcase(db *gorm.DB) TestcaseRepo {
 	return &testcaseRepo{
 		db: db,
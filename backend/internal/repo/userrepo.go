@@ -16,6 +16,7 @@ type UserRepo interface {
 	UpdateUser(id uuid.UUID, user domain.User) (domain.User, error)
 	UpdateUserRating(id uuid.UUID, rating float64) error
 	UpdateUserSolvedCount(id uuid.UUID, solvedCount int) error
+	RefreshSolvedCount(id uuid.UUID) error
 	ListUser() ([]domain.User, error)
 }
 
@@ -70,6 +71,29 @@ func (u *userRepo) UpdateUserSolvedCount(id uuid.UUID, solvedCount int) error {
 	return nil
 }
 
+// RefreshSolvedCount implements [UserRepo]. It recounts the problems the user
+// has an accepted submission for with the user's row locked, so concurrent
+// verdicts for the same user cannot save an outdated count.
+func (u *userRepo) RefreshSolvedCount(id uuid.UUID) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, "id = ?", id).Error; err != nil {
+			return err
+		}
+		var solved int64
+		err := tx.Model(&domain.Submission{}).
+			Joins("JOIN problems ON problems.id = submissions.problem_id").
+			Where("submissions.user_id = ? AND submissions.status = ?", id, domain.STATUS_ACCEPTED).
+			Where("problems.difficulty IN ?", []string{domain.EASY, domain.MEDIUM, domain.HARD}).
+			Distinct("submissions.problem_id").
+			Count(&solved).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("solvedcount", solved).Error
+	})
+}
+
 func (u *userRepo) ListUser() ([]domain.User, error) {
 	var users []domain.User
 	if err := u.db.Find(&users).Error; err != nil {
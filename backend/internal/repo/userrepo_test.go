@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+func TestRefreshSolvedCount(t *testing.T) {
+	db := testDB(t)
+	user := &domain.User{Username: "bob", Email: "bob@example.com", Password: "x"}
+	require.NoError(t, db.Create(user).Error)
+	easy := &domain.Problem{MainHeading: "A", Slug: "a", Difficulty: domain.EASY}
+	hard := &domain.Problem{MainHeading: "B", Slug: "b", Difficulty: domain.HARD}
+	require.NoError(t, db.Create(easy).Error)
+	require.NoError(t, db.Create(hard).Error)
+	for _, sub := range []*domain.Submission{
+		{ProblemID: easy.ID, Status: domain.STATUS_ACCEPTED},
+		{ProblemID: easy.ID, Status: domain.STATUS_ACCEPTED},
+		{ProblemID: hard.ID, Status: domain.STATUS_WRONG_ANSWER},
+	} {
+		sub.UserID, sub.Language, sub.Code = user.ID, "py", "x"
+		require.NoError(t, db.Create(sub).Error)
+	}
+	users := NewUserRepo(db)
+
+	require.NoError(t, users.RefreshSolvedCount(user.ID))
+
+	saved, err := users.FindUserById(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved.Solvedcount)
+}
@@ -0,0 +1,289 @@
+// Package sandbox runs untrusted programs with CPU, memory, wall-clock and
+// output limits. On Linux every run gets fresh namespaces (no network, private
+// mounts and PIDs), a root holding only the toolchain and the run directory,
+// rlimits, a seccomp syscall filter, an unprivileged identity of its own and a
+// tmpfs scratch directory. Other platforms only get the limits
+// that can be enforced from the parent process.
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+const (
+	DefaultOutputLimit  = 64 << 20 // bytes of stdout kept per run
+	DefaultStderrLimit  = 64 << 10 // bytes of stderr kept per run
+	DefaultMaxProcesses = 512
+	DefaultTmpfsSizeKB  = 64 * 1024
+	DefaultNoFile       = 256
+	DefaultIDBase       = 40000
+	DefaultIDs          = 1024
+)
+
+// DefaultRootBinds are the host paths visible, read-only, to sandboxed
+// programs: the usual toolchain and library locations. Missing ones are
+// skipped.
+var DefaultRootBinds = []string{
+	"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/libx32", "/opt",
+	"/etc/alternatives", "/etc/ld.so.cache", "/etc/java-*",
+}
+
+// Config holds host-wide sandbox settings.
+type Config struct {
+	// UID and GID are the first of IDs uids and gids handed out to runs when
+	// the judge itself runs as root. Concurrent runs never share one.
+	UID int
+	GID int
+	IDs int
+	// MaxProcesses bounds processes and threads per run (RLIMIT_NPROC).
+	MaxProcesses int
+	// TmpfsSizeKB is the size of the scratch tmpfs mounted at <Dir>/tmp.
+	TmpfsSizeKB int
+	// DisableNamespaces skips namespace creation on hosts that forbid it.
+	// Rlimits and the syscall filter are still applied.
+	DisableNamespaces bool
+
+	// RootBinds are glob patterns of host paths mounted read-only into the
+	// otherwise empty root of every run.
+	RootBinds []string
+}
+
+// DefaultConfig returns the settings used by the judge.
+func DefaultConfig() Config {
+	return Config{
+		UID:          DefaultIDBase,
+		GID:          DefaultIDBase,
+		IDs:          DefaultIDs,
+		MaxProcesses: DefaultMaxProcesses,
+		TmpfsSizeKB:  DefaultTmpfsSizeKB,
+		RootBinds:    DefaultRootBinds,
+	}
+}
+
+// Spec describes one program execution. Dir must already exist and contain
+// everything the program needs.
+type Spec struct {
+	Dir  string
+	Args []string
+	Env  []string
+
+	// Stdin is fed to the program; nil means an empty input.
+	Stdin io.Reader
+	// Stdout receives program output when set; otherwise output is captured
+	// into Result.Stdout up to OutputLimit bytes.
+	Stdout io.Writer
+	// CloseAfterStart is closed in the parent once the program has started,
+	// typically the parent's copies of pipe ends handed to the program.
+	CloseAfterStart []io.Closer
+
+	// TimeLimit bounds CPU time. WallTimeLimit bounds real time and defaults
+	// to twice the CPU limit plus one second.
+	TimeLimit     time.Duration
+	WallTimeLimit time.Duration
+	// MemoryLimitKB bounds the data segment of the program; zero disables it.
+	MemoryLimitKB int
+	OutputLimit   int
+}
+
+// Result reports how a program run ended.
+type Result struct {
+	ExitCode int
+	Signal   string
+	Stdout   string
+	Stderr   string
+
+	CPUTime  time.Duration
+	WallTime time.Duration
+	MemoryKB int
+
+	TimedOut       bool
+	MemoryExceeded bool
+	OutputExceeded bool
+}
+
+// Status maps the run onto the submission status constants. It returns an
+// empty string when the program exited normally, leaving the output check to
+// the caller.
+func (r *Result) Status() string {
+	switch {
+	case r.TimedOut:
+		return domain.STATUS_TIME_LIMIT
+	case r.MemoryExceeded:
+		return domain.STATUS_MEMORY_LIMIT
+	case r.ExitCode != 0 || r.Signal != "" || r.OutputExceeded:
+		return domain.STATUS_RUNTIME_ERROR
+	default:
+		return ""
+	}
+}
+
+// CompileStatus is Status for a compiler run: any failure is a compile error.
+func (r *Result) CompileStatus() string {
+	if r.Status() != "" {
+		return domain.STATUS_COMPILE_ERROR
+	}
+	return ""
+}
+
+// Sandbox runs programs according to a Config.
+type Sandbox struct {
+	cfg Config
+}
+
+func closeAll(closers []io.Closer) {
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
+// ids tracks the identities of running programs. It is shared by all
+// sandboxes so that two of them with the same Config never hand out the same
+// identity at once.
+var ids = struct {
+	sync.Mutex
+	inUse map[int]bool
+	next  int
+}{inUse: map[int]bool{}}
+
+// acquireID reserves one of the n uids starting at base, round robin so a
+// just-released identity is not reused straight away.
+func acquireID(base, n int) (int, error) {
+	n = max(n, 1)
+	ids.Lock()
+	defer ids.Unlock()
+	for i := range n {
+		id := base + (ids.next+i)%n
+		if !ids.inUse[id] {
+			ids.inUse[id] = true
+			ids.next = (ids.next + i + 1) % n
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("all %d sandbox identities are in use", n)
+}
+
+func releaseID(id int) {
+	ids.Lock()
+	defer ids.Unlock()
+	delete(ids.inUse, id)
+}
+
+func New(cfg Config) *Sandbox {
+	return &Sandbox{cfg: cfg}
+}
+
+// Run executes spec and waits for it to finish. Errors are only returned when
+// the sandbox itself fails; program failures are reported in the Result.
+func (s *Sandbox) Run(ctx context.Context, spec Spec) (*Result, error) {
+	started := false
+	defer func() {
+		if !started {
+			closeAll(spec.CloseAfterStart)
+		}
+	}()
+	if len(spec.Args) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	wall := spec.WallTimeLimit
+	if wall == 0 && spec.TimeLimit > 0 {
+		wall = 2*spec.TimeLimit + time.Second
+	}
+	if wall > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, wall)
+		defer cancel()
+	}
+
+	outLimit := spec.OutputLimit
+	if outLimit == 0 {
+		outLimit = DefaultOutputLimit
+	}
+	stdout := &limitedBuffer{limit: outLimit}
+	stderr := &limitedBuffer{limit: DefaultStderrLimit}
+
+	cmd, setupErr, err := s.command(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Dir = spec.Dir
+	cmd.Env = spec.Env
+	cmd.Stdin = spec.Stdin
+	cmd.Stdout = stdout
+	if spec.Stdout != nil {
+		cmd.Stdout = spec.Stdout
+	}
+	cmd.Stderr = stderr
+
+	start := time.Now()
+	runErr := cmd.Start()
+	started = true
+	closeAll(spec.CloseAfterStart)
+	if runErr == nil {
+		runErr = cmd.Wait()
+	}
+	wallTime := time.Since(start)
+
+	if msg := setupErr(); msg != "" {
+		return nil, errors.New("sandbox setup failed: " + msg)
+	}
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) && ctx.Err() == nil {
+		return nil, runErr
+	}
+
+	res := &Result{
+		Stdout:         stdout.String(),
+		Stderr:         stderr.String(),
+		WallTime:       wallTime,
+		OutputExceeded: stdout.exceeded,
+	}
+	if ps := cmd.ProcessState; ps != nil {
+		res.ExitCode = ps.ExitCode()
+		res.CPUTime = ps.UserTime() + ps.SystemTime()
+		res.MemoryKB = peakMemoryKB(ps)
+		res.Signal = exitSignal(ps)
+	}
+
+	res.TimedOut = ctx.Err() == context.DeadlineExceeded ||
+		res.Signal == cpuLimitSignal ||
+		(spec.TimeLimit > 0 && res.CPUTime > spec.TimeLimit)
+	res.MemoryExceeded = spec.MemoryLimitKB > 0 && res.MemoryKB > spec.MemoryLimitKB
+
+	return res, nil
+}
+
+// limitedBuffer keeps the first limit bytes written and discards the rest.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.exceeded = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,441 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// initArg marks a re-executed copy of the current binary that prepares the
+// sandbox from inside the new namespaces and then execs the real program.
+const initArg = "__codearena_sandbox_init__"
+
+// setupFD is the descriptor the init process reports setup errors on. It is
+// closed on exec, so an empty read means the program started.
+const setupFD = 3
+
+var cpuLimitSignal = syscall.SIGXCPU.String()
+
+// initConfig is passed from the parent to the init process.
+type initConfig struct {
+	CPUSeconds   uint64 `json:"cpu_seconds"`
+	DataBytes    uint64 `json:"data_bytes"`
+	MaxProcesses uint64 `json:"max_processes"`
+	TmpfsDir     string `json:"tmpfs_dir"`
+	TmpfsSizeKB  int    `json:"tmpfs_size_kb"`
+	DropToUID    int    `json:"drop_to_uid"`
+	DropToGID    int    `json:"drop_to_gid"`
+	DropPrivs    bool   `json:"drop_privs"`
+
+	// Root is an empty host directory the new root is built on. Binds are
+	// host paths mounted read-only at the same place, Links are symlinks
+	// recreated as they are and Dir is the only writable host path.
+	Root  string            `json:"root"`
+	Binds []string          `json:"binds"`
+	Links map[string]string `json:"links"`
+	Dir   string            `json:"dir"`
+}
+
+// devices are bound into the new root; everything else in /dev is absent.
+var devices = []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom"}
+
+var devLinks = map[string]string{
+	"/dev/fd":     "/proc/self/fd",
+	"/dev/stdin":  "/proc/self/fd/0",
+	"/dev/stdout": "/proc/self/fd/1",
+	"/dev/stderr": "/proc/self/fd/2",
+}
+
+func init() {
+	if len(os.Args) < 4 || os.Args[1] != initArg {
+		return
+	}
+	// Everything below must run on one thread: credentials and the seccomp
+	// filter are per-thread and only the calling thread survives exec.
+	runtime.LockOSThread()
+
+	report := os.NewFile(setupFD, "setup")
+	fail := func(err error) {
+		fmt.Fprint(report, err.Error())
+		os.Exit(127)
+	}
+
+	var cfg initConfig
+	if err := json.Unmarshal([]byte(os.Args[2]), &cfg); err != nil {
+		fail(err)
+	}
+	argv := os.Args[3:]
+
+	if err := cfg.apply(); err != nil {
+		fail(err)
+	}
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		fail(err)
+	}
+	unix.CloseOnExec(setupFD)
+	if err := installSeccomp(); err != nil {
+		fail(err)
+	}
+	fail(syscall.Exec(path, argv, os.Environ()))
+}
+
+// apply runs inside the new namespaces, before the program is executed.
+func (c initConfig) apply() error {
+	if c.Root != "" {
+		if err := c.pivot(); err != nil {
+			return err
+		}
+	}
+
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{unix.RLIMIT_CORE, 0},
+		{unix.RLIMIT_NOFILE, DefaultNoFile},
+		{unix.RLIMIT_FSIZE, DefaultOutputLimit},
+		{unix.RLIMIT_CPU, c.CPUSeconds},
+		{unix.RLIMIT_DATA, c.DataBytes},
+		{unix.RLIMIT_NPROC, c.MaxProcesses},
+	}
+	for _, l := range limits {
+		if l.value == 0 && l.resource != unix.RLIMIT_CORE {
+			continue
+		}
+		hard := l.value
+		if l.resource == unix.RLIMIT_CPU {
+			// SIGXCPU at the soft limit, SIGKILL a second later.
+			hard++
+		}
+		if err := unix.Setrlimit(l.resource, &unix.Rlimit{Cur: l.value, Max: hard}); err != nil {
+			return fmt.Errorf("setrlimit %d: %w", l.resource, err)
+		}
+	}
+
+	if c.DropPrivs {
+		// Raw per-thread calls are fine here: this thread execs next.
+		if _, _, errno := unix.RawSyscall(unix.SYS_SETGROUPS, 0, 0, 0); errno != 0 {
+			return fmt.Errorf("setgroups: %w", errno)
+		}
+		gid, uid := uintptr(c.DropToGID), uintptr(c.DropToUID)
+		if _, _, errno := unix.RawSyscall(unix.SYS_SETRESGID, gid, gid, gid); errno != 0 {
+			return fmt.Errorf("setresgid: %w", errno)
+		}
+		if _, _, errno := unix.RawSyscall(unix.SYS_SETRESUID, uid, uid, uid); errno != 0 {
+			return fmt.Errorf("setresuid: %w", errno)
+		}
+	}
+
+	return unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+}
+
+// pivot builds a root holding only the toolchain, a few devices and the run
+// directory, switches to it and detaches the host file system.
+func (c initConfig) pivot() error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	if err := unix.Mount("tmpfs", c.Root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=1m,mode=755"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+
+	for _, path := range append(c.Binds, devices...) {
+		if err := bind(path, filepath.Join(c.Root, path), true); err != nil {
+			return err
+		}
+	}
+	for path, target := range c.Links {
+		if err := symlink(target, filepath.Join(c.Root, path)); err != nil {
+			return err
+		}
+	}
+	for path, target := range devLinks {
+		if err := symlink(target, filepath.Join(c.Root, path)); err != nil {
+			return err
+		}
+	}
+
+	proc := filepath.Join(c.Root, "proc")
+	if err := os.MkdirAll(proc, 0o755); err != nil {
+		return err
+	}
+	if err := unix.Mount("proc", proc, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount proc: %w", err)
+	}
+
+	if err := bind(c.Dir, filepath.Join(c.Root, c.Dir), false); err != nil {
+		return err
+	}
+	opts := fmt.Sprintf("size=%dk,mode=1777", c.TmpfsSizeKB)
+	if err := unix.Mount("tmpfs", filepath.Join(c.Root, c.TmpfsDir), "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, opts); err != nil {
+		return fmt.Errorf("mount tmpfs: %w", err)
+	}
+
+	// pivot_root(".", ".") stacks the old root under the new one, so it can
+	// be detached without a directory to put it in.
+	if err := unix.Chdir(c.Root); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach old root: %w", err)
+	}
+	if err := unix.Mount("", "/", "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("remount root read-only: %w", err)
+	}
+	return unix.Chdir(c.Dir)
+}
+
+// bind mounts the host path src at dst, creating dst to match its type.
+func bind(src, dst string, readOnly bool) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		err = os.MkdirAll(dst, 0o755)
+	} else if err = os.MkdirAll(filepath.Dir(dst), 0o755); err == nil {
+		var f *os.File
+		if f, err = os.OpenFile(dst, os.O_CREATE|os.O_WRONLY, 0o644); err == nil {
+			f.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := unix.Mount(src, dst, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %w", src, err)
+	}
+	if !readOnly {
+		return nil
+	}
+	// A read-only remount must keep the flags the kernel locked on the
+	// source mount, or it fails inside a user namespace.
+	var st unix.Statfs_t
+	if err := unix.Statfs(dst, &st); err != nil {
+		return err
+	}
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY | unix.MS_NOSUID)
+	if fi.Mode()&os.ModeDevice == 0 {
+		flags |= unix.MS_NODEV
+	}
+	for locked, ms := range map[int64]uintptr{
+		unix.ST_NODEV:      unix.MS_NODEV,
+		unix.ST_NOEXEC:     unix.MS_NOEXEC,
+		unix.ST_NOATIME:    unix.MS_NOATIME,
+		unix.ST_NODIRATIME: unix.MS_NODIRATIME,
+		unix.ST_RELATIME:   unix.MS_RELATIME,
+	} {
+		if st.Flags&locked != 0 {
+			flags |= ms
+		}
+	}
+	if err := unix.Mount("", dst, "", flags, ""); err != nil {
+		return fmt.Errorf("remount %s read-only: %w", src, err)
+	}
+	return nil
+}
+
+func symlink(target, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.Symlink(target, path)
+}
+
+// command builds the re-exec of the current binary that wraps spec.Args. The
+// returned wait function also undoes the per-run setup on the host.
+func (s *Sandbox) command(ctx context.Context, spec Spec) (_ *exec.Cmd, _ func() string, err error) {
+	cfg := initConfig{
+		MaxProcesses: uint64(s.cfg.MaxProcesses),
+	}
+	if spec.TimeLimit > 0 {
+		// Round up; the exact CPU time is checked after the run.
+		cfg.CPUSeconds = uint64((spec.TimeLimit.Milliseconds()+999)/1000) + 1
+	}
+	if spec.MemoryLimitKB > 0 {
+		// Leave headroom so the overrun is observed rather than an allocation
+		// failure in the language runtime.
+		cfg.DataBytes = uint64(spec.MemoryLimitKB) * 1024 * 5 / 4
+	}
+
+	var cleanups []func()
+	cleanup := func() {
+		for _, f := range cleanups {
+			f()
+		}
+	}
+	defer func() {
+		if err != nil {
+			cleanup()
+		}
+	}()
+
+	root := os.Geteuid() == 0
+	attr := &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+	}
+	if !s.cfg.DisableNamespaces {
+		attr.Cloneflags = syscall.CLONE_NEWNS | syscall.CLONE_NEWNET | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+		if !root {
+			attr.Cloneflags |= syscall.CLONE_NEWUSER
+			attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Geteuid(), Size: 1}}
+			attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getegid(), Size: 1}}
+		}
+
+		if cfg.Dir, err = filepath.Abs(spec.Dir); err != nil {
+			return nil, nil, err
+		}
+		cfg.TmpfsDir = filepath.Join(cfg.Dir, "tmp")
+		if err := os.MkdirAll(cfg.TmpfsDir, 0o1777); err != nil {
+			return nil, nil, err
+		}
+		cfg.TmpfsSizeKB = s.cfg.TmpfsSizeKB
+		if cfg.Binds, cfg.Links, err = rootPaths(s.cfg.RootBinds); err != nil {
+			return nil, nil, err
+		}
+		// This stays empty on the host: the new root is a tmpfs mounted over
+		// it inside the program's mount namespace.
+		if cfg.Root, err = os.MkdirTemp("", "codearena-root-"); err != nil {
+			return nil, nil, err
+		}
+		cleanups = append(cleanups, func() { os.Remove(cfg.Root) })
+	}
+
+	if root && s.cfg.UID != 0 {
+		id, err := acquireID(s.cfg.UID, s.cfg.IDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.DropPrivs = true
+		cfg.DropToUID = id
+		cfg.DropToGID = s.cfg.GID + id - s.cfg.UID
+		// The identity owns this run's directory only while the run lasts,
+		// so no two programs ever share files or an identity.
+		cleanups = append(cleanups, func() {
+			if chownTree(spec.Dir, os.Geteuid(), os.Getegid()) == nil {
+				releaseID(id)
+			}
+		})
+		if err := chownTree(spec.Dir, cfg.DropToUID, cfg.DropToGID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	encoded, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, nil, err
+	}
+	args := append([]string{initArg, string(encoded)}, spec.Args...)
+	cmd := exec.CommandContext(ctx, self, args...)
+	cmd.SysProcAttr = attr
+	cmd.Cancel = func() error {
+		// Kill the whole process group, not just the direct child.
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd.ExtraFiles = []*os.File{w}
+
+	var setupErr []byte
+	done := make(chan struct{})
+	go func() {
+		setupErr, _ = io.ReadAll(r)
+		r.Close()
+		close(done)
+	}()
+
+	// The write end is only needed by the child; closing ours after the run
+	// lets the reader see EOF.
+	wait := func() string {
+		w.Close()
+		<-done
+		cleanup()
+		return string(setupErr)
+	}
+	return cmd, wait, nil
+}
+
+// rootPaths expands the RootBinds patterns into the existing paths to bind and
+// the symlinks among them, such as /bin on merged-/usr systems.
+func rootPaths(patterns []string) ([]string, map[string]string, error) {
+	var binds []string
+	links := map[string]string{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, path := range matches {
+			fi, err := os.Lstat(path)
+			if err != nil {
+				return nil, nil, err
+			}
+			if fi.Mode()&os.ModeSymlink == 0 {
+				binds = append(binds, path)
+				continue
+			}
+			if links[path], err = os.Readlink(path); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return binds, links, nil
+}
+
+// chownTree hands dir and everything in it to uid and gid. Symlinks are not
+// followed and hard-linked files are left alone, since they may be shared with
+// files outside dir.
+func chownTree(dir string, uid, gid int) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Nlink > 1 {
+				return nil
+			}
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+func peakMemoryKB(ps *os.ProcessState) int {
+	if ru, ok := ps.SysUsage().(*syscall.Rusage); ok {
+		return int(ru.Maxrss)
+	}
+	return 0
+}
+
+func exitSignal(ps *os.ProcessState) string {
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return ws.Signal().String()
+	}
+	return ""
+}
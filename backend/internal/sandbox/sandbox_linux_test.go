@@ -0,0 +1,145 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+	"golang.org/x/sys/unix"
+)
+
+func runShell(t *testing.T, script string, spec Spec) *Result {
+	t.Helper()
+	spec.Dir = t.TempDir()
+	spec.Args = []string{"/bin/sh", "-c", script}
+	spec.Env = []string{"PATH=/usr/bin:/bin"}
+	if spec.TimeLimit == 0 {
+		spec.TimeLimit = 2 * time.Second
+	}
+
+	res, err := New(DefaultConfig()).Run(context.Background(), spec)
+	require.NoError(t, err)
+	return res
+}
+
+func TestSandbox_Run(t *testing.T) {
+	t.Run("captures output and exit code", func(t *testing.T) {
+		res := runShell(t, "read x; echo got $x; echo oops >&2; exit 3", Spec{Stdin: strings.NewReader("42\n")})
+
+		assert.Equal(t, "got 42\n", res.Stdout)
+		assert.Equal(t, "oops\n", res.Stderr)
+		assert.Equal(t, 3, res.ExitCode)
+		assert.Equal(t, domain.STATUS_RUNTIME_ERROR, res.Status())
+	})
+
+	t.Run("clean exit has no status", func(t *testing.T) {
+		res := runShell(t, "echo ok", Spec{})
+
+		assert.Equal(t, "", res.Status())
+		assert.Equal(t, "", res.CompileStatus())
+	})
+
+	t.Run("cpu limit", func(t *testing.T) {
+		res := runShell(t, "while :; do :; done", Spec{TimeLimit: 500 * time.Millisecond})
+
+		assert.True(t, res.TimedOut)
+		assert.Equal(t, domain.STATUS_TIME_LIMIT, res.Status())
+	})
+
+	t.Run("wall clock limit", func(t *testing.T) {
+		res := runShell(t, "sleep 10", Spec{TimeLimit: 200 * time.Millisecond, WallTimeLimit: 500 * time.Millisecond})
+
+		assert.True(t, res.TimedOut)
+		assert.Less(t, res.WallTime, 5*time.Second)
+	})
+
+	t.Run("output limit", func(t *testing.T) {
+		res := runShell(t, "yes | head -c 4096", Spec{OutputLimit: 100})
+
+		assert.True(t, res.OutputExceeded)
+		assert.Len(t, res.Stdout, 100)
+		assert.Equal(t, domain.STATUS_RUNTIME_ERROR, res.Status())
+	})
+
+	t.Run("compile failures are compile errors", func(t *testing.T) {
+		res := runShell(t, "exit 1", Spec{})
+
+		assert.Equal(t, domain.STATUS_COMPILE_ERROR, res.CompileStatus())
+	})
+
+	t.Run("no network and unprivileged", func(t *testing.T) {
+		if os.Geteuid() != 0 {
+			t.Skip("identity drop only applies when the judge runs as root")
+		}
+		res := runShell(t, "tail -n +3 /proc/self/net/dev | cut -d: -f1 | tr -d ' '; id -u; touch tmp/x && echo scratch-ok", Spec{})
+
+		lines := strings.Split(res.Stdout, "\n")
+		require.Len(t, lines, 4, res.Stderr)
+		assert.Equal(t, "lo", lines[0])
+		uid, err := strconv.Atoi(lines[1])
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, uid, DefaultIDBase)
+		assert.Less(t, uid, DefaultIDBase+DefaultIDs)
+		assert.Equal(t, "scratch-ok", lines[2])
+	})
+
+	t.Run("no new namespaces", func(t *testing.T) {
+		if _, err := os.Stat("/usr/bin/python3"); err != nil {
+			t.Skip("python3 is not installed")
+		}
+		// clone(CLONE_NEWUSER|SIGCHLD) and clone3(NULL, 0) print their result and errno
+		script := fmt.Sprintf(`python3 -c "import ctypes
+l = ctypes.CDLL(None, use_errno=True)
+print(l.syscall(%d, 0x10000011, 0, 0, 0, 0), ctypes.get_errno())
+print(l.syscall(%d, 0, 0), ctypes.get_errno())"`, unix.SYS_CLONE, unix.SYS_CLONE3)
+		res := runShell(t, script, Spec{})
+
+		assert.Equal(t, fmt.Sprintf("-1 %d\n-1 %d\n", unix.EPERM, unix.ENOSYS), res.Stdout, res.Stderr)
+	})
+
+	t.Run("host file system is hidden", func(t *testing.T) {
+		secret := filepath.Join(t.TempDir(), "answer.txt")
+		require.NoError(t, os.WriteFile(secret, []byte("42"), 0o644))
+
+		res := runShell(t, "echo ok >/dev/null && test ! -e "+secret+" && echo hidden; test -e /home || echo no-home; touch /usr/x 2>/dev/null || echo read-only", Spec{})
+
+		assert.Equal(t, "hidden\nno-home\nread-only\n", res.Stdout, res.Stderr)
+	})
+}
+
+func TestSandbox_RunIdentities(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("identities are only handed out when the judge runs as root")
+	}
+	dir := t.TempDir()
+	sb := New(DefaultConfig())
+	run := func() string {
+		res, err := sb.Run(context.Background(), Spec{
+			Dir:       dir,
+			Args:      []string{"/bin/sh", "-c", "touch out && id -u"},
+			Env:       []string{"PATH=/usr/bin:/bin"},
+			TimeLimit: 2 * time.Second,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "", res.Status(), res.Stderr)
+		return res.Stdout
+	}
+
+	assert.NotEqual(t, run(), run())
+
+	// The directory is handed back, so the next program cannot inherit it.
+	for _, path := range []string{dir, filepath.Join(dir, "out")} {
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(os.Geteuid()), fi.Sys().(*syscall.Stat_t).Uid, path)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// cpuLimitSignal never matches: there is no RLIMIT_CPU here.
+const cpuLimitSignal = "-"
+
+// command runs the program directly. Only the wall-clock limit applies on
+// this platform; it exists so the judge can be developed outside Linux.
+func (s *Sandbox) command(ctx context.Context, spec Spec) (*exec.Cmd, func() string, error) {
+	cmd := exec.CommandContext(ctx, spec.Args[0], spec.Args[1:]...)
+	return cmd, func() string { return "" }, nil
+}
+
+func peakMemoryKB(ps *os.ProcessState) int {
+	return 0
+}
+
+func exitSignal(ps *os.ProcessState) string {
+	return ""
+}
@@ -0,0 +1,15 @@
+//go:build linux && !amd64 && !arm64
+
+package sandbox
+
+// No syscall table is maintained for this architecture; the filter is skipped.
+const auditArch = 0
+
+const syscallABIMask = 0
+
+var deniedSyscalls []uintptr
+
+const (
+	sysClone  = 0
+	sysClone3 = 0
+)
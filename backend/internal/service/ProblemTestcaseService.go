@@ -1,31 +1,171 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/sudankdk/codearena/configs"
 	"github.com/sudankdk/codearena/internal/domain"
 	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/harness"
 	"github.com/sudankdk/codearena/internal/helper"
+	"github.com/sudankdk/codearena/internal/judge"
 	"github.com/sudankdk/codearena/internal/mapper"
+	"github.com/sudankdk/codearena/internal/problempkg"
 	"github.com/sudankdk/codearena/internal/repo"
+	"github.com/sudankdk/codearena/internal/storage"
+)
+
+var (
+	ErrInvalidLimits     = errors.New("invalid resource limits")
+	ErrInvalidChecker    = errors.New("invalid checker")
+	ErrInvalidInteractor = errors.New("invalid interactor")
+	ErrInvalidSubtasks   = errors.New("invalid subtasks")
+	ErrInvalidValidator  = errors.New("invalid input validator")
+	ErrInvalidSolution   = errors.New("invalid reference solution")
+	ErrInvalidGenerator  = errors.New("invalid test generator")
 )
 
 type ProblemTestService struct {
-	Repo     repo.ProblemsRepo
-	TestRepo repo.TestcaseRepo
-	Auth     helper.Auth
-	Config   configs.AppConfigs
+	Repo         repo.ProblemsRepo
+	TestRepo     repo.TestcaseRepo
+	LanguageRepo repo.LanguageRepo
+	UserRepo     repo.UserRepo
+	RevisionRepo repo.RevisionRepo
+	Auth         helper.Auth
+	Config       configs.AppConfigs
+	Data         storage.TestData
+	Attachments  storage.Attachments
+}
+
+func (p *ProblemTestService) CreateProblem(dto dto.CreateProblemDTO, author uuid.UUID) error {
+	_, err := p.createProblem(dto, author, "problem created")
+	return err
 }
 
-func (p *ProblemTestService) CreateProblem(dto dto.CreateProblemDTO) error {
+// ImportPackage creates a problem from a problem package, see package
+// problempkg.
+func (p *ProblemTestService) ImportPackage(r io.ReaderAt, size int64, author uuid.UUID) (*domain.Problem, error) {
+	languages, err := p.LanguageRepo.ListLanguages(true)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := problempkg.Read(r, size, languages)
+	if err != nil {
+		return nil, err
+	}
+	return p.createProblem(pkg.Problem, author, "package imported")
+}
+
+// ExportPackage writes a problem with all its tests and programs to w as a
+// problem package.
+func (p *ProblemTestService) ExportPackage(id string, w io.Writer) (*domain.Problem, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	problem, err := p.Repo.GetProblemByID(problemID, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Data.LoadAll(context.Background(), problem.TestCases); err != nil {
+		return nil, err
+	}
+	programs, err := p.Repo.ListPrograms(problemID)
+	if err != nil {
+		return nil, err
+	}
+	languages, err := p.LanguageRepo.ListLanguages(true)
+	if err != nil {
+		return nil, err
+	}
+	return problem, problempkg.Write(w, problem, programs, languages)
+}
+
+// createProblem validates and stores a problem with its tests and programs,
+// and records its first revision with the given summary.
+func (p *ProblemTestService) createProblem(dto dto.CreateProblemDTO, author uuid.UUID, summary string) (*domain.Problem, error) {
+	for _, bp := range dto.Boilerplates {
+		if _, err := resolveLanguage(p.LanguageRepo, bp.Language); err != nil {
+			return nil, fmt.Errorf("boilerplate language %q: %w", bp.Language, err)
+		}
+	}
+	if err := p.validateLimits(dto.TimeLimitMs, dto.MemoryLimitMB, dto.LanguageLimits); err != nil {
+		return nil, err
+	}
+	if err := p.validateChecker(dto.Checker); err != nil {
+		return nil, err
+	}
+	if dto.Type == domain.PROBLEM_INTERACTIVE && dto.Interactor == nil {
+		return nil, fmt.Errorf("%w: interactive problems need an interactor", ErrInvalidInteractor)
+	}
+	if err := p.validateInteractor(dto.Interactor); err != nil {
+		return nil, err
+	}
+	if err := validateSubtasks(dto.Subtasks); err != nil {
+		return nil, err
+	}
+	if dto.Validator != nil {
+		if err := p.validateValidator(*dto.Validator); err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[string]bool, len(dto.Solutions))
+	for _, s := range dto.Solutions {
+		if names[s.Name] {
+			return nil, fmt.Errorf("%w: duplicate name %q", ErrInvalidSolution, s.Name)
+		}
+		names[s.Name] = true
+		if err := p.validateSolution(s); err != nil {
+			return nil, err
+		}
+	}
+	generators := make(map[string]bool, len(dto.Generators))
+	for _, g := range dto.Generators {
+		if generators[g.Name] {
+			return nil, fmt.Errorf("%w: duplicate name %q", ErrInvalidGenerator, g.Name)
+		}
+		generators[g.Name] = true
+		if err := p.validateGenerator(g); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateScript(dto.GenerationScript); err != nil {
+		return nil, err
+	}
 	problem := mapper.ToDomain(dto)
+	problem.Status = domain.PROBLEM_DRAFT
+	if dto.Type == domain.PROBLEM_FUNCTION {
+		sig, err := parseSignature(dto.Signature)
+		if err != nil {
+			return nil, err
+		}
+		problem.Signature = sig.String()
+		generated, err := p.generateBoilerplates(sig, dto.Boilerplates)
+		if err != nil {
+			return nil, err
+		}
+		problem.Boilerplates = append(problem.Boilerplates, generated...)
+	} else if dto.Signature != "" {
+		return nil, fmt.Errorf("%w: only function problems have a signature", harness.ErrInvalidSignature)
+	}
+	for i := range problem.TestCases {
+		if err := p.Data.Save(context.Background(), &problem.TestCases[i]); err != nil {
+			return nil, err
+		}
+	}
 	if err := p.Repo.CreateProblem(&problem); err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	if err := recordRevision(p.Repo, p.RevisionRepo, problem.ID, author, summary); err != nil {
+		return nil, err
+	}
+	return &problem, nil
 }
 
 func (p *ProblemTestService) ListProblems(q dto.ProblemListQueryDTO) (*dto.ProblemListResponse, error) {
@@ -37,6 +177,10 @@ func (p *ProblemTestService) ListProblems(q dto.ProblemListQueryDTO) (*dto.Probl
 
 	res := make([]dto.ProblemResponseDTO, 0, len(problems))
 	for _, problem := range problems {
+		problem.TestCases = domain.SampleTestCases(problem.TestCases)
+		if err := p.Data.LoadAll(context.Background(), problem.TestCases); err != nil {
+			return nil, err
+		}
 		res = append(res, mapper.ToProblemResponse(problem))
 	}
 
@@ -48,47 +192,261 @@ func (p *ProblemTestService) ListProblems(q dto.ProblemListQueryDTO) (*dto.Probl
 	}, nil
 }
 
-func (p *ProblemTestService) GetProblemById(id string, includeTc bool) (domain.Problem, error) {
-
-	problem, err := p.Repo.GetProblemByID(uuid.MustParse(id), includeTc)
+// GetProblemById returns a problem for the public statement endpoints; only
+// sample test cases are included. Problems that are not public yet are only
+// returned to admins.
+func (p *ProblemTestService) GetProblemById(id string, includeTc, admin bool) (domain.Problem, error) {
+	problemID, err := uuid.Parse(id)
 	if err != nil {
+		return domain.Problem{}, errors.New("invalid problem ID")
+	}
+	if err := checkVisible(p.Repo, problemID, admin); err != nil {
+		return domain.Problem{}, err
+	}
+	problem, err := p.Repo.GetProblemByID(problemID, includeTc)
+	if err != nil {
+		return domain.Problem{}, err
+	}
+	problem.TestCases = domain.SampleTestCases(problem.TestCases)
+	if err := p.Data.LoadAll(context.Background(), problem.TestCases); err != nil {
 		return domain.Problem{}, err
 	}
 	return *problem, nil
 }
 
-func (p *ProblemTestService) GetProblemBySlug(slug string, includeTc bool) (domain.Problem, error) {
+// GetProblemBySlug returns a problem for the public statement endpoints; only
+// sample test cases are included. Problems that are not public yet are only
+// returned to admins.
+func (p *ProblemTestService) GetProblemBySlug(slug string, includeTc, admin bool) (domain.Problem, error) {
 
 	problem, err := p.Repo.GetProblemBySlug(slug, includeTc)
 	if err != nil {
 		return domain.Problem{}, err
 	}
+	if err := checkVisible(p.Repo, problem.ID, admin); err != nil {
+		return domain.Problem{}, err
+	}
+	problem.TestCases = domain.SampleTestCases(problem.TestCases)
+	if err := p.Data.LoadAll(context.Background(), problem.TestCases); err != nil {
+		return domain.Problem{}, err
+	}
 	return *problem, nil
 }
 
-func (p *ProblemTestService) CreateTestCase(dto dto.CreateTestCaseWithProblemDTO) error {
+func (p *ProblemTestService) CreateTestCase(dto dto.CreateTestCaseWithProblemDTO, author uuid.UUID) error {
 	fmt.Println(dto.ProblemID.ID())
 	_, err := p.Repo.GetProblemByID(dto.ProblemID, false)
 	if err != nil {
 		return errors.New("problem does not exist")
 	}
 
-	return p.TestRepo.CreateTestcase(domain.TestCases{
-		Input:     dto.Input,
-		Expected:  dto.Expected,
-		ProblemID: dto.ProblemID,
-	})
+	var order int
+	if dto.OrderIndex != nil {
+		order = *dto.OrderIndex
+	} else if order, err = p.TestRepo.NextOrderIndex(dto.ProblemID); err != nil {
+		return err
+	}
+
+	tc := domain.TestCases{
+		Input:       dto.Input,
+		Expected:    dto.Expected,
+		ProblemID:   dto.ProblemID,
+		IsSample:    dto.IsSample,
+		OrderIndex:  order,
+		Explanation: dto.Explanation,
+		Subtask:     dto.Subtask,
+	}
+	if err := p.Data.Save(context.Background(), &tc); err != nil {
+		return err
+	}
+	if err := p.TestRepo.CreateTestcase(tc); err != nil {
+		return err
+	}
+	return recordRevision(p.Repo, p.RevisionRepo, dto.ProblemID, author, "test added")
 }
 
-func (p *ProblemTestService) ListTestCasesOfProblems(id string) ([]domain.TestCases, error) {
-	testcases, err := p.TestRepo.ListTestcase(uuid.MustParse(id))
+// UploadTestCases adds the tests of a test archive to a problem, or replaces
+// its tests with them, in one transaction. See problempkg.ReadTests for the
+// archive layout.
+func (p *ProblemTestService) UploadTestCases(id string, r io.ReaderAt, size int64, mode string, author uuid.UUID) (*dto.TestUploadDTO, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	if mode == "" {
+		mode = dto.TEST_UPLOAD_APPEND
+	}
+	if mode != dto.TEST_UPLOAD_APPEND && mode != dto.TEST_UPLOAD_REPLACE {
+		return nil, fmt.Errorf("%w: mode must be append or replace", problempkg.ErrInvalidPackage)
+	}
+	problem, err := p.Repo.GetProblemByID(problemID, false)
+	if err != nil {
+		return nil, errors.New("problem not found")
+	}
+	files, err := problempkg.ReadTests(r, size)
 	if err != nil {
+		return nil, err
+	}
+
+	subtasks := make(map[int]bool, len(problem.Subtasks))
+	for _, s := range problem.Subtasks {
+		subtasks[s.Index] = true
+	}
+	tests := make([]domain.TestCases, len(files))
+	for i, f := range files {
+		if f.Subtask > 0 && !subtasks[f.Subtask] {
+			return nil, fmt.Errorf("%w: %s is in subtask %d, which the problem does not have", ErrInvalidSubtasks, f.Name, f.Subtask)
+		}
+		tests[i] = domain.TestCases{
+			Input:    f.Input,
+			Expected: f.Expected,
+			IsSample: f.IsSample,
+			Subtask:  f.Subtask,
+		}
+		if err := p.Data.Save(context.Background(), &tests[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	removed, err := p.TestRepo.ImportTestcases(problemID, tests, mode == dto.TEST_UPLOAD_REPLACE)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordRevision(p.Repo, p.RevisionRepo, problemID, author, "tests uploaded"); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Name
+	}
+	return uploadSummary(mode, removed, tests, names), nil
+}
+
+// uploadSummary reports the tests added by an upload; names are their names
+// in the archive or script.
+func uploadSummary(mode string, removed int64, tests []domain.TestCases, names []string) *dto.TestUploadDTO {
+	summary := &dto.TestUploadDTO{Mode: mode, Added: len(tests), Removed: removed}
+	for i, tc := range tests {
+		if tc.IsSample {
+			summary.Samples++
+		}
+		summary.Tests = append(summary.Tests, dto.UploadedTestDTO{
+			ID:            tc.ID.String(),
+			Name:          names[i],
+			OrderIndex:    tc.OrderIndex,
+			IsSample:      tc.IsSample,
+			Subtask:       tc.Subtask,
+			InputBytes:    tc.InputSize,
+			ExpectedBytes: tc.ExpectedSize,
+			GeneratedBy:   tc.GeneratedBy,
+		})
+	}
+	return summary
+}
+
+// ListTestCasesOfProblems returns the sample test cases of a problem.
+func (p *ProblemTestService) ListTestCasesOfProblems(id string, admin bool) ([]domain.TestCases, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return []domain.TestCases{}, errors.New("invalid problem ID")
+	}
+	if err := checkVisible(p.Repo, problemID, admin); err != nil {
 		return []domain.TestCases{}, err
 	}
-	return testcases, nil
+	samples, err := p.TestRepo.ListSampleTestcases(problemID)
+	if err != nil {
+		return []domain.TestCases{}, err
+	}
+	return samples, p.Data.LoadAll(context.Background(), samples)
 }
 
-func (p *ProblemTestService) UpdateProblem(id string, dto dto.UpdateProblemDTO) error {
+// ListAllTestCasesOfProblems returns every test case, hidden ones included.
+// It is only exposed to admins. Test data is left in the store; the tests
+// carry previews and sizes, and OpenTestFile streams the full files.
+func (p *ProblemTestService) ListAllTestCasesOfProblems(id string) ([]domain.TestCases, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return []domain.TestCases{}, errors.New("invalid problem ID")
+	}
+	return p.TestRepo.ListTestcase(problemID)
+}
+
+// OpenTestFile streams the input or expected output of a test, named by
+// file "input" or "expected", and returns its size.
+func (p *ProblemTestService) OpenTestFile(id, file string) (io.ReadCloser, int64, error) {
+	testCaseID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, 0, errors.New("invalid testcase ID")
+	}
+	tc, err := p.TestRepo.GetTestcase(testCaseID)
+	if err != nil {
+		return nil, 0, err
+	}
+	hash, size := tc.InputHash, tc.InputSize
+	if file == "expected" {
+		hash, size = tc.ExpectedHash, tc.ExpectedSize
+	}
+	r, err := p.Data.Open(context.Background(), hash)
+	if err != nil {
+		return nil, 0, err
+	}
+	return r, size, nil
+}
+
+func (p *ProblemTestService) UpdateTestCase(id string, dto dto.UpdateTestCaseDTO, author uuid.UUID) error {
+	testCaseID, err := uuid.Parse(id)
+	if err != nil {
+		return errors.New("invalid testcase ID")
+	}
+
+	// Build updates map
+	updates := make(map[string]interface{})
+	if dto.Input != nil {
+		f, err := p.Data.Put(context.Background(), *dto.Input)
+		if err != nil {
+			return err
+		}
+		updates["input_hash"], updates["input_size"], updates["input_preview"] = f.Hash, f.Size, f.Preview
+	}
+	if dto.Expected != nil {
+		f, err := p.Data.Put(context.Background(), *dto.Expected)
+		if err != nil {
+			return err
+		}
+		updates["expected_hash"], updates["expected_size"], updates["expected_preview"] = f.Hash, f.Size, f.Preview
+	}
+	if dto.IsSample != nil {
+		updates["is_sample"] = *dto.IsSample
+	}
+	if dto.OrderIndex != nil {
+		updates["order_index"] = *dto.OrderIndex
+	}
+	if dto.Explanation != nil {
+		updates["explanation"] = *dto.Explanation
+	}
+	if dto.Subtask != nil {
+		if *dto.Subtask < 0 {
+			return fmt.Errorf("%w: subtask must not be negative", ErrInvalidSubtasks)
+		}
+		updates["subtask"] = *dto.Subtask
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := p.TestRepo.UpdateTestcase(testCaseID, updates); err != nil {
+		return err
+	}
+	tc, err := p.TestRepo.GetTestcase(testCaseID)
+	if err != nil {
+		return err
+	}
+	return recordRevision(p.Repo, p.RevisionRepo, tc.ProblemID, author, "test updated")
+}
+
+// UpdateProblem applies the given changes to a problem. A revision is
+// recorded when they change its statement, limits or subtasks.
+func (p *ProblemTestService) UpdateProblem(id string, dto dto.UpdateProblemDTO, author uuid.UUID) error {
 	problemID, err := uuid.Parse(id)
 	if err != nil {
 		return errors.New("invalid problem ID")
@@ -108,15 +466,433 @@ func (p *ProblemTestService) UpdateProblem(id string, dto dto.UpdateProblemDTO)
 	if dto.Difficulty != "" {
 		updates["difficulty"] = dto.Difficulty
 	}
+	if dto.TimeLimitMs != 0 {
+		updates["time_limit_ms"] = dto.TimeLimitMs
+	}
+	if dto.MemoryLimitMB != 0 {
+		updates["memory_limit_mb"] = dto.MemoryLimitMB
+	}
+
+	if err := p.validateLimits(dto.TimeLimitMs, dto.MemoryLimitMB, nil); err != nil {
+		return err
+	}
+	if dto.LanguageLimits != nil {
+		if err := p.validateLimits(0, 0, *dto.LanguageLimits); err != nil {
+			return err
+		}
+	}
+	if dto.Checker != nil {
+		if err := p.validateChecker(dto.Checker); err != nil {
+			return err
+		}
+		updates["checker_type"] = dto.Checker.Type
+		updates["checker_abs_epsilon"] = dto.Checker.AbsEpsilon
+		updates["checker_rel_epsilon"] = dto.Checker.RelEpsilon
+	}
+	if err := p.validateInteractor(dto.Interactor); err != nil {
+		return err
+	}
+	if v := dto.Validator; v != nil && v.Code != "" {
+		if err := p.validateValidator(*v); err != nil {
+			return err
+		}
+	}
+	if dto.Subtasks != nil {
+		if err := validateSubtasks(*dto.Subtasks); err != nil {
+			return err
+		}
+	}
+	if dto.GenerationScript != nil {
+		if err := validateScript(*dto.GenerationScript); err != nil {
+			return err
+		}
+		updates["generation_script"] = *dto.GenerationScript
+	}
+	if dto.Type != "" {
+		updates["type"] = dto.Type
+	}
+	var boilerplates []domain.BoilerPlate
+	if dto.Type == domain.PROBLEM_FUNCTION || dto.Signature != "" {
+		if boilerplates, err = p.updateSignature(problemID, dto, updates); err != nil {
+			return err
+		}
+	} else if dto.Type != "" {
+		updates["signature"] = ""
+	}
+	if dto.Type == domain.PROBLEM_INTERACTIVE && dto.Interactor == nil {
+		if _, err := p.Repo.GetProgram(problemID, domain.PROGRAM_INTERACTOR, ""); err != nil {
+			return fmt.Errorf("%w: interactive problems need an interactor", ErrInvalidInteractor)
+		}
+	}
 
 	// Update problem
 	if err := p.Repo.UpdateProblem(problemID, updates); err != nil {
 		return err
 	}
 
-	// Handle test cases and boilerplates updates if needed
-	// For now, we'll keep this simple - you can extend to handle nested updates
+	if dto.LanguageLimits != nil {
+		if err := p.Repo.ReplaceLanguageLimits(problemID, mapper.ToLanguageLimits(*dto.LanguageLimits)); err != nil {
+			return err
+		}
+	}
+
+	if c := dto.Checker; c != nil {
+		if c.Type == domain.CHECKER_CUSTOM {
+			err = p.Repo.SaveProgram(&domain.ProblemProgram{
+				ProblemID: problemID,
+				Role:      domain.PROGRAM_CHECKER,
+				Language:  c.Language,
+				Code:      c.Code,
+			})
+		} else {
+			err = p.Repo.DeleteProgram(problemID, domain.PROGRAM_CHECKER, "")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if dto.Subtasks != nil {
+		if err := p.Repo.ReplaceSubtasks(problemID, mapper.ToSubtasks(*dto.Subtasks)); err != nil {
+			return err
+		}
+	}
+
+	if boilerplates != nil {
+		if err := p.Repo.ReplaceBoilerplates(problemID, boilerplates); err != nil {
+			return err
+		}
+	}
+
+	if i := dto.Interactor; i != nil {
+		err = p.Repo.SaveProgram(&domain.ProblemProgram{
+			ProblemID: problemID,
+			Role:      domain.PROGRAM_INTERACTOR,
+			Language:  i.Language,
+			Code:      i.Code,
+		})
+	} else if dto.Type == domain.PROBLEM_STANDARD {
+		err = p.Repo.DeleteProgram(problemID, domain.PROGRAM_INTERACTOR, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	if v := dto.Validator; v != nil {
+		if v.Code != "" {
+			err = p.Repo.SaveProgram(&domain.ProblemProgram{
+				ProblemID: problemID,
+				Role:      domain.PROGRAM_VALIDATOR,
+				Language:  v.Language,
+				Code:      v.Code,
+			})
+		} else {
+			err = p.Repo.DeleteProgram(problemID, domain.PROGRAM_VALIDATOR, "")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return recordRevision(p.Repo, p.RevisionRepo, problemID, author, "problem updated")
+}
+
+// validateLimits rejects negative limits and overrides for languages that
+// are not in the registry. Zero limits mean "unchanged" or "default".
+func (p *ProblemTestService) validateLimits(timeLimitMs, memoryLimitMB int, overrides []dto.LanguageLimitDTO) error {
+	if timeLimitMs < 0 || memoryLimitMB < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidLimits)
+	}
+	seen := make(map[string]bool, len(overrides))
+	for _, l := range overrides {
+		if l.TimeLimitMs < 0 || l.MemoryLimitMB < 0 {
+			return fmt.Errorf("%w: limits must not be negative", ErrInvalidLimits)
+		}
+		if seen[l.Language] {
+			return fmt.Errorf("%w: duplicate override for %q", ErrInvalidLimits, l.Language)
+		}
+		seen[l.Language] = true
+		if _, err := resolveLanguage(p.LanguageRepo, l.Language); err != nil {
+			return fmt.Errorf("limit override language %q: %w", l.Language, err)
+		}
+	}
+	return nil
+}
+
+// validateChecker checks a checker choice; nil keeps the default.
+func (p *ProblemTestService) validateChecker(c *dto.CheckerDTO) error {
+	if c == nil {
+		return nil
+	}
+	spec := judge.CheckerSpec{
+		Type:       c.Type,
+		AbsEpsilon: c.AbsEpsilon,
+		RelEpsilon: c.RelEpsilon,
+		Program:    c.Code,
+	}
+	if c.Type == domain.CHECKER_CUSTOM {
+		lang, err := resolveLanguage(p.LanguageRepo, c.Language)
+		if err != nil {
+			return fmt.Errorf("checker language %q: %w", c.Language, err)
+		}
+		spec.Language = *lang
+	}
+	if err := judge.ValidateChecker(spec); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidChecker, err)
+	}
+	return nil
+}
+
+// validateInteractor checks an uploaded interactor; nil means none was sent.
+func (p *ProblemTestService) validateInteractor(i *dto.ProgramDTO) error {
+	if i == nil {
+		return nil
+	}
+	lang, err := resolveLanguage(p.LanguageRepo, i.Language)
+	if err != nil {
+		return fmt.Errorf("interactor language %q: %w", i.Language, err)
+	}
+	if err := judge.ValidateInteractor(judge.InteractorSpec{Program: i.Code, Language: *lang}); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInteractor, err)
+	}
+	return nil
+}
+
+// validateValidator checks an uploaded input validator.
+func (p *ProblemTestService) validateValidator(v dto.ProgramDTO) error {
+	lang, err := resolveLanguage(p.LanguageRepo, v.Language)
+	if err != nil {
+		return fmt.Errorf("validator language %q: %w", v.Language, err)
+	}
+	if err := judge.ValidateValidator(judge.ValidatorSpec{Program: v.Code, Language: *lang}); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidValidator, err)
+	}
+	return nil
+}
+
+// validateSolution checks a reference solution.
+func (p *ProblemTestService) validateSolution(s dto.SolutionDTO) error {
+	switch {
+	case strings.TrimSpace(s.Name) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidSolution)
+	case strings.TrimSpace(s.Code) == "":
+		return fmt.Errorf("%w: %s has no code", ErrInvalidSolution, s.Name)
+	case !slices.Contains(domain.SolutionOutcomes, s.Expected):
+		return fmt.Errorf("%w: %s: expected must be one of %s", ErrInvalidSolution, s.Name, strings.Join(domain.SolutionOutcomes, ", "))
+	}
+	if _, err := resolveLanguage(p.LanguageRepo, s.Language); err != nil {
+		return fmt.Errorf("solution %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+// ListSolutions returns the reference solutions of a problem.
+func (p *ProblemTestService) ListSolutions(id string) ([]domain.ProblemProgram, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	programs, err := p.Repo.ListPrograms(problemID)
+	if err != nil {
+		return nil, err
+	}
+	solutions := make([]domain.ProblemProgram, 0, len(programs))
+	for _, program := range programs {
+		if program.Role == domain.PROGRAM_SOLUTION {
+			solutions = append(solutions, program)
+		}
+	}
+	return solutions, nil
+}
+
+// SaveSolution adds a reference solution to a problem, replacing the one
+// with the same name.
+func (p *ProblemTestService) SaveSolution(id string, s dto.SolutionDTO) (*domain.ProblemProgram, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	if err := p.validateSolution(s); err != nil {
+		return nil, err
+	}
+	if _, err := p.Repo.GetProblemByID(problemID, false); err != nil {
+		return nil, errors.New("problem not found")
+	}
+	program := mapper.ToSolution(s)
+	program.ProblemID = problemID
+	if err := p.Repo.SaveProgram(&program); err != nil {
+		return nil, err
+	}
+	return &program, nil
+}
+
+func (p *ProblemTestService) DeleteSolution(id, name string) error {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return errors.New("invalid problem ID")
+	}
+	if _, err := p.Repo.GetProgram(problemID, domain.PROGRAM_SOLUTION, name); err != nil {
+		return errors.New("solution not found")
+	}
+	return p.Repo.DeleteProgram(problemID, domain.PROGRAM_SOLUTION, name)
+}
+
+// validateGenerator checks a test generator.
+func (p *ProblemTestService) validateGenerator(g dto.GeneratorDTO) error {
+	switch {
+	case !problempkg.ValidGeneratorName(g.Name):
+		return fmt.Errorf("%w: name %q may only contain letters, digits, '.', '_' and '-'", ErrInvalidGenerator, g.Name)
+	case strings.TrimSpace(g.Code) == "":
+		return fmt.Errorf("%w: %s has no code", ErrInvalidGenerator, g.Name)
+	}
+	if _, err := resolveLanguage(p.LanguageRepo, g.Language); err != nil {
+		return fmt.Errorf("generator %s: %w", g.Name, err)
+	}
+	return nil
+}
+
+// validateScript checks the syntax of a generation script; an empty script
+// has nothing to check.
+func validateScript(script string) error {
+	if strings.TrimSpace(script) == "" {
+		return nil
+	}
+	_, err := problempkg.ParseScript(script)
+	return err
+}
+
+// GetGeneration returns the generation script and generators of a problem.
+func (p *ProblemTestService) GetGeneration(id string) (*dto.GenerationDTO, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	problem, err := p.Repo.GetProblemByID(problemID, false)
+	if err != nil {
+		return nil, errors.New("problem not found")
+	}
+	programs, err := p.Repo.ListPrograms(problemID)
+	if err != nil {
+		return nil, err
+	}
+	out := &dto.GenerationDTO{Script: problem.GenerationScript, Generators: []dto.GeneratorDTO{}}
+	for _, program := range programs {
+		if program.Role == domain.PROGRAM_GENERATOR {
+			out.Generators = append(out.Generators, dto.GeneratorDTO{Name: program.Name, Language: program.Language, Code: program.Code})
+		}
+	}
+	return out, nil
+}
+
+// SaveGenerator adds a test generator to a problem, replacing the one with
+// the same name.
+func (p *ProblemTestService) SaveGenerator(id string, g dto.GeneratorDTO) error {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return errors.New("invalid problem ID")
+	}
+	if err := p.validateGenerator(g); err != nil {
+		return err
+	}
+	if _, err := p.Repo.GetProblemByID(problemID, false); err != nil {
+		return errors.New("problem not found")
+	}
+	program := mapper.ToGenerator(g)
+	program.ProblemID = problemID
+	return p.Repo.SaveProgram(&program)
+}
+
+func (p *ProblemTestService) DeleteGenerator(id, name string) error {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return errors.New("invalid problem ID")
+	}
+	if _, err := p.Repo.GetProgram(problemID, domain.PROGRAM_GENERATOR, name); err != nil {
+		return errors.New("generator not found")
+	}
+	return p.Repo.DeleteProgram(problemID, domain.PROGRAM_GENERATOR, name)
+}
 
+// updateSignature records a changed signature in updates and returns the
+// boilerplates to replace the current ones with, or nil to keep them. They
+// are regenerated when the signature changes or a problem becomes a
+// function problem.
+func (p *ProblemTestService) updateSignature(problemID uuid.UUID, dto dto.UpdateProblemDTO, updates map[string]interface{}) ([]domain.BoilerPlate, error) {
+	problem, err := p.Repo.GetProblemByID(problemID, false)
+	if err != nil {
+		return nil, err
+	}
+	typ := dto.Type
+	if typ == "" {
+		typ = problem.Type
+	}
+	if typ != domain.PROBLEM_FUNCTION {
+		return nil, fmt.Errorf("%w: only function problems have a signature", harness.ErrInvalidSignature)
+	}
+	text := dto.Signature
+	if text == "" {
+		text = problem.Signature
+	}
+	sig, err := parseSignature(text)
+	if err != nil {
+		return nil, err
+	}
+	if problem.Type == domain.PROBLEM_FUNCTION && sig.String() == problem.Signature && dto.Boilerplates == nil {
+		return nil, nil
+	}
+	updates["signature"] = sig.String()
+
+	generated, err := p.generateBoilerplates(sig, dto.Boilerplates)
+	if err != nil {
+		return nil, err
+	}
+	boilerplates := make([]domain.BoilerPlate, 0, len(dto.Boilerplates)+len(generated))
+	for _, bp := range dto.Boilerplates {
+		if _, err := resolveLanguage(p.LanguageRepo, bp.Language); err != nil {
+			return nil, fmt.Errorf("boilerplate language %q: %w", bp.Language, err)
+		}
+		boilerplates = append(boilerplates, domain.BoilerPlate{Language: bp.Language, Code: bp.Code})
+	}
+	return append(boilerplates, generated...), nil
+}
+
+// parseSignature checks the signature of a function problem.
+func parseSignature(text string) (*harness.Signature, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("%w: function problems need a signature", harness.ErrInvalidSignature)
+	}
+	return harness.Parse(text)
+}
+
+// generateBoilerplates returns the stub of every enabled language the
+// harness supports, except those the setter wrote themselves.
+func (p *ProblemTestService) generateBoilerplates(sig *harness.Signature, given []dto.CreateBoilerplateDTO) ([]domain.BoilerPlate, error) {
+	languages, err := p.LanguageRepo.ListLanguages(false)
+	if err != nil {
+		return nil, err
+	}
+	written := make(map[string]bool, len(given))
+	for _, bp := range given {
+		written[bp.Language] = true
+	}
+	var out []domain.BoilerPlate
+	for _, lang := range languages {
+		if written[lang.ID] || !harness.Supports(lang.ID) {
+			continue
+		}
+		stub, err := harness.Stub(sig, lang.ID)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, domain.BoilerPlate{Language: lang.ID, Code: stub})
+	}
+	return out, nil
+}
+
+func validateSubtasks(subtasks []dto.SubtaskDTO) error {
+	if err := judge.ValidateSubtasks(mapper.ToSubtasks(subtasks)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSubtasks, err)
+	}
 	return nil
 }
 
@@ -128,3 +904,85 @@ func (p *ProblemTestService) DeleteProblem(id string) error {
 
 	return p.Repo.DeleteProblem(problemID)
 }
+
+// ErrInvalidAttachment is returned for attachment names that cannot be used
+// in a URL path segment.
+var ErrInvalidAttachment = errors.New("invalid attachment")
+
+// AddAttachment stores a file under name on a problem, replacing an earlier
+// attachment of the same name.
+func (p *ProblemTestService) AddAttachment(id, name, contentType string, data []byte) (*domain.ProblemAttachment, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	if name == "" || len(name) > 255 || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return nil, fmt.Errorf("%w: name must be a plain file name", ErrInvalidAttachment)
+	}
+	if _, err := p.Repo.GetProblemByID(problemID, false); err != nil {
+		return nil, errors.New("problem not found")
+	}
+	hash, err := p.Attachments.Put(context.Background(), data)
+	if err != nil {
+		return nil, err
+	}
+	attachment := &domain.ProblemAttachment{
+		ProblemID:   problemID,
+		Name:        name,
+		ContentType: contentType,
+		Hash:        hash,
+		Size:        int64(len(data)),
+	}
+	if err := p.Repo.SaveAttachment(attachment); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (p *ProblemTestService) ListAttachments(id string, admin bool) ([]domain.ProblemAttachment, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	if err := checkVisible(p.Repo, problemID, admin); err != nil {
+		return nil, err
+	}
+	return p.Repo.ListAttachments(problemID)
+}
+
+// OpenAttachment streams an attachment of a problem.
+func (p *ProblemTestService) OpenAttachment(id, name string, admin bool) (*domain.ProblemAttachment, io.ReadCloser, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, nil, errors.New("invalid problem ID")
+	}
+	if err := checkVisible(p.Repo, problemID, admin); err != nil {
+		return nil, nil, err
+	}
+	attachment, err := p.Repo.GetAttachment(problemID, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := p.Attachments.Open(context.Background(), attachment.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, r, nil
+}
+
+// DeleteAttachment removes an attachment, and its blob when no other
+// attachment shares the content.
+func (p *ProblemTestService) DeleteAttachment(id, name string) error {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return errors.New("invalid problem ID")
+	}
+	attachment, err := p.Repo.DeleteAttachment(problemID, name)
+	if err != nil {
+		return err
+	}
+	if n, err := p.Repo.CountAttachmentsWithHash(attachment.Hash); err != nil || n > 0 {
+		return err
+	}
+	return p.Attachments.Delete(context.Background(), attachment.Hash)
+}
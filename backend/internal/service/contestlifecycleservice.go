@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+// FinalizeTimeout is how long a contest may stay finalizing before an admin
+// can finalize it again, in case the server finalizing it died.
+const FinalizeTimeout = 10 * time.Minute
+
+var (
+	ErrContestNotEnded     = errors.New("contest has not ended")
+	ErrContestJudging      = errors.New("contest submissions are still being judged")
+	ErrContestFinalized    = errors.New("contest is already finalized or being finalized")
+	ErrContestNotFinalized = errors.New("contest is not finalized")
+)
+
+// UpdateStatuses starts and ends contests by their start and end times.
+func (cs *ContestService) UpdateStatuses(now time.Time) (started, ended int64, err error) {
+	return cs.ContestRepo.AdvanceStatuses(now)
+}
+
+// FinalizeEndedContests finalizes the rankings of ended contests whose
+// submissions have all been judged, and returns the ones it finalized.
+// A contest that fails to finalize is left finalizing with the error
+// recorded, for an admin to retry. Contests whose finalizing was rolled
+// back are left for an admin too.
+func (cs *ContestService) FinalizeEndedContests(now time.Time) ([]uuid.UUID, error) {
+	contests, err := cs.ContestRepo.ListByStatus(domain.CONTEST_ENDED)
+	if err != nil {
+		return nil, err
+	}
+
+	var finalized []uuid.UUID
+	var errs []error
+	for _, contest := range contests {
+		pending, err := cs.SubmissionRepo.CountPendingContestSubmissions(contest.ID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if pending > 0 || contest.RolledBackAt != nil {
+			continue
+		}
+		err = cs.finalize(contest.ID, now, nil)
+		switch {
+		case errors.Is(err, ErrContestFinalized):
+			// Another server claimed it first
+		case err != nil:
+			errs = append(errs, err)
+		default:
+			finalized = append(finalized, contest.ID)
+		}
+	}
+	return finalized, errors.Join(errs...)
+}
+
+// FinalizeContest finalizes a contest's rankings on request. The contest
+// must have ended and its submissions must all be judged. A contest whose
+// finalizing failed, or has been running for longer than FinalizeTimeout,
+// is finalized again.
+func (cs *ContestService) FinalizeContest(contestID uuid.UUID, now time.Time) error {
+	contest, err := cs.ContestRepo.GetByID(contestID)
+	if err != nil {
+		return err
+	}
+	if now.Before(contest.EndTime) {
+		return ErrContestNotEnded
+	}
+	if contest.Status == domain.CONTEST_FINALIZED {
+		return ErrContestFinalized
+	}
+	pending, err := cs.SubmissionRepo.CountPendingContestSubmissions(contestID)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return ErrContestJudging
+	}
+	retryBefore := now.Add(-FinalizeTimeout)
+	return cs.finalize(contestID, now, &retryBefore)
+}
+
+// finalize claims a contest, finalizes its rankings and records the error
+// if that fails. A failed finalize changes nothing, so it can be retried.
+func (cs *ContestService) finalize(contestID uuid.UUID, now time.Time, retryBefore *time.Time) error {
+	claimed, err := cs.ContestRepo.ClaimFinalize(contestID, now, retryBefore)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrContestFinalized
+	}
+
+	finalizeErr := cs.FinalizeContestRankings(contestID, now)
+	if finalizeErr == nil {
+		return nil
+	}
+	if err := cs.ContestRepo.FailFinalize(contestID, finalizeErr); err != nil {
+		return errors.Join(finalizeErr, err)
+	}
+	return finalizeErr
+}
+
+// RollbackFinalization undoes finalizing a contest on an admin's request:
+// every participant's rating and global leaderboard entry go back to what
+// they were, and the contest goes back to ended. It fails with
+// repo.ErrRatingChanged if a participant's rating has changed since, for
+// instance by a contest finalized later, which must be rolled back first.
+func (cs *ContestService) RollbackFinalization(contestID, adminID uuid.UUID, now time.Time) error {
+	contest, err := cs.ContestRepo.GetByID(contestID)
+	if err != nil {
+		return err
+	}
+	if contest.Status != domain.CONTEST_FINALIZED {
+		return ErrContestNotFinalized
+	}
+	return cs.ContestRepo.RollbackFinalization(contestID, adminID, now)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/repo"
+)
+
+type MockSubmissionRepo struct {
+	repo.SubmissionRepo
+	mock.Mock
+}
+
+func (m *MockSubmissionRepo) CountPendingContestSubmissions(contestID uuid.UUID) (int64, error) {
+	args := m.Called(contestID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSubmissionRepo) GetUserSolvedProblems(userID uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func TestFinalizeEndedContests(t *testing.T) {
+	now := time.Now()
+	judging := &domain.Contest{ID: uuid.New(), Status: domain.CONTEST_ENDED}
+	judged := &domain.Contest{ID: uuid.New(), Status: domain.CONTEST_ENDED}
+	taken := &domain.Contest{ID: uuid.New(), Status: domain.CONTEST_ENDED}
+	rolledBack := &domain.Contest{ID: uuid.New(), Status: domain.CONTEST_ENDED, RolledBackAt: &now}
+
+	contests := new(MockContestRepo)
+	contests.On("ListByStatus", domain.CONTEST_ENDED).Return([]*domain.Contest{judging, judged, taken, rolledBack}, nil)
+	contests.On("ClaimFinalize", judged.ID, now, (*time.Time)(nil)).Return(true, nil)
+	contests.On("ClaimFinalize", taken.ID, now, (*time.Time)(nil)).Return(false, nil)
+	contests.On("GetByID", judged.ID).Return(judged, nil)
+	contests.On("GetParticipants", judged.ID).Return([]*domain.ContestParticipant{}, nil)
+	contests.On("SaveFinalization", mock.Anything, now).Return(nil)
+	submissions := new(MockSubmissionRepo)
+	submissions.On("CountPendingContestSubmissions", judging.ID).Return(int64(2), nil)
+	submissions.On("CountPendingContestSubmissions", judged.ID).Return(int64(0), nil)
+	submissions.On("CountPendingContestSubmissions", taken.ID).Return(int64(0), nil)
+	submissions.On("CountPendingContestSubmissions", rolledBack.ID).Return(int64(0), nil)
+
+	svc := &ContestService{ContestRepo: contests, SubmissionRepo: submissions, ScoringService: &ContestScoringService{}}
+	finalized, err := svc.FinalizeEndedContests(now)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{judged.ID}, finalized)
+	contests.AssertNotCalled(t, "ClaimFinalize", judging.ID, mock.Anything, mock.Anything)
+	contests.AssertNotCalled(t, "ClaimFinalize", rolledBack.ID, mock.Anything, mock.Anything)
+	contests.AssertExpectations(t)
+}
+
+func TestFinalizeContest(t *testing.T) {
+	now := time.Now()
+
+	t.Run("not ended", func(t *testing.T) {
+		contest := &domain.Contest{ID: uuid.New(), EndTime: now.Add(time.Hour), Status: domain.CONTEST_RUNNING}
+		contests := new(MockContestRepo)
+		contests.On("GetByID", contest.ID).Return(contest, nil)
+
+		svc := &ContestService{ContestRepo: contests}
+		assert.ErrorIs(t, svc.FinalizeContest(contest.ID, now), ErrContestNotEnded)
+	})
+
+	t.Run("already finalized", func(t *testing.T) {
+		contest := &domain.Contest{ID: uuid.New(), EndTime: now.Add(-time.Hour), Status: domain.CONTEST_FINALIZED}
+		contests := new(MockContestRepo)
+		contests.On("GetByID", contest.ID).Return(contest, nil)
+
+		svc := &ContestService{ContestRepo: contests}
+		assert.ErrorIs(t, svc.FinalizeContest(contest.ID, now), ErrContestFinalized)
+	})
+
+	t.Run("still judging", func(t *testing.T) {
+		contest := &domain.Contest{ID: uuid.New(), EndTime: now.Add(-time.Hour), Status: domain.CONTEST_ENDED}
+		contests := new(MockContestRepo)
+		contests.On("GetByID", contest.ID).Return(contest, nil)
+		submissions := new(MockSubmissionRepo)
+		submissions.On("CountPendingContestSubmissions", contest.ID).Return(int64(1), nil)
+
+		svc := &ContestService{ContestRepo: contests, SubmissionRepo: submissions}
+		assert.ErrorIs(t, svc.FinalizeContest(contest.ID, now), ErrContestJudging)
+	})
+}
+
+func TestFinalizeContest_Failure(t *testing.T) {
+	now := time.Now()
+	contest := &domain.Contest{ID: uuid.New(), EndTime: now.Add(-time.Hour), Status: domain.CONTEST_FINALIZING}
+	saveErr := errors.New("connection reset")
+
+	contests := new(MockContestRepo)
+	contests.On("GetByID", contest.ID).Return(contest, nil)
+	contests.On("ClaimFinalize", contest.ID, now, mock.Anything).Return(true, nil)
+	contests.On("GetParticipants", contest.ID).Return([]*domain.ContestParticipant{}, nil)
+	contests.On("SaveFinalization", mock.Anything, now).Return(saveErr)
+	contests.On("FailFinalize", contest.ID, saveErr).Return(nil)
+	submissions := new(MockSubmissionRepo)
+	submissions.On("CountPendingContestSubmissions", contest.ID).Return(int64(0), nil)
+
+	svc := &ContestService{ContestRepo: contests, SubmissionRepo: submissions, ScoringService: &ContestScoringService{}}
+	assert.ErrorIs(t, svc.FinalizeContest(contest.ID, now), saveErr)
+	contests.AssertExpectations(t)
+}
+
+func TestRollbackFinalization(t *testing.T) {
+	now := time.Now()
+	admin := uuid.New()
+
+	t.Run("not finalized", func(t *testing.T) {
+		contest := &domain.Contest{ID: uuid.New(), Status: domain.CONTEST_ENDED}
+		contests := new(MockContestRepo)
+		contests.On("GetByID", contest.ID).Return(contest, nil)
+
+		svc := &ContestService{ContestRepo: contests}
+		assert.ErrorIs(t, svc.RollbackFinalization(contest.ID, admin, now), ErrContestNotFinalized)
+		contests.AssertNotCalled(t, "RollbackFinalization", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("finalized", func(t *testing.T) {
+		contest := &domain.Contest{ID: uuid.New(), Status: domain.CONTEST_FINALIZED}
+		contests := new(MockContestRepo)
+		contests.On("GetByID", contest.ID).Return(contest, nil)
+		contests.On("RollbackFinalization", contest.ID, admin, now).Return(nil)
+
+		svc := &ContestService{ContestRepo: contests}
+		assert.NoError(t, svc.RollbackFinalization(contest.ID, admin, now))
+		contests.AssertExpectations(t)
+	})
+}
@@ -1,10 +1,13 @@
 package service
 
 import (
+	"cmp"
 	"math"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
 )
 
 // ContestScoringService handles all scoring and ranking calculations
@@ -12,24 +15,6 @@ type ContestScoringService struct {
 	// Add repository dependencies here
 }
 
-// ScoringConfig holds configuration for point calculation
-type ScoringConfig struct {
-	BasePoints          int     // Base points for solving a problem (default: 100)
-	TimePenaltyPerMin   float64 // Points deducted per minute (default: 0.5)
-	WrongAttemptPenalty int     // Penalty per wrong submission (default: 10 points or 5 minutes)
-	PartialCreditMin    float64 // Minimum percentage for partial credit (default: 0.5 = 50%)
-}
-
-// DefaultScoringConfig returns default scoring configuration
-func DefaultScoringConfig() ScoringConfig {
-	return ScoringConfig{
-		BasePoints:          100,
-		TimePenaltyPerMin:   0.5,
-		WrongAttemptPenalty: 10,
-		PartialCreditMin:    0.5,
-	}
-}
-
 // CalculateSubmissionPoints calculates points for a single submission
 // Formula: BasePoints * (TestCasesPassed/TotalTestCases) - TimePenalty - AttemptPenalty
 func (s *ContestScoringService) CalculateSubmissionPoints(
@@ -40,7 +25,7 @@ func (s *ContestScoringService) CalculateSubmissionPoints(
 	attemptNumber int, // which attempt this is (1st, 2nd, etc.)
 	timeSinceStart int, // minutes since contest/problem start
 	allowPartialCredit bool,
-	config ScoringConfig,
+	config domain.ScoringConfig,
 ) int {
 	// If no test cases passed, no points
 	if testCasesPassed == 0 {
@@ -75,11 +60,82 @@ func (s *ContestScoringService) CalculateSubmissionPoints(
 	return int(math.Round(finalPoints))
 }
 
-// CalculateContestRank calculates ranks for all participants based on:
-// 1. Total points (descending)
-// 2. Problems solved (descending) - tiebreaker
-// 3. Penalty time (ascending) - tiebreaker
-// 4. Last submission time (ascending) - final tiebreaker
+// BestSubtaskPoints takes the best score of every subtask across results and
+// scales their sum from the subtasks' own points to maxPoints.
+func (s *ContestScoringService) BestSubtaskPoints(maxPoints int, subtasks []domain.Subtask, results []domain.SubmissionSubtaskResult) int {
+	total := 0
+	for _, st := range subtasks {
+		total += st.Points
+	}
+	if total == 0 {
+		return 0
+	}
+
+	best := make(map[int]float64, len(subtasks))
+	for _, r := range results {
+		best[r.SubtaskIndex] = math.Max(best[r.SubtaskIndex], r.Score)
+	}
+	var earned float64
+	for _, st := range subtasks {
+		earned += math.Min(best[st.Index], float64(st.Points))
+	}
+	return int(math.Round(float64(maxPoints) * earned / float64(total)))
+}
+
+// ReplayedScore is a participant's contest totals rebuilt from their
+// submissions.
+type ReplayedScore struct {
+	TotalPoints       int
+	ProblemsSolved    int
+	ProblemsAttempted int
+	PenaltyTime       int
+	SubmissionPoints  map[uuid.UUID]int // points of every judged submission
+}
+
+// ReplaySubmissions scores a participant's submissions under a scoring
+// strategy. Submissions still waiting for a verdict score nothing;
+// ProblemsAttempted counts the judged ones.
+func (s *ContestScoringService) ReplaySubmissions(
+	strategy ScoringStrategy,
+	contest *domain.Contest,
+	subtasks map[uuid.UUID][]domain.Subtask, // by problem ID
+	submissions []domain.Submission,
+) ReplayedScore {
+	byProblem := make(map[uuid.UUID][]domain.Submission)
+	for _, sub := range submissions {
+		byProblem[sub.ProblemID] = append(byProblem[sub.ProblemID], sub)
+	}
+
+	score := ReplayedScore{SubmissionPoints: make(map[uuid.UUID]int)}
+	for _, cp := range contest.Problems {
+		subs := byProblem[cp.ProblemID]
+		if len(subs) == 0 {
+			continue
+		}
+		problem := strategy.ScoreProblem(ProblemSubmissions{
+			Problem:     cp,
+			Subtasks:    subtasks[cp.ProblemID],
+			StartTime:   contest.StartTime,
+			Submissions: subs,
+		})
+		for _, sub := range subs {
+			if !domain.IsPendingStatus(sub.Status) {
+				score.ProblemsAttempted++
+			}
+		}
+		score.TotalPoints += problem.Points
+		score.PenaltyTime += problem.Penalty
+		if problem.Solved {
+			score.ProblemsSolved++
+		}
+		for id, points := range problem.SubmissionPoints {
+			score.SubmissionPoints[id] = points
+		}
+	}
+	return score
+}
+
+// ParticipantScore is a participant's standing in a contest.
 type ParticipantScore struct {
 	UserID           uuid.UUID
 	TotalPoints      int
@@ -89,103 +145,129 @@ type ParticipantScore struct {
 	CurrentRank      int
 }
 
-func (s *ContestScoringService) CalculateContestRank(participants []ParticipantScore) []ParticipantScore {
-	// Sort participants for ranking
-	// Using a simple bubble sort approach for clarity (use more efficient sort in production)
-	for i := 0; i < len(participants); i++ {
-		for j := i + 1; j < len(participants); j++ {
-			// Compare based on criteria
-			shouldSwap := false
-
-			// Primary: Total points (higher is better)
-			if participants[i].TotalPoints < participants[j].TotalPoints {
-				shouldSwap = true
-			} else if participants[i].TotalPoints == participants[j].TotalPoints {
-				// Tiebreaker 1: Problems solved (more is better)
-				if participants[i].ProblemsSolved < participants[j].ProblemsSolved {
-					shouldSwap = true
-				} else if participants[i].ProblemsSolved == participants[j].ProblemsSolved {
-					// Tiebreaker 2: Penalty time (less is better)
-					if participants[i].PenaltyTime > participants[j].PenaltyTime {
-						shouldSwap = true
-					} else if participants[i].PenaltyTime == participants[j].PenaltyTime {
-						// Tiebreaker 3: Last submission time (earlier is better)
-						if participants[i].LastSubmissionAt != nil &&
-							participants[j].LastSubmissionAt != nil &&
-							participants[i].LastSubmissionAt.After(*participants[j].LastSubmissionAt) {
-							shouldSwap = true
-						}
-					}
-				}
-			}
-
-			if shouldSwap {
-				participants[i], participants[j] = participants[j], participants[i]
-			}
+// CalculateContestRank orders participants and numbers their ranks by a
+// scoring strategy.
+func (s *ContestScoringService) CalculateContestRank(strategy ScoringStrategy, participants []ParticipantScore) []ParticipantScore {
+	// Among participants the strategy ties, the earlier last submission
+	// comes first but they share a rank
+	slices.SortStableFunc(participants, func(a, b ParticipantScore) int {
+		if c := strategy.Compare(a, b); c != 0 {
+			return c
 		}
-	}
+		if a.LastSubmissionAt != nil && b.LastSubmissionAt != nil {
+			return a.LastSubmissionAt.Compare(*b.LastSubmissionAt)
+		}
+		return 0
+	})
 
-	// Assign ranks (handle tied ranks)
-	currentRank := 1
-	for i := 0; i < len(participants); i++ {
-		if i > 0 {
-			// Check if this participant has same score as previous
-			prev := participants[i-1]
-			curr := participants[i]
-
-			if prev.TotalPoints == curr.TotalPoints &&
-				prev.ProblemsSolved == curr.ProblemsSolved &&
-				prev.PenaltyTime == curr.PenaltyTime {
-				// Same rank as previous
-				participants[i].CurrentRank = participants[i-1].CurrentRank
-			} else {
-				// New rank
-				currentRank = i + 1
-				participants[i].CurrentRank = currentRank
-			}
-		} else {
-			participants[i].CurrentRank = currentRank
+	for i := range participants {
+		participants[i].CurrentRank = i + 1
+		if i > 0 && strategy.Compare(participants[i-1], participants[i]) == 0 {
+			participants[i].CurrentRank = participants[i-1].CurrentRank
 		}
 	}
-
 	return participants
 }
 
-// CalculateRatingChange calculates ELO-like rating change after a contest
-// Based on expected vs actual performance
-func (s *ContestScoringService) CalculateRatingChange(
-	oldRating float64,
-	expectedRank int,
-	actualRank int,
-	totalParticipants int,
-) int {
-	// K-factor: determines maximum rating change (higher for newer players)
-	kFactor := 32.0
-	if oldRating > 2000 {
-		kFactor = 16.0 // Experienced players change slower
-	} else if oldRating < 1200 {
-		kFactor = 48.0 // New players change faster
+// RatedParticipant is a participant's rating going into a contest and
+// their final rank in it.
+type RatedParticipant struct {
+	UserID uuid.UUID
+	Rating float64
+	Rank   int
+}
+
+// CalculateRatingChanges works out everyone's rating change in a contest,
+// Codeforces style. A participant's expected rank follows from the chance
+// of every other participant beating them, given the ratings going in. The
+// change is half the gap between their rating and the rating whose
+// expected rank is the geometric mean of their expected and actual ranks.
+// The changes are then shifted so that ratings do not inflate: the sum of
+// all changes is slightly negative, and the strongest players do not gain
+// as a group. Changes are returned in the order of participants.
+func (s *ContestScoringService) CalculateRatingChanges(participants []RatedParticipant) []int {
+	changes := make([]int, len(participants))
+	n := len(participants)
+	if n < 2 {
+		return changes
 	}
 
-	// Calculate expected performance (0 to 1, where 1 is best)
-	expectedPerformance := 1.0 - (float64(expectedRank-1) / float64(totalParticipants))
+	deltas := make([]float64, n)
+	for i, p := range participants {
+		seed := expectedRank(participants, p.Rating, i)
+		target := math.Sqrt(seed * float64(p.Rank))
+		deltas[i] = (ratingForRank(participants, target, i) - p.Rating) / 2
+	}
 
-	// Calculate actual performance (0 to 1, where 1 is best)
-	actualPerformance := 1.0 - (float64(actualRank-1) / float64(totalParticipants))
+	var sum float64
+	for _, d := range deltas {
+		sum += d
+	}
+	inc := -sum/float64(n) - 1
+	for i := range deltas {
+		deltas[i] += inc
+	}
 
-	// Rating change = K * (Actual - Expected)
-	ratingChange := kFactor * (actualPerformance - expectedPerformance) * float64(totalParticipants)
+	// The top 4·√n by rating should not gain in total
+	byRating := make([]int, n)
+	for i := range byRating {
+		byRating[i] = i
+	}
+	slices.SortStableFunc(byRating, func(a, b int) int {
+		return cmp.Compare(participants[b].Rating, participants[a].Rating)
+	})
+	top := min(n, 4*int(math.Round(math.Sqrt(float64(n)))))
+	var topSum float64
+	for _, i := range byRating[:top] {
+		topSum += deltas[i]
+	}
+	inc = min(max(-topSum/float64(top), -10), 0)
+
+	for i := range deltas {
+		changes[i] = int(math.Round(deltas[i] + inc))
+	}
+	return changes
+}
 
-	return int(math.Round(ratingChange))
+// winProbability is the chance that a player rated a beats one rated b.
+func winProbability(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// expectedRank is the rank a player with the given rating is expected to
+// finish at among participants, leaving out participants[skip].
+func expectedRank(participants []RatedParticipant, rating float64, skip int) float64 {
+	rank := 1.0
+	for j, p := range participants {
+		if j != skip {
+			rank += winProbability(p.Rating, rating)
+		}
+	}
+	return rank
+}
+
+// ratingForRank finds by bisection the rating whose expected rank among
+// participants, leaving out participants[skip], is rank.
+func ratingForRank(participants []RatedParticipant, rank float64, skip int) float64 {
+	lo, hi := -2000.0, 6000.0
+	for hi-lo > 0.5 {
+		mid := (lo + hi) / 2
+		if expectedRank(participants, mid, skip) < rank {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return lo
 }
 
 // CalculatePenaltyTime calculates penalty time for a participant
-// Based on ACM ICPC rules: time to solve + 20 minutes per wrong attempt
+// Based on ACM ICPC rules: time to solve + penalty minutes per wrong attempt
 func (s *ContestScoringService) CalculatePenaltyTime(
 	solveTimeMinutes int, // time from contest start to AC submission
 	wrongAttempts int, // number of wrong submissions before AC
+	penaltyPerWrongAttempt int, // minutes, 20 by default
 ) int {
-	penaltyPerWrongAttempt := 20 // minutes
 	return solveTimeMinutes + (wrongAttempts * penaltyPerWrongAttempt)
 }
 
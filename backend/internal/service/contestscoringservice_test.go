@@ -0,0 +1,202 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+func TestBestSubtaskPoints(t *testing.T) {
+	s := &ContestScoringService{}
+	subtasks := []domain.Subtask{{Index: 1, Points: 30}, {Index: 2, Points: 70}}
+	results := []domain.SubmissionSubtaskResult{
+		{SubtaskIndex: 1, Score: 30}, {SubtaskIndex: 2, Score: 0}, // first submission
+		{SubtaskIndex: 1, Score: 0}, {SubtaskIndex: 2, Score: 35}, // second submission
+	}
+
+	assert.Equal(t, 65, s.BestSubtaskPoints(100, subtasks, results))
+	assert.Equal(t, 130, s.BestSubtaskPoints(200, subtasks, results))
+	assert.Equal(t, 0, s.BestSubtaskPoints(100, subtasks, nil))
+	assert.Equal(t, 0, s.BestSubtaskPoints(100, nil, results))
+}
+
+func TestReplaySubmissions(t *testing.T) {
+	s := &ContestScoringService{}
+	start := time.Now()
+	standard, subtasked := uuid.New(), uuid.New()
+	contest := &domain.Contest{StartTime: start, Problems: []domain.ContestProblem{
+		{ProblemID: standard, MaxPoints: 100, TimeMultiplier: 1},
+		{ProblemID: subtasked, MaxPoints: 100, TimeMultiplier: 1},
+	}}
+	subtasks := map[uuid.UUID][]domain.Subtask{
+		subtasked: {{Index: 1, Points: 40}, {Index: 2, Points: 60}},
+	}
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	submissions := []domain.Submission{
+		{ID: uuid.New(), ProblemID: standard, Status: domain.STATUS_WRONG_ANSWER, TestCasesPassed: 1, TotalTestCases: 2, CreatedAt: at(5)},
+		{ID: uuid.New(), ProblemID: standard, Status: domain.STATUS_ACCEPTED, TestCasesPassed: 2, TotalTestCases: 2, CreatedAt: at(10)},
+		{ID: uuid.New(), ProblemID: subtasked, Status: domain.STATUS_WRONG_ANSWER, CreatedAt: at(20),
+			SubtaskResults: []domain.SubmissionSubtaskResult{{SubtaskIndex: 1, Score: 40}}},
+		{ID: uuid.New(), ProblemID: subtasked, Status: domain.STATUS_QUEUED, CreatedAt: at(30)},
+		{ID: uuid.New(), ProblemID: uuid.New(), Status: domain.STATUS_ACCEPTED, CreatedAt: at(40)}, // not in the contest
+	}
+
+	got := s.ReplaySubmissions(hybridScoring{s, domain.DefaultScoringConfig()}, contest, subtasks, submissions)
+
+	// 100 - 10 minutes * 0.5 - 2 * 10, with attempts counted the way the
+	// hybrid rules count them
+	assert.Equal(t, 0, got.SubmissionPoints[submissions[0].ID])
+	assert.Equal(t, 75, got.SubmissionPoints[submissions[1].ID])
+	assert.Equal(t, 40, got.SubmissionPoints[submissions[2].ID])
+	assert.NotContains(t, got.SubmissionPoints, submissions[3].ID)
+	assert.Equal(t, ReplayedScore{
+		TotalPoints:       115,
+		ProblemsSolved:    1,
+		ProblemsAttempted: 3,
+		PenaltyTime:       10 + 2*20,
+		SubmissionPoints:  got.SubmissionPoints,
+	}, got)
+}
+
+func TestScoringStrategies(t *testing.T) {
+	s := &ContestScoringService{}
+	start := time.Now()
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	problem := ProblemSubmissions{
+		Problem:   domain.ContestProblem{MaxPoints: 500, PartialCredit: true, TimeMultiplier: 1},
+		StartTime: start,
+		Submissions: []domain.Submission{
+			{ID: uuid.New(), Status: domain.STATUS_COMPILE_ERROR, CreatedAt: at(5)},
+			{ID: uuid.New(), Status: domain.STATUS_WRONG_ANSWER, TestCasesPassed: 3, TotalTestCases: 5, CreatedAt: at(10)},
+			{ID: uuid.New(), Status: domain.STATUS_ACCEPTED, TestCasesPassed: 5, TotalTestCases: 5, CreatedAt: at(50)},
+			{ID: uuid.New(), Status: domain.STATUS_ACCEPTED, TestCasesPassed: 5, TotalTestCases: 5, CreatedAt: at(60)},
+			{ID: uuid.New(), Status: domain.STATUS_RUNNING, CreatedAt: at(70)},
+		},
+	}
+
+	tests := []struct {
+		mode    string
+		points  int
+		penalty int
+	}{
+		// One wrong attempt; the compile error does not count
+		{domain.SCORING_ICPC, 0, 50 + 20},
+		{domain.SCORING_IOI, 500, 0},
+		// 500 * (1 - 50/250 - 0.1)
+		{domain.SCORING_DECAYING, 350, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			strategy, err := s.Strategy(tt.mode, domain.DefaultScoringConfig())
+			assert.NoError(t, err)
+			got := strategy.ScoreProblem(problem)
+			assert.True(t, got.Solved)
+			assert.Equal(t, tt.points, got.Points)
+			assert.Equal(t, tt.penalty, got.Penalty)
+			assert.Len(t, got.SubmissionPoints, 4)
+		})
+	}
+
+	t.Run("ioi partial credit", func(t *testing.T) {
+		strategy, _ := s.Strategy(domain.SCORING_IOI, domain.DefaultScoringConfig())
+		got := strategy.ScoreProblem(ProblemSubmissions{
+			Problem:     problem.Problem,
+			Submissions: problem.Submissions[:2],
+		})
+		assert.False(t, got.Solved)
+		assert.Equal(t, 300, got.Points)
+	})
+
+	t.Run("decaying floor", func(t *testing.T) {
+		strategy, _ := s.Strategy(domain.SCORING_DECAYING, domain.DefaultScoringConfig())
+		got := strategy.ScoreProblem(ProblemSubmissions{
+			Problem:     problem.Problem,
+			StartTime:   start,
+			Submissions: []domain.Submission{{ID: uuid.New(), Status: domain.STATUS_ACCEPTED, CreatedAt: at(240)}},
+		})
+		assert.Equal(t, 150, got.Points)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		config := domain.DefaultScoringConfig()
+		config.TimePenaltyMinutes = 5
+		strategy, _ := s.Strategy(domain.SCORING_ICPC, config)
+		assert.Equal(t, 50+5, strategy.ScoreProblem(problem).Penalty)
+
+		// The problem's own penalty wins over the contest's
+		penalty := 0
+		overridden := problem
+		overridden.Problem.TimePenaltyMinutes = &penalty
+		assert.Equal(t, 50, strategy.ScoreProblem(overridden).Penalty)
+
+		// Decay is scaled by the problem's time multiplier
+		strategy, _ = s.Strategy(domain.SCORING_DECAYING, config)
+		overridden.Problem.TimeMultiplier = 2
+		assert.Equal(t, 250, strategy.ScoreProblem(overridden).Points)
+	})
+
+	_, err := s.Strategy("golf", domain.DefaultScoringConfig())
+	assert.ErrorIs(t, err, ErrInvalidScoringMode)
+}
+
+func TestCalculateContestRank(t *testing.T) {
+	s := &ContestScoringService{}
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	participants := func() []ParticipantScore {
+		return []ParticipantScore{
+			{UserID: a, TotalPoints: 300, ProblemsSolved: 1, PenaltyTime: 10},
+			{UserID: b, TotalPoints: 100, ProblemsSolved: 2, PenaltyTime: 90},
+			{UserID: c, TotalPoints: 300, ProblemsSolved: 2, PenaltyTime: 90},
+		}
+	}
+	ranks := func(ranked []ParticipantScore) map[uuid.UUID]int {
+		got := make(map[uuid.UUID]int)
+		for _, p := range ranked {
+			got[p.UserID] = p.CurrentRank
+		}
+		return got
+	}
+
+	icpc, _ := s.Strategy(domain.SCORING_ICPC, domain.DefaultScoringConfig())
+	assert.Equal(t, map[uuid.UUID]int{b: 1, c: 1, a: 3}, ranks(s.CalculateContestRank(icpc, participants())))
+	ioi, _ := s.Strategy(domain.SCORING_IOI, domain.DefaultScoringConfig())
+	assert.Equal(t, map[uuid.UUID]int{a: 1, c: 1, b: 3}, ranks(s.CalculateContestRank(ioi, participants())))
+	hybrid, _ := s.Strategy(domain.SCORING_HYBRID, domain.DefaultScoringConfig())
+	assert.Equal(t, map[uuid.UUID]int{c: 1, a: 2, b: 3}, ranks(s.CalculateContestRank(hybrid, participants())))
+}
+
+func TestCalculateRatingChanges(t *testing.T) {
+	s := &ContestScoringService{}
+	field := func(ratings []float64, ranks []int) []RatedParticipant {
+		participants := make([]RatedParticipant, len(ratings))
+		for i := range ratings {
+			participants[i] = RatedParticipant{UserID: uuid.New(), Rating: ratings[i], Rank: ranks[i]}
+		}
+		return participants
+	}
+
+	// Equally rated: the winners gain, the losers lose, and in total the
+	// field loses a little
+	changes := s.CalculateRatingChanges(field([]float64{1500, 1500, 1500, 1500}, []int{1, 2, 3, 4}))
+	assert.Greater(t, changes[0], changes[1])
+	assert.Greater(t, changes[1], 0)
+	assert.Greater(t, changes[2], changes[3])
+	assert.Less(t, changes[2], 0)
+	assert.LessOrEqual(t, changes[0]+changes[1]+changes[2]+changes[3], 0)
+
+	// Ties share a change
+	changes = s.CalculateRatingChanges(field([]float64{1500, 1500, 1500}, []int{1, 1, 3}))
+	assert.Equal(t, changes[0], changes[1])
+
+	// Beating a stronger player is worth more than beating a weaker one
+	upset := s.CalculateRatingChanges(field([]float64{1200, 1800}, []int{1, 2}))
+	expected := s.CalculateRatingChanges(field([]float64{1800, 1200}, []int{1, 2}))
+	assert.Greater(t, upset[0], expected[0])
+	assert.Less(t, upset[1], expected[1])
+	assert.Less(t, expected[1], 0)
+
+	assert.Equal(t, []int{0}, s.CalculateRatingChanges(field([]float64{1500}, []int{1})))
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,6 +11,11 @@ import (
 	"github.com/sudankdk/codearena/internal/repo"
 )
 
+var (
+	ErrInvalidContestTimes = errors.New("invalid contest times: the end must be after the start")
+	ErrContestEnded        = errors.New("contest has ended")
+)
+
 // ContestService handles contest operations and orchestrates scoring
 type ContestService struct {
 	ContestRepo    repo.ContestRepo
@@ -21,6 +27,22 @@ type ContestService struct {
 
 // CreateContest creates a new contest
 func (cs *ContestService) CreateContest(dto dto.CreateContestDTO) (*domain.Contest, error) {
+	mode := dto.ScoringMode
+	if mode == "" {
+		mode = domain.SCORING_HYBRID
+	}
+	scoring := domain.DefaultScoringConfig()
+	applyScoringConfig(&scoring, dto.Scoring)
+	if _, err := cs.ScoringService.Strategy(mode, scoring); err != nil {
+		return nil, err
+	}
+	if err := validateScoringConfig(scoring); err != nil {
+		return nil, err
+	}
+	if !dto.EndTime.After(dto.StartTime) {
+		return nil, ErrInvalidContestTimes
+	}
+
 	// Calculate duration in minutes
 	duration := int(dto.EndTime.Sub(dto.StartTime).Minutes())
 
@@ -32,6 +54,9 @@ func (cs *ContestService) CreateContest(dto dto.CreateContestDTO) (*domain.Conte
 		Duration:    duration,
 		IsRated:     dto.IsRated,
 		IsActive:    false, // New contests start inactive
+		Status:      domain.CONTEST_SCHEDULED,
+		ScoringMode: mode,
+		Scoring:     &scoring,
 	}
 	if err := cs.ContestRepo.Create(contest); err != nil {
 		return nil, err
@@ -40,6 +65,114 @@ func (cs *ContestService) CreateContest(dto dto.CreateContestDTO) (*domain.Conte
 	return contest, nil
 }
 
+// UpdateContest changes a contest's details and scoring. Times can only
+// change until the contest ends, and scoring only until it is finalized.
+// A change to scoring rescores every participant.
+func (cs *ContestService) UpdateContest(contestID uuid.UUID, req dto.UpdateContestDTO, now time.Time) (*domain.Contest, error) {
+	contest, err := cs.ContestRepo.GetByID(contestID)
+	if err != nil {
+		return nil, err
+	}
+
+	rescore := req.ScoringMode != nil || req.Scoring != nil || len(req.Problems) > 0
+	if rescore && (contest.Status == domain.CONTEST_FINALIZING || contest.Status == domain.CONTEST_FINALIZED) {
+		return nil, ErrContestFinalized
+	}
+
+	if req.StartTime != nil || req.EndTime != nil {
+		if contest.Status != domain.CONTEST_SCHEDULED && contest.Status != domain.CONTEST_RUNNING {
+			return nil, ErrContestEnded
+		}
+		if req.StartTime != nil {
+			contest.StartTime = *req.StartTime
+		}
+		if req.EndTime != nil {
+			contest.EndTime = *req.EndTime
+		}
+		if !contest.EndTime.After(contest.StartTime) {
+			return nil, ErrInvalidContestTimes
+		}
+		contest.Duration = int(contest.EndTime.Sub(contest.StartTime).Minutes())
+		// The scheduler only moves contests forward, so a contest moved
+		// later goes back to scheduled here; one moved into the past is
+		// ended by the scheduler
+		contest.Status = domain.CONTEST_SCHEDULED
+		if !now.Before(contest.StartTime) {
+			contest.Status = domain.CONTEST_RUNNING
+		}
+		contest.IsActive = contest.Status == domain.CONTEST_RUNNING
+	}
+
+	if req.Title != nil {
+		contest.Name = *req.Title
+	}
+	if req.Description != nil {
+		contest.Description = *req.Description
+	}
+	if req.IsRated != nil {
+		contest.IsRated = *req.IsRated
+	}
+	if req.ScoringMode != nil {
+		contest.ScoringMode = *req.ScoringMode
+	}
+	scoring := contest.ScoringOrDefault()
+	applyScoringConfig(&scoring, req.Scoring)
+	contest.Scoring = &scoring
+	if _, err := cs.ScoringService.Strategy(contest.ScoringMode, scoring); err != nil {
+		return nil, err
+	}
+	if err := validateScoringConfig(scoring); err != nil {
+		return nil, err
+	}
+
+	var problems []*domain.ContestProblem
+	for _, change := range req.Problems {
+		var problem *domain.ContestProblem
+		for i := range contest.Problems {
+			if contest.Problems[i].ProblemID == change.ProblemID {
+				problem = &contest.Problems[i]
+				break
+			}
+		}
+		if problem == nil {
+			return nil, errors.New("contest problem not found: " + change.ProblemID.String())
+		}
+		if change.MaxPoints != nil {
+			problem.MaxPoints = *change.MaxPoints
+		}
+		if change.PartialCredit != nil {
+			problem.PartialCredit = *change.PartialCredit
+		}
+		if change.TimeMultiplier != nil {
+			problem.TimeMultiplier = *change.TimeMultiplier
+		}
+		if change.TimePenaltyMinutes != nil {
+			problem.TimePenaltyMinutes = change.TimePenaltyMinutes
+		}
+		if err := validateContestProblem(problem); err != nil {
+			return nil, err
+		}
+		problems = append(problems, problem)
+	}
+
+	if err := cs.ContestRepo.Update(contest); err != nil {
+		return nil, err
+	}
+	for _, problem := range problems {
+		if err := cs.ContestRepo.UpdateProblemScoring(problem); err != nil {
+			return nil, err
+		}
+	}
+	if rescore {
+		for _, p := range contest.Participants {
+			if err := cs.recomputeParticipant(contest, p.UserID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return cs.ContestRepo.GetByID(contestID)
+}
+
 // GetByID retrieves a contest by its ID
 func (cs *ContestService) GetByID(contestIDStr string) (*domain.Contest, error) {
 	contestID, err := uuid.Parse(contestIDStr)
@@ -77,8 +210,22 @@ func (cs *ContestService) AddProblemsToContest(contestIDStr string, dto dto.AddP
 	}
 	orderIndex := len(existingProblems) + 1
 
-	// Use time_penalty_minutes directly, set defaults for partial credit and time multiplier
-	return cs.ContestRepo.AddProblem(contestID, problem.ID, orderIndex, dto.MaxPoints, false, 1.0)
+	contestProblem := &domain.ContestProblem{
+		ContestID:          contestID,
+		ProblemID:          problem.ID,
+		OrderIndex:         orderIndex,
+		MaxPoints:          dto.MaxPoints,
+		PartialCredit:      dto.PartialCredit,
+		TimeMultiplier:     1.0,
+		TimePenaltyMinutes: dto.TimePenaltyMinutes,
+	}
+	if dto.TimeMultiplier != nil {
+		contestProblem.TimeMultiplier = *dto.TimeMultiplier
+	}
+	if err := validateContestProblem(contestProblem); err != nil {
+		return err
+	}
+	return cs.ContestRepo.AddProblem(contestProblem)
 }
 
 // remove problem from contest
@@ -163,15 +310,20 @@ func (cs *ContestService) GetContestParticipants(contestIDStr string) ([]*domain
 	return participants, nil
 }
 
-// GetContestProblems returns list of problems in a contest
-func (cs *ContestService) GetContestProblems(contestIDStr string) ([]*domain.ContestProblem, error) {
+// GetContestProblems returns list of problems in a contest. Until the
+// contest starts the list is empty for everyone but admins.
+func (cs *ContestService) GetContestProblems(contestIDStr string, admin bool) ([]*domain.ContestProblem, error) {
 	contestID, err := uuid.Parse(contestIDStr)
 	if err != nil {
 		return nil, err
 	}
-	if _, err := cs.ContestRepo.GetByID(contestID); err != nil {
+	contest, err := cs.ContestRepo.GetByID(contestID)
+	if err != nil {
 		return nil, err
 	}
+	if !admin && time.Now().Before(contest.StartTime) {
+		return []*domain.ContestProblem{}, nil
+	}
 	problems, err := cs.ContestRepo.GetProblems(contestID)
 	if err != nil {
 		return nil, err
@@ -185,16 +337,40 @@ func (cs *ContestService) GetContestLeaderboard(contestIDStr string, limit int)
 	if err != nil {
 		return nil, err
 	}
-	if _, err := cs.ContestRepo.GetByID(contestID); err != nil {
+	contest, err := cs.ContestRepo.GetByID(contestID)
+	if err != nil {
+		return nil, err
+	}
+	strategy, err := cs.ScoringService.Strategy(contest.ScoringMode, contest.ScoringOrDefault())
+	if err != nil {
 		return nil, err
 	}
 	leaderboard, err := cs.ContestRepo.GetLeaderboard(contestID)
 	if err != nil {
 		return nil, err
 	}
+	rankLeaderboard(strategy, leaderboard)
 	return leaderboard, nil
 }
 
+// rankLeaderboard orders a live leaderboard and numbers its ranks by a
+// scoring strategy. Entries the strategy ties keep their order and share a
+// rank.
+func rankLeaderboard(strategy ScoringStrategy, entries []*domain.ContestLeaderboardEntry) {
+	standing := func(e *domain.ContestLeaderboardEntry) ParticipantScore {
+		return ParticipantScore{TotalPoints: e.Score, ProblemsSolved: e.Solved, PenaltyTime: e.Penalty}
+	}
+	slices.SortStableFunc(entries, func(a, b *domain.ContestLeaderboardEntry) int {
+		return strategy.Compare(standing(a), standing(b))
+	})
+	for i, e := range entries {
+		e.Rank = i + 1
+		if i > 0 && strategy.Compare(standing(entries[i-1]), standing(e)) == 0 {
+			e.Rank = entries[i-1].Rank
+		}
+	}
+}
+
 // list contests with pagination and filtering
 func (cs *ContestService) ListContests(query dto.ListQuery) ([]*domain.Contest, error) {
 	contests, err := cs.ContestRepo.List(query)
@@ -204,113 +380,95 @@ func (cs *ContestService) ListContests(query dto.ListQuery) ([]*domain.Contest,
 	return contests, nil
 }
 
-// ProcessSubmission handles a new submission in a contest
-// This should be called whenever a user submits a solution during a contest
-func (cs *ContestService) ProcessSubmission(
-	contestID uuid.UUID,
-	userID uuid.UUID,
-	problemID uuid.UUID,
-	submissionID uuid.UUID,
-	status string, // "accepted", "wrong_answer", etc.
-	testCasesPassed int,
-	totalTestCases int,
-	executionTime int,
-) error {
-	// 1. Get contest problem to find max points and settings
+// ProcessSubmission updates a participant's contest totals once one of
+// their submissions has been judged. The totals are rebuilt from all of the
+// participant's submissions under the contest's scoring mode, so a verdict
+// is never counted twice.
+func (cs *ContestService) ProcessSubmission(contestID, userID, problemID uuid.UUID) error {
 	contest, err := cs.ContestRepo.GetByID(contestID)
 	if err != nil {
 		return err
 	}
 
-	var contestProblem *domain.ContestProblem
+	inContest := false
 	for _, cp := range contest.Problems {
 		if cp.ProblemID == problemID {
-			contestProblem = &cp
+			inContest = true
 			break
 		}
 	}
-	if contestProblem == nil {
+	if !inContest {
 		return errors.New("contest problem not found")
 	}
 
-	// 2. Get participant record
-	var participant *domain.ContestParticipant
+	registered := false
 	for _, p := range contest.Participants {
 		if p.UserID == userID {
-			participant = &p
+			registered = true
 			break
 		}
 	}
-	if participant == nil {
+	if !registered {
 		return errors.New("participant not found")
 	}
+	return cs.recomputeParticipant(contest, userID)
+}
 
-	// 3. Count previous attempts for this problem
-	attempts, err := cs.SubmissionRepo.CountContestProblemAttempts(contestID, userID, problemID)
+// RecomputeParticipant rebuilds a participant's contest totals and the
+// points of their submissions from the stored verdicts. It is used once
+// submissions have been rejudged.
+func (cs *ContestService) RecomputeParticipant(contestID, userID uuid.UUID) error {
+	contest, err := cs.ContestRepo.GetByID(contestID)
 	if err != nil {
 		return err
 	}
+	return cs.recomputeParticipant(contest, userID)
+}
 
-	// 4. Calculate time since contest start
-	timeSinceStart := int(time.Since(contest.StartTime).Minutes())
-
-	// 5. Calculate points using scoring service
-	config := DefaultScoringConfig()
-	points := cs.ScoringService.CalculateSubmissionPoints(
-		contestProblem.MaxPoints,
-		testCasesPassed,
-		totalTestCases,
-		executionTime,
-		attempts+1, // current attempt
-		timeSinceStart,
-		contestProblem.PartialCredit,
-		config,
-	)
-
-	// 6. Update submission with points earned
-	err = cs.SubmissionRepo.UpdateSubmissionPoints(submissionID, points)
+func (cs *ContestService) recomputeParticipant(contest *domain.Contest, userID uuid.UUID) error {
+	strategy, err := cs.ScoringService.Strategy(contest.ScoringMode, contest.ScoringOrDefault())
 	if err != nil {
 		return err
 	}
+	// The replay runs with the participant locked, so concurrent verdicts
+	// for the same participant cannot overwrite each other's totals.
+	return cs.ContestRepo.RescoreParticipant(contest.ID, userID, func(submissions []domain.Submission) (*domain.ContestTotals, error) {
+		subtasks := make(map[uuid.UUID][]domain.Subtask)
+		for _, sub := range submissions {
+			if _, ok := subtasks[sub.ProblemID]; ok {
+				continue
+			}
+			problem, err := cs.ProblemRepo.GetProblemByID(sub.ProblemID, false)
+			if err != nil {
+				return nil, err
+			}
+			subtasks[sub.ProblemID] = problem.Subtasks
+		}
 
-	// 7. Track participant activity
-	now := time.Now()
-	err = cs.ContestRepo.UpdateParticipantActivity(contestID, userID, &now, &now, 1)
+		score := cs.ScoringService.ReplaySubmissions(strategy, contest, subtasks, submissions)
+		return &domain.ContestTotals{
+			Points:            score.TotalPoints,
+			ProblemsSolved:    score.ProblemsSolved,
+			ProblemsAttempted: score.ProblemsAttempted,
+			PenaltyTime:       score.PenaltyTime,
+			SubmissionPoints:  score.SubmissionPoints,
+		}, nil
+	})
+}
+
+// FinalizeContestRankings calculates final rankings and rating changes and
+// saves them, with a snapshot to roll them back from, in one transaction.
+// The contest must be claimed for finalizing first.
+func (cs *ContestService) FinalizeContestRankings(contestID uuid.UUID, now time.Time) error {
+	contest, err := cs.ContestRepo.GetByID(contestID)
 	if err != nil {
 		return err
 	}
-
-	// 8. If status is ACCEPTED, update participant's total points, etc.
-	if status == domain.STATUS_ACCEPTED {
-		// Check if this is the first accepted submission for this problem in this contest
-		// Exclude the current submission to see if there was a PREVIOUS accepted submission
-		alreadySolved, err := cs.SubmissionRepo.HasUserSolvedContestProblem(contestID, userID, problemID, submissionID)
-		if err != nil {
-			return err
-		}
-
-		// Calculate penalty time for this solve
-		penaltyTime := cs.ScoringService.CalculatePenaltyTime(timeSinceStart, attempts)
-
-		// Increment problemsSolved only if this is the first time solving this problem
-		problemsSolvedIncrement := 0
-		if !alreadySolved {
-			problemsSolvedIncrement = 1
-		}
-
-		// Update participant
-		err = cs.ContestRepo.UpdateParticipantScore(contestID, userID, points, problemsSolvedIncrement, penaltyTime)
-		if err != nil {
-			return err
-		}
+	strategy, err := cs.ScoringService.Strategy(contest.ScoringMode, contest.ScoringOrDefault())
+	if err != nil {
+		return err
 	}
 
-	return nil
-}
-
-// FinalizeContestRankings calculates final rankings and rating changes
-// This should be called when a contest ends
-func (cs *ContestService) FinalizeContestRankings(contestID uuid.UUID) error {
 	// 1. Get all participants with their scores
 	participants, err := cs.ContestRepo.GetParticipants(contestID)
 	if err != nil {
@@ -331,50 +489,77 @@ func (cs *ContestService) FinalizeContestRankings(contestID uuid.UUID) error {
 	}
 
 	// 3. Calculate final rankings using scoring service
-	rankedParticipants := cs.ScoringService.CalculateContestRank(participantScores)
-
-	// 4. For each participant, calculate rating change and update records
-	for _, rp := range rankedParticipants {
-		// Get user to get current rating
-		user, err := cs.UserRepo.FindUserById(rp.UserID)
-		if err != nil {
-			return err
-		}
-
-		// Calculate rating change
-		ratingChange := cs.ScoringService.CalculateRatingChange(
-			user.Rating,
-			len(participants), // expected rank (simplified)
-			rp.CurrentRank,
-			len(participants),
-		)
+	rankedParticipants := cs.ScoringService.CalculateContestRank(strategy, participantScores)
 
-		newRating := user.Rating + float64(ratingChange)
+	// 4. Work out rating changes from everyone's rating going in. Unrated
+	// contests leave ratings alone.
+	ratings := make(map[uuid.UUID]float64, len(participants))
+	for _, p := range participants {
+		ratings[p.UserID] = p.User.Rating
+	}
+	rated := make([]RatedParticipant, len(rankedParticipants))
+	for i, rp := range rankedParticipants {
+		rated[i] = RatedParticipant{UserID: rp.UserID, Rating: ratings[rp.UserID], Rank: rp.CurrentRank}
+	}
+	changes := make([]int, len(rated))
+	if contest.IsRated {
+		changes = cs.ScoringService.CalculateRatingChanges(rated)
+	}
 
-		// Update participant record with final rank and new rating
-		// Note: This would require adding methods to update participant rating and rank
-		// For now, we'll update the user rating
-		err = cs.UserRepo.UpdateUserRating(rp.UserID, newRating)
+	// 5. Record each participant's result and the global leaderboard stats
+	finalization := &domain.ContestFinalization{
+		ContestID: contestID,
+		IsRated:   contest.IsRated,
+		Results:   make([]domain.FinalizedResult, len(rankedParticipants)),
+	}
+	for i, rp := range rankedParticipants {
+		solvedProblems, err := cs.SubmissionRepo.GetUserSolvedProblems(rp.UserID)
 		if err != nil {
 			return err
 		}
-
-		// 5. Create leaderboard entries
-		err = cs.ContestRepo.UpdateLeaderboardEntry(contestID, rp.UserID, rp.TotalPoints, newRating, rp.CurrentRank)
-		if err != nil {
-			return err
+		finalization.Results[i] = domain.FinalizedResult{
+			UserID:       rp.UserID,
+			Rank:         rp.CurrentRank,
+			Score:        rp.TotalPoints,
+			OldRating:    rated[i].Rating,
+			NewRating:    rated[i].Rating + float64(changes[i]),
+			RatingChange: changes[i],
+			SolvedCount:  len(solvedProblems),
 		}
 	}
 
-	// 6. Update global leaderboard if needed
-	for _, rp := range rankedParticipants {
-		err = cs.UpdateGlobalLeaderboard(rp.UserID)
-		if err != nil {
-			return err
-		}
+	// 6. Apply it all at once
+	return cs.ContestRepo.SaveFinalization(finalization, now)
+}
+
+// GetRatingHistory returns a user's results in finalized rated contests,
+// oldest first.
+func (cs *ContestService) GetRatingHistory(userIDStr string) ([]dto.RatingHistoryEntry, error) {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cs.UserRepo.FindUserById(userID); err != nil {
+		return nil, err
+	}
+	participants, err := cs.ContestRepo.GetRatingHistory(userID)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	history := make([]dto.RatingHistoryEntry, len(participants))
+	for i, p := range participants {
+		history[i] = dto.RatingHistoryEntry{
+			ContestID:    p.ContestID,
+			ContestName:  p.Contest.Name,
+			EndTime:      p.Contest.EndTime,
+			Rank:         p.Rank,
+			OldRating:    p.OldRating,
+			NewRating:    p.NewRating,
+			RatingChange: p.RatingChange,
+		}
+	}
+	return history, nil
 }
 
 // UpdateGlobalLeaderboard updates the global leaderboard after contest
@@ -40,8 +40,13 @@ func (m *MockContestRepo) List(query dto.ListQuery) ([]*domain.Contest, error) {
 	return args.Get(0).([]*domain.Contest), args.Error(1)
 }
 
-func (m *MockContestRepo) AddProblem(contestID, problemID uuid.UUID, orderIndex int, maxPoints int, partialCredit bool, timeMultiplier float64) error {
-	args := m.Called(contestID, problemID, orderIndex, maxPoints, partialCredit, timeMultiplier)
+func (m *MockContestRepo) AddProblem(problem *domain.ContestProblem) error {
+	args := m.Called(problem)
+	return args.Error(0)
+}
+
+func (m *MockContestRepo) UpdateProblemScoring(problem *domain.ContestProblem) error {
+	args := m.Called(problem)
 	return args.Error(0)
 }
 
@@ -65,6 +70,11 @@ func (m *MockContestRepo) UnregisterParticipant(contestID, userID uuid.UUID) err
 	return args.Error(0)
 }
 
+func (m *MockContestRepo) IsUserRegistered(contestID, userID uuid.UUID) (bool, error) {
+	args := m.Called(contestID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockContestRepo) GetParticipants(contestID uuid.UUID) ([]*domain.ContestParticipant, error) {
 	args := m.Called(contestID)
 	return args.Get(0).([]*domain.ContestParticipant), args.Error(1)
@@ -75,6 +85,16 @@ func (m *MockContestRepo) UpdateParticipantScore(contestID, userID uuid.UUID, po
 	return args.Error(0)
 }
 
+func (m *MockContestRepo) RescoreParticipant(contestID, userID uuid.UUID, score func(submissions []domain.Submission) (*domain.ContestTotals, error)) error {
+	args := m.Called(contestID, userID, score)
+	return args.Error(0)
+}
+
+func (m *MockContestRepo) GetRatingHistory(userID uuid.UUID) ([]*domain.ContestParticipant, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]*domain.ContestParticipant), args.Error(1)
+}
+
 func (m *MockContestRepo) GetLeaderboard(contestID uuid.UUID) ([]*domain.ContestLeaderboardEntry, error) {
 	args := m.Called(contestID)
 	return args.Get(0).([]*domain.ContestLeaderboardEntry), args.Error(1)
@@ -95,13 +115,48 @@ func (m *MockContestRepo) GetGlobalLeaderboard(limit int) ([]*domain.GlobalLeade
 	return args.Get(0).([]*domain.GlobalLeaderboardEntry), args.Error(1)
 }
 
+func (m *MockContestRepo) AdvanceStatuses(now time.Time) (int64, int64, error) {
+	args := m.Called(now)
+	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockContestRepo) ListByStatus(status string) ([]*domain.Contest, error) {
+	args := m.Called(status)
+	return args.Get(0).([]*domain.Contest), args.Error(1)
+}
+
+func (m *MockContestRepo) ClaimFinalize(contestID uuid.UUID, now time.Time, retryStartedBefore *time.Time) (bool, error) {
+	args := m.Called(contestID, now, retryStartedBefore)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockContestRepo) FailFinalize(contestID uuid.UUID, finalizeErr error) error {
+	args := m.Called(contestID, finalizeErr)
+	return args.Error(0)
+}
+
+func (m *MockContestRepo) SaveFinalization(finalization *domain.ContestFinalization, now time.Time) error {
+	args := m.Called(finalization, now)
+	return args.Error(0)
+}
+
+func (m *MockContestRepo) RollbackFinalization(contestID, by uuid.UUID, now time.Time) error {
+	args := m.Called(contestID, by, now)
+	return args.Error(0)
+}
+
+func (m *MockContestRepo) MarkFinalizedContests() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func TestContestService(t *testing.T) {
 	mockRepo := new(MockContestRepo)
 	contestService := &ContestService{
 		ContestRepo: mockRepo,
 	}
 	dto := dto.CreateContestDTO{
-		Name:      "Test Contest",
+		Title:     "Test Contest",
 		StartTime: time.Now(),
 		EndTime:   time.Now().Add(2 * time.Hour),
 	}
@@ -111,7 +166,7 @@ func TestContestService(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.NotNil(t, contest)
-	assert.Equal(t, dto.Name, contest.Name)
+	assert.Equal(t, dto.Title, contest.Name)
 	assert.Equal(t, dto.StartTime, contest.StartTime)
 	assert.Equal(t, dto.EndTime, contest.EndTime)
 
@@ -124,7 +179,7 @@ func TestContestService_CreateContest_Error(t *testing.T) {
 		ContestRepo: mockRepo,
 	}
 	dto := dto.CreateContestDTO{
-		Name:      "Test Contest",
+		Title:     "Test Contest",
 		StartTime: time.Now(),
 		EndTime:   time.Now().Add(2 * time.Hour),
 	}
@@ -193,3 +248,91 @@ func TestContestService_ListContests_Error(t *testing.T) {
 	assert.Nil(t, contests)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestContestService_UpdateContest(t *testing.T) {
+	now := time.Now()
+	icpc := domain.SCORING_ICPC
+
+	t.Run("scoring of a finalized contest", func(t *testing.T) {
+		contest := &domain.Contest{ID: uuid.New(), Status: domain.CONTEST_FINALIZED}
+		mockRepo := new(MockContestRepo)
+		mockRepo.On("GetByID", contest.ID).Return(contest, nil)
+
+		svc := &ContestService{ContestRepo: mockRepo}
+		_, err := svc.UpdateContest(contest.ID, dto.UpdateContestDTO{ScoringMode: &icpc}, now)
+		assert.ErrorIs(t, err, ErrContestFinalized)
+	})
+
+	t.Run("times of an ended contest", func(t *testing.T) {
+		contest := &domain.Contest{ID: uuid.New(), Status: domain.CONTEST_ENDED}
+		mockRepo := new(MockContestRepo)
+		mockRepo.On("GetByID", contest.ID).Return(contest, nil)
+
+		svc := &ContestService{ContestRepo: mockRepo}
+		end := now.Add(time.Hour)
+		_, err := svc.UpdateContest(contest.ID, dto.UpdateContestDTO{EndTime: &end}, now)
+		assert.ErrorIs(t, err, ErrContestEnded)
+	})
+
+	t.Run("invalid problem scoring", func(t *testing.T) {
+		problemID := uuid.New()
+		contest := &domain.Contest{ID: uuid.New(), Status: domain.CONTEST_RUNNING,
+			Problems: []domain.ContestProblem{{ProblemID: problemID, MaxPoints: 100, TimeMultiplier: 1}}}
+		mockRepo := new(MockContestRepo)
+		mockRepo.On("GetByID", contest.ID).Return(contest, nil)
+
+		svc := &ContestService{ContestRepo: mockRepo}
+		multiplier := 0.0
+		_, err := svc.UpdateContest(contest.ID, dto.UpdateContestDTO{Problems: []dto.ContestProblemScoringDTO{
+			{ProblemID: problemID, TimeMultiplier: &multiplier},
+		}}, now)
+		assert.ErrorIs(t, err, ErrInvalidScoringConfig)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+}
+
+func TestContestService_FinalizeContestRankings(t *testing.T) {
+	now := time.Now()
+	first, second := uuid.New(), uuid.New()
+	participants := []*domain.ContestParticipant{
+		{UserID: second, TotalPoints: 100, User: domain.User{Rating: 1500}},
+		{UserID: first, TotalPoints: 200, User: domain.User{Rating: 1500}},
+	}
+
+	for _, rated := range []bool{true, false} {
+		contest := &domain.Contest{ID: uuid.New(), IsRated: rated}
+		changes := []int{0, 0}
+		if rated {
+			changes = (&ContestScoringService{}).CalculateRatingChanges([]RatedParticipant{
+				{UserID: first, Rating: 1500, Rank: 1},
+				{UserID: second, Rating: 1500, Rank: 2},
+			})
+		}
+
+		contests := new(MockContestRepo)
+		contests.On("GetByID", contest.ID).Return(contest, nil)
+		contests.On("GetParticipants", contest.ID).Return(participants, nil)
+		var saved *domain.ContestFinalization
+		contests.On("SaveFinalization", mock.Anything, now).Return(nil).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*domain.ContestFinalization)
+		})
+		submissions := new(MockSubmissionRepo)
+		submissions.On("GetUserSolvedProblems", first).Return([]uuid.UUID{uuid.New()}, nil)
+		submissions.On("GetUserSolvedProblems", second).Return([]uuid.UUID{}, nil)
+
+		svc := &ContestService{ContestRepo: contests, SubmissionRepo: submissions, ScoringService: &ContestScoringService{}}
+		assert.NoError(t, svc.FinalizeContestRankings(contest.ID, now))
+		assert.Equal(t, &domain.ContestFinalization{
+			ContestID: contest.ID,
+			IsRated:   rated,
+			Results: []domain.FinalizedResult{
+				{UserID: first, Rank: 1, Score: 200, OldRating: 1500, NewRating: 1500 + float64(changes[0]), RatingChange: changes[0], SolvedCount: 1},
+				{UserID: second, Rank: 2, Score: 100, OldRating: 1500, NewRating: 1500 + float64(changes[1]), RatingChange: changes[1]},
+			},
+		}, saved)
+		if rated {
+			assert.Positive(t, changes[0])
+			assert.Negative(t, changes[1])
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/judge"
+	"github.com/sudankdk/codearena/internal/problempkg"
+)
+
+var ErrNoReferenceSolution = errors.New("generating expected outputs needs a reference solution expected to be accepted")
+
+// GenerateTests runs the generation script of problem id and stores the
+// tests it makes, like a test archive upload. Each input is written by a
+// generator and its expected output by a reference solution. Interactive
+// problems get no expected outputs; their interactor decides on its own.
+func (rs *RunService) GenerateTests(ctx context.Context, id string, req dto.GenerateTestsDTO, author uuid.UUID) (*dto.TestUploadDTO, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = dto.TEST_UPLOAD_APPEND
+	}
+	if mode != dto.TEST_UPLOAD_APPEND && mode != dto.TEST_UPLOAD_REPLACE {
+		return nil, fmt.Errorf("%w: mode must be append or replace", problempkg.ErrInvalidScript)
+	}
+	problem, err := rs.ProblemRepo.GetProblemByID(problemID, false)
+	if err != nil {
+		return nil, errors.New("problem not found")
+	}
+	if strings.TrimSpace(problem.GenerationScript) == "" {
+		return nil, fmt.Errorf("%w: the problem has no generation script", problempkg.ErrInvalidScript)
+	}
+	cmds, err := problempkg.ParseScript(problem.GenerationScript)
+	if err != nil {
+		return nil, err
+	}
+	subtasks := make(map[int]bool, len(problem.Subtasks))
+	for _, s := range problem.Subtasks {
+		subtasks[s.Index] = true
+	}
+	for _, cmd := range cmds {
+		if cmd.Subtask > 0 && !subtasks[cmd.Subtask] {
+			return nil, fmt.Errorf("%w: %s is in subtask %d, which the problem does not have", ErrInvalidSubtasks, cmd.Name, cmd.Subtask)
+		}
+	}
+
+	programs, err := rs.ProblemRepo.ListPrograms(problemID)
+	if err != nil {
+		return nil, err
+	}
+	greq := judge.GenerateRequest{Steps: make([]judge.GenerateStep, len(cmds))}
+	for i, cmd := range cmds {
+		greq.Steps[i] = judge.GenerateStep{Line: cmd.Line, Generator: cmd.Generator, Args: cmd.Args}
+	}
+	var solution *domain.ProblemProgram
+	for i, program := range programs {
+		switch program.Role {
+		case domain.PROGRAM_GENERATOR:
+			lang, err := resolveLanguage(rs.LanguageRepo, program.Language)
+			if err != nil {
+				return nil, fmt.Errorf("generator %s: %w", program.Name, err)
+			}
+			greq.Generators = append(greq.Generators, judge.GeneratorSpec{Name: program.Name, Program: program.Code, Language: *lang})
+		case domain.PROGRAM_SOLUTION:
+			if program.Expected != domain.STATUS_ACCEPTED || (req.Solution != "" && program.Name != req.Solution) {
+				continue
+			}
+			// Without a name the first accepted solution by name is used
+			if solution == nil || program.Name < solution.Name {
+				solution = &programs[i]
+			}
+		}
+	}
+	if problem.Type == domain.PROBLEM_INTERACTIVE {
+		solution = nil
+	} else if solution == nil {
+		if req.Solution != "" {
+			return nil, fmt.Errorf("%w: %s is not an accepted solution of the problem", ErrNoReferenceSolution, req.Solution)
+		}
+		return nil, ErrNoReferenceSolution
+	} else {
+		lang, err := resolveLanguage(rs.LanguageRepo, solution.Language)
+		if err != nil {
+			return nil, fmt.Errorf("solution %s: %w", solution.Name, err)
+		}
+		sreq := judge.Request{Language: *lang}
+		if sreq.Code, err = submissionSource(problem, lang.ID, solution.Code); err != nil {
+			return nil, fmt.Errorf("solution %s: %w", solution.Name, err)
+		}
+		sreq.TimeLimit, sreq.MemoryLimitKB = judge.Limits(problem, *lang)
+		greq.Solution = &sreq
+	}
+
+	select {
+	case rs.Slots <- struct{}{}:
+		defer func() { <-rs.Slots }()
+	default:
+		return nil, ErrRunnerBusy
+	}
+
+	tests := make([]domain.TestCases, 0, len(cmds))
+	err = rs.Judge.Generate(ctx, greq, func(step int, input, expected string) error {
+		cmd := cmds[step]
+		tc := domain.TestCases{
+			Input:       input,
+			Expected:    expected,
+			IsSample:    cmd.IsSample,
+			Subtask:     cmd.Subtask,
+			GeneratedBy: cmd.String(),
+		}
+		if solution != nil {
+			tc.AnsweredBy = solution.Name
+		}
+		if err := rs.Data.Save(ctx, &tc); err != nil {
+			return err
+		}
+		// The data is in the store now; only the row is kept
+		tc.Input, tc.Expected = "", ""
+		tests = append(tests, tc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := rs.TestRepo.ImportTestcases(problemID, tests, mode == dto.TEST_UPLOAD_REPLACE)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordRevision(rs.ProblemRepo, rs.RevisionRepo, problemID, author, "tests generated"); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		names[i] = cmd.Name
+	}
+	return uploadSummary(mode, removed, tests, names), nil
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/harness"
+	"github.com/sudankdk/codearena/internal/judge"
+	"github.com/sudankdk/codearena/internal/repo"
+)
+
+// ErrScoring wraps failures to update contest scores after a submission was
+// judged and stored. Retrying only scores it again; the verdict is kept.
+var ErrScoring = errors.New("contest scoring failed")
+
+// JudgeService judges queued submissions and stores their verdicts. It is
+// driven by the judge workers.
+type JudgeService struct {
+	Repo         repo.SubmissionRepo
+	UserRepo     repo.UserRepo
+	ProblemRepo  repo.ProblemsRepo
+	LanguageRepo repo.LanguageRepo
+	Judge        *judge.Judge
+	Contests     *ContestService
+	Data         judge.TestData
+}
+
+// JudgeSubmission evaluates a submission, stores the verdict with its
+// per-test and subtask results and updates solved counts and contest
+// scores. A submission that was judged before is being rejudged; its
+// contest totals are then rebuilt rather than added to.
+func (js *JudgeService) JudgeSubmission(ctx context.Context, id uuid.UUID) error {
+	submission, err := js.Repo.GetSubmissionByID(id)
+	if err != nil {
+		return err
+	}
+	// A worker that died after storing the verdict leaves its job to be
+	// retried. Judging again would record a second verdict, so only the
+	// scoring, which is safe to repeat, is done again.
+	if !domain.IsPendingStatus(submission.Status) {
+		previous, err := js.Repo.ListJudgements(id)
+		if err != nil {
+			return err
+		}
+		if len(previous) > 0 {
+			return js.score(submission, len(previous) > 1)
+		}
+	}
+	problem, err := js.ProblemRepo.GetProblemByID(submission.ProblemID, true)
+	if err != nil {
+		return fmt.Errorf("load problem: %w", err)
+	}
+	// The language was checked when the submission was queued; it is judged
+	// even if the language has been disabled since.
+	lang, err := js.LanguageRepo.GetLanguage(submission.Language)
+	if err != nil {
+		return fmt.Errorf("load language: %w", err)
+	}
+	checker, err := checkerSpec(js.ProblemRepo, js.LanguageRepo, problem)
+	if err != nil {
+		return err
+	}
+	interactor, err := interactorSpec(js.ProblemRepo, js.LanguageRepo, problem)
+	if err != nil {
+		return err
+	}
+
+	code, err := submissionSource(problem, lang.ID, submission.Code)
+	if err != nil {
+		return err
+	}
+
+	timeLimit, memoryLimitKB := judge.Limits(problem, *lang)
+	result, err := js.Judge.Evaluate(ctx, judge.Request{
+		Language:      *lang,
+		Code:          code,
+		Tests:         problem.TestCases,
+		Data:          js.Data,
+		TimeLimit:     timeLimit,
+		MemoryLimitKB: memoryLimitKB,
+		Checker:       checker,
+		Interactor:    interactor,
+		Progress: func(status string) {
+			_ = js.Repo.UpdateSubmissionStatus(id, status)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	previous, err := js.Repo.ListJudgements(id)
+	if err != nil {
+		return err
+	}
+	applyResult(submission, problem, result)
+	if err := js.Repo.SaveJudgement(submission); err != nil {
+		return err
+	}
+	return js.score(submission, len(previous) > 0)
+}
+
+// score updates the solved count and contest totals for a stored verdict.
+// Both are recounted from the stored submissions, so scoring the same
+// verdict twice changes nothing.
+func (js *JudgeService) score(submission *domain.Submission, rejudged bool) error {
+	if rejudged {
+		return js.rescore(submission)
+	}
+
+	if submission.ContestID == nil {
+		if submission.Status == domain.STATUS_ACCEPTED {
+			// Keep the user's solved count in sync with their submissions
+			_ = js.UserRepo.RefreshSolvedCount(submission.UserID)
+		}
+		return nil
+	}
+
+	err := js.Contests.ProcessSubmission(*submission.ContestID, submission.UserID, submission.ProblemID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrScoring, err)
+	}
+	return nil
+}
+
+// FailSubmission gives a submission a judge_error verdict when it could not
+// be judged at all. A verdict that was stored but could not be scored is
+// kept.
+func (js *JudgeService) FailSubmission(id uuid.UUID, message string) error {
+	submission, err := js.Repo.GetSubmissionByID(id)
+	if err != nil {
+		return err
+	}
+	previous, err := js.Repo.ListJudgements(id)
+	if err != nil {
+		return err
+	}
+	if !domain.IsPendingStatus(submission.Status) && len(previous) > 0 {
+		return nil
+	}
+	err = js.Repo.SaveJudgement(&domain.Submission{
+		ID:           id,
+		Status:       domain.STATUS_JUDGE_ERROR,
+		ErrorMessage: message,
+	})
+	if err != nil || len(previous) == 0 {
+		return err
+	}
+	// A rejudge that failed still takes back what the old verdict earned
+	return js.rescore(submission)
+}
+
+// rescore brings the user's solved count and contest totals in line with
+// the new verdict of a rejudged submission. A rejudge queues all of a
+// participant's submissions at once, so both are recounted with the user's
+// or participant's row locked.
+func (js *JudgeService) rescore(submission *domain.Submission) error {
+	_ = js.UserRepo.RefreshSolvedCount(submission.UserID)
+	if submission.ContestID == nil {
+		return nil
+	}
+	if err := js.Contests.RecomputeParticipant(*submission.ContestID, submission.UserID); err != nil {
+		return fmt.Errorf("%w: %v", ErrScoring, err)
+	}
+	return nil
+}
+
+// applyResult copies a judge result onto the submission.
+func applyResult(submission *domain.Submission, problem *domain.Problem, result *judge.Result) {
+	submission.ProblemRevision = problem.Revision
+	submission.Status = result.Status
+	submission.ExecutionTime = result.ExecutionTime
+	submission.MemoryUsed = result.MemoryUsed
+	submission.TestCasesPassed = result.TestCasesPassed
+	submission.TotalTestCases = result.TotalTestCases
+	submission.ErrorMessage = result.ErrorMessage
+
+	submission.TestResults = nil
+	for i, tr := range result.Tests {
+		testCaseID := tr.TestCaseID
+		submission.TestResults = append(submission.TestResults, domain.SubmissionTestResult{
+			TestCaseID:     &testCaseID,
+			OrderIndex:     i,
+			Status:         tr.Status,
+			ExecutionTime:  tr.ExecutionTime,
+			MemoryUsed:     tr.MemoryUsed,
+			StdoutExcerpt:  judge.Excerpt(tr.Stdout),
+			StderrExcerpt:  judge.Excerpt(tr.Stderr),
+			CheckerMessage: tr.CheckerMessage,
+			Score:          tr.Score,
+		})
+	}
+
+	submission.SubtaskResults = nil
+	for _, sr := range judge.ScoreSubtasks(problem.Subtasks, problem.TestCases, result) {
+		submission.SubtaskResults = append(submission.SubtaskResults, domain.SubmissionSubtaskResult{
+			SubtaskIndex: sr.Index,
+			Name:         sr.Name,
+			Status:       sr.Status,
+			Points:       sr.Points,
+			Score:        sr.Score,
+		})
+	}
+}
+
+// checkerSpec loads the checker configured for a problem. The checker
+// program keeps working even if its language is later disabled for
+// submissions.
+func checkerSpec(problems repo.ProblemsRepo, languages repo.LanguageRepo, problem *domain.Problem) (judge.CheckerSpec, error) {
+	spec := judge.CheckerSpec{
+		Type:       problem.CheckerType,
+		AbsEpsilon: problem.CheckerAbsEpsilon,
+		RelEpsilon: problem.CheckerRelEpsilon,
+	}
+	if spec.Type != domain.CHECKER_CUSTOM {
+		return spec, nil
+	}
+
+	program, err := problems.GetProgram(problem.ID, domain.PROGRAM_CHECKER, "")
+	if err != nil {
+		return spec, fmt.Errorf("load checker: %w", err)
+	}
+	lang, err := languages.GetLanguage(program.Language)
+	if err != nil {
+		return spec, fmt.Errorf("load checker: %w", err)
+	}
+	spec.Program = program.Code
+	spec.Language = *lang
+	return spec, nil
+}
+
+// interactorSpec loads the interactor of an interactive problem and returns
+// nil for standard problems.
+func interactorSpec(problems repo.ProblemsRepo, languages repo.LanguageRepo, problem *domain.Problem) (*judge.InteractorSpec, error) {
+	if problem.Type != domain.PROBLEM_INTERACTIVE {
+		return nil, nil
+	}
+	program, err := problems.GetProgram(problem.ID, domain.PROGRAM_INTERACTOR, "")
+	if err != nil {
+		return nil, fmt.Errorf("load interactor: %w", err)
+	}
+	lang, err := languages.GetLanguage(program.Language)
+	if err != nil {
+		return nil, fmt.Errorf("load interactor: %w", err)
+	}
+	return &judge.InteractorSpec{Program: program.Code, Language: *lang}, nil
+}
+
+// submissionSource returns the program to judge for code. On function
+// problems it is the contestant's function followed by the generated driver.
+func submissionSource(problem *domain.Problem, language, code string) (string, error) {
+	if problem.Type != domain.PROBLEM_FUNCTION {
+		return code, nil
+	}
+	sig, err := harness.Parse(problem.Signature)
+	if err != nil {
+		return "", fmt.Errorf("load signature: %w", err)
+	}
+	src, err := harness.Source(sig, language, code)
+	if errors.Is(err, harness.ErrUnsupportedLanguage) {
+		return "", judge.ErrUnsupportedLanguage
+	}
+	return src, err
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+func (m *MockSubmissionRepo) GetSubmissionByID(id uuid.UUID) (*domain.Submission, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.Submission), args.Error(1)
+}
+
+func (m *MockSubmissionRepo) ListJudgements(submissionID uuid.UUID) ([]domain.SubmissionJudgement, error) {
+	args := m.Called(submissionID)
+	return args.Get(0).([]domain.SubmissionJudgement), args.Error(1)
+}
+
+func (m *MockUserRepo) RefreshSolvedCount(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// A worker that dies after storing a verdict leaves its job to be retried.
+// The retry must not judge the submission again or count it twice.
+func TestJudgeSubmission_RetryAfterVerdict(t *testing.T) {
+	stored := []domain.SubmissionJudgement{{Status: domain.STATUS_ACCEPTED}}
+
+	t.Run("practice submission", func(t *testing.T) {
+		sub := &domain.Submission{ID: uuid.New(), UserID: uuid.New(), Status: domain.STATUS_ACCEPTED}
+		submissions := new(MockSubmissionRepo)
+		submissions.On("GetSubmissionByID", sub.ID).Return(sub, nil)
+		submissions.On("ListJudgements", sub.ID).Return(stored, nil)
+		users := new(MockUserRepo)
+		users.On("RefreshSolvedCount", sub.UserID).Return(nil)
+
+		// Judging again would need the problem repo, which is unset
+		js := &JudgeService{Repo: submissions, UserRepo: users}
+		require.NoError(t, js.JudgeSubmission(context.Background(), sub.ID))
+
+		submissions.AssertExpectations(t)
+		users.AssertExpectations(t)
+	})
+
+	t.Run("contest submission", func(t *testing.T) {
+		contest := &domain.Contest{ID: uuid.New(), ScoringMode: domain.SCORING_ICPC}
+		sub := &domain.Submission{ID: uuid.New(), UserID: uuid.New(), ProblemID: uuid.New(),
+			ContestID: &contest.ID, Status: domain.STATUS_ACCEPTED}
+		contest.Problems = []domain.ContestProblem{{ProblemID: sub.ProblemID}}
+		contest.Participants = []domain.ContestParticipant{{UserID: sub.UserID}}
+
+		submissions := new(MockSubmissionRepo)
+		submissions.On("GetSubmissionByID", sub.ID).Return(sub, nil)
+		submissions.On("ListJudgements", sub.ID).Return(stored, nil)
+		contests := new(MockContestRepo)
+		contests.On("GetByID", contest.ID).Return(contest, nil)
+		// Totals are rebuilt from the stored submissions, never added to
+		contests.On("RescoreParticipant", contest.ID, sub.UserID, mock.Anything).Return(nil).Once()
+
+		js := &JudgeService{Repo: submissions, Contests: &ContestService{
+			ContestRepo:    contests,
+			SubmissionRepo: submissions,
+			ScoringService: &ContestScoringService{},
+		}}
+		require.NoError(t, js.JudgeSubmission(context.Background(), sub.ID))
+
+		contests.AssertExpectations(t)
+		contests.AssertNotCalled(t, "UpdateParticipantScore", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// A job whose scoring keeps failing is eventually given up on. The verdict
+// it stored must stand rather than become a judge error.
+func TestFailSubmission_KeepsStoredVerdict(t *testing.T) {
+	sub := &domain.Submission{ID: uuid.New(), UserID: uuid.New(), Status: domain.STATUS_ACCEPTED}
+	submissions := new(MockSubmissionRepo)
+	submissions.On("GetSubmissionByID", sub.ID).Return(sub, nil)
+	submissions.On("ListJudgements", sub.ID).Return([]domain.SubmissionJudgement{{Status: domain.STATUS_ACCEPTED}}, nil)
+
+	js := &JudgeService{Repo: submissions}
+	require.NoError(t, js.FailSubmission(sub.ID, "judging failed repeatedly"))
+	submissions.AssertNotCalled(t, "SaveJudgement", mock.Anything)
+}
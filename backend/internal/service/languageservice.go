@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/judge"
+	"github.com/sudankdk/codearena/internal/repo"
+)
+
+type LanguageService struct {
+	Repo repo.LanguageRepo
+}
+
+func (ls *LanguageService) ListLanguages(includeDisabled bool) ([]domain.Language, error) {
+	return ls.Repo.ListLanguages(includeDisabled)
+}
+
+func (ls *LanguageService) CreateLanguage(req dto.CreateLanguageDTO) (*domain.Language, error) {
+	lang := &domain.Language{
+		ID:               strings.TrimSpace(req.ID),
+		Name:             req.Name,
+		SourceFile:       req.SourceFile,
+		CompileCmd:       req.CompileCmd,
+		RunCmd:           req.RunCmd,
+		Version:          req.Version,
+		TimeMultiplier:   req.TimeMultiplier,
+		MemoryMultiplier: req.MemoryMultiplier,
+		Enabled:          true,
+	}
+	if lang.CompileCmd == nil {
+		lang.CompileCmd = []string{}
+	}
+	if lang.TimeMultiplier == 0 {
+		lang.TimeMultiplier = 1
+	}
+	if lang.MemoryMultiplier == 0 {
+		lang.MemoryMultiplier = 1
+	}
+	if err := validateLanguage(lang); err != nil {
+		return nil, err
+	}
+	if err := ls.Repo.CreateLanguage(lang); err != nil {
+		return nil, err
+	}
+	return lang, nil
+}
+
+func (ls *LanguageService) UpdateLanguage(id string, req dto.UpdateLanguageDTO) (*domain.Language, error) {
+	lang, err := ls.Repo.GetLanguage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build updates map
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		lang.Name = *req.Name
+		updates["name"] = lang.Name
+	}
+	if req.SourceFile != nil {
+		lang.SourceFile = *req.SourceFile
+		updates["source_file"] = lang.SourceFile
+	}
+	if req.CompileCmd != nil {
+		lang.CompileCmd = *req.CompileCmd
+		updates["compile_cmd"] = lang.CompileCmd
+	}
+	if req.RunCmd != nil {
+		lang.RunCmd = *req.RunCmd
+		updates["run_cmd"] = lang.RunCmd
+	}
+	if req.Version != nil {
+		lang.Version = *req.Version
+		updates["version"] = lang.Version
+	}
+	if req.TimeMultiplier != nil {
+		lang.TimeMultiplier = *req.TimeMultiplier
+		updates["time_multiplier"] = lang.TimeMultiplier
+	}
+	if req.MemoryMultiplier != nil {
+		lang.MemoryMultiplier = *req.MemoryMultiplier
+		updates["memory_multiplier"] = lang.MemoryMultiplier
+	}
+	if req.Enabled != nil {
+		lang.Enabled = *req.Enabled
+		updates["enabled"] = lang.Enabled
+	}
+	if err := validateLanguage(lang); err != nil {
+		return nil, err
+	}
+	if len(updates) == 0 {
+		return lang, nil
+	}
+
+	if err := ls.Repo.UpdateLanguage(id, updates); err != nil {
+		return nil, err
+	}
+	return lang, nil
+}
+
+// SetEnabled enables or disables a language. Disabled languages stay in the
+// registry so existing submissions keep their reference, but new submissions,
+// boilerplates and preferences are rejected.
+func (ls *LanguageService) SetEnabled(id string, enabled bool) error {
+	return ls.Repo.UpdateLanguage(id, map[string]interface{}{"enabled": enabled})
+}
+
+func validateLanguage(lang *domain.Language) error {
+	switch {
+	case lang.ID == "" || strings.ContainsAny(lang.ID, " /"):
+		return errors.New("language id must be a non-empty token")
+	case lang.Name == "":
+		return errors.New("language name is required")
+	case lang.SourceFile == "" || strings.Contains(lang.SourceFile, "/"):
+		return errors.New("source file must be a plain file name")
+	case len(lang.RunCmd) == 0:
+		return errors.New("run command is required")
+	case lang.TimeMultiplier <= 0 || lang.MemoryMultiplier <= 0:
+		return errors.New("multipliers must be positive")
+	}
+	return nil
+}
+
+// resolveLanguage returns the enabled registry entry for id, or
+// judge.ErrUnsupportedLanguage if it is unknown or disabled. Other lookup
+// failures are returned as they are.
+func resolveLanguage(languages repo.LanguageRepo, id string) (*domain.Language, error) {
+	lang, err := languages.GetLanguage(id)
+	if errors.Is(err, repo.ErrLanguageNotFound) {
+		return nil, judge.ErrUnsupportedLanguage
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !lang.Enabled {
+		return nil, judge.ErrUnsupportedLanguage
+	}
+	return lang, nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/judge"
+	"github.com/sudankdk/codearena/internal/repo"
+)
+
+type MockLanguageRepo struct {
+	repo.LanguageRepo
+	mock.Mock
+}
+
+func (m *MockLanguageRepo) GetLanguage(id string) (*domain.Language, error) {
+	args := m.Called(id)
+	lang, _ := args.Get(0).(*domain.Language)
+	return lang, args.Error(1)
+}
+
+func TestResolveLanguage(t *testing.T) {
+	dbErr := errors.New("connection refused")
+	languages := new(MockLanguageRepo)
+	languages.On("GetLanguage", "py").Return(&domain.Language{ID: "py", Enabled: true}, nil)
+	languages.On("GetLanguage", "rb").Return(&domain.Language{ID: "rb"}, nil)
+	languages.On("GetLanguage", "cobol").Return(nil, repo.ErrLanguageNotFound)
+	languages.On("GetLanguage", "go").Return(nil, dbErr)
+
+	lang, err := resolveLanguage(languages, "py")
+	assert.NoError(t, err)
+	assert.Equal(t, "py", lang.ID)
+
+	_, err = resolveLanguage(languages, "rb")
+	assert.ErrorIs(t, err, judge.ErrUnsupportedLanguage)
+	_, err = resolveLanguage(languages, "cobol")
+	assert.ErrorIs(t, err, judge.ErrUnsupportedLanguage)
+
+	_, err = resolveLanguage(languages, "go")
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, judge.ErrUnsupportedLanguage)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/repo"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid status change")
+	ErrNotReviewer       = errors.New("only the assigned reviewer can sign off a problem")
+)
+
+// checkVisible hides problems that are not public yet from non-admins
+// behind the same error as a missing problem.
+func checkVisible(problems repo.ProblemsRepo, id uuid.UUID, admin bool) error {
+	if admin {
+		return nil
+	}
+	public, err := problems.IsPublic(id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !public {
+		return errors.New("problem not found")
+	}
+	return nil
+}
+
+// ChangeStatus moves a problem along its lifecycle on behalf of admin by.
+// Sending a problem to review needs a reviewer, another admin; publishing
+// it from review is the reviewer's sign-off. See domain.ProblemTransitions.
+func (p *ProblemTestService) ChangeStatus(id string, req dto.ProblemStatusDTO, by domain.User) (*domain.Problem, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	problem, err := p.Repo.GetProblemByID(problemID, false)
+	if err != nil {
+		return nil, errors.New("problem not found")
+	}
+
+	from, to := problem.Status, req.Status
+	reassign := from == domain.PROBLEM_IN_REVIEW && to == domain.PROBLEM_IN_REVIEW && req.ReviewerID != nil
+	if !reassign && !slices.Contains(domain.ProblemTransitions[from], to) {
+		return nil, fmt.Errorf("%w: a %s problem cannot become %s", ErrInvalidTransition, from, to)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": to}
+	if req.ReviewerID != nil {
+		if to != domain.PROBLEM_IN_REVIEW {
+			return nil, fmt.Errorf("%w: reviewers are assigned when a problem goes to review", ErrInvalidTransition)
+		}
+		if err := p.checkReviewer(*req.ReviewerID, by); err != nil {
+			return nil, err
+		}
+		updates["reviewer_id"] = *req.ReviewerID
+	}
+	switch to {
+	case domain.PROBLEM_IN_REVIEW:
+		if req.ReviewerID == nil && problem.ReviewerID == nil {
+			return nil, fmt.Errorf("%w: assign a reviewer first", ErrInvalidTransition)
+		}
+		if req.ReviewerID == nil && *problem.ReviewerID == by.ID {
+			return nil, fmt.Errorf("%w: the reviewer cannot send the problem to review", ErrInvalidTransition)
+		}
+		updates["signed_off_by"] = nil
+		updates["signed_off_at"] = nil
+	case domain.PROBLEM_PUBLISHED:
+		if from == domain.PROBLEM_IN_REVIEW {
+			if problem.ReviewerID == nil || *problem.ReviewerID != by.ID {
+				return nil, ErrNotReviewer
+			}
+			updates["signed_off_by"] = by.ID
+			updates["signed_off_at"] = now
+		}
+		updates["published_at"] = now
+	case domain.PROBLEM_DRAFT:
+		// Changes after a review need a new sign-off
+		updates["signed_off_by"] = nil
+		updates["signed_off_at"] = nil
+	}
+
+	if err := p.Repo.UpdateProblem(problemID, updates); err != nil {
+		return nil, err
+	}
+	return p.Repo.GetProblemByID(problemID, false)
+}
+
+// checkReviewer makes sure reviewerID is another admin than by.
+func (p *ProblemTestService) checkReviewer(reviewerID uuid.UUID, by domain.User) error {
+	if reviewerID == by.ID {
+		return fmt.Errorf("%w: a problem cannot be reviewed by the admin sending it to review", ErrInvalidTransition)
+	}
+	reviewer, err := p.UserRepo.FindUserById(reviewerID)
+	if err != nil {
+		return fmt.Errorf("%w: reviewer not found", ErrInvalidTransition)
+	}
+	if reviewer.Role != domain.ADMIN {
+		return fmt.Errorf("%w: reviewers must be admins", ErrInvalidTransition)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/repo"
+)
+
+// MockProblemsRepo mocks the methods ChangeStatus uses; the embedded
+// interface panics on any other call.
+type MockProblemsRepo struct {
+	repo.ProblemsRepo
+	mock.Mock
+}
+
+func (m *MockProblemsRepo) GetProblemByID(id uuid.UUID, includeTestCases bool) (*domain.Problem, error) {
+	args := m.Called(id, includeTestCases)
+	return args.Get(0).(*domain.Problem), args.Error(1)
+}
+
+func (m *MockProblemsRepo) UpdateProblem(id uuid.UUID, updates map[string]interface{}) error {
+	args := m.Called(id, updates)
+	return args.Error(0)
+}
+
+type MockUserRepo struct {
+	repo.UserRepo
+	mock.Mock
+}
+
+func (m *MockUserRepo) FindUserById(id uuid.UUID) (domain.User, error) {
+	args := m.Called(id)
+	return args.Get(0).(domain.User), args.Error(1)
+}
+
+func TestChangeStatus(t *testing.T) {
+	author := domain.User{ID: uuid.New(), Role: domain.ADMIN}
+	reviewer := domain.User{ID: uuid.New(), Role: domain.ADMIN}
+	player := domain.User{ID: uuid.New(), Role: domain.REGULAR}
+
+	setup := func(problem *domain.Problem) (*ProblemTestService, *MockProblemsRepo) {
+		problems := new(MockProblemsRepo)
+		users := new(MockUserRepo)
+		problems.On("GetProblemByID", problem.ID, false).Return(problem, nil)
+		problems.On("UpdateProblem", problem.ID, mock.Anything).Return(nil)
+		users.On("FindUserById", reviewer.ID).Return(reviewer, nil)
+		users.On("FindUserById", player.ID).Return(player, nil)
+		users.On("FindUserById", mock.Anything).Return(domain.User{}, errors.New("user not found"))
+		return &ProblemTestService{Repo: problems, UserRepo: users}, problems
+	}
+	updatesOf := func(problems *MockProblemsRepo) map[string]interface{} {
+		for i := len(problems.Calls) - 1; i >= 0; i-- {
+			if call := problems.Calls[i]; call.Method == "UpdateProblem" {
+				return call.Arguments.Get(1).(map[string]interface{})
+			}
+		}
+		return nil
+	}
+
+	t.Run("review needs another admin", func(t *testing.T) {
+		problem := &domain.Problem{ID: uuid.New(), Status: domain.PROBLEM_DRAFT}
+		svc, _ := setup(problem)
+		for _, id := range []*uuid.UUID{nil, &author.ID, &player.ID} {
+			_, err := svc.ChangeStatus(problem.ID.String(), dto.ProblemStatusDTO{Status: domain.PROBLEM_IN_REVIEW, ReviewerID: id}, author)
+			assert.ErrorIs(t, err, ErrInvalidTransition)
+		}
+
+		_, err := svc.ChangeStatus(problem.ID.String(), dto.ProblemStatusDTO{Status: domain.PROBLEM_IN_REVIEW, ReviewerID: &reviewer.ID}, author)
+		require.NoError(t, err)
+	})
+
+	t.Run("drafts cannot be published", func(t *testing.T) {
+		problem := &domain.Problem{ID: uuid.New(), Status: domain.PROBLEM_DRAFT}
+		svc, _ := setup(problem)
+		_, err := svc.ChangeStatus(problem.ID.String(), dto.ProblemStatusDTO{Status: domain.PROBLEM_PUBLISHED}, author)
+		assert.ErrorIs(t, err, ErrInvalidTransition)
+	})
+
+	t.Run("only the reviewer signs off", func(t *testing.T) {
+		problem := &domain.Problem{ID: uuid.New(), Status: domain.PROBLEM_IN_REVIEW, ReviewerID: &reviewer.ID}
+		svc, problems := setup(problem)
+		_, err := svc.ChangeStatus(problem.ID.String(), dto.ProblemStatusDTO{Status: domain.PROBLEM_PUBLISHED}, author)
+		assert.ErrorIs(t, err, ErrNotReviewer)
+
+		_, err = svc.ChangeStatus(problem.ID.String(), dto.ProblemStatusDTO{Status: domain.PROBLEM_PUBLISHED}, reviewer)
+		require.NoError(t, err)
+		updates := updatesOf(problems)
+		assert.Equal(t, domain.PROBLEM_PUBLISHED, updates["status"])
+		assert.Equal(t, reviewer.ID, updates["signed_off_by"])
+		assert.Contains(t, updates, "published_at")
+	})
+
+	t.Run("back to draft drops the sign-off", func(t *testing.T) {
+		problem := &domain.Problem{ID: uuid.New(), Status: domain.PROBLEM_ARCHIVED, SignedOffBy: &reviewer.ID}
+		svc, problems := setup(problem)
+		_, err := svc.ChangeStatus(problem.ID.String(), dto.ProblemStatusDTO{Status: domain.PROBLEM_DRAFT}, author)
+		require.NoError(t, err)
+		updates := updatesOf(problems)
+		assert.Contains(t, updates, "signed_off_by")
+		assert.Nil(t, updates["signed_off_by"])
+	})
+}
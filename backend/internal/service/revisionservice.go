@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/repo"
+)
+
+// maxDiffCells bounds the table of the description line diff. Longer
+// statements are shown as removed and added whole.
+const maxDiffCells = 4 << 20
+
+// snapshot builds a revision of problem as it is now. The problem must have
+// its tests loaded.
+func snapshot(problem *domain.Problem) *domain.ProblemRevision {
+	rev := &domain.ProblemRevision{
+		ProblemID:      problem.ID,
+		MainHeading:    problem.MainHeading,
+		Description:    problem.Description,
+		Tag:            problem.Tag,
+		Difficulty:     problem.Difficulty,
+		TimeLimitMs:    problem.TimeLimitMs,
+		MemoryLimitMB:  problem.MemoryLimitMB,
+		LanguageLimits: make([]domain.RevisionLanguageLimit, 0, len(problem.LanguageLimits)),
+		Subtasks:       make([]domain.RevisionSubtask, 0, len(problem.Subtasks)),
+		Tests:          make([]domain.RevisionTest, 0, len(problem.TestCases)),
+	}
+	for _, l := range problem.LanguageLimits {
+		rev.LanguageLimits = append(rev.LanguageLimits, domain.RevisionLanguageLimit{
+			Language:      l.LanguageID,
+			TimeLimitMs:   l.TimeLimitMs,
+			MemoryLimitMB: l.MemoryLimitMB,
+		})
+	}
+	for _, s := range problem.Subtasks {
+		rev.Subtasks = append(rev.Subtasks, domain.RevisionSubtask{
+			Index:        s.Index,
+			Name:         s.Name,
+			Points:       s.Points,
+			Aggregation:  s.Aggregation,
+			Dependencies: append([]int{}, s.Dependencies...),
+		})
+	}
+	for _, tc := range problem.TestCases {
+		rev.Tests = append(rev.Tests, domain.RevisionTest{
+			InputHash:    tc.InputHash,
+			InputSize:    tc.InputSize,
+			ExpectedHash: tc.ExpectedHash,
+			ExpectedSize: tc.ExpectedSize,
+			IsSample:     tc.IsSample,
+			Explanation:  tc.Explanation,
+			Subtask:      tc.Subtask,
+			GeneratedBy:  tc.GeneratedBy,
+			AnsweredBy:   tc.AnsweredBy,
+		})
+	}
+	return rev
+}
+
+// sameContent reports whether two revisions hold the same statement, limits
+// and tests.
+func sameContent(a, b *domain.ProblemRevision) bool {
+	return a.MainHeading == b.MainHeading &&
+		a.Description == b.Description &&
+		a.Tag == b.Tag &&
+		a.Difficulty == b.Difficulty &&
+		a.TimeLimitMs == b.TimeLimitMs &&
+		a.MemoryLimitMB == b.MemoryLimitMB &&
+		slices.Equal(a.LanguageLimits, b.LanguageLimits) &&
+		sameSubtasks(a.Subtasks, b.Subtasks) &&
+		slices.Equal(a.Tests, b.Tests)
+}
+
+func sameSubtasks(a, b []domain.RevisionSubtask) bool {
+	return slices.EqualFunc(a, b, func(x, y domain.RevisionSubtask) bool {
+		return x.Index == y.Index && x.Name == y.Name && x.Points == y.Points &&
+			x.Aggregation == y.Aggregation && slices.Equal(x.Dependencies, y.Dependencies)
+	})
+}
+
+// recordRevision records the problem as it is now as a revision by author,
+// unless nothing changed since its latest revision. author is uuid.Nil for
+// changes nobody made, such as migrations.
+func recordRevision(problems repo.ProblemsRepo, revisions repo.RevisionRepo, problemID, author uuid.UUID, summary string) error {
+	problem, err := problems.GetProblemByID(problemID, true)
+	if err != nil {
+		return errors.New("problem not found")
+	}
+	rev := snapshot(problem)
+	latest, err := revisions.LatestRevision(problemID)
+	if err != nil && err.Error() != "revision not found" {
+		return err
+	}
+	if latest != nil && sameContent(latest, rev) {
+		return nil
+	}
+	rev.Summary = summary
+	if author != uuid.Nil {
+		rev.AuthorID = &author
+	}
+	if err := revisions.CreateRevision(rev); err != nil {
+		return fmt.Errorf("record revision: %w", err)
+	}
+	return nil
+}
+
+// RecordInitialRevisions records a first revision of every problem that has
+// none, so that problems from before revisions existed can be restored to
+// their state at the time. It returns the number of recorded revisions.
+func RecordInitialRevisions(problems repo.ProblemsRepo, revisions repo.RevisionRepo) (int, error) {
+	ids, err := revisions.ProblemsWithoutRevisions()
+	if err != nil {
+		return 0, err
+	}
+	for i, id := range ids {
+		if err := recordRevision(problems, revisions, id, uuid.Nil, "initial revision"); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+// ListRevisions lists the revisions of a problem, newest first.
+func (p *ProblemTestService) ListRevisions(id string) ([]dto.RevisionSummaryDTO, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	problem, err := p.Repo.GetProblemByID(problemID, false)
+	if err != nil {
+		return nil, errors.New("problem not found")
+	}
+	revisions, err := p.RevisionRepo.ListRevisions(problemID)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]dto.RevisionSummaryDTO, len(revisions))
+	for i, rev := range revisions {
+		summaries[i] = dto.RevisionSummaryDTO{
+			Number:    rev.Number,
+			AuthorID:  rev.AuthorID,
+			Summary:   rev.Summary,
+			Tests:     len(rev.Tests),
+			Current:   rev.Number == problem.Revision,
+			CreatedAt: rev.CreatedAt,
+		}
+	}
+	return summaries, nil
+}
+
+// GetRevision returns revision number of a problem.
+func (p *ProblemTestService) GetRevision(id string, number int) (*domain.ProblemRevision, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	return p.RevisionRepo.GetRevision(problemID, number)
+}
+
+// DiffRevisions compares revision from of a problem with revision to.
+func (p *ProblemTestService) DiffRevisions(id string, from, to int) (*dto.RevisionDiffDTO, error) {
+	a, err := p.GetRevision(id, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := p.GetRevision(id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &dto.RevisionDiffDTO{From: from, To: to, Fields: []dto.FieldChangeDTO{}, Tests: []dto.TestChangeDTO{}}
+	field := func(name string, x, y interface{}, same bool) {
+		if !same {
+			diff.Fields = append(diff.Fields, dto.FieldChangeDTO{Field: name, From: x, To: y})
+		}
+	}
+	field("main_heading", a.MainHeading, b.MainHeading, a.MainHeading == b.MainHeading)
+	field("tag", a.Tag, b.Tag, a.Tag == b.Tag)
+	field("difficulty", a.Difficulty, b.Difficulty, a.Difficulty == b.Difficulty)
+	field("time_limit_ms", a.TimeLimitMs, b.TimeLimitMs, a.TimeLimitMs == b.TimeLimitMs)
+	field("memory_limit_mb", a.MemoryLimitMB, b.MemoryLimitMB, a.MemoryLimitMB == b.MemoryLimitMB)
+	field("language_limits", a.LanguageLimits, b.LanguageLimits, slices.Equal(a.LanguageLimits, b.LanguageLimits))
+	field("subtasks", a.Subtasks, b.Subtasks, sameSubtasks(a.Subtasks, b.Subtasks))
+
+	if a.Description != b.Description {
+		diff.Description = diffLines(strings.Split(a.Description, "\n"), strings.Split(b.Description, "\n"))
+	}
+	for i := 0; i < max(len(a.Tests), len(b.Tests)); i++ {
+		switch {
+		case i >= len(a.Tests):
+			diff.Tests = append(diff.Tests, dto.TestChangeDTO{OrderIndex: i, Change: dto.DIFF_ADDED})
+		case i >= len(b.Tests):
+			diff.Tests = append(diff.Tests, dto.TestChangeDTO{OrderIndex: i, Change: dto.DIFF_REMOVED})
+		case a.Tests[i] != b.Tests[i]:
+			diff.Tests = append(diff.Tests, dto.TestChangeDTO{OrderIndex: i, Change: dto.DIFF_CHANGED, Fields: testChanges(a.Tests[i], b.Tests[i])})
+		}
+	}
+	return diff, nil
+}
+
+// testChanges names what differs between two versions of a test.
+func testChanges(a, b domain.RevisionTest) []string {
+	var fields []string
+	if a.InputHash != b.InputHash {
+		fields = append(fields, "input")
+	}
+	if a.ExpectedHash != b.ExpectedHash {
+		fields = append(fields, "expected")
+	}
+	if a.IsSample != b.IsSample {
+		fields = append(fields, "is_sample")
+	}
+	if a.Subtask != b.Subtask {
+		fields = append(fields, "subtask")
+	}
+	if a.Explanation != b.Explanation {
+		fields = append(fields, "explanation")
+	}
+	if a.GeneratedBy != b.GeneratedBy || a.AnsweredBy != b.AnsweredBy {
+		fields = append(fields, "provenance")
+	}
+	return fields
+}
+
+// diffLines is a line diff of a and b by longest common subsequence.
+func diffLines(a, b []string) []dto.DiffLineDTO {
+	lines := make([]dto.DiffLineDTO, 0, len(a)+len(b))
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			lines = append(lines, dto.DiffLineDTO{Change: dto.DIFF_REMOVED, Text: line})
+		}
+		for _, line := range b {
+			lines = append(lines, dto.DiffLineDTO{Change: dto.DIFF_ADDED, Text: line})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, dto.DiffLineDTO{Change: dto.DIFF_SAME, Text: a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, dto.DiffLineDTO{Change: dto.DIFF_REMOVED, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, dto.DiffLineDTO{Change: dto.DIFF_ADDED, Text: b[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// RestoreRevision brings the statement, limits and tests of a problem back
+// to revision number. The restored state is recorded as a new revision by
+// author, so the restore can be undone too.
+func (p *ProblemTestService) RestoreRevision(id string, number int, author uuid.UUID) (*domain.ProblemRevision, error) {
+	rev, err := p.GetRevision(id, number)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	tests := make([]domain.TestCases, len(rev.Tests))
+	for i, t := range rev.Tests {
+		in, err := p.Data.Describe(ctx, t.InputHash, t.InputSize)
+		if err != nil {
+			return nil, fmt.Errorf("load test %d: %w", i, err)
+		}
+		out, err := p.Data.Describe(ctx, t.ExpectedHash, t.ExpectedSize)
+		if err != nil {
+			return nil, fmt.Errorf("load test %d: %w", i, err)
+		}
+		tests[i] = domain.TestCases{
+			InputHash:       in.Hash,
+			InputSize:       in.Size,
+			InputPreview:    in.Preview,
+			ExpectedHash:    out.Hash,
+			ExpectedSize:    out.Size,
+			ExpectedPreview: out.Preview,
+			IsSample:        t.IsSample,
+			Explanation:     t.Explanation,
+			Subtask:         t.Subtask,
+			GeneratedBy:     t.GeneratedBy,
+			AnsweredBy:      t.AnsweredBy,
+		}
+	}
+	if err := p.RevisionRepo.RestoreRevision(rev, tests); err != nil {
+		return nil, err
+	}
+	if err := recordRevision(p.Repo, p.RevisionRepo, rev.ProblemID, author, fmt.Sprintf("restored revision %d", number)); err != nil {
+		return nil, err
+	}
+	return p.RevisionRepo.LatestRevision(rev.ProblemID)
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/repo"
+)
+
+type MockRevisionRepo struct {
+	repo.RevisionRepo
+	mock.Mock
+}
+
+func (m *MockRevisionRepo) LatestRevision(problemID uuid.UUID) (*domain.ProblemRevision, error) {
+	args := m.Called(problemID)
+	return args.Get(0).(*domain.ProblemRevision), args.Error(1)
+}
+
+func (m *MockRevisionRepo) CreateRevision(revision *domain.ProblemRevision) error {
+	args := m.Called(revision)
+	return args.Error(0)
+}
+
+func TestRecordRevision(t *testing.T) {
+	author := uuid.New()
+	problem := &domain.Problem{
+		ID:          uuid.New(),
+		MainHeading: "Two Sum",
+		Description: "Add two numbers.",
+		TimeLimitMs: 1000,
+		Subtasks:    []domain.Subtask{{Index: 1, Points: 100, Aggregation: domain.SUBTASK_ALL}},
+		TestCases:   []domain.TestCases{{InputHash: "in", ExpectedHash: "out", IsSample: true, Subtask: 1}},
+	}
+	problems := new(MockProblemsRepo)
+	problems.On("GetProblemByID", problem.ID, true).Return(problem, nil)
+
+	t.Run("first revision", func(t *testing.T) {
+		revisions := new(MockRevisionRepo)
+		revisions.On("LatestRevision", problem.ID).Return((*domain.ProblemRevision)(nil), errors.New("revision not found"))
+		revisions.On("CreateRevision", mock.Anything).Return(nil)
+
+		require.NoError(t, recordRevision(problems, revisions, problem.ID, author, "problem created"))
+		rev := revisions.Calls[1].Arguments.Get(0).(*domain.ProblemRevision)
+		assert.Equal(t, "problem created", rev.Summary)
+		assert.Equal(t, &author, rev.AuthorID)
+		assert.Equal(t, []domain.RevisionTest{{InputHash: "in", ExpectedHash: "out", IsSample: true, Subtask: 1}}, rev.Tests)
+	})
+
+	t.Run("unchanged problem", func(t *testing.T) {
+		// Revisions read back from the database have empty slices for nil ones
+		latest := snapshot(problem)
+		latest.Subtasks[0].Dependencies = []int{}
+		revisions := new(MockRevisionRepo)
+		revisions.On("LatestRevision", problem.ID).Return(latest, nil)
+
+		require.NoError(t, recordRevision(problems, revisions, problem.ID, author, "problem updated"))
+		revisions.AssertNotCalled(t, "CreateRevision", mock.Anything)
+	})
+
+	t.Run("changed problem", func(t *testing.T) {
+		latest := snapshot(problem)
+		latest.Tests[0].ExpectedHash = "old"
+		revisions := new(MockRevisionRepo)
+		revisions.On("LatestRevision", problem.ID).Return(latest, nil)
+		revisions.On("CreateRevision", mock.Anything).Return(nil)
+
+		require.NoError(t, recordRevision(problems, revisions, problem.ID, uuid.Nil, "test updated"))
+		rev := revisions.Calls[1].Arguments.Get(0).(*domain.ProblemRevision)
+		assert.Nil(t, rev.AuthorID)
+		assert.Equal(t, []string{"expected"}, testChanges(latest.Tests[0], rev.Tests[0]))
+	})
+}
+
+func TestDiffLines(t *testing.T) {
+	lines := diffLines([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"})
+	assert.Equal(t, []dto.DiffLineDTO{
+		{Change: dto.DIFF_SAME, Text: "a"},
+		{Change: dto.DIFF_REMOVED, Text: "b"},
+		{Change: dto.DIFF_ADDED, Text: "x"},
+		{Change: dto.DIFF_SAME, Text: "c"},
+		{Change: dto.DIFF_ADDED, Text: "d"},
+	}, lines)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/judge"
+	"github.com/sudankdk/codearena/internal/repo"
+	"github.com/sudankdk/codearena/internal/storage"
+)
+
+var (
+	ErrRunnerBusy        = errors.New("too many runs in progress, try again shortly")
+	ErrCustomInteractive = errors.New("interactive problems can only be run on their samples")
+)
+
+// RunService runs code the same way submissions are judged. It backs the
+// "Run" button of the editor, which stores nothing, and the setter's tools
+// for verifying problems and generating tests.
+type RunService struct {
+	ProblemRepo  repo.ProblemsRepo
+	LanguageRepo repo.LanguageRepo
+	TestRepo     repo.TestcaseRepo
+	RevisionRepo repo.RevisionRepo
+	Judge        *judge.Judge
+	Data         storage.TestData
+	// Slots bounds the number of runs, verifications and generations
+	// executing at once.
+	Slots chan struct{}
+}
+
+// Run executes req on its custom input, or judges it on the problem's
+// samples when no input is given. Problems that are not public yet can only
+// be run by admins.
+func (rs *RunService) Run(ctx context.Context, req dto.RunCodeDTO, admin bool) (*dto.RunResultDTO, error) {
+	lang, err := resolveLanguage(rs.LanguageRepo, req.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	var problem *domain.Problem
+	if req.ProblemID != nil {
+		if err := checkVisible(rs.ProblemRepo, *req.ProblemID, admin); err != nil {
+			return nil, err
+		}
+		if problem, err = rs.ProblemRepo.GetProblemByID(*req.ProblemID, true); err != nil {
+			return nil, errors.New("problem not found")
+		}
+	} else if req.Stdin == nil {
+		return nil, errors.New("problem_id or stdin is required")
+	}
+	if req.Stdin != nil && problem != nil && problem.Type == domain.PROBLEM_INTERACTIVE {
+		return nil, ErrCustomInteractive
+	}
+
+	jreq := judge.Request{Language: *lang, Code: req.Code}
+	if problem != nil {
+		// Custom input of a function problem is read by the driver too
+		if jreq.Code, err = submissionSource(problem, lang.ID, req.Code); err != nil {
+			return nil, err
+		}
+		jreq.TimeLimit, jreq.MemoryLimitKB = judge.Limits(problem, *lang)
+	}
+
+	select {
+	case rs.Slots <- struct{}{}:
+		defer func() { <-rs.Slots }()
+	default:
+		return nil, ErrRunnerBusy
+	}
+
+	if req.Stdin != nil {
+		result, err := rs.Judge.Execute(ctx, jreq, *req.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return toRunResult(result, nil), nil
+	}
+
+	// Samples are small and shown in full, so they are loaded up front
+	jreq.Tests = domain.SampleTestCases(problem.TestCases)
+	if err := rs.Data.LoadAll(ctx, jreq.Tests); err != nil {
+		return nil, err
+	}
+	if jreq.Checker, err = checkerSpec(rs.ProblemRepo, rs.LanguageRepo, problem); err != nil {
+		return nil, err
+	}
+	if jreq.Interactor, err = interactorSpec(rs.ProblemRepo, rs.LanguageRepo, problem); err != nil {
+		return nil, err
+	}
+	result, err := rs.Judge.Evaluate(ctx, jreq)
+	if err != nil {
+		return nil, err
+	}
+	return toRunResult(result, jreq.Tests), nil
+}
+
+// toRunResult reports a judge result; samples are the judged tests, which
+// may all be shown to the user.
+func toRunResult(result *judge.Result, samples []domain.TestCases) *dto.RunResultDTO {
+	out := &dto.RunResultDTO{
+		Status:        result.Status,
+		ExecutionTime: result.ExecutionTime,
+		MemoryUsed:    result.MemoryUsed,
+		ErrorMessage:  result.ErrorMessage,
+		Tests:         make([]dto.RunTestDTO, 0, len(result.Tests)),
+	}
+	for i, tr := range result.Tests {
+		row := dto.RunTestDTO{
+			Index:          i + 1,
+			Status:         tr.Status,
+			ExecutionTime:  tr.ExecutionTime,
+			MemoryUsed:     tr.MemoryUsed,
+			Stdout:         judge.Excerpt(tr.Stdout),
+			Stderr:         judge.Excerpt(tr.Stderr),
+			CheckerMessage: tr.CheckerMessage,
+		}
+		if i < len(samples) {
+			row.Input = samples[i].Input
+			row.Expected = samples[i].Expected
+		}
+		out.Tests = append(out.Tests, row)
+	}
+	return out
+}
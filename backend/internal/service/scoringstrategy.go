@@ -0,0 +1,307 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/dto"
+)
+
+var (
+	ErrInvalidScoringMode   = errors.New("invalid scoring mode")
+	ErrInvalidScoringConfig = errors.New("invalid scoring config")
+)
+
+// ScoringStrategy holds the rules of one contest scoring mode: how a
+// participant's submissions to a problem are scored and how participants
+// are ranked.
+type ScoringStrategy interface {
+	// ScoreProblem scores a participant's submissions to one problem.
+	ScoreProblem(p ProblemSubmissions) ProblemScore
+	// Compare is negative when a ranks above b and zero when they tie.
+	Compare(a, b ParticipantScore) int
+}
+
+// ProblemSubmissions are a participant's submissions to one contest
+// problem in the order they were made, including ones still waiting for a
+// verdict.
+type ProblemSubmissions struct {
+	Problem     domain.ContestProblem
+	Subtasks    []domain.Subtask
+	StartTime   time.Time // contest start, which submission times count from
+	Submissions []domain.Submission
+}
+
+// ProblemScore is a participant's result on one contest problem.
+type ProblemScore struct {
+	Points           int
+	Solved           bool
+	Penalty          int               // minutes
+	SubmissionPoints map[uuid.UUID]int // points of every judged submission
+}
+
+// Strategy returns the strategy of a scoring mode, tuned by config.
+// Contests without a mode use the hybrid rules.
+func (s *ContestScoringService) Strategy(mode string, config domain.ScoringConfig) (ScoringStrategy, error) {
+	switch mode {
+	case domain.SCORING_HYBRID, "":
+		return hybridScoring{s, config}, nil
+	case domain.SCORING_ICPC:
+		return icpcScoring{s, config}, nil
+	case domain.SCORING_IOI:
+		return ioiScoring{s}, nil
+	case domain.SCORING_DECAYING:
+		return decayingScoring{config}, nil
+	}
+	return nil, ErrInvalidScoringMode
+}
+
+func minutesSince(start, at time.Time) int {
+	return int(at.Sub(start).Minutes())
+}
+
+// isRejected reports whether a verdict counts as a wrong attempt. Compile
+// errors and judge errors do not.
+func isRejected(status string) bool {
+	return status != domain.STATUS_ACCEPTED &&
+		status != domain.STATUS_COMPILE_ERROR &&
+		status != domain.STATUS_JUDGE_ERROR &&
+		!domain.IsPendingStatus(status)
+}
+
+// hybridScoring is the original CodeArena rule set: points less time and
+// attempt penalties for problems without subtasks, the best score of each
+// subtask for problems with them, and ICPC penalty time on every solve.
+type hybridScoring struct {
+	s      *ContestScoringService
+	config domain.ScoringConfig
+}
+
+func (h hybridScoring) ScoreProblem(p ProblemSubmissions) ProblemScore {
+	score := ProblemScore{SubmissionPoints: make(map[uuid.UUID]int)}
+	config := h.config
+	config.TimePenaltyPerMin *= p.Problem.TimeMultiplier
+	var results []domain.SubmissionSubtaskResult
+	for i, sub := range p.Submissions {
+		if domain.IsPendingStatus(sub.Status) {
+			continue
+		}
+		attempt := i + 1
+		minutes := minutesSince(p.StartTime, sub.CreatedAt)
+		firstSolve := sub.Status == domain.STATUS_ACCEPTED && !score.Solved
+
+		if len(p.Subtasks) > 0 {
+			score.SubmissionPoints[sub.ID] = h.s.BestSubtaskPoints(p.Problem.MaxPoints, p.Subtasks, sub.SubtaskResults)
+			results = append(results, sub.SubtaskResults...)
+			score.Points = h.s.BestSubtaskPoints(p.Problem.MaxPoints, p.Subtasks, results)
+		} else {
+			points := h.s.CalculateSubmissionPoints(
+				p.Problem.MaxPoints,
+				sub.TestCasesPassed,
+				sub.TotalTestCases,
+				sub.ExecutionTime,
+				attempt+1,
+				minutes,
+				p.Problem.PartialCredit,
+				config,
+			)
+			score.SubmissionPoints[sub.ID] = points
+			if firstSolve {
+				score.Points = points
+			}
+		}
+
+		if firstSolve {
+			score.Solved = true
+			score.Penalty = h.s.CalculatePenaltyTime(minutes, attempt, p.Problem.PenaltyMinutes(h.config))
+		}
+	}
+	return score
+}
+
+func (hybridScoring) Compare(a, b ParticipantScore) int {
+	return cmp.Or(
+		cmp.Compare(b.TotalPoints, a.TotalPoints),
+		cmp.Compare(b.ProblemsSolved, a.ProblemsSolved),
+		cmp.Compare(a.PenaltyTime, b.PenaltyTime),
+	)
+}
+
+// icpcScoring ranks by problems solved, then by penalty time: the minutes
+// to each solve plus the penalty minutes for every wrong attempt before it.
+// No points are given.
+type icpcScoring struct {
+	s      *ContestScoringService
+	config domain.ScoringConfig
+}
+
+func (c icpcScoring) ScoreProblem(p ProblemSubmissions) ProblemScore {
+	score := ProblemScore{SubmissionPoints: make(map[uuid.UUID]int)}
+	wrong := 0
+	for _, sub := range p.Submissions {
+		if domain.IsPendingStatus(sub.Status) {
+			continue
+		}
+		score.SubmissionPoints[sub.ID] = 0
+		switch {
+		case score.Solved:
+		case sub.Status == domain.STATUS_ACCEPTED:
+			score.Solved = true
+			score.Penalty = c.s.CalculatePenaltyTime(minutesSince(p.StartTime, sub.CreatedAt), wrong, p.Problem.PenaltyMinutes(c.config))
+		case isRejected(sub.Status):
+			wrong++
+		}
+	}
+	return score
+}
+
+func (icpcScoring) Compare(a, b ParticipantScore) int {
+	return cmp.Or(
+		cmp.Compare(b.ProblemsSolved, a.ProblemsSolved),
+		cmp.Compare(a.PenaltyTime, b.PenaltyTime),
+	)
+}
+
+// ioiScoring gives each problem the best score of any submission to it,
+// subtask by subtask for problems with subtasks. Time and attempts do not
+// matter.
+type ioiScoring struct{ s *ContestScoringService }
+
+func (o ioiScoring) ScoreProblem(p ProblemSubmissions) ProblemScore {
+	score := ProblemScore{SubmissionPoints: make(map[uuid.UUID]int)}
+	var results []domain.SubmissionSubtaskResult
+	for _, sub := range p.Submissions {
+		if domain.IsPendingStatus(sub.Status) {
+			continue
+		}
+		if len(p.Subtasks) > 0 {
+			score.SubmissionPoints[sub.ID] = o.s.BestSubtaskPoints(p.Problem.MaxPoints, p.Subtasks, sub.SubtaskResults)
+			results = append(results, sub.SubtaskResults...)
+			score.Points = o.s.BestSubtaskPoints(p.Problem.MaxPoints, p.Subtasks, results)
+		} else {
+			points := testPoints(p.Problem, sub)
+			score.SubmissionPoints[sub.ID] = points
+			score.Points = max(score.Points, points)
+		}
+		if sub.Status == domain.STATUS_ACCEPTED {
+			score.Solved = true
+		}
+	}
+	return score
+}
+
+// testPoints scores a submission by the tests it passed: in proportion when
+// the problem gives partial credit, all or nothing otherwise.
+func testPoints(cp domain.ContestProblem, sub domain.Submission) int {
+	if sub.Status == domain.STATUS_ACCEPTED {
+		return cp.MaxPoints
+	}
+	if !cp.PartialCredit || sub.TotalTestCases == 0 {
+		return 0
+	}
+	return int(math.Round(float64(cp.MaxPoints) * float64(sub.TestCasesPassed) / float64(sub.TotalTestCases)))
+}
+
+func (ioiScoring) Compare(a, b ParticipantScore) int {
+	return cmp.Compare(b.TotalPoints, a.TotalPoints)
+}
+
+// decayingScoring is Codeforces style: a problem's points fall steadily
+// from the contest start and by a fixed amount for every wrong attempt
+// before the solve, but never below a floor. Only solves score.
+type decayingScoring struct {
+	config domain.ScoringConfig
+}
+
+func (d decayingScoring) ScoreProblem(p ProblemSubmissions) ProblemScore {
+	score := ProblemScore{SubmissionPoints: make(map[uuid.UUID]int)}
+	wrong := 0
+	for _, sub := range p.Submissions {
+		if domain.IsPendingStatus(sub.Status) {
+			continue
+		}
+		if sub.Status != domain.STATUS_ACCEPTED {
+			score.SubmissionPoints[sub.ID] = 0
+			if !score.Solved && isRejected(sub.Status) {
+				wrong++
+			}
+			continue
+		}
+		points := d.points(p.Problem, minutesSince(p.StartTime, sub.CreatedAt), wrong)
+		score.SubmissionPoints[sub.ID] = points
+		if !score.Solved {
+			score.Solved = true
+			score.Points = points
+		}
+	}
+	return score
+}
+
+func (d decayingScoring) points(cp domain.ContestProblem, minutes, wrong int) int {
+	full := float64(cp.MaxPoints)
+	decay := d.config.DecayPerMinute * cp.TimeMultiplier
+	points := full * (1 - decay*float64(minutes) - d.config.WrongAttemptDecay*float64(wrong))
+	return int(math.Round(math.Max(points, full*d.config.PointsFloor)))
+}
+
+func (decayingScoring) Compare(a, b ParticipantScore) int {
+	return cmp.Compare(b.TotalPoints, a.TotalPoints)
+}
+
+// applyScoringConfig sets the fields of config that req has.
+func applyScoringConfig(config *domain.ScoringConfig, req *dto.ScoringConfigDTO) {
+	if req == nil {
+		return
+	}
+	if req.TimePenaltyPerMin != nil {
+		config.TimePenaltyPerMin = *req.TimePenaltyPerMin
+	}
+	if req.WrongAttemptPenalty != nil {
+		config.WrongAttemptPenalty = *req.WrongAttemptPenalty
+	}
+	if req.PartialCreditMin != nil {
+		config.PartialCreditMin = *req.PartialCreditMin
+	}
+	if req.TimePenaltyMinutes != nil {
+		config.TimePenaltyMinutes = *req.TimePenaltyMinutes
+	}
+	if req.DecayPerMinute != nil {
+		config.DecayPerMinute = *req.DecayPerMinute
+	}
+	if req.WrongAttemptDecay != nil {
+		config.WrongAttemptDecay = *req.WrongAttemptDecay
+	}
+	if req.PointsFloor != nil {
+		config.PointsFloor = *req.PointsFloor
+	}
+}
+
+func validateScoringConfig(config domain.ScoringConfig) error {
+	switch {
+	case config.TimePenaltyPerMin < 0, config.WrongAttemptPenalty < 0, config.TimePenaltyMinutes < 0,
+		config.DecayPerMinute < 0, config.WrongAttemptDecay < 0:
+		return fmt.Errorf("%w: penalties must not be negative", ErrInvalidScoringConfig)
+	case config.PartialCreditMin < 0 || config.PartialCreditMin > 1:
+		return fmt.Errorf("%w: partial_credit_min must be between 0 and 1", ErrInvalidScoringConfig)
+	case config.PointsFloor < 0 || config.PointsFloor > 1:
+		return fmt.Errorf("%w: points_floor must be between 0 and 1", ErrInvalidScoringConfig)
+	}
+	return nil
+}
+
+func validateContestProblem(cp *domain.ContestProblem) error {
+	switch {
+	case cp.MaxPoints <= 0:
+		return fmt.Errorf("%w: max_points must be positive", ErrInvalidScoringConfig)
+	case cp.TimeMultiplier <= 0:
+		return fmt.Errorf("%w: time_multiplier must be positive", ErrInvalidScoringConfig)
+	case cp.TimePenaltyMinutes != nil && *cp.TimePenaltyMinutes < 0:
+		return fmt.Errorf("%w: time_penalty_minutes must not be negative", ErrInvalidScoringConfig)
+	}
+	return nil
+}
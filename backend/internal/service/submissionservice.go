@@ -1,59 +1,64 @@
 package service
 
 import (
+	"context"
+	"errors"
+
 	"github.com/google/uuid"
 	"github.com/sudankdk/codearena/configs"
 	"github.com/sudankdk/codearena/internal/domain"
 	"github.com/sudankdk/codearena/internal/dto"
 	"github.com/sudankdk/codearena/internal/helper"
+	"github.com/sudankdk/codearena/internal/judge"
 	"github.com/sudankdk/codearena/internal/repo"
+	"github.com/sudankdk/codearena/internal/storage"
 )
 
 type SubmissionService struct {
-	Repo     repo.SubmissionRepo
-	UserRepo repo.UserRepo
-	Auth     helper.Auth
-	Config   configs.AppConfigs
-}
-
-func (ss *SubmissionService) CreateSubmission(userID uuid.UUID, req dto.CreateSubmissionDTO) (*domain.Submission, error) {
-	// Check if user already solved this problem BEFORE creating the submission
-	wasAlreadySolved := false
-	if req.Status == domain.STATUS_ACCEPTED && req.ContestID == nil {
-		alreadySolved, err := ss.Repo.HasUserSolvedProblem(userID, req.ProblemID)
-		if err == nil {
-			wasAlreadySolved = alreadySolved
-		}
-	}
+	Repo         repo.SubmissionRepo
+	UserRepo     repo.UserRepo
+	ProblemRepo  repo.ProblemsRepo
+	LanguageRepo repo.LanguageRepo
+	Auth         helper.Auth
+	Config       configs.AppConfigs
+	Data         storage.TestData
+}
 
-	submission := &domain.Submission{
-		UserID:          userID,
-		ProblemID:       req.ProblemID,
-		ContestID:       req.ContestID, // Will be NULL for practice, UUID for contest
-		Language:        req.Language,
-		Code:            req.Code,
-		Status:          req.Status,
-		ExecutionTime:   req.ExecutionTime,
-		MemoryUsed:      req.MemoryUsed,
-		TestCasesPassed: req.TestCasesPassed,
-		TotalTestCases:  req.TotalTestCases,
-		ErrorMessage:    req.ErrorMessage,
+// CreateSubmission checks and stores a submission and queues it for the
+// judge workers; the verdict is filled in once it has been judged. Only
+// admins can submit to problems that are not public yet.
+func (ss *SubmissionService) CreateSubmission(userID uuid.UUID, req dto.CreateSubmissionDTO, admin bool) (*domain.Submission, error) {
+	if _, err := resolveLanguage(ss.LanguageRepo, req.Language); err != nil {
+		return nil, err
 	}
-
-	if err := ss.Repo.CreateSubmission(submission); err != nil {
+	if err := checkVisible(ss.ProblemRepo, req.ProblemID, admin); err != nil {
 		return nil, err
 	}
 
-	// Update user's solved count if this is the first time solving this problem (non-contest)
-	if req.Status == domain.STATUS_ACCEPTED && req.ContestID == nil && !wasAlreadySolved {
-		// Get updated stats to sync user's solved count
-		stats, err := ss.Repo.GetUserStats(userID)
-		if err == nil {
-			// Update user's solved count in the database
-			_ = ss.UserRepo.UpdateUserSolvedCount(userID, stats.TotalSolved)
-		}
+	problem, err := ss.ProblemRepo.GetProblemByID(req.ProblemID, true)
+	if err != nil {
+		return nil, errors.New("problem not found")
+	}
+	if len(problem.TestCases) == 0 {
+		return nil, judge.ErrNoTestCases
+	}
+	if _, err := submissionSource(problem, req.Language, req.Code); err != nil {
+		return nil, err
 	}
 
+	submission := &domain.Submission{
+		UserID:         userID,
+		ProblemID:      req.ProblemID,
+		ContestID:      req.ContestID, // Will be NULL for practice, UUID for contest
+		Language:       req.Language,
+		Code:           req.Code,
+		Status:         domain.STATUS_QUEUED,
+		TotalTestCases: len(problem.TestCases),
+		Job:            &domain.JudgeJob{},
+	}
+	if err := ss.Repo.CreateSubmission(submission); err != nil {
+		return nil, err
+	}
 	return submission, nil
 }
 
@@ -61,6 +66,128 @@ func (ss *SubmissionService) GetSubmissionByID(id uuid.UUID) (*domain.Submission
 	return ss.Repo.GetSubmissionByID(id)
 }
 
+// GetSubmissionStatus returns where a submission is in the judge.
+func (ss *SubmissionService) GetSubmissionStatus(id uuid.UUID) (*dto.SubmissionStatusDTO, error) {
+	sub, err := ss.Repo.GetSubmissionStatus(id)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.SubmissionStatusDTO{
+		ID:              sub.ID,
+		Status:          sub.Status,
+		Pending:         domain.IsPendingStatus(sub.Status),
+		TestCasesPassed: sub.TestCasesPassed,
+		TotalTestCases:  sub.TotalTestCases,
+		PointsEarned:    sub.PointsEarned,
+	}, nil
+}
+
+// ListJudgements returns every verdict a submission received, oldest first.
+func (ss *SubmissionService) ListJudgements(id uuid.UUID) ([]domain.SubmissionJudgement, error) {
+	if _, err := ss.Repo.GetSubmissionStatus(id); err != nil {
+		return nil, err
+	}
+	return ss.Repo.ListJudgements(id)
+}
+
+// RejudgeSubmission queues a submission to be judged again. Its current
+// verdict stays in its judgement history.
+func (ss *SubmissionService) RejudgeSubmission(id uuid.UUID) (*dto.RejudgeDTO, error) {
+	if _, err := ss.Repo.GetSubmissionStatus(id); err != nil {
+		return nil, err
+	}
+	return ss.rejudge([]uuid.UUID{id})
+}
+
+// RejudgeProblem queues every submission to a problem to be judged again,
+// for example after one of its tests was fixed.
+func (ss *SubmissionService) RejudgeProblem(problemID uuid.UUID) (*dto.RejudgeDTO, error) {
+	if _, err := ss.ProblemRepo.GetProblemByID(problemID, false); err != nil {
+		return nil, errors.New("problem not found")
+	}
+	ids, err := ss.Repo.ListSubmissionIDs(&problemID, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ss.rejudge(ids)
+}
+
+// RejudgeContest queues every submission made in a contest to be judged
+// again.
+func (ss *SubmissionService) RejudgeContest(contestID uuid.UUID) (*dto.RejudgeDTO, error) {
+	ids, err := ss.Repo.ListSubmissionIDs(nil, &contestID)
+	if err != nil {
+		return nil, err
+	}
+	return ss.rejudge(ids)
+}
+
+// rejudge puts submissions back on the judge queue. The judge workers store
+// the new verdicts and rebuild solved counts and contest totals from them.
+func (ss *SubmissionService) rejudge(ids []uuid.UUID) (*dto.RejudgeDTO, error) {
+	if err := ss.Repo.RequeueSubmissions(ids); err != nil {
+		return nil, err
+	}
+	return &dto.RejudgeDTO{Queued: len(ids)}, nil
+}
+
+// GetSubmissionDetail returns a submission with its per-test results. Data of
+// hidden tests is left out so it cannot be read back through submissions.
+func (ss *SubmissionService) GetSubmissionDetail(id uuid.UUID) (*dto.SubmissionDetailDTO, error) {
+	sub, err := ss.Repo.GetSubmissionByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &dto.SubmissionDetailDTO{
+		SubmissionResponseDTO: toSubmissionResponse(*sub),
+		Code:                  sub.Code,
+		ErrorMessage:          sub.ErrorMessage,
+		TestResults:           make([]dto.SubmissionTestResultDTO, 0, len(sub.TestResults)),
+	}
+	for _, tr := range sub.TestResults {
+		row := dto.SubmissionTestResultDTO{
+			TestCaseID:    tr.TestCaseID,
+			Index:         tr.OrderIndex + 1,
+			Hidden:        !isPublicTest(tr.TestCase),
+			Status:        tr.Status,
+			ExecutionTime: tr.ExecutionTime,
+			MemoryUsed:    tr.MemoryUsed,
+		}
+		if tr.TestCase != nil {
+			row.Subtask = tr.TestCase.Subtask
+		}
+		if !row.Hidden {
+			tc := *tr.TestCase
+			if err := ss.Data.Load(context.Background(), &tc); err != nil {
+				return nil, err
+			}
+			row.Input = tc.Input
+			row.Expected = tc.Expected
+			row.Stdout = tr.StdoutExcerpt
+			row.Stderr = tr.StderrExcerpt
+			row.CheckerMessage = tr.CheckerMessage
+		}
+		detail.TestResults = append(detail.TestResults, row)
+	}
+	for _, sr := range sub.SubtaskResults {
+		detail.Subtasks = append(detail.Subtasks, dto.SubtaskResultDTO{
+			Index:  sr.SubtaskIndex,
+			Name:   sr.Name,
+			Status: sr.Status,
+			Points: sr.Points,
+			Score:  sr.Score,
+		})
+	}
+	return detail, nil
+}
+
+// isPublicTest reports whether a test's data may be shown to the submitter:
+// only samples are. Deleted test cases are treated as hidden.
+func isPublicTest(tc *domain.TestCases) bool {
+	return tc != nil && tc.IsSample
+}
+
 func (ss *SubmissionService) ListSubmissions(opts dto.SubmissionListQueryDTO) ([]dto.SubmissionResponseDTO, int64, error) {
 	submissions, total, err := ss.Repo.ListSubmissions(opts)
 	if err != nil {
@@ -69,23 +196,7 @@ func (ss *SubmissionService) ListSubmissions(opts dto.SubmissionListQueryDTO) ([
 
 	response := make([]dto.SubmissionResponseDTO, len(submissions))
 	for i, sub := range submissions {
-		response[i] = dto.SubmissionResponseDTO{
-			ID:              sub.ID,
-			UserID:          sub.UserID,
-			ProblemID:       sub.ProblemID,
-			ContestID:       sub.ContestID,
-			ProblemSlug:     sub.Problem.Slug,
-			ProblemTitle:    sub.Problem.MainHeading,
-			Difficulty:      sub.Problem.Difficulty,
-			Language:        sub.Language,
-			Status:          sub.Status,
-			ExecutionTime:   sub.ExecutionTime,
-			MemoryUsed:      sub.MemoryUsed,
-			TestCasesPassed: sub.TestCasesPassed,
-			TotalTestCases:  sub.TotalTestCases,
-			PointsEarned:    sub.PointsEarned,
-			CreatedAt:       sub.CreatedAt.Format("2006-01-02 15:04:05"),
-		}
+		response[i] = toSubmissionResponse(sub)
 	}
 
 	return response, total, nil
@@ -105,23 +216,7 @@ func (ss *SubmissionService) GetUserStats(userID uuid.UUID) (*dto.UserStatsDTO,
 
 	stats.RecentSubmissions = make([]dto.SubmissionResponseDTO, len(recentSubmissions))
 	for i, sub := range recentSubmissions {
-		stats.RecentSubmissions[i] = dto.SubmissionResponseDTO{
-			ID:              sub.ID,
-			UserID:          sub.UserID,
-			ProblemID:       sub.ProblemID,
-			ContestID:       sub.ContestID,
-			ProblemSlug:     sub.Problem.Slug,
-			ProblemTitle:    sub.Problem.MainHeading,
-			Difficulty:      sub.Problem.Difficulty,
-			Language:        sub.Language,
-			Status:          sub.Status,
-			ExecutionTime:   sub.ExecutionTime,
-			MemoryUsed:      sub.MemoryUsed,
-			TestCasesPassed: sub.TestCasesPassed,
-			TotalTestCases:  sub.TotalTestCases,
-			PointsEarned:    sub.PointsEarned,
-			CreatedAt:       sub.CreatedAt.Format("2006-01-02 15:04:05"),
-		}
+		stats.RecentSubmissions[i] = toSubmissionResponse(sub)
 	}
 
 	return stats, nil
@@ -138,3 +233,24 @@ func (ss *SubmissionService) GetUserSolvedProblems(userID uuid.UUID) ([]uuid.UUI
 func (ss *SubmissionService) GetTopicStats() ([]dto.TopicStatsDTO, error) {
 	return ss.Repo.GetTopicStats()
 }
+
+func toSubmissionResponse(sub domain.Submission) dto.SubmissionResponseDTO {
+	return dto.SubmissionResponseDTO{
+		ID:              sub.ID,
+		UserID:          sub.UserID,
+		ProblemID:       sub.ProblemID,
+		ContestID:       sub.ContestID,
+		ProblemSlug:     sub.Problem.Slug,
+		ProblemTitle:    sub.Problem.MainHeading,
+		Difficulty:      sub.Problem.Difficulty,
+		Language:        sub.Language,
+		Status:          sub.Status,
+		ExecutionTime:   sub.ExecutionTime,
+		MemoryUsed:      sub.MemoryUsed,
+		TestCasesPassed: sub.TestCasesPassed,
+		TotalTestCases:  sub.TotalTestCases,
+		PointsEarned:    sub.PointsEarned,
+		ProblemRevision: sub.ProblemRevision,
+		CreatedAt:       sub.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
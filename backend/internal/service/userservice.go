@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/sudankdk/codearena/configs"
 	"github.com/sudankdk/codearena/internal/domain"
 	"github.com/sudankdk/codearena/internal/dto"
@@ -12,9 +13,10 @@ import (
 )
 
 type UserService struct {
-	Repo   repo.UserRepo
-	Auth   helper.Auth
-	Config configs.AppConfigs
+	Repo         repo.UserRepo
+	LanguageRepo repo.LanguageRepo
+	Auth         helper.Auth
+	Config       configs.AppConfigs
 }
 
 func (u *UserService) Register(dto dto.UserRegister) (domain.User, error) {
@@ -59,6 +61,16 @@ func (u *UserService) VerifyCode(code string, id uint) error {
 	return nil
 }
 
+// UpdatePreferences stores the user's preferred language after checking it
+// against the language registry.
+func (u *UserService) UpdatePreferences(id uuid.UUID, req dto.UpdatePreferencesDTO) (domain.User, error) {
+	lang, err := resolveLanguage(u.LanguageRepo, req.LanguagePreference)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return u.Repo.UpdateUser(id, domain.User{LanguagePreference: lang.ID})
+}
+
 func (u *UserService) ListUsers() ([]domain.User, error) {
 
 	users, err := u.Repo.ListUser()
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/dto"
+	"github.com/sudankdk/codearena/internal/judge"
+)
+
+// Verify checks problem id before it is published: the input validator
+// runs on every test and each reference solution is judged on all tests
+// and compared with the verdict it is expected to get.
+func (rs *RunService) Verify(ctx context.Context, id string) (*dto.VerificationDTO, error) {
+	problemID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid problem ID")
+	}
+	problem, err := rs.ProblemRepo.GetProblemByID(problemID, true)
+	if err != nil {
+		return nil, errors.New("problem not found")
+	}
+	if len(problem.TestCases) == 0 {
+		return nil, judge.ErrNoTestCases
+	}
+	programs, err := rs.ProblemRepo.ListPrograms(problemID)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case rs.Slots <- struct{}{}:
+		defer func() { <-rs.Slots }()
+	default:
+		return nil, ErrRunnerBusy
+	}
+
+	out := &dto.VerificationDTO{Tests: len(problem.TestCases), Solutions: []dto.SolutionReportDTO{}, Warnings: []string{}}
+	var solutions []domain.ProblemProgram
+	for _, program := range programs {
+		switch program.Role {
+		case domain.PROGRAM_VALIDATOR:
+			if out.Validator, err = rs.validate(ctx, program, problem.TestCases); err != nil {
+				return nil, err
+			}
+			out.Mismatches += len(out.Validator.Invalid)
+			if out.Validator.CompileError != "" {
+				out.Mismatches++
+			}
+		case domain.PROGRAM_SOLUTION:
+			solutions = append(solutions, program)
+		}
+	}
+	if out.Validator == nil {
+		out.Warnings = append(out.Warnings, "the problem has no input validator")
+	}
+
+	checker, err := checkerSpec(rs.ProblemRepo, rs.LanguageRepo, problem)
+	if err != nil {
+		return nil, err
+	}
+	interactor, err := interactorSpec(rs.ProblemRepo, rs.LanguageRepo, problem)
+	if err != nil {
+		return nil, err
+	}
+	accepted := false
+	for _, s := range solutions {
+		lang, err := resolveLanguage(rs.LanguageRepo, s.Language)
+		if err != nil {
+			return nil, fmt.Errorf("solution %s: %w", s.Name, err)
+		}
+		req := judge.Request{
+			Language:   *lang,
+			Tests:      problem.TestCases,
+			Data:       rs.Data,
+			Checker:    checker,
+			Interactor: interactor,
+		}
+		if req.Code, err = submissionSource(problem, lang.ID, s.Code); err != nil {
+			return nil, fmt.Errorf("solution %s: %w", s.Name, err)
+		}
+		req.TimeLimit, req.MemoryLimitKB = judge.Limits(problem, *lang)
+		result, err := rs.Judge.Evaluate(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("solution %s: %w", s.Name, err)
+		}
+
+		report := dto.SolutionReportDTO{
+			Name:            s.Name,
+			Language:        s.Language,
+			Expected:        s.Expected,
+			Status:          result.Status,
+			Matches:         result.Status == s.Expected,
+			TestCasesPassed: result.TestCasesPassed,
+			TotalTestCases:  result.TotalTestCases,
+			ExecutionTime:   result.ExecutionTime,
+			ErrorMessage:    result.ErrorMessage,
+		}
+		for i, tr := range result.Tests {
+			if tr.Status != domain.STATUS_ACCEPTED {
+				report.FailedTest = i + 1
+				break
+			}
+		}
+		if !report.Matches {
+			out.Mismatches++
+		}
+		if s.Expected == domain.STATUS_ACCEPTED {
+			accepted = true
+			// Accepted solutions close to the limit fail on a slower judge
+			if limit := req.TimeLimit.Milliseconds(); report.Matches && int64(result.ExecutionTime)*2 > limit {
+				out.Warnings = append(out.Warnings, fmt.Sprintf("solution %s uses %d ms of the %d ms time limit", s.Name, result.ExecutionTime, limit))
+			}
+		}
+		out.Solutions = append(out.Solutions, report)
+	}
+	if !accepted {
+		out.Warnings = append(out.Warnings, "the problem has no solution expected to be accepted")
+	}
+	out.OK = out.Mismatches == 0
+	return out, nil
+}
+
+// validate runs the input validator program over tests.
+func (rs *RunService) validate(ctx context.Context, program domain.ProblemProgram, tests []domain.TestCases) (*dto.ValidatorReportDTO, error) {
+	lang, err := resolveLanguage(rs.LanguageRepo, program.Language)
+	if err != nil {
+		return nil, fmt.Errorf("input validator: %w", err)
+	}
+	validation, err := rs.Judge.ValidateInputs(ctx, judge.ValidatorSpec{Program: program.Code, Language: *lang}, tests, rs.Data)
+	if err != nil {
+		return nil, fmt.Errorf("input validator: %w", err)
+	}
+	report := &dto.ValidatorReportDTO{CompileError: validation.CompileError, Invalid: []dto.InvalidInputDTO{}}
+	for i, check := range validation.Tests {
+		if !check.Valid {
+			report.Invalid = append(report.Invalid, dto.InvalidInputDTO{TestCaseID: check.TestCaseID, Index: i + 1, Message: check.Message})
+		}
+	}
+	return report, nil
+}
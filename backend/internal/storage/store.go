@@ -0,0 +1,56 @@
+// Package storage keeps large blobs, such as test data and problem
+// attachments, outside the database.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned when a key has no blob.
+var ErrNotFound = errors.New("blob not found")
+
+// Store is a flat key/value store of blobs. Keys are slash separated paths
+// such as "tests/ab/abcd...". Putting an existing key replaces its blob.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Key is where the blob with the given SHA-256 is kept below prefix. The
+// first byte of the hash becomes a directory so no directory grows too big.
+func Key(prefix, hash string) string {
+	return path.Join(prefix, hash[:2], hash)
+}
+
+// Hash returns the hex SHA-256 of data.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// PutHashed stores data under its SHA-256 below prefix and returns the hash.
+// Identical blobs share a key, so storing one twice is cheap to undo.
+func PutHashed(ctx context.Context, s Store, prefix string, data []byte) (string, error) {
+	hash := Hash(data)
+	if err := s.Put(ctx, Key(prefix, hash), bytes.NewReader(data), int64(len(data))); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// checkKey rejects keys that could escape the store's root.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"unicode/utf8"
+
+	"github.com/sudankdk/codearena/internal/domain"
+)
+
+// PreviewSize is how many bytes of each test file the database keeps.
+const PreviewSize = 256
+
+const (
+	testsPrefix       = "tests"
+	attachmentsPrefix = "attachments"
+)
+
+// File describes a blob kept in a Store by its SHA-256.
+type File struct {
+	Hash    string
+	Size    int64
+	Preview string
+}
+
+// TestData keeps the inputs and expected outputs of tests in a Store. The
+// test rows only hold the hashes, sizes and previews of their files.
+type TestData struct {
+	Store Store
+}
+
+// Put stores one test file.
+func (d TestData) Put(ctx context.Context, content string) (File, error) {
+	hash, err := PutHashed(ctx, d.Store, testsPrefix, []byte(content))
+	if err != nil {
+		return File{}, err
+	}
+	return File{Hash: hash, Size: int64(len(content)), Preview: Preview(content)}, nil
+}
+
+// Save stores the Input and Expected of tc and records where they went.
+func (d TestData) Save(ctx context.Context, tc *domain.TestCases) error {
+	in, err := d.Put(ctx, tc.Input)
+	if err != nil {
+		return err
+	}
+	out, err := d.Put(ctx, tc.Expected)
+	if err != nil {
+		return err
+	}
+	tc.InputHash, tc.InputSize, tc.InputPreview = in.Hash, in.Size, in.Preview
+	tc.ExpectedHash, tc.ExpectedSize, tc.ExpectedPreview = out.Hash, out.Size, out.Preview
+	return nil
+}
+
+// Load reads the Input and Expected of tc back from the store. Files
+// without a hash are left alone.
+func (d TestData) Load(ctx context.Context, tc *domain.TestCases) error {
+	var err error
+	if tc.InputHash != "" {
+		if tc.Input, err = d.read(ctx, tc.InputHash); err != nil {
+			return err
+		}
+	}
+	if tc.ExpectedHash != "" {
+		tc.Expected, err = d.read(ctx, tc.ExpectedHash)
+	}
+	return err
+}
+
+// LoadAll loads every test of tests.
+func (d TestData) LoadAll(ctx context.Context, tests []domain.TestCases) error {
+	for i := range tests {
+		if err := d.Load(ctx, &tests[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Describe returns the File of a stored test file of the given size,
+// reading only as much of it as the preview needs.
+func (d TestData) Describe(ctx context.Context, hash string, size int64) (File, error) {
+	r, err := d.Open(ctx, hash)
+	if err != nil {
+		return File{}, err
+	}
+	defer r.Close()
+	head, err := io.ReadAll(io.LimitReader(r, PreviewSize+utf8.UTFMax))
+	if err != nil {
+		return File{}, err
+	}
+	return File{Hash: hash, Size: size, Preview: Preview(string(head))}, nil
+}
+
+// Open streams the test file with the given hash.
+func (d TestData) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	return d.Store.Get(ctx, Key(testsPrefix, hash))
+}
+
+func (d TestData) read(ctx context.Context, hash string) (string, error) {
+	r, err := d.Open(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	return string(data), err
+}
+
+// Preview returns the start of content, at most PreviewSize bytes, without
+// cutting a UTF-8 sequence in half.
+func Preview(content string) string {
+	if len(content) <= PreviewSize {
+		return content
+	}
+	end := PreviewSize
+	for end > 0 && !utf8.RuneStart(content[end]) {
+		end--
+	}
+	return content[:end]
+}
+
+// Attachments keeps the files attached to problems in a Store.
+type Attachments struct {
+	Store Store
+}
+
+// Put stores an attachment and returns its hash.
+func (a Attachments) Put(ctx context.Context, data []byte) (string, error) {
+	return PutHashed(ctx, a.Store, attachmentsPrefix, data)
+}
+
+// Open streams the attachment with the given hash.
+func (a Attachments) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	return a.Store.Get(ctx, Key(attachmentsPrefix, hash))
+}
+
+// Delete removes the attachment with the given hash.
+func (a Attachments) Delete(ctx context.Context, hash string) error {
+	return a.Store.Delete(ctx, Key(attachmentsPrefix, hash))
+}
@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const DefaultScheduleInterval = 15 * time.Second
+
+// ContestLifecycle moves contests through their statuses.
+// *service.ContestService implements it.
+type ContestLifecycle interface {
+	UpdateStatuses(now time.Time) (started, ended int64, err error)
+	FinalizeEndedContests(now time.Time) ([]uuid.UUID, error)
+}
+
+// Scheduler starts and ends contests on time and finalizes ended contests
+// once their submissions are judged. All its state is in the database, so it
+// picks up where it left off after a restart, and several servers can run
+// one at the same time.
+type Scheduler struct {
+	Contests ContestLifecycle
+	Interval time.Duration
+	Logger   *zap.Logger
+}
+
+func NewScheduler(contests ContestLifecycle, logger *zap.Logger) *Scheduler {
+	return &Scheduler{
+		Contests: contests,
+		Interval: DefaultScheduleInterval,
+		Logger:   logger,
+	}
+}
+
+// Run updates contests right away and then every Interval until ctx is
+// cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		s.tick(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(now time.Time) {
+	started, ended, err := s.Contests.UpdateStatuses(now)
+	if err != nil {
+		s.Logger.Error("Failed to update contest statuses", zap.Error(err))
+	}
+	if started > 0 || ended > 0 {
+		s.Logger.Info("Updated contest statuses", zap.Int64("started", started), zap.Int64("ended", ended))
+	}
+
+	finalized, err := s.Contests.FinalizeEndedContests(now)
+	for _, id := range finalized {
+		s.Logger.Info("Finalized contest rankings", zap.String("contest_id", id.String()))
+	}
+	if err != nil {
+		s.Logger.Error("Failed to finalize contests", zap.Error(err))
+	}
+}
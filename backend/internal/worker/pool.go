@@ -0,0 +1,155 @@
+// Package worker runs the background workers: the judge workers that drain
+// the judge queue and the scheduler that moves contests along.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/repo"
+	"github.com/sudankdk/codearena/internal/service"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultLease        = 2 * time.Minute
+	DefaultPollInterval = time.Second
+	DefaultMaxAttempts  = 3
+)
+
+// Judger judges one queued submission. *service.JudgeService implements it.
+type Judger interface {
+	JudgeSubmission(ctx context.Context, id uuid.UUID) error
+	FailSubmission(id uuid.UUID, message string) error
+}
+
+// Pool is a fixed number of workers claiming jobs from the judge queue.
+// Workers hold a lease on their job and renew it while judging; a job whose
+// worker died is claimed again once the lease runs out, up to MaxAttempts
+// times before the submission gets a judge error.
+type Pool struct {
+	Jobs         repo.JudgeJobRepo
+	Judge        Judger
+	Workers      int
+	Lease        time.Duration
+	PollInterval time.Duration
+	MaxAttempts  int
+	Logger       *zap.Logger
+}
+
+func New(jobs repo.JudgeJobRepo, judge Judger, workers int, logger *zap.Logger) *Pool {
+	return &Pool{
+		Jobs:         jobs,
+		Judge:        judge,
+		Workers:      workers,
+		Lease:        DefaultLease,
+		PollInterval: DefaultPollInterval,
+		MaxAttempts:  DefaultMaxAttempts,
+		Logger:       logger,
+	}
+}
+
+// Run starts the workers and blocks until ctx is cancelled and they have
+// finished their current jobs.
+func (p *Pool) Run(ctx context.Context) {
+	host, _ := os.Hostname()
+	var wg sync.WaitGroup
+	for i := 0; i < max(p.Workers, 1); i++ {
+		wg.Add(1)
+		id := fmt.Sprintf("%s-%d-%d", host, os.Getpid(), i)
+		go func() {
+			defer wg.Done()
+			p.work(ctx, id)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *Pool) work(ctx context.Context, workerID string) {
+	for ctx.Err() == nil {
+		job, err := p.Jobs.Claim(workerID, p.Lease)
+		if err != nil {
+			p.Logger.Error("Failed to claim judge job", zap.String("worker", workerID), zap.Error(err))
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(p.PollInterval):
+			}
+			continue
+		}
+		p.process(ctx, workerID, job)
+	}
+}
+
+// process judges one claimed job. Judging is not cancelled with ctx so that
+// a shutdown does not leave half-stored verdicts behind.
+func (p *Pool) process(ctx context.Context, workerID string, job *domain.JudgeJob) {
+	log := p.Logger.With(
+		zap.String("worker", workerID),
+		zap.String("submission_id", job.SubmissionID.String()),
+		zap.Int("attempt", job.Attempts))
+
+	if job.Attempts > p.MaxAttempts {
+		log.Error("Giving up on submission", zap.String("last_error", job.LastError))
+		if err := p.Judge.FailSubmission(job.SubmissionID, "judging failed repeatedly: "+job.LastError); err != nil {
+			log.Error("Failed to store judge error", zap.Error(err))
+		}
+		p.finish(log, job, workerID, domain.JOB_FAILED, job.LastError)
+		return
+	}
+
+	stop := p.keepLease(log, job, workerID)
+	err := p.Judge.JudgeSubmission(context.WithoutCancel(ctx), job.SubmissionID)
+	stop()
+
+	switch {
+	case err == nil:
+		p.finish(log, job, workerID, domain.JOB_DONE, "")
+	case errors.Is(err, service.ErrScoring):
+		// The verdict is stored, so the retry only scores it again
+		log.Warn("Contest scoring failed, job will be retried", zap.Error(err))
+		p.release(log, job, workerID, err)
+	default:
+		log.Warn("Judging failed, job will be retried", zap.Error(err))
+		p.release(log, job, workerID, err)
+	}
+}
+
+func (p *Pool) release(log *zap.Logger, job *domain.JudgeJob, workerID string, jobErr error) {
+	if err := p.Jobs.Release(job.ID, workerID, jobErr.Error()); err != nil {
+		log.Error("Failed to release judge job", zap.Error(err))
+	}
+}
+
+func (p *Pool) finish(log *zap.Logger, job *domain.JudgeJob, workerID, status, lastError string) {
+	if err := p.Jobs.Finish(job.ID, workerID, status, lastError); err != nil {
+		log.Error("Failed to finish judge job", zap.Error(err))
+	}
+}
+
+// keepLease renews the job's lease until the returned function is called.
+func (p *Pool) keepLease(log *zap.Logger, job *domain.JudgeJob, workerID string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(p.Lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := p.Jobs.Renew(job.ID, workerID, p.Lease); err != nil {
+					log.Warn("Failed to renew judge job lease", zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
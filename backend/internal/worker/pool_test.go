@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/sudankdk/codearena/internal/domain"
+	"github.com/sudankdk/codearena/internal/service"
+	"go.uber.org/zap"
+)
+
+type fakeJobs struct {
+	calls []string
+}
+
+func (f *fakeJobs) Claim(string, time.Duration) (*domain.JudgeJob, error) { return nil, nil }
+func (f *fakeJobs) Renew(uuid.UUID, string, time.Duration) error          { return nil }
+
+func (f *fakeJobs) Finish(_ uuid.UUID, _, status, _ string) error {
+	f.calls = append(f.calls, "finish "+status)
+	return nil
+}
+
+func (f *fakeJobs) Release(uuid.UUID, string, string) error {
+	f.calls = append(f.calls, "release")
+	return nil
+}
+
+type fakeJudge struct {
+	err    error
+	failed string
+}
+
+func (f *fakeJudge) JudgeSubmission(context.Context, uuid.UUID) error { return f.err }
+
+func (f *fakeJudge) FailSubmission(_ uuid.UUID, message string) error {
+	f.failed = message
+	return nil
+}
+
+func TestPoolProcess(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		calls    []string
+		failed   bool
+	}{
+		{"judged", 1, nil, []string{"finish done"}, false},
+		{"retried", 1, errors.New("sandbox setup failed"), []string{"release"}, false},
+		{"scoring error is retried", 1, fmt.Errorf("%w: db down", service.ErrScoring), []string{"release"}, false},
+		{"gives up", DefaultMaxAttempts + 1, nil, []string{"finish failed"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, judge := &fakeJobs{}, &fakeJudge{err: tt.err}
+			pool := New(jobs, judge, 1, zap.NewNop())
+
+			pool.process(context.Background(), "w", &domain.JudgeJob{Attempts: tt.attempts, LastError: "boom"})
+
+			assert.Equal(t, tt.calls, jobs.calls)
+			assert.Equal(t, tt.failed, judge.failed != "")
+		})
+	}
+}
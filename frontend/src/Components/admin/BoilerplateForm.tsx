@@ -11,9 +11,11 @@ interface BoilerplateFormProps {
 }
 
 const SUPPORTED_LANGUAGES = [
-  { value: "python", label: "Python" },
-  { value: "javascript", label: "JavaScript" },
+  { value: "py", label: "Python" },
+  { value: "js", label: "JavaScript" },
   { value: "go", label: "Go" },
+  { value: "cpp", label: "C++" },
+  { value: "java", label: "Java" },
 ];
 
 export const BoilerplateForm = ({
@@ -68,7 +70,7 @@ export const BoilerplateForm = ({
               <Label htmlFor={`code-${index}`}>Code</Label>
               <Editor
                 height="300px"
-                language={boilerplate.language === "go" ? "go" : boilerplate.language === "python" ? "python" : "javascript"}
+                language={boilerplate.language === "go" ? "go" : boilerplate.language === "py" ? "python" : boilerplate.language === "cpp" ? "cpp" : boilerplate.language === "java" ? "java" : "javascript"}
                 value={boilerplate.code}
                 theme="vs-dark"
                 onChange={(value) => onBoilerplateChange(index, "code", value || "")}
@@ -14,7 +14,7 @@ interface CreateProblemDialogProps {
   loading: boolean;
   onSubmit: (e: React.FormEvent) => void;
   onInputChange: (e: React.ChangeEvent<HTMLInputElement | HTMLTextAreaElement | HTMLSelectElement>) => void;
-  onTestCaseChange: (index: number, field: keyof ITestCase, value: string) => void;
+  onTestCaseChange: (index: number, field: keyof ITestCase, value: string | boolean) => void;
   onAddTestCase: () => void;
   onRemoveTestCase: (index: number) => void;
   onBoilerplateChange: (index: number, field: keyof IBoilerplate, value: string) => void;
@@ -104,7 +104,7 @@ export const ProblemsTable = ({ problems, loading, onDelete, onEdit }: ProblemsT
                   <svg className="w-4 h-4 text-gray-400 mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24">
                     <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M9 5H7a2 2 0 00-2 2v12a2 2 0 002 2h10a2 2 0 002-2V7a2 2 0 00-2-2h-2M9 5a2 2 0 002 2h2a2 2 0 002-2M9 5a2 2 0 012-2h2a2 2 0 012 2" />
                   </svg>
-                  <span className="text-sm font-medium text-gray-700">{problem.test_cases.length}</span>
+                  <span className="text-sm font-medium text-gray-700">{problem.test_case_count ?? problem.test_cases?.length ?? 0}</span>
                 </div>
               </td>
               <td className="px-6 py-4 whitespace-nowrap text-sm font-medium">
@@ -5,7 +5,7 @@ import type { ITestCase } from '@/types/problemstest/problemtest';
 
 interface TestCaseFormProps {
   testCases: ITestCase[];
-  onTestCaseChange: (index: number, field: keyof ITestCase, value: string) => void;
+  onTestCaseChange: (index: number, field: keyof ITestCase, value: string | boolean) => void;
   onAddTestCase: () => void;
   onRemoveTestCase: (index: number) => void;
 }
@@ -53,6 +53,19 @@ export const TestCaseForm = ({
               onChange={(e) => onTestCaseChange(index, "expected", e.target.value)}
               required
             />
+            <Input
+              placeholder="Explanation (optional, shown with samples)"
+              value={testCase.explanation || ""}
+              onChange={(e) => onTestCaseChange(index, "explanation", e.target.value)}
+            />
+            <label className="flex items-center gap-2 text-sm">
+              <input
+                type="checkbox"
+                checked={!!testCase.is_sample}
+                onChange={(e) => onTestCaseChange(index, "is_sample", e.target.checked)}
+              />
+              Sample (visible to contestants)
+            </label>
           </div>
         ))}
       </div>
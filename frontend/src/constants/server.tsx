@@ -1,2 +1 @@
 export const server= "http://localhost:8080/"
-export const server2= "http://localhost:3000"
\ No newline at end of file
@@ -4,7 +4,7 @@ import { Input } from '@/components/ui/input';
 import { Label } from '@/components/ui/label';
 import { Textarea } from '@/components/ui/textarea';
 import { Switch } from '@/components/ui/switch';
-import type { ICreateContest } from '@/types/contest/contest';
+import type { ICreateContest, ScoringMode } from '@/types/contest/contest';
 
 interface ContestFormProps {
   onSubmit: (contest: ICreateContest) => void;
@@ -18,6 +18,7 @@ export const ContestForm = ({ onSubmit, isLoading }: ContestFormProps) => {
     start_time: '',
     end_time: '',
     is_rated: true,
+    scoring_mode: 'hybrid',
   });
 
   const handleSubmit = (e: React.FormEvent) => {
@@ -101,6 +102,25 @@ export const ContestForm = ({ onSubmit, isLoading }: ContestFormProps) => {
         </div>
       </div>
 
+      <div className="space-y-2">
+        <Label htmlFor="scoring_mode">
+          Scoring Mode
+        </Label>
+        <select
+          id="scoring_mode"
+          value={formData.scoring_mode}
+          onChange={(e) =>
+            setFormData((prev) => ({ ...prev, scoring_mode: e.target.value as ScoringMode }))
+          }
+          className="w-full border border-gray-300 rounded px-4 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500"
+        >
+          <option value="hybrid">Hybrid (points with time and attempt penalties)</option>
+          <option value="icpc">ICPC (problems solved, then penalty time)</option>
+          <option value="ioi">IOI (best score per problem)</option>
+          <option value="decaying">Decaying points (Codeforces style)</option>
+        </select>
+      </div>
+
       <div className="flex items-center space-x-3">
         <Switch
           id="is_rated"
@@ -12,7 +12,8 @@ import {
   getContestParticipants,
   getContestLeaderboard,
   getGlobalLeaderboard,
-  finalizeContestRankings
+  finalizeContestRankings,
+  rollbackContestFinalization
 } from '@/services/auth/api/contest';
 import type { 
   ICreateContest, 
@@ -179,3 +180,17 @@ export const useFinalizeContest = () => {
     },
   });
 };
+
+// Roll Back Contest Finalization Mutation
+export const useRollbackContest = () => {
+  const queryClient = useQueryClient();
+
+  return useMutation({
+    mutationFn: (contestId: string) => rollbackContestFinalization(contestId),
+    onSuccess: (_, contestId) => {
+      queryClient.invalidateQueries({ queryKey: contestKeys.detail(contestId) });
+      queryClient.invalidateQueries({ queryKey: contestKeys.leaderboard(contestId) });
+      queryClient.invalidateQueries({ queryKey: contestKeys.globalLeaderboard() });
+    },
+  });
+};
@@ -8,6 +8,7 @@ import {
   useAddProblemToContest,
   useRemoveProblemFromContest,
   useFinalizeContest,
+  useRollbackContest,
 } from '../hooks/useContests';
 import { AddProblemForm } from '../components/admin/AddProblemForm';
 import { ProblemsList } from '../components/admin/ProblemsList';
@@ -30,6 +31,7 @@ export const AdminContestDetail = () => {
   const addProblemMutation = useAddProblemToContest(contestId!);
   const removeProblemMutation = useRemoveProblemFromContest(contestId!);
   const finalizeMutation = useFinalizeContest();
+  const rollbackMutation = useRollbackContest();
 
   const handleAddProblem = async (problem: IAddProblemToContest) => {
     try {
@@ -65,6 +67,18 @@ export const AdminContestDetail = () => {
     }
   };
 
+  const handleRollbackContest = async () => {
+    if (!confirm('Roll back finalization? Ratings and the global leaderboard will be restored.')) return;
+
+    try {
+      await rollbackMutation.mutateAsync(contestId!);
+      alert('Contest finalization rolled back!');
+    } catch (error) {
+      console.error('Failed to roll back contest:', error);
+      alert('Failed to roll back contest');
+    }
+  };
+
   if (contestLoading) {
     return (
       <AdminDashboardLayout>
@@ -106,6 +120,15 @@ export const AdminContestDetail = () => {
             {finalizeMutation.isPending ? 'Finalizing...' : 'Finalize Rankings'}
           </Button>
         )}
+        {contest.status === 'finalized' && (
+          <Button
+            variant="outline"
+            onClick={handleRollbackContest}
+            disabled={rollbackMutation.isPending}
+          >
+            {rollbackMutation.isPending ? 'Rolling back...' : 'Roll Back Finalization'}
+          </Button>
+        )}
       </div>
 
       {/* Stats */}
@@ -186,7 +209,7 @@ export const AdminContestDetail = () => {
         <ContestLeaderboard
           entries={leaderboard}
           isLoading={leaderboardLoading}
-          showRatingChange={contest.is_finalized}
+          showRatingChange={contest.status === 'finalized'}
         />
       )}
     </div>
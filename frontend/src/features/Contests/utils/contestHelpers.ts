@@ -188,7 +188,7 @@ export const canAccessProblems = (contest: IContest, isRegistered: boolean): {
 
 export const canFinalize = (contest: IContest): boolean => {
   const status = getContestStatus(contest);
-  return status === 'ended' && contest.is_rated && !contest.is_finalized;
+  return status === 'ended' && contest.is_rated && contest.status !== 'finalized';
 };
 
 // Ranking Helpers
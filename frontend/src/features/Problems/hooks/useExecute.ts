@@ -1,10 +1,10 @@
 import { useMutation } from "@tanstack/react-query";
-import { executeCode } from "../../../services/auth/api/cee";
+import { runCode } from "../../../services/auth/api/submission";
+import type { IRunCode } from "@/types/submission/submission";
 
 export const useExecuteCode = () => {
   return useMutation({
-    mutationFn: ({ language, code, stdin }: { language: string; code: string; stdin: string }) =>
-      executeCode(language, code, stdin),
+    mutationFn: (req: IRunCode) => runCode(req),
     onSuccess: (data) => {
       console.log("Execution Result:", data);
     },
@@ -13,5 +13,3 @@ export const useExecuteCode = () => {
     },
   });
 };
-
-
@@ -4,15 +4,20 @@ import {
   getUserStats, 
   getProblemStats, 
   listSubmissions,
-  getTopicStats
+  getTopicStats,
+  waitForVerdict
 } from '../services/auth/api/submission';
 import type { ICreateSubmission } from '@/types/submission/submission';
 
-export const useCreateSubmission = () => {
+// Resolves with the judged submission; onStatus sees the judge's progress.
+export const useCreateSubmission = (onStatus?: (status: string) => void) => {
   const queryClient = useQueryClient();
   
   return useMutation({
-    mutationFn: (submission: ICreateSubmission) => createSubmission(submission),
+    mutationFn: async (submission: ICreateSubmission) => {
+      const queued = await createSubmission(submission);
+      return waitForVerdict(queued.id, onStatus);
+    },
     onSuccess: () => {
       // Invalidate user stats and submissions to refetch
       queryClient.invalidateQueries({ queryKey: ['userStats'] });
@@ -58,7 +58,7 @@ const AdminProblems = () => {
     setFormData(prev => ({ ...prev, [name]: value }));
   };
 
-  const handleTestCaseChange = (index: number, field: keyof ITestCase, value: string) => {
+  const handleTestCaseChange = (index: number, field: keyof ITestCase, value: string | boolean) => {
     const newTestCases = [...formData.test_cases];
     newTestCases[index] = { ...newTestCases[index], [field]: value };
     setFormData(prev => ({ ...prev, test_cases: newTestCases }));
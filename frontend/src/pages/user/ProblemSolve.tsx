@@ -84,7 +84,9 @@ const ProblemSolve = () => {
   // };
 
   const executeMutation = useExecuteCode();
-  const createSubmissionMutation = useCreateSubmission();
+  const createSubmissionMutation = useCreateSubmission(status =>
+    setOutput(`${status.replace(/_/g, " ").toUpperCase()}...`)
+  );
   const { data: problemStats } = useProblemStats(data?.id || "");
   const { data: submissionsData } = useSubmissions(1, 20, { 
     problem_id: data?.id,
@@ -95,20 +97,27 @@ const ProblemSolve = () => {
     setIsRunning(true);
     setTestTab("OUTPUT");
     try {
+      // Runs in the judge's sandbox with the problem's limits; nothing is stored
       const result = await executeMutation.mutateAsync({
-        language,
+        problem_id: data?.id,
+        language: getApiLanguage(language),
         code,
         stdin: testCases[activeTestCase].input
       });
-      
-      // Check for errors in stderr
-      if (result.stderr && result.stderr.trim()) {
-        setOutput(`Test Case ${activeTestCase + 1} - Error:\n\n${result.stderr}\n\nOutput:\n${result.stdout || 'No output'}`);
+      const run = result.tests?.[0];
+
+      if (!run) {
+        setOutput(`${result.status.replace(/_/g, " ").toUpperCase()}\n\n${result.error_message || ''}`);
+      } else if (run.status !== "ok") {
+        setOutput(`Test Case ${activeTestCase + 1} - ${run.status.replace(/_/g, " ").toUpperCase()}:\n\n${run.stderr || run.checker_message || ''}\n\nOutput:\n${run.stdout || 'No output'}`);
+      } else if (run.stderr && run.stderr.trim()) {
+        setOutput(`Test Case ${activeTestCase + 1} - Error:\n\n${run.stderr}\n\nOutput:\n${run.stdout || 'No output'}`);
       } else {
-        setOutput(`Test Case ${activeTestCase + 1} Output:\n\n${result.stdout}\n\nExpected:\n${testCases[activeTestCase].expected}`);
+        setOutput(`Test Case ${activeTestCase + 1} Output:\n\n${run.stdout}\n\nExpected:\n${testCases[activeTestCase].expected}\n\nRuntime: ${run.execution_time}ms`);
       }
     } catch (error: any) {
-      setOutput("ERROR: " + error.message);
+      // The server answers errors with a plain message, e.g. when too many runs are in progress
+      setOutput("ERROR: " + (typeof error?.response?.data === "string" ? error.response.data : error.message));
     } finally {
       setIsRunning(false);
     }
@@ -124,55 +133,21 @@ const ProblemSolve = () => {
     setTestTab("OUTPUT");
     
     try {
-      let passedCount = 0;
-      let failedTestCase = -1;
-      let executionTime = 0;
-
-      // Run all test cases
-      for (let i = 0; i < testCases.length; i++) {
-        const startTime = performance.now();
-        const result = await executeMutation.mutateAsync({
-          language,
-          code,
-          stdin: testCases[i].input
-        });
-        const endTime = performance.now();
-        executionTime += (endTime - startTime);
-
-        // Check for runtime errors
-        if (result.stderr && result.stderr.trim()) {
-          throw new Error(`Runtime error in test case ${i + 1}: ${result.stderr}`);
-        }
-
-        const trimmedStdout = result.stdout.trim();
-        const trimmedExpected = testCases[i].expected.trim();
-        
-        if (trimmedStdout === trimmedExpected) {
-          passedCount++;
-        } else {
-          if (failedTestCase === -1) failedTestCase = i;
-        }
-      }
-
-      const totalTestCases = testCases.length;
-      const allPassed = passedCount === totalTestCases;
-      const status = allPassed ? SubmissionStatus.ACCEPTED : SubmissionStatus.WRONG_ANSWER;
-
-      // Create submission record
+      // The server queues the submission and judges it against every test case
       const submissionResult = await createSubmissionMutation.mutateAsync({
         problem_id: data.id,
         contest_id: isContestProblem ? contestId : null, // Include contest_id for contest submissions
         language: language === 'python' ? 'py' : language === 'javascript' ? 'js' : language,
         code,
-        status,
-        execution_time: Math.round(executionTime),
-        test_cases_passed: passedCount,
-        total_test_cases: totalTestCases,
       });
 
+      const passedCount = submissionResult?.test_cases_passed ?? 0;
+      const totalTestCases = submissionResult?.total_test_cases ?? 0;
+      const executionTime = submissionResult?.execution_time ?? 0;
+
       // Display result
-      if (allPassed) {
-        let resultMessage = `✓ ACCEPTED\n\nAll ${totalTestCases} test cases passed!\n\nExecution Time: ${Math.round(executionTime)}ms`;
+      if (submissionResult?.status === SubmissionStatus.ACCEPTED) {
+        let resultMessage = `✓ ACCEPTED\n\nAll ${totalTestCases} test cases passed!\n\nExecution Time: ${executionTime}ms`;
         
         // Show points for contest submissions
         if (isContestProblem && submissionResult?.points_earned !== undefined) {
@@ -181,22 +156,24 @@ const ProblemSolve = () => {
         
         setOutput(resultMessage);
       } else {
-        setOutput(`✗ WRONG ANSWER\n\nPassed: ${passedCount}/${totalTestCases} test cases\n\nFailed at test case ${failedTestCase + 1}`);
+        const verdict = (submissionResult?.status || "").replace(/_/g, " ").toUpperCase();
+        const failed = submissionResult?.test_results?.find(t => t.status !== SubmissionStatus.ACCEPTED);
+        let details = "";
+        if (failed) {
+          details += `\n\nFailed on test #${failed.index}${failed.hidden ? " (hidden)" : ""}`;
+          if (failed.checker_message) details += `\n${failed.checker_message}`;
+          if (!failed.hidden && failed.input !== undefined) details += `\n\nInput:\n${failed.input}`;
+        }
+        if (submissionResult?.subtasks?.length) {
+          details += "\n\nSubtasks:";
+          for (const s of submissionResult.subtasks) {
+            details += `\n  #${s.index}${s.name ? ` ${s.name}` : ""}: ${s.score}/${s.points} (${s.status.replace(/_/g, " ")})`;
+          }
+        }
+        if (submissionResult?.error_message) details += `\n\n${submissionResult.error_message}`;
+        setOutput(`✗ ${verdict}\n\nPassed: ${passedCount}/${totalTestCases} test cases${details}`);
       }
     } catch (error: any) {
-      // Save failed submission
-      if (data?.id) {
-        await createSubmissionMutation.mutateAsync({
-          problem_id: data.id,
-          contest_id: isContestProblem ? contestId : null, // Include contest_id for contest submissions
-          language: language === 'python' ? 'py' : language === 'javascript' ? 'js' : language,
-          code,
-          status: SubmissionStatus.RUNTIME_ERROR,
-          test_cases_passed: 0,
-          total_test_cases: testCases.length,
-          error_message: error.message,
-        });
-      }
       setOutput("ERROR: " + error.message);
     } finally {
       setIsRunning(false);
@@ -268,6 +245,11 @@ const ProblemSolve = () => {
               <span className={`px-2 py-1 text-[10px] tracking-widest border ${getDifficultyColor(data?.difficulty)}`}>
                 {data?.difficulty || 'UNKNOWN'}
               </span>
+              {data?.time_limit_ms !== undefined && (
+                <span className="px-2 py-1 text-[10px] tracking-widest border border-gray-600 text-gray-400">
+                  {data.time_limit_ms} MS · {data.memory_limit_mb} MB
+                </span>
+              )}
               {isContestProblem && (
                 <>
                   <span className="px-2 py-1 text-[10px] tracking-widest border-2 border-[#F7D046] bg-[#F7D046]/10 text-[#F7D046]">
@@ -6,6 +6,7 @@ import type {
   IContestParticipant,
   IContestLeaderboardEntry,
   IGlobalLeaderboardEntry,
+  IRatingHistoryEntry,
   ICreateContest,
   IAddProblemToContest,
   IContestsResponse,
@@ -119,8 +120,19 @@ export const getGlobalLeaderboard = async (limit: number = 100): Promise<IGlobal
   return resp?.data || resp || [];
 }
 
+export const getRatingHistory = async (userId: string): Promise<IRatingHistoryEntry[]> => {
+  const resp = await contestClient.get<{data: IRatingHistoryEntry[]}>(`/users/${userId}/rating-history`);
+  console.log("Fetched Rating History:", resp);
+  return resp?.data || resp || [];
+}
+
 // Admin APIs
 export const finalizeContestRankings = async (contestId: string): Promise<void> => {
   await contestClient.post(`/contests/${contestId}/finalize`);
   console.log("Finalized Contest Rankings:", contestId);
 }
+
+export const rollbackContestFinalization = async (contestId: string): Promise<void> => {
+  await contestClient.post(`/contests/${contestId}/rollback`);
+  console.log("Rolled Back Contest Finalization:", contestId);
+}
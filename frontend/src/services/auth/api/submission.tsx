@@ -6,7 +6,10 @@ import type {
   IUserStats, 
   IProblemStats,
   ITopicStats,
-  ISubmissionsResponse
+  ISubmissionsResponse,
+  ISubmissionStatus,
+  IRunCode,
+  IRunResult
 } from '@/types/submission/submission';
 
 export const submissionClient = new ApiClient(server);
@@ -23,6 +26,33 @@ export const getSubmissionById = async (id: string): Promise<ISubmission> => {
   return resp?.data || resp;
 }
 
+export const runCode = async (req: IRunCode): Promise<IRunResult> => {
+  const resp = await submissionClient.post<{data: IRunResult}>("/run", req);
+  return resp?.data || resp;
+}
+
+export const getSubmissionStatus = async (id: string): Promise<ISubmissionStatus> => {
+  const resp = await submissionClient.get<{data: ISubmissionStatus}>(`/submissions/${id}/status`);
+  return resp?.data || resp;
+}
+
+// Submissions are judged in the background; poll until the verdict is in
+// and return the full submission.
+export const waitForVerdict = async (
+  id: string,
+  onStatus?: (status: string) => void,
+  intervalMs: number = 1000
+): Promise<ISubmission> => {
+  for (;;) {
+    const status = await getSubmissionStatus(id);
+    onStatus?.(status.status);
+    if (!status.pending) {
+      return getSubmissionById(id);
+    }
+    await new Promise(resolve => setTimeout(resolve, intervalMs));
+  }
+}
+
 export const listSubmissions = async (
   page: number = 1, 
   pageSize: number = 20,
@@ -5,7 +5,12 @@ export interface IContest {
   start_time: string;
   end_time: string;
   is_rated: boolean;
-  is_finalized?: boolean;
+  scoring_mode?: ScoringMode;
+  scoring?: IScoringConfig | null;
+  status?: 'scheduled' | 'running' | 'ended' | 'finalizing' | 'finalized';
+  finalized_at?: string;
+  finalize_error?: string;
+  rolled_back_at?: string;
   participant_count?: number;
   participant_ids?: string[];
   created_at: string;
@@ -26,6 +31,7 @@ export interface IContestProblem {
   max_points: number;
   partial_credit: boolean;
   time_multiplier: number;
+  time_penalty_minutes?: number;
   created_at: string;
   updated_at: string;
 }
@@ -74,18 +80,43 @@ export interface IGlobalLeaderboardEntry {
   rank: number;
 }
 
+export interface IRatingHistoryEntry {
+  contest_id: string;
+  contest_name: string;
+  end_time: string;
+  rank: number;
+  old_rating: number;
+  new_rating: number;
+  rating_change: number;
+}
+
+export type ScoringMode = 'hybrid' | 'icpc' | 'ioi' | 'decaying';
+
 export interface ICreateContest {
   title: string;
   description: string;
   start_time: string;
   end_time: string;
   is_rated: boolean;
+  scoring_mode: ScoringMode;
 }
 
 export interface IAddProblemToContest {
   problem_title: string;
   max_points: number;
   time_penalty_minutes: number;
+  partial_credit?: boolean;
+  time_multiplier?: number;
+}
+
+export interface IScoringConfig {
+  time_penalty_per_min: number;
+  wrong_attempt_penalty: number;
+  partial_credit_min: number;
+  time_penalty_minutes: number;
+  decay_per_minute: number;
+  wrong_attempt_decay: number;
+  points_floor: number;
 }
 
 export interface IContestsResponse {
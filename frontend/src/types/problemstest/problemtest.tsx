@@ -5,16 +5,45 @@ export interface IProblemTest {
   description: string;
   tag: string;
   difficulty: "easy" | "medium" | "hard";
+  type?: "standard" | "interactive" | "function";
+  signature?: string; // function problems, e.g. "twoSum(nums: int[], target: int) -> int[]"
   test_cases: ITestCase[];
+  test_case_count?: number; // all tests; lists only include the samples
   boilerplates: IBoilerplate[];
+  time_limit_ms?: number;
+  memory_limit_mb?: number;
+  language_limits?: ILanguageLimit[];
+  checker_type?: string;
+  checker_abs_epsilon?: number;
+  checker_rel_epsilon?: number;
+  subtasks?: ISubtask[];
   acceptance?: string;
   status?: string | null;
 }
 
 
 export interface ITestCase {
+  id?: string;
   input: string;
   expected: string;
+  is_sample?: boolean; // hidden tests are only returned to admins
+  order_index?: number;
+  explanation?: string;
+  subtask?: number; // ISubtask.index, absent when the test is in no subtask
+}
+
+export interface ISubtask {
+  index: number;
+  name?: string;
+  points: number;
+  aggregation?: "all" | "min";
+  dependencies?: number[];
+}
+
+export interface ILanguageLimit {
+  language: string;
+  time_limit_ms?: number;
+  memory_limit_mb?: number;
 }
 
 export interface IBoilerplate {
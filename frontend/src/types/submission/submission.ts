@@ -13,20 +13,53 @@ export interface ISubmission {
   test_cases_passed: number;
   total_test_cases: number;
   points_earned?: number; // Points earned in contest submissions
+  error_message?: string;
   created_at: string;
+  code?: string;
+  test_results?: ISubmissionTestResult[]; // only on single-submission responses
+  subtasks?: ISubtaskResult[]; // only for problems with subtasks
+}
+
+export interface ISubtaskResult {
+  index: number;
+  name?: string;
+  status: string;
+  points: number;
+  score: number;
 }
 
+// Input, expected and program output are omitted for hidden tests.
+export interface ISubmissionTestResult {
+  test_case_id?: string;
+  index: number;
+  subtask?: number;
+  hidden: boolean;
+  status: string;
+  execution_time: number;
+  memory_used: number;
+  input?: string;
+  expected?: string;
+  stdout?: string;
+  stderr?: string;
+  checker_message?: string;
+}
+
+// Returned by /submissions/:id/status while the judge works on a submission.
+export interface ISubmissionStatus {
+  id: string;
+  status: string;
+  pending: boolean;
+  test_cases_passed: number;
+  total_test_cases: number;
+  points_earned: number;
+}
+
+// Verdict fields are decided by the server-side judge.
 export interface ICreateSubmission {
   problem_id: string;
   contest_id?: string | null; // Pass contest ID when solving contest problems
   language: string;
   code: string;
-  status: string;
-  execution_time?: number;
-  memory_used?: number;
-  test_cases_passed: number;
-  total_test_cases: number;
-  error_message?: string;
 }
 
 export interface IUserStats {
@@ -69,6 +102,40 @@ export const SubmissionStatus = {
   COMPILE_ERROR: "compile_error",
   TIME_LIMIT_EXCEEDED: "time_limit_exceeded",
   MEMORY_LIMIT_EXCEEDED: "memory_limit_exceeded",
+  JUDGE_ERROR: "judge_error",
+  // Not verdicts yet: the submission is waiting for or going through the judge
+  QUEUED: "queued",
+  COMPILING: "compiling",
+  RUNNING: "running",
 } as const;
 
 export type SubmissionStatusType = typeof SubmissionStatus[keyof typeof SubmissionStatus];
+
+// Runs code without creating a submission. Without stdin the code is judged
+// on the problem's sample tests.
+export interface IRunCode {
+  problem_id?: string;
+  language: string;
+  code: string;
+  stdin?: string;
+}
+
+export interface IRunTest {
+  index: number;
+  status: string; // "ok" for custom input, a verdict for samples
+  execution_time: number;
+  memory_used: number;
+  input?: string;
+  expected?: string;
+  stdout: string;
+  stderr: string;
+  checker_message?: string;
+}
+
+export interface IRunResult {
+  status: string;
+  execution_time: number;
+  memory_used: number;
+  error_message?: string;
+  tests: IRunTest[];
+}